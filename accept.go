@@ -0,0 +1,92 @@
+package jsonbody
+
+import (
+	"mime"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// acceptableTypes returns the media types that the middleware can respond with
+// when Accept header enforcement is enabled.
+func (m *middleware) acceptableTypes() []string {
+	types := append([]string{"application/json"}, m.acceptAlternates...)
+	if m.versions != nil {
+		for version := range m.versions.schemas {
+			types = append(types, VendorMediaType{Vendor: m.versions.vendor, Version: version}.String())
+		}
+		sort.Strings(types[1+len(m.acceptAlternates):])
+	}
+
+	return types
+}
+
+// acceptsAny reports whether the Accept header value accept allows a response
+// with any of the given media types. An empty header accepts anything.
+func acceptsAny(accept string, types []string) bool {
+	if strings.TrimSpace(accept) == "" {
+		return true
+	}
+
+	ranges := parseAccept(accept)
+	for _, typ := range types {
+		if acceptQuality(ranges, typ) > 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+type mediaRange struct {
+	typ     string // e.g. "application/*"
+	quality float64
+}
+
+func parseAccept(accept string) []mediaRange {
+	ranges := make([]mediaRange, 0)
+	for _, part := range strings.Split(accept, ",") {
+		typ, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+
+		quality := 1.0
+		if q, ok := params["q"]; ok {
+			if parsed, err := strconv.ParseFloat(q, 64); err == nil {
+				quality = parsed
+			}
+		}
+
+		ranges = append(ranges, mediaRange{typ: typ, quality: quality})
+	}
+
+	return ranges
+}
+
+// acceptQuality returns the quality the most specific of the ranges matching
+// typ assigns to it, or 0 if none match.
+func acceptQuality(ranges []mediaRange, typ string) float64 {
+	mainType := strings.SplitN(typ, "/", 2)[0]
+
+	quality, specificity := 0.0, -1
+	for _, r := range ranges {
+		var s int
+		switch r.typ {
+		case typ:
+			s = 2
+		case mainType + "/*":
+			s = 1
+		case "*/*":
+			s = 0
+		default:
+			continue
+		}
+
+		if s > specificity {
+			quality, specificity = r.quality, s
+		}
+	}
+
+	return quality
+}
@@ -0,0 +1,45 @@
+package jsonbody
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAcceptsAny(t *testing.T) {
+	json := []string{"application/json"}
+
+	assert.True(t, acceptsAny("", json))
+	assert.True(t, acceptsAny("application/json", json))
+	assert.True(t, acceptsAny("text/html, application/*;q=0.5", json))
+	assert.True(t, acceptsAny("*/*", json))
+	assert.False(t, acceptsAny("text/html", json))
+	assert.False(t, acceptsAny("application/json;q=0, */*", json))
+	assert.False(t, acceptsAny("application/xml", json))
+	assert.True(t, acceptsAny("application/x-ndjson", []string{"application/json", "application/x-ndjson"}))
+}
+
+func TestServeHTTPEnforcesAcceptHeader(t *testing.T) {
+	called := false
+	handler := NewMiddleware("", WithAcceptEnforcement("application/x-ndjson"))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept", "text/html")
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, req)
+
+	assert.Equal(t, 406, recorder.Code)
+	assert.Equal(t, `{"errors":["the response can only be sent as application/json, application/x-ndjson"]}`, recorder.Body.String())
+	assert.False(t, called)
+
+	req.Header.Set("Accept", "application/x-ndjson")
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, req)
+
+	assert.Equal(t, 200, recorder.Code)
+	assert.True(t, called)
+}
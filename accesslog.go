@@ -0,0 +1,114 @@
+package jsonbody
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// accessLogEntry is a line of the access log written by the middleware when it
+// was created with WithAccessLog.
+type accessLogEntry struct {
+	Time          string  `json:"time"`
+	Method        string  `json:"method"`
+	Path          string  `json:"path"`
+	RemoteAddr    string  `json:"remote_addr"`
+	Status        int     `json:"status"`
+	RequestBytes  int64   `json:"request_bytes"`
+	ResponseBytes int64   `json:"response_bytes"`
+	DurationMS    float64 `json:"duration_ms"`
+	Schema        string  `json:"schema,omitempty"`
+
+	start time.Time
+}
+
+// setSchema records the name of the schema the request was validated against.
+// It does nothing on a nil *accessLogEntry.
+func (e *accessLogEntry) setSchema(name string) {
+	if e != nil {
+		e.Schema = name
+	}
+}
+
+// setRequestBytes records the size of the request body. It does nothing on a
+// nil *accessLogEntry.
+func (e *accessLogEntry) setRequestBytes(n int64) {
+	if e != nil {
+		e.RequestBytes = n
+	}
+}
+
+// accessLogger writes one JSON line per request to out.
+type accessLogger struct {
+	mu  sync.Mutex
+	out io.Writer
+}
+
+func (l *accessLogger) begin(r *http.Request) *accessLogEntry {
+	start := time.Now()
+	return &accessLogEntry{
+		Time:         start.UTC().Format(time.RFC3339Nano),
+		Method:       r.Method,
+		Path:         r.URL.Path,
+		RemoteAddr:   r.RemoteAddr,
+		RequestBytes: r.ContentLength,
+		start:        start,
+	}
+}
+
+func (l *accessLogger) finish(e *accessLogEntry, stats *statsWriter) {
+	e.Status = stats.status
+	if e.Status == 0 {
+		e.Status = http.StatusOK // what net/http sends if the handler writes nothing
+	}
+	e.ResponseBytes = stats.bytes
+	e.DurationMS = float64(time.Since(e.start)) / float64(time.Millisecond)
+	if e.RequestBytes < 0 {
+		e.RequestBytes = 0
+	}
+
+	line, err := json.Marshal(e)
+	if err != nil {
+		log.Printf("jsonbody: failed to encode access log entry: %v\n", err)
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, err := l.out.Write(append(line, '\n')); err != nil {
+		log.Printf("jsonbody: failed to write access log entry: %v\n", err)
+	}
+}
+
+// statsWriter records the status code and number of body bytes of the response
+// written through it.
+type statsWriter struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+func (sw *statsWriter) WriteHeader(statusCode int) {
+	if sw.status == 0 && (statusCode < 100 || statusCode > 199) {
+		sw.status = statusCode
+	}
+	sw.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (sw *statsWriter) Write(b []byte) (int, error) {
+	if sw.status == 0 {
+		sw.status = http.StatusOK
+	}
+
+	n, err := sw.ResponseWriter.Write(b)
+	sw.bytes += int64(n)
+	return n, err
+}
+
+// Unwrap returns the underlying http.ResponseWriter for http.ResponseController.
+func (sw *statsWriter) Unwrap() http.ResponseWriter {
+	return sw.ResponseWriter
+}
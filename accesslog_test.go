@@ -0,0 +1,58 @@
+package jsonbody
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAccessLogWritesOneLinePerRequest(t *testing.T) {
+	var out bytes.Buffer
+	handler := NewMiddleware(`{"$schemaName": "createPost", "title": ""}`, WithAccessLog(&out))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writer := w.(Writer)
+		writer.WriteJSON(http.StatusCreated, map[string]int{"id": 1})
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/posts", strings.NewReader(`{"title": "hi"}`))
+	req.Header.Set("Content-Type", "application/json")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	req = httptest.NewRequest(http.MethodPost, "/posts", strings.NewReader(`{}`))
+	req.Header.Set("Content-Type", "application/json")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	assert.Equal(t, 2, len(lines))
+
+	var entry map[string]interface{}
+	assert.Nil(t, json.Unmarshal([]byte(lines[0]), &entry))
+	assert.Equal(t, "POST", entry["method"])
+	assert.Equal(t, "/posts", entry["path"])
+	assert.Equal(t, float64(201), entry["status"])
+	assert.Equal(t, float64(15), entry["request_bytes"])
+	assert.Equal(t, float64(8), entry["response_bytes"])
+	assert.Equal(t, "createPost", entry["schema"])
+	assert.Contains(t, entry, "duration_ms")
+	assert.Contains(t, entry, "time")
+
+	assert.Nil(t, json.Unmarshal([]byte(lines[1]), &entry))
+	assert.Equal(t, float64(400), entry["status"])
+}
+
+func TestAccessLogDefaultsStatusTo200(t *testing.T) {
+	var out bytes.Buffer
+	handler := NewMiddleware("", WithAccessLog(&out))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	var entry map[string]interface{}
+	assert.Nil(t, json.Unmarshal(out.Bytes(), &entry))
+	assert.Equal(t, float64(200), entry["status"])
+	assert.Equal(t, float64(0), entry["request_bytes"])
+	assert.NotContains(t, entry, "schema")
+}
@@ -0,0 +1,130 @@
+package jsonbody
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const schemaAliasesKey = "$aliases"
+
+// extractAliases removes the "$aliases" key from the top level of the schema and
+// returns its value, which maps the paths of deprecated keys (e.g.
+// "author.userName", without question marks) to the names that replace them.
+func extractAliases(schema map[string]interface{}) (map[string]string, error) {
+	val, ok := schema[schemaAliasesKey]
+	if !ok {
+		return nil, nil
+	}
+	delete(schema, schemaAliasesKey)
+
+	obj, ok := val.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("jsonbody: value for schema key '%v' must be an object", schemaAliasesKey)
+	}
+
+	aliases := make(map[string]string, len(obj))
+	for path, name := range obj {
+		str, ok := name.(string)
+		if !ok || str == "" || strings.ContainsAny(str, ".[") {
+			return nil, fmt.Errorf("jsonbody: alias for '%v' in schema must be a key name", path)
+		}
+		aliases[path] = str
+	}
+
+	return aliases, nil
+}
+
+// applyAliases renames the deprecated keys in body to the names that replace
+// them, returning a CodeDeprecatedKey error for each alias that was used. If a
+// body contains both a deprecated key and its replacement, the deprecated key
+// is removed. The errors have SeverityWarn unless the schema's "$severity" key
+// sets a severity for the alias's path.
+func (s *Schema) applyAliases(body interface{}) []ValidationError {
+	errs := make([]ValidationError, 0)
+	if s == nil || body == nil {
+		return errs
+	}
+
+	for _, part := range s.allOf {
+		errs = append(errs, part.applyAliases(body)...)
+	}
+
+	paths := make([]string, 0, len(s.aliases))
+	for path := range s.aliases {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		parent, key := "", path
+		if i := strings.LastIndexAny(path, ".]"); i >= 0 {
+			parent, key = path[:i+1], strings.TrimPrefix(path[i+1:], ".")
+			parent = strings.TrimSuffix(parent, ".")
+		}
+
+		used := false
+		for _, obj := range objectsAt(body, parent) {
+			val, ok := obj[key]
+			if !ok {
+				continue
+			}
+
+			used = true
+			delete(obj, key)
+			if _, ok := obj[s.aliases[path]]; !ok {
+				obj[s.aliases[path]] = val
+			}
+		}
+
+		if used {
+			severity, ok := s.severities[path]
+			if !ok {
+				severity = SeverityWarn
+			}
+
+			errs = append(errs, ValidationError{
+				Key:      path,
+				Code:     CodeDeprecatedKey,
+				Params:   map[string]string{"replacement": s.aliases[path]},
+				Severity: severity,
+			})
+		}
+	}
+
+	return errs
+}
+
+// objectsAt returns the objects in val at path, in which "[]" refers to every
+// element of an array, e.g. "items[].author".
+func objectsAt(val interface{}, path string) []map[string]interface{} {
+	if path == "" {
+		if obj, ok := val.(map[string]interface{}); ok {
+			return []map[string]interface{}{obj}
+		}
+		return nil
+	}
+
+	if strings.HasPrefix(path, "[]") {
+		arr, _ := val.([]interface{})
+		rest := strings.TrimPrefix(strings.TrimPrefix(path, "[]"), ".")
+
+		var objs []map[string]interface{}
+		for _, elem := range arr {
+			objs = append(objs, objectsAt(elem, rest)...)
+		}
+		return objs
+	}
+
+	obj, ok := val.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+
+	key, rest := path, ""
+	if i := strings.IndexAny(path, ".["); i >= 0 {
+		key, rest = path[:i], strings.TrimPrefix(path[i:], ".")
+	}
+
+	return objectsAt(obj[key], rest)
+}
@@ -0,0 +1,107 @@
+package jsonbody
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApplyAliasesRenamesDeprecatedKeys(t *testing.T) {
+	schema := MustParseSchema(`{
+		"$aliases": {"userName": "username", "author.fullName": "name", "tags[].label": "name"},
+		"username": "",
+		"author": {"name": ""},
+		"tags": [{"name": ""}]
+	}`)
+
+	body := map[string]interface{}{
+		"userName": "a",
+		"author":   map[string]interface{}{"fullName": "b"},
+		"tags": []interface{}{
+			map[string]interface{}{"label": "c"},
+			map[string]interface{}{"name": "d"},
+		},
+	}
+
+	errs := schema.applyAliases(body)
+	assert.Equal(t, []ValidationError{
+		{Key: "author.fullName", Code: CodeDeprecatedKey, Params: map[string]string{"replacement": "name"}, Severity: SeverityWarn},
+		{Key: "tags[].label", Code: CodeDeprecatedKey, Params: map[string]string{"replacement": "name"}, Severity: SeverityWarn},
+		{Key: "userName", Code: CodeDeprecatedKey, Params: map[string]string{"replacement": "username"}, Severity: SeverityWarn},
+	}, errs)
+
+	assert.Equal(t, map[string]interface{}{
+		"username": "a",
+		"author":   map[string]interface{}{"name": "b"},
+		"tags": []interface{}{
+			map[string]interface{}{"name": "c"},
+			map[string]interface{}{"name": "d"},
+		},
+	}, body)
+	assert.Empty(t, schema.Validate(context.Background(), body))
+}
+
+func TestApplyAliasesPrefersReplacement(t *testing.T) {
+	schema := MustParseSchema(`{"$aliases": {"userName": "username"}, "username": ""}`)
+
+	body := map[string]interface{}{"userName": "old", "username": "new"}
+	errs := schema.applyAliases(body)
+
+	assert.Len(t, errs, 1)
+	assert.Equal(t, map[string]interface{}{"username": "new"}, body)
+}
+
+func TestApplyAliasesIgnoresUnusedAliases(t *testing.T) {
+	schema := MustParseSchema(`{"$aliases": {"userName": "username"}, "username": ""}`)
+
+	body := map[string]interface{}{"username": "a"}
+	assert.Empty(t, schema.applyAliases(body))
+	assert.Equal(t, map[string]interface{}{"username": "a"}, body)
+}
+
+func TestParseSchemaRejectsInvalidAliases(t *testing.T) {
+	for _, schema := range []string{
+		`{"$aliases": "userName", "username": ""}`,
+		`{"$aliases": {"userName": 1}, "username": ""}`,
+		`{"$aliases": {"userName": ""}, "username": ""}`,
+		`{"$aliases": {"author.fullName": "author.name"}, "author": {"name": ""}}`,
+	} {
+		_, err := ParseSchema(schema)
+		assert.NotNil(t, err, schema)
+	}
+}
+
+func TestServeHTTPWarnsAboutDeprecatedKeys(t *testing.T) {
+	for _, tc := range []struct {
+		schema  string
+		warning string
+	}{
+		{`{"$aliases": {"userName": "username"}, "username": ""}`, `199 - "key 'userName' is deprecated; use 'username' instead"`},
+		{`{"$aliases": {"userName": "username"}, "$severity": {"userName": "info"}, "username": ""}`, ""},
+	} {
+		var got map[string]interface{}
+		handler := NewMiddleware(tc.schema)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			got = r.Body.(Reader).JSON()
+		}))
+
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"userName": "a"}`))
+		req.Header.Set("Content-Type", "application/json")
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+
+		assert.Equal(t, http.StatusOK, recorder.Code, tc.schema)
+		assert.Equal(t, tc.warning, recorder.Header().Get("Warning"), tc.schema)
+		assert.Equal(t, map[string]interface{}{"username": "a"}, got, tc.schema)
+	}
+}
+
+func TestExtendSchemaKeepsAliases(t *testing.T) {
+	base := MustParseSchema(`{"$aliases": {"userName": "username"}, "username": ""}`)
+	extended, err := ExtendSchema(base, `{"bio": ""}`)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"userName": "username"}, extended.aliases)
+}
@@ -0,0 +1,64 @@
+package jsonbody
+
+import "net/url"
+
+// AllOf combines schemas into a single schema that a request must satisfy all
+// of, e.g. an envelope schema shared by every endpoint and an endpoint-specific
+// payload schema. The errors from each schema are reported together, in the
+// order the schemas are given, with duplicates removed. The combined schema's
+// name and error code prefix are those of the first schema that has one. nil
+// schemas accept any request, so they are ignored.
+func AllOf(schemas ...*Schema) *Schema {
+	parts := make([]*Schema, 0, len(schemas))
+	for _, s := range schemas {
+		if s == nil {
+			continue
+		}
+
+		if len(s.allOf) > 0 {
+			parts = append(parts, s.allOf...)
+		} else {
+			parts = append(parts, s)
+		}
+	}
+
+	switch len(parts) {
+	case 0:
+		return nil
+	case 1:
+		return parts[0]
+	}
+
+	combined := &Schema{allOf: parts}
+	for _, s := range parts {
+		if combined.meta.name == "" {
+			combined.meta.name = s.meta.name
+			combined.meta.description = s.meta.description
+		}
+		if combined.meta.errorCodePrefix == "" {
+			combined.meta.errorCodePrefix = s.meta.errorCodePrefix
+		}
+	}
+
+	return combined
+}
+
+// validateAllOf validates the request against each of the schemas combined by
+// AllOf.
+func (s *Schema) validateAllOf(order ErrorOrder, body interface{}, query url.Values) []ValidationError {
+	errs := make([]ValidationError, 0)
+	seen := make(map[string]bool)
+	for _, part := range s.allOf {
+		for _, e := range part.validate(order, body, query) {
+			id := e.Code + "\x00" + e.Error()
+			if seen[id] {
+				continue
+			}
+
+			seen[id] = true
+			errs = append(errs, e)
+		}
+	}
+
+	return errs
+}
@@ -0,0 +1,65 @@
+package jsonbody
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestAllOfIgnoresNilSchemas(t *testing.T) {
+	s := MustParseSchema(`{"a": ""}`)
+	assert.Nil(t, AllOf(nil, nil))
+	assert.Equal(t, s, AllOf(nil, s))
+}
+
+func TestAllOfMergesErrorsWithoutDuplicates(t *testing.T) {
+	envelope := MustParseSchema(`{"$schemaName": "Envelope", "requestId": "", "data": {}}`)
+	payload := MustParseSchema(`{"$errorCodePrefix": "POSTS_", "requestId": "", "data": {"title": ""}}`)
+	combined := AllOf(envelope, payload)
+
+	errs := combined.validate(OrderAlphabetical, map[string]interface{}{"data": map[string]interface{}{}}, nil)
+	assert.Equal(t, []string{
+		"expected key 'requestId' missing",
+		"expected key 'data.title' missing",
+	}, errorMessages(errs))
+	assert.Equal(t, "Envelope", combined.Name())
+	assert.Equal(t, "POSTS_", combined.ErrorCodePrefix())
+}
+
+func TestAllOfAcceptsAnyBodyOnlyIfAllDo(t *testing.T) {
+	query := MustParseSchema(`{"$query": {"page": 0}}`)
+	assert.True(t, AllOf(query, MustParseSchema(`{"$query": {"?sort": ""}}`)).acceptsAnyBody())
+	assert.False(t, AllOf(query, MustParseSchema(`{}`)).acceptsAnyBody())
+}
+
+func TestExtendSchemaReturnsErrIfAllOf(t *testing.T) {
+	_, err := ExtendSchema(AllOf(MustParseSchema(`{"a": ""}`), MustParseSchema(`{"b": ""}`)), `{"c": ""}`)
+	assert.NotNil(t, err)
+}
+
+func TestServeHTTPValidatesAllOf(t *testing.T) {
+	next := &mockHandler{}
+	next.On("ServeHTTP", mock.Anything, mock.Anything).Return()
+	handler := NewMiddleware(`{"title": ""}`, WithAllOf(`{"requestId": ""}`))(next)
+
+	recorder := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{}`))
+	r.Header.Set("Content-Type", "application/json")
+	handler.ServeHTTP(recorder, r)
+
+	assert.Equal(t, 400, recorder.Code)
+	assert.Equal(t, `{"errors":["expected key 'title' missing","expected key 'requestId' missing"]}`, recorder.Body.String())
+}
+
+func TestParseBundleCombinesArrayOfSchemas(t *testing.T) {
+	routes, err := parseBundle(strings.NewReader(`{"POST /posts": [{"requestId": ""}, {"title": ""}]}`))
+	assert.Nil(t, err)
+
+	schema, _ := routes.match("POST", "/posts")
+	errs := schema.validate(OrderAlphabetical, map[string]interface{}{}, nil)
+	assert.Equal(t, 2, len(errs))
+}
@@ -0,0 +1,87 @@
+package jsonbody
+
+import "strings"
+
+// Anonymize returns a copy of the decoded body with every value replaced by a
+// placeholder of the same type ("" for strings, 0 for numbers, and false for
+// booleans), so that rejected or sampled bodies can be sent to analytics or
+// debugging systems without exposing user data. The structure is kept intact:
+// objects keep their keys, arrays keep their length, and nulls stay null.
+//
+// Values that the schema limits to a fixed set with "$enum" are kept, since they
+// can't contain user data and are often what the analysis is about (e.g. which
+// statuses are requested most). If schema is nil, all values are replaced.
+func Anonymize(body map[string]interface{}, schema *Schema) map[string]interface{} {
+	if body == nil {
+		return nil
+	}
+
+	var expected []map[string]interface{}
+	for _, val := range schema.values() {
+		if obj, ok := val.(map[string]interface{}); ok {
+			expected = append(expected, obj)
+		}
+	}
+
+	return anonymizeObject(expected, body)
+}
+
+func anonymizeObject(expected []map[string]interface{}, actual map[string]interface{}) map[string]interface{} {
+	anonymized := make(map[string]interface{}, len(actual))
+	for k, v := range actual {
+		var nested []interface{}
+		for _, obj := range expected {
+			for schemaKey, val := range obj {
+				if strings.TrimPrefix(schemaKey, "?") == k {
+					nested = append(nested, val)
+				}
+			}
+		}
+
+		anonymized[k] = anonymizeValue(nested, v)
+	}
+
+	return anonymized
+}
+
+// anonymizeValue anonymizes actual, the value for a key whose schema values are
+// expected.
+func anonymizeValue(expected []interface{}, actual interface{}) interface{} {
+	for _, val := range expected {
+		if c, ok := val.(*constraint); ok && c.enum != nil && c.allows(actual) {
+			return actual
+		}
+	}
+
+	switch actual := actual.(type) {
+	case string:
+		return ""
+	case float64:
+		return 0.0
+	case bool:
+		return false
+	case map[string]interface{}:
+		objs := make([]map[string]interface{}, 0, len(expected))
+		for _, val := range expected {
+			if obj, ok := val.(map[string]interface{}); ok {
+				objs = append(objs, obj)
+			}
+		}
+		return anonymizeObject(objs, actual)
+	case []interface{}:
+		elems := make([]interface{}, 0, len(expected))
+		for _, val := range expected {
+			if arr, ok := val.([]interface{}); ok && len(arr) > 0 {
+				elems = append(elems, arr[0])
+			}
+		}
+
+		anonymized := make([]interface{}, len(actual))
+		for i, elem := range actual {
+			anonymized[i] = anonymizeValue(elems, elem)
+		}
+		return anonymized
+	}
+
+	return actual // null
+}
@@ -0,0 +1,26 @@
+package jsonbody
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAnonymizeReplacesValuesKeepingStructure(t *testing.T) {
+	body := decodeObject(t, `{"email": "a@b.c", "age": 30, "admin": true, "nick": null, "tags": ["x", 1], "author": {"name": "n"}}`)
+
+	anonymized, err := json.Marshal(Anonymize(body, nil))
+	assert.Nil(t, err)
+	assert.JSONEq(t, `{"email": "", "age": 0, "admin": false, "nick": null, "tags": ["", 0], "author": {"name": ""}}`, string(anonymized))
+	assert.Equal(t, "a@b.c", body["email"])
+}
+
+func TestAnonymizeKeepsEnumValues(t *testing.T) {
+	schema := MustParseSchema(`{"status": {"$enum": ["draft", "published"]}, "items": [{"kind": {"$enum": ["a", "b"]}, "note": ""}]}`)
+	body := decodeObject(t, `{"status": "draft", "items": [{"kind": "a", "note": "secret"}, {"kind": "zzz"}]}`)
+
+	anonymized, err := json.Marshal(Anonymize(body, schema))
+	assert.Nil(t, err)
+	assert.JSONEq(t, `{"status": "draft", "items": [{"kind": "a", "note": ""}, {"kind": ""}]}`, string(anonymized))
+}
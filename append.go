@@ -0,0 +1,95 @@
+package jsonbody
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+)
+
+// appendState is shared by the copies of a Writer so that the array started by
+// AppendJSON can be closed once the handler returns.
+type appendState struct {
+	mu      sync.Mutex
+	started bool
+	closed  bool
+}
+
+// AppendJSON encodes v as JSON and sends it as the next element of a top-level
+// JSON array in the response body, allowing large responses to be composed
+// piece by piece rather than built in memory and passed to WriteJSON. The first
+// call sends statusCode, the headers, and the opening bracket; statusCode is
+// ignored by later calls. The closing bracket is sent when the handler returns.
+//
+// AppendJSON is only available if the middleware was created with
+// WithAppendJSON, since writing more than once is usually a bug. It can't be
+// combined with WriteJSON, WriteErrors, or WriteNDJSONStream, and responses
+// written with it aren't signed.
+func (w *Writer) AppendJSON(statusCode int, v interface{}) error {
+	if w.appended == nil {
+		return errors.New("AppendJSON is not enabled; see WithAppendJSON")
+	}
+
+	w.appended.mu.Lock()
+	defer w.appended.mu.Unlock()
+
+	if w.appended.closed {
+		return errors.New("the response has already been completed")
+	}
+
+	if !w.appended.started && w.written {
+		return errors.New("method cannot be called after the response has been written")
+	}
+
+	w.writes.beginJSON()
+	defer w.writes.endJSON()
+
+	element, err := w.encode(statusCode, v)
+	if err != nil {
+		log.Println(fmt.Errorf("jsonbody: failed to encode body: %v", err))
+		return errors.New("encoding the response body as JSON failed")
+	}
+
+	var chunk []byte
+	if !w.appended.started {
+		w.setDefaultHeaders()
+		w.Header().Set("Content-Type", w.jsonContentType())
+		w.Header().Del("Content-Length")
+		w.WriteHeader(statusCode)
+		w.appended.started = true
+		w.written = true
+		chunk = append([]byte{'['}, element...)
+	} else {
+		chunk = append([]byte{','}, element...)
+	}
+
+	if _, err := w.Write(chunk); err != nil {
+		log.Println(fmt.Errorf("jsonbody: failed to write body: %v", err))
+		return errors.New("sending the response body failed")
+	}
+
+	return nil
+}
+
+// finishAppend sends the closing bracket of the array started by AppendJSON, if
+// there is one.
+func (w Writer) finishAppend() {
+	if w.appended == nil {
+		return
+	}
+
+	w.appended.mu.Lock()
+	defer w.appended.mu.Unlock()
+
+	if !w.appended.started || w.appended.closed {
+		return
+	}
+	w.appended.closed = true
+
+	w.writes.beginJSON()
+	defer w.writes.endJSON()
+
+	if _, err := w.Write([]byte{']'}); err != nil {
+		log.Println(fmt.Errorf("jsonbody: failed to write body: %v", err))
+	}
+}
@@ -0,0 +1,69 @@
+package jsonbody
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAppendJSONComposesArray(t *testing.T) {
+	var errAfter error
+	handler := NewMiddleware("", WithAppendJSON())(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writer := w.(Writer)
+		for i := 0; i < 3; i++ {
+			assert.Nil(t, writer.AppendJSON(http.StatusOK, map[string]int{"i": i}))
+		}
+		errAfter = writer.WriteJSON(http.StatusOK, "x")
+	}))
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	assert.Equal(t, 200, recorder.Code)
+	assert.Equal(t, "application/json", recorder.Header().Get("Content-Type"))
+	assert.Equal(t, `[{"i":0},{"i":1},{"i":2}]`, recorder.Body.String())
+	assert.NotNil(t, errAfter)
+}
+
+func TestAppendJSONRequiresOption(t *testing.T) {
+	var err error
+	handler := NewMiddleware("")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writer := w.(Writer)
+		err = writer.AppendJSON(http.StatusOK, 1)
+	}))
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	assert.NotNil(t, err)
+	assert.Equal(t, "", recorder.Body.String())
+}
+
+func TestAppendJSONAfterWriteJSON(t *testing.T) {
+	var err error
+	handler := NewMiddleware("", WithAppendJSON())(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writer := w.(Writer)
+		writer.WriteJSON(http.StatusOK, map[string]int{"a": 1})
+		err = writer.AppendJSON(http.StatusOK, 1)
+	}))
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	assert.NotNil(t, err)
+	assert.Equal(t, `{"a":1}`, recorder.Body.String())
+}
+
+func TestAppendJSONWithWriteDiagnostics(t *testing.T) {
+	handler := NewMiddleware("", WithAppendJSON(), WithWriteDiagnostics(true))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writer := w.(Writer)
+		writer.AppendJSON(http.StatusOK, 1)
+		writer.AppendJSON(http.StatusOK, 2)
+	}))
+
+	recorder := httptest.NewRecorder()
+	assert.NotPanics(t, func() { handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil)) })
+	assert.Equal(t, `[1,2]`, recorder.Body.String())
+}
@@ -0,0 +1,108 @@
+package jsonbody
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// AsyncValidator checks a single value from the request body, typically by
+// performing I/O, e.g. checking that an ID exists in a database. It should
+// return an error describing why the value is invalid, or nil if it is valid,
+// and should stop early if ctx is done.
+type AsyncValidator func(ctx context.Context, value interface{}) error
+
+type asyncValidatorEntry struct {
+	path     string
+	validate AsyncValidator
+}
+
+// defaultAsyncConcurrency is the number of validators run at once for each
+// request if WithAsyncValidation doesn't set a limit.
+const defaultAsyncConcurrency = 8
+
+type asyncConfig struct {
+	validators  []asyncValidatorEntry
+	timeout     time.Duration
+	concurrency int
+}
+
+// run calls the registered validators for every value in body that they apply
+// to, returning the errors they report in the order the validators were
+// registered. timedOut reports whether any of the validators timed out.
+func (c *asyncConfig) run(ctx context.Context, body interface{}) (errs []ValidationError, timedOut bool) {
+	type job struct {
+		key      string
+		value    interface{}
+		validate AsyncValidator
+	}
+
+	jobs := make([]job, 0)
+	for _, v := range c.validators {
+		for _, match := range matchPath(body, v.path) {
+			jobs = append(jobs, job{key: match.key, value: match.value, validate: v.validate})
+		}
+	}
+
+	if len(jobs) == 0 {
+		return []ValidationError{}, false
+	}
+
+	if c.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+
+	concurrency := c.concurrency
+	if concurrency <= 0 {
+		concurrency = defaultAsyncConcurrency
+	}
+	if concurrency > len(jobs) {
+		concurrency = len(jobs)
+	}
+
+	// a fixed number of workers run the jobs, so that bodies with many values
+	// to validate don't start a goroutine for each one
+	next := make(chan int)
+	go func() {
+		defer close(next)
+		for i := range jobs {
+			next <- i
+		}
+	}()
+
+	results := make([]error, len(jobs))
+	var wg sync.WaitGroup
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for i := range next {
+				if err := ctx.Err(); err != nil {
+					results[i] = err
+					continue
+				}
+
+				results[i] = jobs[i].validate(ctx, jobs[i].value)
+			}
+		}()
+	}
+	wg.Wait()
+
+	errs = make([]ValidationError, 0)
+	for i, err := range results {
+		switch {
+		case err == nil:
+		case errors.Is(err, context.DeadlineExceeded):
+			timedOut = true
+			errs = append(errs, ValidationError{Key: jobs[i].key, Code: CodeValidationTimeout})
+		default:
+			errs = append(errs, ValidationError{Key: jobs[i].key, Code: CodeInvalidValue, Params: map[string]string{"reason": err.Error()}})
+		}
+	}
+
+	return errs, timedOut
+}
@@ -0,0 +1,111 @@
+package jsonbody
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestMatchPathExpandsArrays(t *testing.T) {
+	body := bodyMap(`{"items": [{"id": 1}, {"id": 2}, {}], "id": 3}`)
+
+	assert.Equal(t, []pathMatch{
+		{key: "items[0].id", value: float64(1)},
+		{key: "items[1].id", value: float64(2)},
+	}, matchPath(body, "items[].id"))
+	assert.Equal(t, []pathMatch{{key: "id", value: float64(3)}}, matchPath(body, "id"))
+	assert.Equal(t, 0, len(matchPath(body, "missing")))
+
+	arr := []interface{}{bodyMap(`{"id": 1}`), bodyMap(`{"tags": ["a"]}`)}
+	assert.Equal(t, []pathMatch{{key: "[0].id", value: float64(1)}}, matchPath(arr, "[].id"))
+	assert.Equal(t, []pathMatch{{key: "[1].tags[0]", value: "a"}}, matchPath(arr, "[].tags[]"))
+}
+
+func TestAsyncConfigRunMergesErrorsInOrder(t *testing.T) {
+	c := asyncConfig{validators: []asyncValidatorEntry{
+		{path: "ids[]", validate: func(ctx context.Context, v interface{}) error {
+			if v.(float64) > 1 {
+				return errors.New("does not exist")
+			}
+			return nil
+		}},
+		{path: "name", validate: func(ctx context.Context, v interface{}) error {
+			return errors.New("is taken")
+		}},
+	}}
+
+	errs, timedOut := c.run(context.Background(), bodyMap(`{"ids": [1, 2, 3], "name": "x"}`))
+	assert.False(t, timedOut)
+	assert.Equal(t, []string{
+		"value for key 'ids[1]' is invalid: does not exist",
+		"value for key 'ids[2]' is invalid: does not exist",
+		"value for key 'name' is invalid: is taken",
+	}, errorMessages(errs))
+}
+
+func TestAsyncConfigRunLimitsConcurrencyByDefault(t *testing.T) {
+	var mu sync.Mutex
+	running, maxRunning := 0, 0
+	c := asyncConfig{validators: []asyncValidatorEntry{{path: "ids[]", validate: func(ctx context.Context, v interface{}) error {
+		mu.Lock()
+		running++
+		if running > maxRunning {
+			maxRunning = running
+		}
+		mu.Unlock()
+
+		time.Sleep(time.Millisecond)
+
+		mu.Lock()
+		running--
+		mu.Unlock()
+		return nil
+	}}}}
+
+	ids := make([]string, 50)
+	for i := range ids {
+		ids[i] = fmt.Sprint(i)
+	}
+
+	errs, _ := c.run(context.Background(), bodyMap(`{"ids": [`+strings.Join(ids, ", ")+`]}`))
+	assert.Empty(t, errs)
+	assert.True(t, maxRunning <= defaultAsyncConcurrency, maxRunning)
+}
+
+func TestAsyncConfigRunReportsTimeouts(t *testing.T) {
+	c := asyncConfig{
+		timeout: time.Millisecond,
+		validators: []asyncValidatorEntry{{path: "id", validate: func(ctx context.Context, v interface{}) error {
+			<-ctx.Done()
+			return ctx.Err()
+		}}},
+	}
+
+	errs, timedOut := c.run(context.Background(), bodyMap(`{"id": 1}`))
+	assert.True(t, timedOut)
+	assert.Equal(t, []string{"validation of value for key 'id' timed out"}, errorMessages(errs))
+}
+
+func TestServeHTTPSends400IfAsyncValidatorFails(t *testing.T) {
+	next := &mockHandler{}
+	next.On("ServeHTTP", mock.Anything, mock.Anything).Return()
+	handler := NewMiddleware("", WithAsyncValidator("categoryId", func(ctx context.Context, v interface{}) error {
+		return errors.New("does not exist")
+	}))(next)
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"categoryId": 7}`)))
+
+	assert.Equal(t, 400, recorder.Code)
+	assert.Equal(t, `{"errors":["value for key 'categoryId' is invalid: does not exist"]}`, recorder.Body.String())
+	next.AssertNotCalled(t, "ServeHTTP", mock.Anything, mock.Anything)
+}
@@ -0,0 +1,443 @@
+package jsonbody
+
+import (
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"reflect"
+	"strings"
+)
+
+// BindMap stores the request body in the value pointed to by target, which is
+// typically a pointer to a struct. Unlike decoding the raw body with
+// encoding/json, BindMap converts the map already produced by the middleware
+// directly, so the body is not parsed a second time.
+//
+// Struct fields are matched to JSON keys in the same way as encoding/json: the
+// name in the field's json tag is used if present (and fields tagged "-" are
+// skipped), the field name otherwise, preferring an exact match but accepting a
+// case-insensitive one. The fields of embedded structs are promoted, with the
+// same rules for conflicting names, and fields with the ",string" option take
+// their values from strings. Values whose types implement json.Unmarshaler or
+// encoding.TextUnmarshaler, such as time.Time, are decoded with encoding/json
+// instead. Keys that don't match any field are ignored, unless the
+// struct has a field of type map[string]json.RawMessage tagged
+// `jsonbody:"extras"`, in which case they are stored in it. Such a field lets
+// handlers preserve fields they don't model, e.g.
+//
+//	type Post struct {
+//		Title  string                     `json:"title"`
+//		Extras map[string]json.RawMessage `json:"-" jsonbody:"extras"`
+//	}
+func (r Reader) BindMap(target interface{}) error {
+	return bindValue(r.json, target)
+}
+
+// bindValue stores the decoded JSON value src in the value pointed to by target.
+func bindValue(src interface{}, target interface{}) error {
+	rv := reflect.ValueOf(target)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return errors.New("jsonbody: bind target must be a non-nil pointer")
+	}
+
+	return bindReflect("", src, rv.Elem())
+}
+
+// BindError describes a JSON value that could not be stored in a Go value while
+// binding.
+type BindError struct {
+	Key    string       // the key of the value in the body, e.g. "author.tags[0]"
+	Value  string       // the JSON type of the value, e.g. "number"
+	Type   reflect.Type // the type of the Go value it could not be stored in
+	Reason string       // why the value could not be stored, if more than a type mismatch
+}
+
+func (e *BindError) Error() string {
+	key := e.Key
+	if key == "" {
+		key = "(body)"
+	}
+
+	if e.Reason != "" {
+		return fmt.Sprintf("jsonbody: cannot bind %v value for key '%v' into Go value of type %v: %v", e.Value, key, e.Type, e.Reason)
+	}
+
+	return fmt.Sprintf("jsonbody: cannot bind %v value for key '%v' into Go value of type %v", e.Value, key, e.Type)
+}
+
+// writeBindError sends the response for an error that occurred while binding a
+// validated body for the handler. If WithBindErrors is used and the error is
+// about a number that can't be represented in its field, a 400 response with a
+// CodeInvalidValue error for the number's key is sent. Otherwise, the error is
+// logged and a 500 response is sent.
+func (w *Writer) writeBindError(err error) {
+	var bindErr *BindError
+	if w.config != nil && w.config.bindErrors && errors.As(err, &bindErr) && bindErr.Reason != "" {
+		w.writeErrors(http.StatusBadRequest, ValidationError{
+			Key:    bindErr.Key,
+			Code:   CodeInvalidValue,
+			Params: map[string]string{"reason": bindErr.Reason},
+		})
+		return
+	}
+
+	log.Println(fmt.Errorf("jsonbody: failed to bind body: %v", err))
+	w.writeServerError()
+}
+
+var (
+	jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+func bindReflect(key string, src interface{}, dst reflect.Value) error {
+	if src == nil {
+		switch dst.Kind() {
+		case reflect.Interface, reflect.Ptr, reflect.Map, reflect.Slice:
+			dst.Set(reflect.Zero(dst.Type()))
+		}
+		return nil
+	}
+
+	mismatch := &BindError{Key: key, Value: jsonTypeName(src), Type: dst.Type()}
+
+	if unmarshals(dst) {
+		return bindUnmarshaler(src, dst, mismatch)
+	}
+
+	switch dst.Kind() {
+	case reflect.Ptr:
+		if dst.IsNil() {
+			dst.Set(reflect.New(dst.Type().Elem()))
+		}
+		return bindReflect(key, src, dst.Elem())
+
+	case reflect.Interface:
+		if dst.NumMethod() != 0 {
+			return mismatch
+		}
+		dst.Set(reflect.ValueOf(src))
+		return nil
+
+	case reflect.Bool:
+		b, ok := src.(bool)
+		if !ok {
+			return mismatch
+		}
+		dst.SetBool(b)
+		return nil
+
+	case reflect.String:
+		s, ok := src.(string)
+		if !ok {
+			return mismatch
+		}
+		dst.SetString(s)
+		return nil
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		f, ok := src.(float64)
+		if !ok {
+			return mismatch
+		}
+		if f != math.Trunc(f) {
+			mismatch.Reason = "value is not an integer"
+			return mismatch
+		}
+		if f < math.MinInt64 || f >= math.MaxInt64 || dst.OverflowInt(int64(f)) {
+			mismatch.Reason = "value is out of range"
+			return mismatch
+		}
+		dst.SetInt(int64(f))
+		return nil
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		f, ok := src.(float64)
+		if !ok {
+			return mismatch
+		}
+		if f != math.Trunc(f) {
+			mismatch.Reason = "value is not an integer"
+			return mismatch
+		}
+		if f < 0 || f >= math.MaxUint64 || dst.OverflowUint(uint64(f)) {
+			mismatch.Reason = "value is out of range"
+			return mismatch
+		}
+		dst.SetUint(uint64(f))
+		return nil
+
+	case reflect.Float32, reflect.Float64:
+		f, ok := src.(float64)
+		if !ok {
+			return mismatch
+		}
+		if dst.OverflowFloat(f) {
+			mismatch.Reason = "value is out of range"
+			return mismatch
+		}
+		dst.SetFloat(f)
+		return nil
+
+	case reflect.Slice:
+		arr, ok := src.([]interface{})
+		if !ok {
+			return mismatch
+		}
+		slice := reflect.MakeSlice(dst.Type(), len(arr), len(arr))
+		for i, elem := range arr {
+			err := bindReflect(fmt.Sprintf("%v[%v]", key, i), elem, slice.Index(i))
+			if err != nil {
+				return err
+			}
+		}
+		dst.Set(slice)
+		return nil
+
+	case reflect.Array:
+		arr, ok := src.([]interface{})
+		if !ok {
+			return mismatch
+		}
+		for i := 0; i < dst.Len(); i++ {
+			if i >= len(arr) {
+				dst.Index(i).Set(reflect.Zero(dst.Type().Elem()))
+				continue
+			}
+
+			err := bindReflect(fmt.Sprintf("%v[%v]", key, i), arr[i], dst.Index(i))
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case reflect.Map:
+		obj, ok := src.(map[string]interface{})
+		if !ok || dst.Type().Key().Kind() != reflect.String {
+			return mismatch
+		}
+		if dst.IsNil() {
+			dst.Set(reflect.MakeMap(dst.Type()))
+		}
+		for k, v := range obj {
+			elem := reflect.New(dst.Type().Elem()).Elem()
+			err := bindReflect(joinKey(key, k), v, elem)
+			if err != nil {
+				return err
+			}
+			dst.SetMapIndex(reflect.ValueOf(k).Convert(dst.Type().Key()), elem)
+		}
+		return nil
+
+	case reflect.Struct:
+		obj, ok := src.(map[string]interface{})
+		if !ok {
+			return mismatch
+		}
+		return bindStruct(key, obj, dst)
+	}
+
+	return mismatch
+}
+
+// unmarshals reports whether dst is a value that encoding/json would decode with
+// its UnmarshalJSON or UnmarshalText method.
+func unmarshals(dst reflect.Value) bool {
+	if dst.Kind() == reflect.Ptr || dst.Kind() == reflect.Interface || !dst.CanAddr() {
+		return false
+	}
+
+	ptr := dst.Addr().Type()
+	return ptr.Implements(jsonUnmarshalerType) || ptr.Implements(textUnmarshalerType)
+}
+
+// bindUnmarshaler stores src in dst by encoding it as JSON again and decoding it
+// with encoding/json. Values of the wrong JSON type are reported as mismatch,
+// and values the type's method rejects are reported as mismatch with a reason.
+func bindUnmarshaler(src interface{}, dst reflect.Value, mismatch *BindError) error {
+	raw, err := json.Marshal(src)
+	if err != nil {
+		return err
+	}
+
+	err = json.Unmarshal(raw, dst.Addr().Interface())
+	if err == nil {
+		return nil
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	if !errors.As(err, &typeErr) {
+		mismatch.Reason = err.Error()
+	}
+
+	return mismatch
+}
+
+func bindStruct(key string, obj map[string]interface{}, dst reflect.Value) error {
+	fields := cachedJSONFields(dst.Type())
+	extras, err := extrasField(dst)
+	if err != nil {
+		return err
+	}
+
+	for k, v := range obj {
+		field, ok := matchField(fields, k)
+		if ok && isExtrasField(dst.Type().FieldByIndex(field.index)) {
+			ok = false // the extras field isn't bound to a key
+		}
+		if !ok {
+			if extras.IsValid() {
+				raw, err := json.Marshal(v)
+				if err != nil {
+					return err
+				}
+
+				if extras.IsNil() {
+					extras.Set(reflect.MakeMap(extras.Type()))
+				}
+				extras.SetMapIndex(reflect.ValueOf(k), reflect.ValueOf(json.RawMessage(raw)))
+			}
+			continue
+		}
+
+		fieldVal, ok := fieldByIndex(dst, field.index)
+		if !ok {
+			continue
+		}
+
+		if field.quoted {
+			v, err = unquoteField(joinKey(key, k), v, fieldVal)
+			if err != nil {
+				return err
+			}
+		}
+
+		err := bindReflect(joinKey(key, k), v, fieldVal)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// unquoteField returns the value encoded in src, the value for key of a field
+// with the ",string" option, which must be a string holding a JSON scalar (or
+// null).
+func unquoteField(key string, src interface{}, dst reflect.Value) (interface{}, error) {
+	if src == nil {
+		return nil, nil
+	}
+
+	s, ok := src.(string)
+	if !ok {
+		return nil, &BindError{Key: key, Value: jsonTypeName(src), Type: dst.Type(), Reason: "value must be a string"}
+	}
+
+	var val interface{}
+	if err := json.Unmarshal([]byte(s), &val); err != nil {
+		return nil, &BindError{Key: key, Value: "string", Type: dst.Type(), Reason: "string does not contain a JSON value"}
+	}
+
+	switch val.(type) {
+	case []interface{}, map[string]interface{}:
+		return nil, &BindError{Key: key, Value: "string", Type: dst.Type(), Reason: "string does not contain a JSON scalar"}
+	}
+
+	return val, nil
+}
+
+// extrasField returns the field of the struct v tagged `jsonbody:"extras"`, or
+// the zero Value if there is none.
+func extrasField(v reflect.Value) (reflect.Value, error) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !isExtrasField(f) {
+			continue
+		}
+
+		if f.PkgPath != "" || f.Type != rawMessageMapType {
+			return reflect.Value{}, fmt.Errorf("jsonbody: extras field %v must be an exported field of type map[string]json.RawMessage", f.Name)
+		}
+
+		return v.Field(i), nil
+	}
+
+	return reflect.Value{}, nil
+}
+
+var rawMessageMapType = reflect.TypeOf(map[string]json.RawMessage(nil))
+
+func isExtrasField(f reflect.StructField) bool {
+	for _, rule := range strings.Split(f.Tag.Get("jsonbody"), ",") {
+		if rule == "extras" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// matchField returns the field bound to key, preferring an exact match of its
+// name but accepting a case-insensitive one.
+func matchField(fields []jsonField, key string) (jsonField, bool) {
+	for _, f := range fields {
+		if f.name == key {
+			return f, true
+		}
+	}
+
+	for _, f := range fields {
+		if strings.EqualFold(f.name, key) {
+			return f, true
+		}
+	}
+
+	return jsonField{}, false
+}
+
+// fieldByIndex is like reflect.Value.FieldByIndex, but allocates nil embedded
+// struct pointers along the way. It returns false if a pointer to an unexported
+// embedded struct is nil, since it can't be allocated.
+func fieldByIndex(v reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				if !v.CanSet() {
+					return reflect.Value{}, false
+				}
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+
+	return v, true
+}
+
+// jsonTypeName returns the name of the JSON type of a decoded JSON value.
+func jsonTypeName(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+
+	return fmt.Sprintf("%T", v)
+}
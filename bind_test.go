@@ -0,0 +1,225 @@
+package jsonbody
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type bindInner struct {
+	Tags []string `json:"tags"`
+}
+
+type BindEmbedded struct {
+	Embedded bool
+}
+
+type bindTarget struct {
+	BindEmbedded
+	Name    string            `json:"name"`
+	Age     int               `json:"age"`
+	Score   float32           `json:"score"`
+	Skipped string            `json:"-"`
+	Inner   *bindInner        `json:"inner"`
+	Extra   map[string]string `json:"extra"`
+	Any     interface{}       `json:"any"`
+	Title   string
+	hidden  string
+}
+
+func bodyMap(body string) map[string]interface{} {
+	var m map[string]interface{}
+	json.Unmarshal([]byte(body), &m)
+	return m
+}
+
+func TestBindMapPopulatesStruct(t *testing.T) {
+	r := Reader{json: bodyMap(`{
+		"name": "turtle",
+		"age": 7,
+		"score": 1.5,
+		"Skipped": "x",
+		"inner": { "tags": ["a", "b"] },
+		"extra": { "k": "v" },
+		"any": [1, "2"],
+		"title": "case insensitive",
+		"embedded": true,
+		"hidden": "x"
+	}`)}
+
+	var target bindTarget
+	err := r.BindMap(&target)
+	assert.Nil(t, err)
+	assert.Equal(t, bindTarget{
+		BindEmbedded: BindEmbedded{Embedded: true},
+		Name:         "turtle",
+		Age:          7,
+		Score:        1.5,
+		Inner:        &bindInner{Tags: []string{"a", "b"}},
+		Extra:        map[string]string{"k": "v"},
+		Any:          []interface{}{float64(1), "2"},
+		Title:        "case insensitive",
+	}, target)
+}
+
+func TestBindMapReturnsErrIfTargetNotPointer(t *testing.T) {
+	r := Reader{json: bodyMap(`{}`)}
+
+	err := r.BindMap(bindTarget{})
+	assert.NotNil(t, err)
+}
+
+func TestBindMapReturnsBindErrorOnTypeMismatch(t *testing.T) {
+	r := Reader{json: bodyMap(`{"inner": { "tags": ["a", 5] }}`)}
+
+	var target bindTarget
+	err := r.BindMap(&target)
+	bindErr, ok := err.(*BindError)
+	assert.True(t, ok)
+	assert.Equal(t, "inner.tags[1]", bindErr.Key)
+	assert.Equal(t, "number", bindErr.Value)
+}
+
+func TestBindMapReturnsBindErrorOnFractionalInt(t *testing.T) {
+	r := Reader{json: bodyMap(`{"age": 7.5}`)}
+
+	var target bindTarget
+	err := r.BindMap(&target)
+	assert.IsType(t, &BindError{}, err)
+}
+
+func TestBindMapReturnsBindErrorOnOverflow(t *testing.T) {
+	r := Reader{json: bodyMap(`{"small": 300}`)}
+
+	var target struct {
+		Small int8 `json:"small"`
+	}
+	err := r.BindMap(&target)
+	assert.IsType(t, &BindError{}, err)
+}
+
+func TestBindMapStoresUnknownKeysInExtras(t *testing.T) {
+	r := Reader{json: bodyMap(`{"title": "hi", "inner": {"tags": ["a"], "new": 1}, "flag": true, "meta": {"a": [1]}}`)}
+
+	var target struct {
+		Title string `json:"title"`
+		Inner struct {
+			Tags   []string                   `json:"tags"`
+			Extras map[string]json.RawMessage `jsonbody:"extras"`
+		} `json:"inner"`
+		Extras map[string]json.RawMessage `json:"-" jsonbody:"extras"`
+	}
+	err := r.BindMap(&target)
+	assert.Nil(t, err)
+	assert.Equal(t, "hi", target.Title)
+	assert.Equal(t, map[string]json.RawMessage{"flag": json.RawMessage(`true`), "meta": json.RawMessage(`{"a":[1]}`)}, target.Extras)
+	assert.Equal(t, map[string]json.RawMessage{"new": json.RawMessage(`1`)}, target.Inner.Extras)
+}
+
+func TestBindMapReturnsErrIfExtrasFieldHasWrongType(t *testing.T) {
+	r := Reader{json: bodyMap(`{"a": 1}`)}
+
+	var target struct {
+		Extras map[string]interface{} `jsonbody:"extras"`
+	}
+	assert.NotNil(t, r.BindMap(&target))
+}
+
+type bindLevel int
+
+func (l *bindLevel) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "low":
+		*l = 1
+	case "high":
+		*l = 2
+	default:
+		return fmt.Errorf("unknown level %q", text)
+	}
+	return nil
+}
+
+func TestBindMapUsesUnmarshalers(t *testing.T) {
+	r := Reader{json: bodyMap(`{"at": "2019-09-24T10:30:00Z", "levels": ["low", "high"], "raw": {"a": 1}}`)}
+
+	var target struct {
+		At     time.Time       `json:"at"`
+		Levels []bindLevel     `json:"levels"`
+		Raw    json.RawMessage `json:"raw"`
+	}
+	err := r.BindMap(&target)
+	assert.Nil(t, err)
+	assert.True(t, target.At.Equal(time.Date(2019, 9, 24, 10, 30, 0, 0, time.UTC)))
+	assert.Equal(t, []bindLevel{1, 2}, target.Levels)
+	assert.JSONEq(t, `{"a": 1}`, string(target.Raw))
+}
+
+func TestBindMapReturnsBindErrorIfUnmarshalerFails(t *testing.T) {
+	var target struct {
+		At    time.Time `json:"at"`
+		Level bindLevel `json:"level"`
+	}
+
+	r := Reader{json: bodyMap(`{"at": "yesterday"}`)}
+	bindErr, ok := r.BindMap(&target).(*BindError)
+	assert.True(t, ok)
+	assert.Equal(t, "at", bindErr.Key)
+	assert.NotEmpty(t, bindErr.Reason)
+
+	r = Reader{json: bodyMap(`{"level": 5}`)}
+	bindErr, ok = r.BindMap(&target).(*BindError)
+	assert.True(t, ok)
+	assert.Equal(t, "level", bindErr.Key)
+	assert.Equal(t, "number", bindErr.Value)
+	assert.Empty(t, bindErr.Reason)
+}
+
+type bindShallow struct {
+	Name string
+}
+
+type bindDeep struct {
+	bindShallow
+	Tagged string `json:"name"`
+}
+
+func TestBindMapResolvesEmbeddedFieldsLikeEncodingJSON(t *testing.T) {
+	body := `{"Name": "outer", "name": "tagged"}`
+
+	var target struct {
+		bindDeep
+		Name string
+	}
+	assert.Nil(t, Reader{json: bodyMap(body)}.BindMap(&target))
+
+	var expected struct {
+		bindDeep
+		Name string
+	}
+	assert.Nil(t, json.Unmarshal([]byte(body), &expected))
+	assert.Equal(t, expected, target)
+	assert.Equal(t, "outer", target.Name)
+	assert.Equal(t, "", target.bindShallow.Name)
+}
+
+func TestBindMapDecodesStringOption(t *testing.T) {
+	var target struct {
+		ID    int64   `json:"id,string"`
+		Ratio float64 `json:"ratio,string"`
+		OK    bool    `json:"ok,string"`
+	}
+	r := Reader{json: bodyMap(`{"id": "42", "ratio": "0.5", "ok": "true"}`)}
+	assert.Nil(t, r.BindMap(&target))
+	assert.Equal(t, int64(42), target.ID)
+	assert.Equal(t, float64(0.5), target.Ratio)
+	assert.True(t, target.OK)
+
+	r = Reader{json: bodyMap(`{"id": 5}`)}
+	bindErr, ok := r.BindMap(&target).(*BindError)
+	assert.True(t, ok)
+	assert.Equal(t, "id", bindErr.Key)
+	assert.NotEmpty(t, bindErr.Reason)
+}
@@ -0,0 +1,215 @@
+package jsonbody
+
+import (
+	"sync"
+	"time"
+)
+
+// CircuitState is the state of the circuit breaker for a route.
+type CircuitState int
+
+const (
+	// CircuitClosed means that the route's validation subsystems are working
+	// normally.
+	CircuitClosed CircuitState = iota
+
+	// CircuitOpen means that the route's validation subsystems failed too many
+	// times in a row, so the circuit breaker's policy is being applied.
+	CircuitOpen
+
+	// CircuitHalfOpen means that the cooldown has elapsed and a single request
+	// is being used to check whether the subsystems have recovered.
+	CircuitHalfOpen
+)
+
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	}
+
+	return "closed"
+}
+
+// CircuitPolicy determines how requests are handled while a route's circuit is
+// open.
+type CircuitPolicy int
+
+const (
+	// CircuitPassThrough validates requests against the middleware's own
+	// schema, skipping the schema resolver and async validators.
+	CircuitPassThrough CircuitPolicy = iota
+
+	// CircuitReject rejects requests with a 503 error response without
+	// attempting to validate them.
+	CircuitReject
+)
+
+// CircuitBreaker configures the circuit breaker enabled by WithCircuitBreaker.
+type CircuitBreaker struct {
+	// Threshold is the number of consecutive failures after which a route's
+	// circuit opens.
+	Threshold int
+
+	// Cooldown is how long a route's circuit stays open before a request is
+	// allowed through to check whether the subsystems have recovered.
+	Cooldown time.Duration
+
+	// Policy determines how requests are handled while a circuit is open.
+	Policy CircuitPolicy
+
+	// OnStateChange, if not nil, is called whenever the state of a route's
+	// circuit changes, e.g. to update a metric. It isn't called while the
+	// breaker's lock is held, so it may use the middleware freely. Routes are identified by
+	// method and route pattern, e.g. "POST /posts/{id}", or by method and path
+	// for requests that don't match a pattern (see WithRoutePatterns).
+	OnStateChange func(route string, state CircuitState)
+}
+
+// maxCircuits is the maximum number of routes whose failures are tracked at
+// once. Closed circuits are evicted to make room for new ones, so requests to
+// arbitrarily many unmatched paths can't grow the breaker without bound.
+const maxCircuits = 1024
+
+type circuitBreaker struct {
+	config CircuitBreaker
+
+	mu     sync.Mutex
+	routes map[string]*circuit
+}
+
+type circuit struct {
+	state    CircuitState
+	failures int
+	openedAt time.Time
+	trial    uint64 // incremented whenever a trial request starts
+}
+
+// allow reports whether the protected subsystems should be used for a request
+// to route. If the request is the trial request of a half-open circuit, allow
+// also returns a nonzero trial number, which must be passed to endTrial once
+// the request is done.
+func (b *circuitBreaker) allow(route string) (allowed bool, trial uint64) {
+	var change stateChange
+	defer b.report(&change)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	c, ok := b.routes[route]
+	if !ok {
+		return true, 0
+	}
+
+	switch c.state {
+	case CircuitOpen:
+		if time.Since(c.openedAt) < b.config.Cooldown {
+			return false, 0
+		}
+		c.trial++
+		change = b.setState(route, c, CircuitHalfOpen)
+		return true, c.trial
+	case CircuitHalfOpen:
+		return false, 0 // a trial request is already in progress
+	}
+
+	return true, 0
+}
+
+// endTrial releases the half-open circuit of route if the given trial request
+// finished without recording a result, e.g. because the request failed schema
+// validation before reaching the async validators. The circuit reopens with its
+// cooldown already elapsed, so the next request becomes the trial request.
+func (b *circuitBreaker) endTrial(route string, trial uint64) {
+	var change stateChange
+	defer b.report(&change)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	c, ok := b.routes[route]
+	if ok && c.state == CircuitHalfOpen && c.trial == trial {
+		change = b.setState(route, c, CircuitOpen)
+	}
+}
+
+// record records whether the protected subsystems succeeded for a request to
+// route. It is safe to call on a nil *circuitBreaker.
+func (b *circuitBreaker) record(route string, success bool) {
+	if b == nil {
+		return
+	}
+
+	var change stateChange
+	defer b.report(&change)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.routes == nil {
+		b.routes = make(map[string]*circuit)
+	}
+
+	c, ok := b.routes[route]
+	if !ok {
+		if success {
+			return
+		}
+		if len(b.routes) >= maxCircuits && !b.evictClosed() {
+			return
+		}
+		c = &circuit{}
+		b.routes[route] = c
+	}
+
+	if success {
+		delete(b.routes, route)
+		if c.state != CircuitClosed {
+			change = b.setState(route, c, CircuitClosed)
+		}
+		return
+	}
+
+	c.failures++
+	if c.state == CircuitHalfOpen || (c.state == CircuitClosed && c.failures >= b.config.Threshold) {
+		c.openedAt = time.Now()
+		change = b.setState(route, c, CircuitOpen)
+	}
+}
+
+// evictClosed stops tracking one route whose circuit is closed, reporting
+// whether there was one. b.mu must be held.
+func (b *circuitBreaker) evictClosed() bool {
+	for route, c := range b.routes {
+		if c.state == CircuitClosed {
+			delete(b.routes, route)
+			return true
+		}
+	}
+
+	return false
+}
+
+// stateChange is a change to the state of a route's circuit, which is reported
+// to OnStateChange once b.mu is released.
+type stateChange struct {
+	route   string
+	state   CircuitState
+	changed bool
+}
+
+// setState changes the state of c, the circuit of route. b.mu must be held.
+func (b *circuitBreaker) setState(route string, c *circuit, state CircuitState) stateChange {
+	c.state = state
+	return stateChange{route: route, state: state, changed: true}
+}
+
+// report calls OnStateChange for change, if there was one. b.mu must not be
+// held.
+func (b *circuitBreaker) report(change *stateChange) {
+	if change.changed && b.config.OnStateChange != nil {
+		b.config.OnStateChange(change.route, change.state)
+	}
+}
@@ -0,0 +1,221 @@
+package jsonbody
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestCircuitBreakerOpensAfterThreshold(t *testing.T) {
+	changes := make([]CircuitState, 0)
+	b := &circuitBreaker{config: CircuitBreaker{
+		Threshold:     2,
+		Cooldown:      time.Hour,
+		OnStateChange: func(route string, state CircuitState) { changes = append(changes, state) },
+	}}
+
+	b.record("POST /posts", false)
+	allowed, _ := b.allow("POST /posts")
+	assert.True(t, allowed)
+
+	b.record("POST /posts", false)
+	allowed, _ = b.allow("POST /posts")
+	assert.False(t, allowed)
+	allowed, _ = b.allow("POST /other")
+	assert.True(t, allowed)
+	assert.Equal(t, []CircuitState{CircuitOpen}, changes)
+}
+
+func TestCircuitBreakerClosesAfterSuccessfulTrial(t *testing.T) {
+	var changes []CircuitState
+	b := &circuitBreaker{config: CircuitBreaker{
+		Threshold:     1,
+		Cooldown:      0,
+		OnStateChange: func(route string, state CircuitState) { changes = append(changes, state) },
+	}}
+
+	b.record("r", false)
+	allowed, trial := b.allow("r")
+	assert.True(t, allowed)
+	assert.NotZero(t, trial)
+	assert.Equal(t, []CircuitState{CircuitOpen, CircuitHalfOpen}, changes)
+	allowed, _ = b.allow("r")
+	assert.False(t, allowed)
+
+	b.record("r", true)
+	assert.Equal(t, []CircuitState{CircuitOpen, CircuitHalfOpen, CircuitClosed}, changes)
+	allowed, trial = b.allow("r")
+	assert.True(t, allowed)
+	assert.Zero(t, trial)
+}
+
+func TestCircuitBreakerReopensAfterFailedTrial(t *testing.T) {
+	var changes []CircuitState
+	b := &circuitBreaker{config: CircuitBreaker{
+		Threshold:     3,
+		Cooldown:      0,
+		OnStateChange: func(route string, state CircuitState) { changes = append(changes, state) },
+	}}
+
+	for i := 0; i < 3; i++ {
+		b.record("r", false)
+	}
+	b.allow("r")
+	b.record("r", false)
+
+	assert.Equal(t, []CircuitState{CircuitOpen, CircuitHalfOpen, CircuitOpen}, changes)
+}
+
+func TestCircuitBreakerReleasesUnrecordedTrial(t *testing.T) {
+	var changes []CircuitState
+	b := &circuitBreaker{config: CircuitBreaker{
+		Threshold:     1,
+		Cooldown:      0,
+		OnStateChange: func(route string, state CircuitState) { changes = append(changes, state) },
+	}}
+
+	b.record("r", false)
+	_, first := b.allow("r")
+	b.endTrial("r", first)
+	assert.Equal(t, []CircuitState{CircuitOpen, CircuitHalfOpen, CircuitOpen}, changes)
+
+	// A stale trial mustn't release the circuit while a newer trial runs.
+	_, second := b.allow("r")
+	b.endTrial("r", first)
+	allowed, _ := b.allow("r")
+	assert.False(t, allowed)
+
+	b.record("r", true)
+	b.endTrial("r", second)
+	assert.Equal(t, []CircuitState{CircuitOpen, CircuitHalfOpen, CircuitOpen, CircuitHalfOpen, CircuitClosed}, changes)
+}
+
+func TestCircuitBreakerReportsChangesWithoutLock(t *testing.T) {
+	var b *circuitBreaker
+	var allowedInCallback []bool
+	b = &circuitBreaker{config: CircuitBreaker{
+		Threshold: 1,
+		Cooldown:  time.Hour,
+		OnStateChange: func(route string, state CircuitState) {
+			allowed, _ := b.allow(route)
+			allowedInCallback = append(allowedInCallback, allowed)
+		},
+	}}
+
+	b.record("r", false)
+	assert.Equal(t, []bool{false}, allowedInCallback)
+}
+
+func TestCircuitBreakerBoundsTrackedRoutes(t *testing.T) {
+	b := &circuitBreaker{config: CircuitBreaker{Threshold: 2, Cooldown: time.Hour}}
+
+	for i := 0; i < maxCircuits*2; i++ {
+		b.record(fmt.Sprint("POST /posts/", i), false)
+	}
+	assert.Len(t, b.routes, maxCircuits)
+
+	for route := range b.routes {
+		b.record(route, true)
+		break
+	}
+	assert.Len(t, b.routes, maxCircuits-1)
+}
+
+func TestServeHTTPAppliesCircuitPolicyWhenOpen(t *testing.T) {
+	next := &mockHandler{}
+	next.On("ServeHTTP", mock.Anything, mock.Anything).Return()
+	calls := 0
+	resolver := SchemaResolverFunc(func(ctx context.Context, tenantID, route, method string) (*Schema, error) {
+		calls++
+		return nil, errors.New("registry down")
+	})
+	handler := NewMiddleware("",
+		WithSchemaResolver(resolver, nil, 0),
+		WithCircuitBreaker(CircuitBreaker{Threshold: 1, Cooldown: time.Hour, Policy: CircuitPassThrough}),
+	)(next)
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/posts", strings.NewReader(`{}`)))
+	assert.Equal(t, 503, recorder.Code)
+
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/posts", strings.NewReader(`{}`)))
+	assert.Equal(t, 200, recorder.Code)
+	assert.Equal(t, 1, calls)
+}
+
+func TestServeHTTPReleasesTrialThatFailsSchemaValidation(t *testing.T) {
+	next := &mockHandler{}
+	next.On("ServeHTTP", mock.Anything, mock.Anything).Return()
+	var slow atomic.Bool
+	slow.Store(true)
+	handler := NewMiddleware(`{"id": 0}`,
+		WithAsyncValidator("id", func(ctx context.Context, v interface{}) error {
+			if slow.Load() {
+				<-ctx.Done()
+				return ctx.Err()
+			}
+			return nil
+		}),
+		WithAsyncValidation(10*time.Millisecond, 0),
+		WithCircuitBreaker(CircuitBreaker{Threshold: 1, Cooldown: 0, Policy: CircuitReject}),
+	)(next)
+
+	send := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/posts", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	recorder := send(`{"id": 1}`)
+	assert.Equal(t, 400, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), "timed out")
+
+	// The trial request never reaches the async validators.
+	recorder = send(`{"id": "1"}`)
+	assert.Equal(t, 400, recorder.Code)
+
+	slow.Store(false)
+	recorder = send(`{"id": 1}`)
+	assert.Equal(t, 200, recorder.Code)
+}
+
+func TestServeHTTPTracksCircuitsByRoutePattern(t *testing.T) {
+	next := &mockHandler{}
+	next.On("ServeHTTP", mock.Anything, mock.Anything).Return()
+	calls := 0
+	resolver := SchemaResolverFunc(func(ctx context.Context, tenantID, route, method string) (*Schema, error) {
+		calls++
+		return nil, errors.New("registry down")
+	})
+	var opened []string
+	handler := NewMiddleware("",
+		WithRoutePatterns("/posts/{id}"),
+		WithSchemaResolver(resolver, nil, 0),
+		WithCircuitBreaker(CircuitBreaker{
+			Threshold:     1,
+			Cooldown:      time.Hour,
+			Policy:        CircuitReject,
+			OnStateChange: func(route string, state CircuitState) { opened = append(opened, route) },
+		}),
+	)(next)
+
+	for _, path := range []string{"/posts/1", "/posts/2"} {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, path, strings.NewReader(`{}`)))
+		assert.Equal(t, 503, recorder.Code)
+	}
+	assert.Equal(t, 1, calls)
+	assert.Equal(t, []string{"POST /posts/{id}"}, opened)
+}
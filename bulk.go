@@ -0,0 +1,126 @@
+package jsonbody
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+)
+
+// BulkFunc processes a single record of a bulk request, which has already been
+// validated against the BulkHandler's schema, and returns its result. If the
+// result has no ID, the record's index in the request (starting at 0) is used.
+type BulkFunc func(ctx context.Context, record interface{}) ItemResult
+
+// BulkHandler returns a handler for bulk requests whose bodies are streams of
+// newline-delimited JSON (NDJSON) records. Each record is validated against
+// schema as it is read; records that aren't valid JSON or don't match the
+// schema get a 400 result listing their errors, and the rest are passed to fn,
+// at most concurrency at a time.
+//
+// If the request's Accept header prefers application/x-ndjson to
+// application/json, each result is streamed back as a line of NDJSON as soon as
+// it is ready (see Writer.WriteNDJSONStream), so results may be out of order.
+// Otherwise, the results are sent in the order of the records once they are all
+// ready, in the same way as Writer.WriteMultiStatus.
+//
+// The handler reads the body itself, so it shouldn't be wrapped by the
+// middleware created by NewMiddleware. Use http.MaxBytesReader to limit the
+// size of bodies.
+func BulkHandler(schema *Schema, concurrency int, fn BulkFunc) http.Handler {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writer := &Writer{ResponseWriter: w, acceptEncoding: r.Header.Get("Accept-Encoding")}
+
+		ranges := parseAccept(r.Header.Get("Accept"))
+		if acceptQuality(ranges, "application/x-ndjson") > acceptQuality(ranges, "application/json") {
+			err := writer.WriteNDJSONStream(http.StatusOK, func(send func(v interface{}) error) error {
+				return processBulk(r.Context(), r.Body, schema, concurrency, fn, func(_ int, result ItemResult) {
+					send(result)
+				})
+			})
+			if err != nil {
+				log.Printf("jsonbody: failed to process bulk request: %v\n", err)
+			}
+			return
+		}
+
+		var mu sync.Mutex
+		var indexes []int
+		var results []ItemResult
+		err := processBulk(r.Context(), r.Body, schema, concurrency, fn, func(i int, result ItemResult) {
+			mu.Lock()
+			defer mu.Unlock()
+			indexes = append(indexes, i)
+			results = append(results, result)
+		})
+		if err != nil {
+			writer.writeErrors(http.StatusBadRequest, ValidationError{Code: CodeInvalidJSON, Params: map[string]string{"reason": err.Error()}})
+			return
+		}
+
+		sorted := make([]ItemResult, len(results))
+		for j, i := range indexes {
+			sorted[i] = results[j]
+		}
+		writer.WriteMultiStatus(sorted)
+	})
+}
+
+// processBulk reads the NDJSON records in body, validating each against schema
+// and passing the valid ones to fn in a new goroutine, at most concurrency at a
+// time. The result for each record is passed to emit, along with the index of
+// the record, which may be called concurrently. processBulk returns once every
+// result has been emitted, returning the error that stopped it from reading
+// body, if any.
+func processBulk(ctx context.Context, body io.Reader, schema *Schema, concurrency int, fn BulkFunc, emit func(i int, result ItemResult)) error {
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	reader := bufio.NewReader(body)
+	for i := 0; ; {
+		line, err := reader.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return err
+		}
+
+		if len(bytes.TrimSpace(line)) > 0 {
+			index, id := i, strconv.Itoa(i)
+			i++
+
+			var record interface{}
+			if jsonErr := json.Unmarshal(line, &record); jsonErr != nil {
+				verr := ValidationError{Code: CodeInvalidJSON, Params: map[string]string{"reason": jsonErr.Error()}}
+				emit(index, ItemResult{ID: id, Status: http.StatusBadRequest, Errors: []string{verr.Error()}})
+			} else if errs := schema.Validate(ctx, record); len(errs) > 0 {
+				emit(index, ItemResult{ID: id, Status: http.StatusBadRequest, Errors: errorMessages(errs)})
+			} else {
+				sem <- struct{}{}
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					defer func() { <-sem }()
+
+					result := fn(ctx, record)
+					if result.ID == "" {
+						result.ID = id
+					}
+					emit(index, result)
+				}()
+			}
+		}
+
+		if err == io.EOF {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package jsonbody
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const bulkBody = `{"name": "a"}
+not json
+
+{"name": 1}
+{"name": "b"}
+`
+
+func bulkFunc(record interface{}) ItemResult {
+	return ItemResult{Status: http.StatusCreated, Data: record}
+}
+
+func TestBulkHandlerSendsMultiStatusInOrder(t *testing.T) {
+	var active, maxActive int32
+	handler := BulkHandler(MustParseSchema(`{"name": ""}`), 2, func(ctx context.Context, record interface{}) ItemResult {
+		n := atomic.AddInt32(&active, 1)
+		defer atomic.AddInt32(&active, -1)
+		for {
+			max := atomic.LoadInt32(&maxActive)
+			if n <= max || atomic.CompareAndSwapInt32(&maxActive, max, n) {
+				break
+			}
+		}
+
+		return bulkFunc(record)
+	})
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(bulkBody)))
+
+	assert.Equal(t, http.StatusMultiStatus, recorder.Code)
+	assert.Equal(t, `{"results":[`+
+		`{"id":"0","status":201,"data":{"name":"a"}},`+
+		`{"id":"1","status":400,"errors":["body is not acceptable JSON: invalid character 'o' in literal null (expecting 'u')"]},`+
+		`{"id":"2","status":400,"errors":["value for key 'name' expected to be of type string"]},`+
+		`{"id":"3","status":201,"data":{"name":"b"}}]}`, recorder.Body.String())
+	assert.True(t, maxActive <= 2)
+}
+
+func TestBulkHandlerStreamsNDJSONIfPreferred(t *testing.T) {
+	handler := BulkHandler(MustParseSchema(`{"name": ""}`), 4, func(ctx context.Context, record interface{}) ItemResult {
+		return bulkFunc(record)
+	})
+
+	recorder := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(bulkBody))
+	r.Header.Set("Accept", "application/x-ndjson, application/json;q=0.5")
+	handler.ServeHTTP(recorder, r)
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "application/x-ndjson", recorder.Header().Get("Content-Type"))
+
+	lines := strings.Split(strings.TrimSpace(recorder.Body.String()), "\n")
+	sort.Strings(lines)
+	assert.Equal(t, []string{
+		`{"id":"0","status":201,"data":{"name":"a"}}`,
+		`{"id":"1","status":400,"errors":["body is not acceptable JSON: invalid character 'o' in literal null (expecting 'u')"]}`,
+		`{"id":"2","status":400,"errors":["value for key 'name' expected to be of type string"]}`,
+		`{"id":"3","status":201,"data":{"name":"b"}}`,
+	}, lines)
+}
@@ -0,0 +1,93 @@
+package jsonbody
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// NewMiddlewareFromBundle creates a middleware like NewMiddleware, but reads
+// the schemas for all of a service's routes from a single JSON bundle document.
+// The bundle is an object whose keys are a method and path pattern separated by
+// a space, and whose values are schemas in the format described for
+// NewMiddleware. Path segments written as "{name}" match any single segment:
+//
+//	{
+//		"POST /posts": { "title": "", "body": "" },
+//		"PUT /posts/{id}": { "?title": "", "?body": "" }
+//	}
+//
+// Requests are validated against the schema registered for their method and
+// path; requests that don't match any entry in the bundle are not validated.
+// An empty object as a value means that any JSON body is accepted, while null
+// means that any body at all (or none) is accepted. A value may also be an array
+// of schemas, all of which requests must satisfy (see AllOf).
+func NewMiddlewareFromBundle(bundle io.Reader, opts ...Option) (func(next http.Handler) http.Handler, error) {
+	routes, err := parseBundle(bundle)
+	if err != nil {
+		return nil, err
+	}
+
+	opts = append([]Option{withRoutes(routes)}, opts...)
+	return NewMiddleware("", opts...), nil
+}
+
+func parseBundle(bundle io.Reader) (*routeTable, error) {
+	var entries map[string]json.RawMessage
+	err := json.NewDecoder(bundle).Decode(&entries)
+	if err != nil {
+		return nil, fmt.Errorf("jsonbody: failed to decode schema bundle: %v", err)
+	}
+
+	routes := &routeTable{}
+	for key, raw := range entries {
+		fields := strings.Fields(key)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("jsonbody: schema bundle key '%v' must be a method and a path separated by a space", key)
+		}
+
+		schema, err := parseBundleSchema(raw)
+		if err != nil {
+			return nil, fmt.Errorf("jsonbody: invalid schema for '%v' in bundle: %v", key, err)
+		}
+
+		routes.add(fields[0], fields[1], schema)
+	}
+
+	return routes, nil
+}
+
+// parseBundleSchema parses the schema for a route in a bundle, which may be an
+// array of schemas that are combined with AllOf.
+func parseBundleSchema(raw json.RawMessage) (*Schema, error) {
+	var parts []json.RawMessage
+	if json.Unmarshal(raw, &parts) != nil {
+		parts = []json.RawMessage{raw}
+	}
+
+	schemas := make([]*Schema, len(parts))
+	for i, part := range parts {
+		schemaJSON := string(part)
+		if schemaJSON == "null" {
+			schemaJSON = ""
+		}
+
+		schema, err := ParseSchema(schemaJSON)
+		if err != nil {
+			return nil, err
+		}
+		schemas[i] = schema
+	}
+
+	return AllOf(schemas...), nil
+}
+
+// withRoutes sets the route table the middleware uses to select a schema for
+// each request.
+func withRoutes(routes *routeTable) Option {
+	return func(m *middleware) {
+		m.routes = routes
+	}
+}
@@ -0,0 +1,45 @@
+package jsonbody
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestParseBundleReturnsErrIfKeyInvalid(t *testing.T) {
+	_, err := parseBundle(strings.NewReader(`{"/posts": {}}`))
+	assert.NotNil(t, err)
+}
+
+func TestParseBundleReturnsErrIfNotJSON(t *testing.T) {
+	_, err := parseBundle(strings.NewReader(`not json`))
+	assert.NotNil(t, err)
+}
+
+func TestNewMiddlewareFromBundleValidatesPerRoute(t *testing.T) {
+	mw, err := NewMiddlewareFromBundle(strings.NewReader(`{
+		"POST /posts": { "title": "" },
+		"PUT /posts/{id}": { "?title": "" }
+	}`))
+	assert.Nil(t, err)
+
+	next := &mockHandler{}
+	next.On("ServeHTTP", mock.Anything, mock.Anything).Return()
+	handler := mw(next)
+
+	send := func(method string, path string, body string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		r := httptest.NewRequest(method, path, strings.NewReader(body))
+		r.Header.Set("Content-Type", "application/json")
+		handler.ServeHTTP(recorder, r)
+		return recorder
+	}
+
+	assert.Equal(t, 400, send(http.MethodPost, "/posts", `{}`).Code)
+	assert.Equal(t, 200, send(http.MethodPut, "/posts/7", `{}`).Code)
+	assert.Equal(t, 200, send(http.MethodDelete, "/posts/7", ``).Code)
+}
@@ -0,0 +1,236 @@
+package jsonbody
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"hash"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CachedResponse is a response stored in a ResponseCache.
+type CachedResponse struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+
+	// RequestHash identifies the method, URL, and body of the request that
+	// produced the response.
+	RequestHash string
+}
+
+// ResponseCache stores responses so that they can be replayed for identical
+// requests. Implementations must be safe for concurrent use.
+type ResponseCache interface {
+	// Get returns the response stored for key, if there is one that hasn't
+	// expired.
+	Get(key string) (*CachedResponse, bool)
+
+	// Set stores resp for key until ttl has elapsed.
+	Set(key string, resp *CachedResponse, ttl time.Duration)
+}
+
+// MemoryCache is a ResponseCache that stores responses in memory. The zero
+// value is ready to use.
+type MemoryCache struct {
+	mu        sync.Mutex
+	entries   map[string]memoryCacheEntry
+	lastSweep time.Time
+}
+
+type memoryCacheEntry struct {
+	resp    *CachedResponse
+	expires time.Time
+}
+
+// NewMemoryCache creates an empty MemoryCache.
+func NewMemoryCache() *MemoryCache {
+	return &MemoryCache{}
+}
+
+// Get implements ResponseCache.
+func (c *MemoryCache) Get(key string) (*CachedResponse, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+
+	if time.Now().After(entry.expires) {
+		delete(c.entries, key)
+		return nil, false
+	}
+
+	return entry.resp, true
+}
+
+// Set implements ResponseCache. Expired entries are removed periodically as new
+// ones are added.
+func (c *MemoryCache) Set(key string, resp *CachedResponse, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	if c.entries == nil {
+		c.entries = make(map[string]memoryCacheEntry)
+	}
+
+	if now.Sub(c.lastSweep) > time.Minute {
+		for k, entry := range c.entries {
+			if now.After(entry.expires) {
+				delete(c.entries, k)
+			}
+		}
+		c.lastSweep = now
+	}
+
+	c.entries[key] = memoryCacheEntry{resp: resp, expires: now.Add(ttl)}
+}
+
+// requestHash returns a key identifying the method, URL, and body of r.
+func requestHash(r *http.Request, body []byte) string {
+	h := newRequestHasher(r)
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// requestHashFromBody is like requestHash, but reads the body from r.Body,
+// which must be seekable, rewinding it afterward. This way, bodies that have
+// been spilled to disk don't need to be read back into memory.
+func requestHashFromBody(r *http.Request) (string, error) {
+	body, ok := r.Body.(io.ReadSeeker)
+	if !ok {
+		return "", errors.New("request body is not seekable")
+	}
+
+	h := newRequestHasher(r)
+	if _, err := io.Copy(h, body); err != nil {
+		return "", err
+	}
+
+	if _, err := body.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// newRequestHasher returns a hash that has been written the method and URL of r.
+func newRequestHasher(r *http.Request) hash.Hash {
+	h := sha256.New()
+	h.Write([]byte(r.Method))
+	h.Write([]byte{0})
+	h.Write([]byte(r.URL.RequestURI()))
+	h.Write([]byte{0})
+	return h
+}
+
+// cacheVaryHeaders are the request headers that can change the response for the
+// same method, URL, and body: those identifying the client and those the
+// middleware uses to negotiate the response's form.
+var cacheVaryHeaders = []string{
+	"Authorization",
+	"Cookie",
+	"Accept",
+	"Accept-Encoding",
+	"Accept-Language",
+	"Content-Type",
+}
+
+// cacheKey returns the key of the cached response for r, whose method, URL, and
+// body are identified by hash. It also identifies the headers in
+// cacheVaryHeaders, the field selection header if one is configured, and the
+// value returned by the function set with WithCacheVary, so that responses
+// meant for one client or in one form aren't sent to others.
+func (m *middleware) cacheKey(r *http.Request, hash string) string {
+	h := sha256.New()
+	h.Write([]byte(hash))
+
+	headers := cacheVaryHeaders
+	if m.fieldsHeader != "" {
+		headers = append(headers[:len(headers):len(headers)], m.fieldsHeader)
+	}
+	for _, name := range headers {
+		h.Write([]byte{0})
+		h.Write([]byte(strings.Join(r.Header.Values(name), ",")))
+	}
+
+	if m.cacheVary != nil {
+		h.Write([]byte{0})
+		h.Write([]byte(m.cacheVary(r)))
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// serveCached replays the cached response for r if there is one. Otherwise, it
+// calls the next handler and caches its response if it was successful.
+func (m *middleware) serveCached(writer Writer, r *http.Request, hash string) {
+	key := m.cacheKey(r, hash)
+	if resp, ok := m.cache.Get(key); ok {
+		replayResponse(writer.ResponseWriter, resp)
+		return
+	}
+
+	rec := &responseRecorder{ResponseWriter: writer.ResponseWriter}
+	writer.ResponseWriter = rec
+	m.callNext(writer, r)
+
+	if rec.statusCode >= 200 && rec.statusCode < 300 {
+		resp := rec.response()
+		resp.RequestHash = key
+		m.cache.Set(key, resp, m.cacheTTL)
+	}
+}
+
+func replayResponse(w http.ResponseWriter, resp *CachedResponse) {
+	for k, v := range resp.Header {
+		w.Header()[k] = append([]string(nil), v...)
+	}
+	w.WriteHeader(resp.StatusCode)
+	w.Write(resp.Body)
+}
+
+// responseRecorder passes a response through to the underlying
+// http.ResponseWriter while keeping a copy of it.
+type responseRecorder struct {
+	http.ResponseWriter
+	statusCode int
+	body       bytes.Buffer
+}
+
+func (r *responseRecorder) WriteHeader(statusCode int) {
+	if r.statusCode == 0 && statusCode >= 200 { // ignore informational responses
+		r.statusCode = statusCode
+	}
+	r.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	if r.statusCode == 0 {
+		r.statusCode = http.StatusOK
+	}
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
+
+// Unwrap returns the underlying http.ResponseWriter for http.ResponseController.
+func (r *responseRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// response returns a copy of the recorded response.
+func (r *responseRecorder) response() *CachedResponse {
+	return &CachedResponse{
+		StatusCode: r.statusCode,
+		Header:     r.Header().Clone(),
+		Body:       append([]byte(nil), r.body.Bytes()...),
+	}
+}
@@ -0,0 +1,94 @@
+package jsonbody
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestMemoryCacheExpiresEntries(t *testing.T) {
+	c := NewMemoryCache()
+	c.Set("a", &CachedResponse{StatusCode: 200}, time.Hour)
+	c.Set("b", &CachedResponse{StatusCode: 200}, -time.Second)
+
+	_, ok := c.Get("a")
+	assert.True(t, ok)
+
+	_, ok = c.Get("b")
+	assert.False(t, ok)
+}
+
+func TestRequestHashDependsOnBody(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPost, "/search", nil)
+	assert.Equal(t, requestHash(r, []byte(`{}`)), requestHash(r, []byte(`{}`)))
+	assert.NotEqual(t, requestHash(r, []byte(`{}`)), requestHash(r, []byte(`{"q":1}`)))
+}
+
+type jsonHandler struct {
+	mock.Mock
+}
+
+func (h *jsonHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.Called(w, r)
+	writer := w.(Writer)
+	writer.WriteJSON(200, map[string]int{"calls": len(h.Calls)})
+}
+
+func TestServeHTTPReplaysCachedResponse(t *testing.T) {
+	next := &jsonHandler{}
+	next.On("ServeHTTP", mock.Anything, mock.Anything).Return()
+	handler := NewMiddleware("", WithResponseCache(nil, time.Minute))(next)
+
+	for i := 0; i < 2; i++ {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/search", strings.NewReader(`{"q": "turtles"}`)))
+
+		assert.Equal(t, 200, recorder.Code)
+		assert.Equal(t, `{"calls":1}`, recorder.Body.String())
+		assert.Equal(t, "application/json", recorder.Header().Get("Content-Type"))
+	}
+
+	next.AssertNumberOfCalls(t, "ServeHTTP", 1)
+}
+
+func TestServeHTTPKeysCachedResponsesByClientAndNegotiation(t *testing.T) {
+	next := &jsonHandler{}
+	next.On("ServeHTTP", mock.Anything, mock.Anything).Return()
+	handler := NewMiddleware("",
+		WithResponseCache(nil, time.Minute),
+		WithFieldSelectionHeader("X-Fields"),
+		WithCacheVary(func(r *http.Request) string { return r.Header.Get("X-User") }),
+	)(next)
+
+	calls := 0
+	for _, tc := range []struct {
+		header string
+		value  string
+	}{
+		{"", ""},
+		{"Authorization", "Bearer a"},
+		{"Authorization", "Bearer b"},
+		{"Cookie", "session=a"},
+		{"Accept-Language", "fr"},
+		{"Accept", "application/vnd.example.v2+json"},
+		{"X-Fields", "id"},
+		{"X-User", "alice"},
+	} {
+		// each request is sent twice; only the first should reach the handler
+		for i := 0; i < 2; i++ {
+			r := httptest.NewRequest(http.MethodPost, "/search", strings.NewReader(`{"q": "turtles"}`))
+			if tc.header != "" {
+				r.Header.Set(tc.header, tc.value)
+			}
+			handler.ServeHTTP(httptest.NewRecorder(), r)
+		}
+
+		calls++
+		next.AssertNumberOfCalls(t, "ServeHTTP", calls)
+	}
+}
@@ -0,0 +1,155 @@
+package jsonbody
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// canonicalJSON encodes a decoded JSON value (as produced by a json.Decoder with
+// UseNumber set) in the canonical form defined by RFC 8785: no insignificant
+// whitespace, object keys sorted by their UTF-16 code units, numbers in the
+// shortest form that round-trips (as in JavaScript), and strings with only the
+// required characters escaped.
+func canonicalJSON(val interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	err := writeCanonical(&buf, val)
+	return buf.Bytes(), err
+}
+
+func writeCanonical(buf *bytes.Buffer, val interface{}) error {
+	switch val := val.(type) {
+	case nil:
+		buf.WriteString("null")
+	case bool:
+		buf.WriteString(strconv.FormatBool(val))
+	case string:
+		writeCanonicalString(buf, val)
+	case json.Number:
+		f, err := val.Float64()
+		if err != nil {
+			return err
+		}
+
+		s, err := canonicalNumber(f)
+		if err != nil {
+			return err
+		}
+		buf.WriteString(s)
+	case float64:
+		s, err := canonicalNumber(val)
+		if err != nil {
+			return err
+		}
+		buf.WriteString(s)
+	case []interface{}:
+		buf.WriteByte('[')
+		for i, elem := range val {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+
+			err := writeCanonical(buf, elem)
+			if err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+	case map[string]interface{}:
+		keys := make([]string, 0, len(val))
+		for k := range val {
+			keys = append(keys, k)
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			return lessUTF16(keys[i], keys[j])
+		})
+
+		buf.WriteByte('{')
+		for i, k := range keys {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+
+			writeCanonicalString(buf, k)
+			buf.WriteByte(':')
+			err := writeCanonical(buf, val[k])
+			if err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+	default:
+		return fmt.Errorf("jsonbody: cannot canonicalize value of type %T", val)
+	}
+
+	return nil
+}
+
+func lessUTF16(a string, b string) bool {
+	ua, ub := utf16.Encode([]rune(a)), utf16.Encode([]rune(b))
+	for i := 0; i < len(ua) && i < len(ub); i++ {
+		if ua[i] != ub[i] {
+			return ua[i] < ub[i]
+		}
+	}
+
+	return len(ua) < len(ub)
+}
+
+func writeCanonicalString(buf *bytes.Buffer, s string) {
+	buf.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			buf.WriteString(`\"`)
+		case '\\':
+			buf.WriteString(`\\`)
+		case '\b':
+			buf.WriteString(`\b`)
+		case '\f':
+			buf.WriteString(`\f`)
+		case '\n':
+			buf.WriteString(`\n`)
+		case '\r':
+			buf.WriteString(`\r`)
+		case '\t':
+			buf.WriteString(`\t`)
+		default:
+			if r < 0x20 {
+				fmt.Fprintf(buf, `\u%04x`, r)
+			} else {
+				buf.WriteRune(r)
+			}
+		}
+	}
+	buf.WriteByte('"')
+}
+
+// canonicalNumber formats f in the same way as JavaScript's Number.toString.
+func canonicalNumber(f float64) (string, error) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return "", fmt.Errorf("jsonbody: cannot canonicalize number %v", f)
+	}
+
+	if f == 0 {
+		return "0", nil
+	}
+
+	abs := math.Abs(f)
+	if abs >= 1e-6 && abs < 1e21 {
+		return strconv.FormatFloat(f, 'f', -1, 64), nil
+	}
+
+	s := strconv.FormatFloat(f, 'e', -1, 64) // e.g. 1e+21 or 1.5e-07
+	i := strings.IndexByte(s, 'e')
+	mantissa, exp := s[:i], s[i+1:]
+	sign := exp[:1]
+	exp = strings.TrimLeft(exp[1:], "0")
+
+	return mantissa + "e" + sign + exp, nil
+}
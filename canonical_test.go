@@ -0,0 +1,48 @@
+package jsonbody
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCanonicalNumberMatchesJavaScript(t *testing.T) {
+	for f, expected := range map[float64]string{
+		0:        "0",
+		-1:       "-1",
+		1.5:      "1.5",
+		1e21:     "1e+21",
+		1e20:     "100000000000000000000",
+		0.000001: "0.000001",
+		1.5e-7:   "1.5e-7",
+		333.0003: "333.0003",
+	} {
+		s, err := canonicalNumber(f)
+		assert.Nil(t, err)
+		assert.Equal(t, expected, s)
+	}
+}
+
+func TestCanonicalJSONSortsKeysAndEscapesMinimally(t *testing.T) {
+	b, err := canonicalJSON(map[string]interface{}{
+		"b":          []interface{}{true, nil, "<a>\n\u2028"},
+		"a":          map[string]interface{}{"\u20ac": 1.0, "\r": 2.0},
+		"\U0001F600": "emoji",
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "{\"a\":{\"\\r\":2,\"\u20ac\":1},\"b\":[true,null,\"<a>\\n\u2028\"],\"\U0001F600\":\"emoji\"}", string(b))
+}
+
+func TestWriteJSONWritesCanonicalJSONIfConfigured(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	w := Writer{ResponseWriter: recorder, config: &writerConfig{canonical: true}}
+
+	err := w.WriteJSON(200, struct {
+		Z float64 `json:"z"`
+		A string  `json:"a"`
+	}{Z: 1e21, A: "&"})
+	assert.Nil(t, err)
+
+	assert.Equal(t, `{"a":"&","z":1e+21}`, recorder.Body.String())
+}
@@ -0,0 +1,229 @@
+package jsonbody
+
+import (
+	"encoding/json"
+	"io"
+	"sort"
+	"strings"
+)
+
+// ErrorCatalog lists the errors that requests can produce, with their messages
+// in each language, so that clients can translate them or map them to form
+// fields ahead of time. It is created by NewErrorCatalog and is typically
+// written to a file with WriteJSON as part of a build.
+type ErrorCatalog struct {
+	// Messages holds the message template for every error code, by locale and
+	// then by code. Templates refer to an error's key as {key} and to its
+	// params by name (see ValidationError).
+	Messages map[string]map[string]string `json:"messages"`
+
+	// Schemas holds the errors that each schema can produce, by schema name.
+	Schemas map[string][]CatalogEntry `json:"schemas"`
+}
+
+// CatalogEntry is an error that a schema can produce.
+type CatalogEntry struct {
+	// Key is the key of the value the error is about, e.g. "author.name", or ""
+	// if the error is about the whole body. The elements of arrays are referred
+	// to with "[]", e.g. "tags[]".
+	Key string `json:"key,omitempty"`
+
+	// Code is the error's code, including the schema's error code prefix.
+	Code string `json:"code"`
+
+	// Params holds the values substituted into the message template in
+	// addition to the key.
+	Params map[string]string `json:"params,omitempty"`
+
+	// Messages holds the error's message by locale.
+	Messages map[string]string `json:"messages"`
+}
+
+// defaultCatalogLocale is the locale of the default message catalog when no
+// Translations are given.
+const defaultCatalogLocale = "en"
+
+// NewErrorCatalog creates an ErrorCatalog for the given schemas, keyed by name
+// (e.g. the schemas' "$schemaName" keys), with the messages of the default
+// message catalog and of each locale in translations, which may be nil.
+func NewErrorCatalog(schemas map[string]*Schema, translations *Translations) *ErrorCatalog {
+	locales := []string{defaultCatalogLocale}
+	if translations != nil {
+		locales = []string{translations.defaultLocale}
+		for _, name := range translations.names {
+			if !strings.EqualFold(name, translations.defaultLocale) {
+				locales = append(locales, name)
+			}
+		}
+	}
+
+	translate := func(locale string, e ValidationError) string {
+		if translations == nil {
+			return e.Error()
+		}
+
+		return translations.Translate(locale, e)
+	}
+
+	catalog := &ErrorCatalog{
+		Messages: make(map[string]map[string]string),
+		Schemas:  make(map[string][]CatalogEntry),
+	}
+
+	for _, locale := range locales {
+		templates := make(map[string]string, len(messageCatalog))
+		for code, template := range messageCatalog {
+			templates[code] = template
+			if translations != nil {
+				for _, candidate := range translations.chain(locale) {
+					if translated, ok := translations.bundles[candidate][code]; ok {
+						templates[code] = translated
+						break
+					}
+				}
+			}
+		}
+		catalog.Messages[locale] = templates
+	}
+
+	for name, schema := range schemas {
+		entries := make([]CatalogEntry, 0)
+		for _, e := range schema.possibleErrors() {
+			e.Namespace = schema.ErrorCodePrefix()
+
+			messages := make(map[string]string, len(locales))
+			for _, locale := range locales {
+				messages[locale] = translate(locale, e)
+			}
+
+			entries = append(entries, CatalogEntry{Key: e.Key, Code: e.QualifiedCode(), Params: e.Params, Messages: messages})
+		}
+		catalog.Schemas[name] = entries
+	}
+
+	return catalog
+}
+
+// WriteJSON writes the catalog to out as indented JSON.
+func (c *ErrorCatalog) WriteJSON(out io.Writer) error {
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(c)
+}
+
+// possibleErrors returns the errors that validating a request against the
+// schema can produce, with the keys of array elements written as "[]".
+func (s *Schema) possibleErrors() []ValidationError {
+	errs := make([]ValidationError, 0)
+	if s == nil {
+		return errs
+	}
+
+	for _, part := range s.allOf {
+		errs = append(errs, part.possibleErrors()...)
+	}
+
+	switch {
+	case s.array != nil:
+		errs = append(errs,
+			ValidationError{Code: CodeExpectedBody},
+			ValidationError{Code: CodeBodyWrongType, Params: map[string]string{"type": "array"}})
+		if len(s.array) > 0 {
+			errs = append(errs, possibleValueErrors("[]", s.array[0])...)
+		}
+	case s.scalar != nil:
+		errs = append(errs,
+			ValidationError{Code: CodeExpectedBody},
+			ValidationError{Code: CodeBodyWrongType, Params: map[string]string{"type": jsonTypeName(s.scalar)}})
+	case s.body != nil:
+		errs = append(errs,
+			ValidationError{Code: CodeExpectedBody},
+			ValidationError{Code: CodeBodyWrongType, Params: map[string]string{"type": "object"}})
+		errs = append(errs, possibleObjectErrors("", s.body)...)
+	}
+
+	aliasPaths := make([]string, 0, len(s.aliases))
+	for path := range s.aliases {
+		aliasPaths = append(aliasPaths, path)
+	}
+	sort.Strings(aliasPaths)
+	for _, path := range aliasPaths {
+		errs = append(errs, ValidationError{Key: path, Code: CodeDeprecatedKey, Params: map[string]string{"replacement": s.aliases[path]}})
+	}
+
+	for _, param := range sortedSchemaKeys(s.query) {
+		name := strings.TrimPrefix(param, "?")
+		if !strings.HasPrefix(param, "?") {
+			errs = append(errs, ValidationError{Key: name, Code: CodeMissingParam})
+		}
+
+		expected := s.query[param]
+		if arr, ok := expected.([]interface{}); ok && len(arr) > 0 {
+			expected = arr[0]
+		}
+		if c, ok := expected.(*constraint); ok {
+			if c.typ != "" && c.typ != "string" {
+				errs = append(errs, ValidationError{Key: name, Code: CodeParamWrongType, Params: map[string]string{"type": c.typ}})
+			}
+			for _, err := range c.possibleErrors(name) {
+				if err.Code != CodeWrongType {
+					errs = append(errs, err)
+				}
+			}
+			continue
+		}
+		if typ, _ := queryValueMatches(expected, ""); typ != "string" {
+			errs = append(errs, ValidationError{Key: name, Code: CodeParamWrongType, Params: map[string]string{"type": typ}})
+		}
+	}
+
+	return errs
+}
+
+func possibleObjectErrors(key string, expected map[string]interface{}) []ValidationError {
+	errs := make([]ValidationError, 0)
+	for _, expectedKey := range sortedSchemaKeys(expected) {
+		newKey := joinKey(key, strings.TrimPrefix(expectedKey, "?"))
+		if !strings.HasPrefix(expectedKey, "?") {
+			errs = append(errs, ValidationError{Key: newKey, Code: CodeMissingKey})
+		}
+
+		errs = append(errs, possibleValueErrors(newKey, expected[expectedKey])...)
+	}
+
+	return errs
+}
+
+func possibleValueErrors(key string, expected interface{}) []ValidationError {
+	switch expected := expected.(type) {
+	case []interface{}:
+		errs := []ValidationError{wrongType(key, "array")}
+		if len(expected) > 0 {
+			errs = append(errs, possibleValueErrors(key+"[]", expected[0])...)
+		}
+		return errs
+	case *constraint:
+		return expected.possibleErrors(key)
+	case map[string]interface{}:
+		return append([]ValidationError{wrongType(key, "object")}, possibleObjectErrors(key, expected)...)
+	}
+
+	return []ValidationError{wrongType(key, jsonTypeName(expected))}
+}
+
+// sortedSchemaKeys returns the keys of a schema object, sorted without their
+// question marks, and excluding metadata keys like "$schemaName".
+func sortedSchemaKeys(obj map[string]interface{}) []string {
+	keys := make([]string, 0, len(obj))
+	for k := range obj {
+		if !strings.HasPrefix(k, "$") {
+			keys = append(keys, k)
+		}
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		return strings.TrimPrefix(keys[i], "?") < strings.TrimPrefix(keys[j], "?")
+	})
+
+	return keys
+}
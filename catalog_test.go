@@ -0,0 +1,86 @@
+package jsonbody
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSchemaPossibleErrors(t *testing.T) {
+	s := MustParseSchema(`{
+		"$errorCodePrefix": "POSTS_",
+		"$query": {"?page": 0},
+		"title": "",
+		"?tags": [""],
+		"status": {"$enum": ["draft", "published"]}
+	}`)
+
+	keys := make([]string, 0)
+	for _, e := range s.possibleErrors() {
+		keys = append(keys, e.Key+" "+e.Code)
+	}
+	assert.Equal(t, []string{
+		" expected_body",
+		" body_wrong_type",
+		"status missing_key",
+		"status not_in_enum",
+		"tags wrong_type",
+		"tags[] wrong_type",
+		"title missing_key",
+		"title wrong_type",
+		"page param_wrong_type",
+	}, keys)
+}
+
+func TestNewErrorCatalogTranslatesMessages(t *testing.T) {
+	catalog := NewErrorCatalog(map[string]*Schema{
+		"CreatePost": MustParseSchema(`{"$errorCodePrefix": "POSTS_", "title": ""}`),
+	}, newTestTranslations())
+
+	assert.Equal(t, "clé '{key}' manquante", catalog.Messages["fr-CA"][CodeMissingKey])
+	assert.Equal(t, "la valeur de la clé '{key}' doit être de type {type}", catalog.Messages["fr-CA"][CodeWrongType])
+	assert.Equal(t, "chave '{key}' em falta", catalog.Messages["pt-BR"][CodeMissingKey])
+	assert.Equal(t, messageCatalog[CodeTimeout], catalog.Messages["en"][CodeTimeout])
+
+	entries := catalog.Schemas["CreatePost"]
+	assert.Len(t, entries, 4)
+	assert.Equal(t, CatalogEntry{
+		Key:  "title",
+		Code: "POSTS_missing_key",
+		Messages: map[string]string{
+			"en":    "expected key 'title' missing",
+			"fr":    "clé attendue 'title' manquante",
+			"fr-CA": "clé 'title' manquante",
+			"pt-PT": "chave 'title' em falta",
+			"pt-BR": "chave 'title' em falta",
+		},
+	}, entries[2])
+
+	var buf bytes.Buffer
+	assert.Nil(t, catalog.WriteJSON(&buf))
+	var decoded ErrorCatalog
+	assert.Nil(t, json.Unmarshal(buf.Bytes(), &decoded))
+	assert.Equal(t, *catalog, decoded)
+}
+
+func TestNewErrorCatalogUsesDefaultMessagesWithoutTranslations(t *testing.T) {
+	catalog := NewErrorCatalog(map[string]*Schema{"Tags": MustParseSchema(`[{"$type": "integer", "$min": 1}]`)}, nil)
+
+	assert.Equal(t, []string{"en"}, mapKeys(catalog.Messages))
+	assert.Equal(t, []CatalogEntry{
+		{Code: CodeExpectedBody, Messages: map[string]string{"en": "expected a JSON body"}},
+		{Code: CodeBodyWrongType, Params: map[string]string{"type": "array"}, Messages: map[string]string{"en": "body expected to be of type array"}},
+		{Key: "[]", Code: CodeWrongType, Params: map[string]string{"type": "integer"}, Messages: map[string]string{"en": "value for key '[]' expected to be of type integer"}},
+		{Key: "[]", Code: CodeTooSmall, Params: map[string]string{"min": "1"}, Messages: map[string]string{"en": "value for key '[]' must be at least 1"}},
+	}, catalog.Schemas["Tags"])
+}
+
+func mapKeys(m map[string]map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	return keys
+}
@@ -0,0 +1,128 @@
+package jsonbody
+
+import (
+	"errors"
+	"mime"
+	"net/http"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// transcoders convert request bodies in the charsets supported by
+// WithCharsetTranscoding to UTF-8, keyed by the lowercased charset name.
+var transcoders = map[string]func(body []byte) ([]byte, error){
+	"iso-8859-1": decodeLatin1,
+	"latin1":     decodeLatin1,
+	"utf-16":     func(body []byte) ([]byte, error) { return decodeUTF16(body, true) },
+	"utf-16be":   func(body []byte) ([]byte, error) { return decodeUTF16(body, true) },
+	"utf-16le":   func(body []byte) ([]byte, error) { return decodeUTF16(body, false) },
+}
+
+// requestCharset returns the lowercased charset parameter of r's Content-Type,
+// or "" if there isn't one.
+func requestCharset(r *http.Request) string {
+	_, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		return ""
+	}
+
+	return strings.ToLower(params["charset"])
+}
+
+// isJSONContentType reports whether contentType is application/json, a media
+// type with a +json suffix (such as a vendor media type configured with
+// WithSchemaVersions), or one configured with WithContentTypes. Unless charset
+// transcoding is enabled, the only charset parameter allowed is UTF-8.
+func (m *middleware) isJSONContentType(contentType string) bool {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+
+	if charset, ok := params["charset"]; ok && m.transcodeMaxSize <= 0 && !strings.EqualFold(charset, "utf-8") {
+		return false
+	}
+
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json") || m.contentTypes[mediaType]
+}
+
+// checkCharset checks the charset of r's body before it is read, returning the
+// status and error to reject it with, or 0 if it should be accepted.
+func (m *middleware) checkCharset(r *http.Request) (int, ValidationError) {
+	if m.transcodeMaxSize <= 0 || r.ContentLength == 0 {
+		return 0, ValidationError{}
+	}
+
+	charset := requestCharset(r)
+	if charset == "" || charset == "utf-8" {
+		return 0, ValidationError{}
+	}
+
+	if _, ok := transcoders[charset]; !ok {
+		return http.StatusUnsupportedMediaType, ValidationError{
+			Code:   CodeUnsupportedCharset,
+			Params: map[string]string{"charset": charset},
+		}
+	}
+
+	if r.ContentLength > m.transcodeMaxSize {
+		return http.StatusRequestEntityTooLarge, bodyTooLarge(m.transcodeMaxSize)
+	}
+
+	return 0, ValidationError{}
+}
+
+// transcoder returns the function converting r's body to UTF-8, or nil if it
+// doesn't need to be converted.
+func (m *middleware) transcoder(r *http.Request) func([]byte) ([]byte, error) {
+	if m.transcodeMaxSize <= 0 {
+		return nil
+	}
+
+	return transcoders[requestCharset(r)]
+}
+
+func decodeLatin1(body []byte) ([]byte, error) {
+	utf8Body := make([]byte, 0, len(body))
+	for _, b := range body {
+		utf8Body = utf8.AppendRune(utf8Body, rune(b))
+	}
+
+	return utf8Body, nil
+}
+
+// decodeUTF16 decodes body as UTF-16. A byte order mark takes precedence over
+// bigEndian and is removed.
+func decodeUTF16(body []byte, bigEndian bool) ([]byte, error) {
+	if len(body)%2 != 0 {
+		return nil, errors.New("UTF-16 body has an odd number of bytes")
+	}
+
+	if len(body) >= 2 {
+		switch {
+		case body[0] == 0xFE && body[1] == 0xFF:
+			bigEndian = true
+			body = body[2:]
+		case body[0] == 0xFF && body[1] == 0xFE:
+			bigEndian = false
+			body = body[2:]
+		}
+	}
+
+	units := make([]uint16, len(body)/2)
+	for i := range units {
+		if bigEndian {
+			units[i] = uint16(body[2*i])<<8 | uint16(body[2*i+1])
+		} else {
+			units[i] = uint16(body[2*i+1])<<8 | uint16(body[2*i])
+		}
+	}
+
+	utf8Body := make([]byte, 0, len(body))
+	for _, r := range utf16.Decode(units) {
+		utf8Body = utf8.AppendRune(utf8Body, r)
+	}
+
+	return utf8Body, nil
+}
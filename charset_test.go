@@ -0,0 +1,106 @@
+package jsonbody
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecodeLatin1(t *testing.T) {
+	body, err := decodeLatin1([]byte("{\"a\": \"caf\xe9\"}"))
+	assert.Nil(t, err)
+	assert.Equal(t, `{"a": "café"}`, string(body))
+}
+
+func TestDecodeUTF16(t *testing.T) {
+	body, err := decodeUTF16([]byte{0, '"', 0xD8, 0x3D, 0xDE, 0x00, 0, '"'}, true)
+	assert.Nil(t, err)
+	assert.Equal(t, `"😀"`, string(body))
+
+	body, err = decodeUTF16([]byte{0xFF, 0xFE, '"', 0, 0xE9, 0, '"', 0}, true)
+	assert.Nil(t, err)
+	assert.Equal(t, `"é"`, string(body))
+
+	_, err = decodeUTF16([]byte{0, '"', 0}, true)
+	assert.NotNil(t, err)
+}
+
+func TestServeHTTPTranscodesCharsets(t *testing.T) {
+	var got interface{}
+	var raw []byte
+	handler := NewMiddleware(`{"a": ""}`, WithCharsetTranscoding(1024))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Body.(Reader).JSON()["a"]
+		raw, _ = ioutil.ReadAll(r.Body)
+	}))
+
+	for _, tc := range []struct {
+		contentType string
+		body        []byte
+	}{
+		{"application/json; charset=ISO-8859-1", []byte("{\"a\": \"caf\xe9\"}")},
+		{"application/json; charset=utf-16le", []byte{'{', 0, '"', 0, 'a', 0, '"', 0, ':', 0, '"', 0, 'c', 0, 'a', 0, 'f', 0, 0xE9, 0, '"', 0, '}', 0}},
+		{"application/json; charset=utf-8", []byte(`{"a":"café"}`)},
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(tc.body))
+		req.Header.Set("Content-Type", tc.contentType)
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+
+		assert.Equal(t, 200, recorder.Code, tc.contentType)
+		assert.Equal(t, "café", got, tc.contentType)
+		assert.Contains(t, string(raw), "café", tc.contentType)
+	}
+}
+
+func TestServeHTTPRejectsUntranscodableBodies(t *testing.T) {
+	handler := NewMiddleware(`{"a": ""}`, WithCharsetTranscoding(8))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader([]byte(`{"a": ""}`)))
+	req.Header.Set("Content-Type", "application/json; charset=shift_jis")
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, req)
+	assert.Equal(t, 415, recorder.Code)
+	assert.Equal(t, `{"errors":["charset 'shift_jis' is not supported"]}`, recorder.Body.String())
+
+	req = httptest.NewRequest(http.MethodPost, "/", bytes.NewReader([]byte(`{"a": ""}`)))
+	req.Header.Set("Content-Type", "application/json; charset=latin1")
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, req)
+	assert.Equal(t, 413, recorder.Code)
+	assert.Equal(t, `{"errors":["body must be at most 8 bytes"]}`, recorder.Body.String())
+}
+
+func TestServeHTTPRejectsOtherCharsetsWithoutTranscoding(t *testing.T) {
+	handler := NewMiddleware(`{"a": ""}`)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader([]byte(`{"a": ""}`)))
+	req.Header.Set("Content-Type", "application/json; charset=latin1")
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, req)
+	assert.Equal(t, 400, recorder.Code)
+}
+
+func TestServeHTTPAcceptsJSONMediaTypes(t *testing.T) {
+	handler := NewMiddleware(`{"a": ""}`, WithContentTypes("text/json"))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	for contentType, status := range map[string]int{
+		"application/json":                        200,
+		"Application/JSON; charset=UTF-8":         200,
+		"application/vnd.myapi+json":              200,
+		"application/problem+json; charset=utf-8": 200,
+		"text/json":                 200,
+		"text/plain":                400,
+		"application/jsonx":         400,
+		"application/json; charset": 400,
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader([]byte(`{"a": ""}`)))
+		req.Header.Set("Content-Type", contentType)
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+		assert.Equal(t, status, recorder.Code, contentType)
+	}
+}
@@ -0,0 +1,89 @@
+package jsonbody
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// chunkedRequest creates a request whose body has an unknown length, as for a
+// request with "Transfer-Encoding: chunked".
+func chunkedRequest(body string) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "/", io.MultiReader(strings.NewReader(body)))
+	req.Header.Set("Content-Type", "application/json")
+	return req
+}
+
+func TestServeHTTPReadsBodiesOfUnknownLength(t *testing.T) {
+	var got interface{}
+	var raw []byte
+	handler := NewMiddleware(`{"a": ""}`)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Body.(Reader).JSON()["a"]
+		raw, _ = ioutil.ReadAll(r.Body)
+	}))
+
+	req := chunkedRequest(`{"a": "chunked"}`)
+	assert.Equal(t, int64(-1), req.ContentLength)
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, req)
+
+	assert.Equal(t, 200, recorder.Code)
+	assert.Equal(t, "chunked", got)
+	assert.Equal(t, `{"a": "chunked"}`, string(raw))
+}
+
+func TestServeHTTPTreatsEmptyBodyOfUnknownLengthAsNoBody(t *testing.T) {
+	handler := NewMiddleware(`{"a": ""}`)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, chunkedRequest(""))
+
+	assert.Equal(t, 400, recorder.Code)
+	assert.Equal(t, `{"errors":["expected a JSON body"]}`, recorder.Body.String())
+}
+
+func TestServeHTTPLimitsBodiesOfUnknownLength(t *testing.T) {
+	handler := NewMiddleware("", WithMaxBodySize(8))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, chunkedRequest(`{"a": "too long"}`))
+
+	assert.Equal(t, 413, recorder.Code)
+	assert.Equal(t, `{"errors":["body must be at most 8 bytes"]}`, recorder.Body.String())
+
+	handler = NewMiddleware("", WithCharsetTranscoding(8))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	req := chunkedRequest(`{"a": "too long"}`)
+	req.Header.Set("Content-Type", "application/json; charset=latin1")
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, req)
+
+	assert.Equal(t, 413, recorder.Code)
+}
+
+func TestServeHTTPSpillsBodiesOfUnknownLength(t *testing.T) {
+	var spilled bool
+	var raw []byte
+	handler := NewMiddleware(`{"a": ""}`, WithSpillToDisk(8, t.TempDir()))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, spilled = r.Body.(Reader).ReadCloser.(spilledBody)
+		raw, _ = ioutil.ReadAll(r.Body)
+	}))
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, chunkedRequest(`{"a": "spilled to disk"}`))
+
+	assert.Equal(t, 200, recorder.Code)
+	assert.True(t, spilled)
+	assert.Equal(t, `{"a": "spilled to disk"}`, string(raw))
+
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, chunkedRequest(`{"a":""}`))
+
+	assert.Equal(t, 200, recorder.Code)
+	assert.False(t, spilled)
+}
@@ -0,0 +1,81 @@
+package jsonbody
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// coerce converts the strings in body that the schema expects to be numbers or
+// booleans, like "42" or "true", into those types, for WithCoercion. Values
+// that can't be converted are left as they are, so that they are reported as
+// having the wrong type. Objects and arrays are converted in place, and the
+// possibly converted body is returned.
+func (s *Schema) coerce(body interface{}) interface{} {
+	if s == nil || body == nil {
+		return body
+	}
+
+	for _, expected := range s.withoutGrace().values() {
+		body = coerceValue(expected, body)
+	}
+
+	return body
+}
+
+func coerceObject(expected map[string]interface{}, actual map[string]interface{}) {
+	for key, expectedVal := range expected {
+		key = strings.TrimPrefix(key, "?")
+		if actualVal, ok := actual[key]; ok {
+			actual[key] = coerceValue(expectedVal, actualVal)
+		}
+	}
+}
+
+// coerceValue returns actual converted to the type of the schema value
+// expected, if possible, converting the contents of objects and arrays in
+// place.
+func coerceValue(expected interface{}, actual interface{}) interface{} {
+	switch expected := expected.(type) {
+	case float64:
+		return coerceScalar("number", actual)
+	case bool:
+		return coerceScalar("boolean", actual)
+	case *constraint:
+		return coerceScalar(expected.typ, actual)
+	case map[string]interface{}:
+		if actualObj, ok := actual.(map[string]interface{}); ok {
+			coerceObject(expected, actualObj)
+		}
+	case []interface{}:
+		if actualArr, ok := actual.([]interface{}); ok && len(expected) > 0 {
+			for i, elem := range actualArr {
+				actualArr[i] = coerceValue(expected[0], elem)
+			}
+		}
+	}
+
+	return actual
+}
+
+// coerceScalar converts actual to typ ("number", "integer", or "boolean") if it
+// is a string that can be parsed as one.
+func coerceScalar(typ string, actual interface{}) interface{} {
+	str, ok := actual.(string)
+	if !ok {
+		return actual
+	}
+
+	switch typ {
+	case "number", "integer":
+		if n, err := strconv.ParseFloat(strings.TrimSpace(str), 64); err == nil && !math.IsNaN(n) && !math.IsInf(n, 0) {
+			return n
+		}
+	case "boolean":
+		if str == "true" || str == "false" {
+			return str == "true"
+		}
+	}
+
+	return actual
+}
@@ -0,0 +1,45 @@
+package jsonbody
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCoerceConvertsStrings(t *testing.T) {
+	schema := MustParseSchema(`{"n": 0, "b": false, "s": "", "c": {"$type": "integer"}, "o": {"n": 0}, "a": [true], "?x": 0, "?y": 0}`)
+	body := decodeObject(t, `{"n": "42", "b": "true", "s": "7", "c": " 3 ", "o": {"n": "1.5"}, "a": ["false", "no"], "x": "abc", "y": "NaN"}`)
+
+	schema.coerce(body)
+
+	assert.Equal(t, decodeObject(t, `{"n": 42, "b": true, "s": "7", "c": 3, "o": {"n": 1.5}, "a": [false, "no"], "x": "abc", "y": "NaN"}`), body)
+}
+
+func TestServeHTTPCoercesIfEnabled(t *testing.T) {
+	var body map[string]interface{}
+	handler := NewMiddleware(`{"age": {"$type": "integer", "$min": 0}, "admin": false}`, WithCoercion())(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body = r.Body.(Reader).JSON()
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"age": "42", "admin": "false"}`))
+	req.Header.Set("Content-Type", "application/json")
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, req)
+
+	assert.Equal(t, 200, recorder.Code)
+	assert.Equal(t, map[string]interface{}{"age": 42.0, "admin": false}, body)
+
+	req = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"age": "-1", "admin": "no"}`))
+	req.Header.Set("Content-Type", "application/json")
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, req)
+
+	assert.Equal(t, `{"errors":["value for key 'admin' expected to be of type boolean","value for key 'age' must be at least 0"]}`, recorder.Body.String())
+}
+
+func TestCoerceConvertsTopLevelScalar(t *testing.T) {
+	assert.Equal(t, 42.0, MustParseSchema(`0`).coerce("42"))
+}
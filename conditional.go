@@ -0,0 +1,69 @@
+package jsonbody
+
+import (
+	"net/http"
+	"strings"
+	"time"
+)
+
+// CheckPreconditions evaluates the If-Match and If-Unmodified-Since headers of r
+// against the current state of the resource being modified, as described in RFC
+// 7232. etag is the resource's current entity tag, including quotes (e.g.
+// `"v2"`), or "" if it doesn't exist; lastModified is the time the resource was
+// last modified, or the zero time if unknown.
+//
+// If the preconditions pass, nothing is written and true is returned. Otherwise,
+// a 412 response is sent with an error body in the same format as WriteErrors
+// and false is returned, in which case the handler should return without making
+// any changes.
+func (w *Writer) CheckPreconditions(r *http.Request, etag string, lastModified time.Time) bool {
+	if preconditionsPass(r, etag, lastModified) {
+		return true
+	}
+
+	w.writeErrors(http.StatusPreconditionFailed, ValidationError{Code: CodePreconditionFailed})
+	return false
+}
+
+func preconditionsPass(r *http.Request, etag string, lastModified time.Time) bool {
+	if ifMatch := r.Header.Get("If-Match"); ifMatch != "" {
+		return etagMatches(ifMatch, etag)
+	}
+
+	ifUnmodifiedSince := r.Header.Get("If-Unmodified-Since")
+	if ifUnmodifiedSince == "" || lastModified.IsZero() {
+		return true
+	}
+
+	since, err := http.ParseTime(ifUnmodifiedSince)
+	if err != nil {
+		return true // invalid dates are ignored
+	}
+
+	// HTTP dates only have second precision
+	return !lastModified.Truncate(time.Second).After(since)
+}
+
+// etagMatches reports whether etag matches any of the entity tags in the value
+// of an If-Match header using the strong comparison function.
+func etagMatches(header string, etag string) bool {
+	if etag == "" {
+		return false
+	}
+
+	if strings.TrimSpace(header) == "*" {
+		return true
+	}
+
+	if strings.HasPrefix(etag, "W/") {
+		return false // weak tags never match strongly
+	}
+
+	for _, tag := range strings.Split(header, ",") {
+		if strings.TrimSpace(tag) == etag {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,49 @@
+package jsonbody
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPreconditionsPassWithoutHeaders(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPut, "/", nil)
+	assert.True(t, preconditionsPass(r, `"v1"`, time.Now()))
+}
+
+func TestPreconditionsCheckIfMatch(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPut, "/", nil)
+	r.Header.Set("If-Match", `"v1", "v2"`)
+
+	assert.True(t, preconditionsPass(r, `"v2"`, time.Time{}))
+	assert.False(t, preconditionsPass(r, `"v3"`, time.Time{}))
+	assert.False(t, preconditionsPass(r, `W/"v2"`, time.Time{}))
+
+	r.Header.Set("If-Match", "*")
+	assert.True(t, preconditionsPass(r, `"v3"`, time.Time{}))
+	assert.False(t, preconditionsPass(r, "", time.Time{}))
+}
+
+func TestPreconditionsCheckIfUnmodifiedSince(t *testing.T) {
+	since := time.Date(2019, 9, 24, 10, 0, 0, 0, time.UTC)
+	r := httptest.NewRequest(http.MethodPut, "/", nil)
+	r.Header.Set("If-Unmodified-Since", since.Format(http.TimeFormat))
+
+	assert.True(t, preconditionsPass(r, "", since.Add(500*time.Millisecond)))
+	assert.False(t, preconditionsPass(r, "", since.Add(time.Second)))
+}
+
+func TestCheckPreconditionsSends412IfFailed(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	w := Writer{ResponseWriter: recorder}
+	r := httptest.NewRequest(http.MethodPut, "/", nil)
+	r.Header.Set("If-Match", `"v1"`)
+
+	ok := w.CheckPreconditions(r, `"v2"`, time.Time{})
+	assert.False(t, ok)
+	assert.Equal(t, 412, recorder.Code)
+	assert.Equal(t, `{"errors":["precondition failed: the resource has been modified"]}`, recorder.Body.String())
+}
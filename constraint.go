@@ -0,0 +1,291 @@
+package jsonbody
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Keys of constraint objects, which are used in schemas in place of sample
+// values to constrain values further than by their type, e.g.
+// {"$type": "number", "$min": 0, "$max": 150}.
+const (
+	constraintTypeKey = "$type"
+	constraintMinKey  = "$min"
+	constraintMaxKey  = "$max"
+
+	constraintPatternKey = "$pattern"
+	constraintEnumKey    = "$enum"
+	constraintDefaultKey = "$default"
+	constraintMessageKey = "$message"
+)
+
+// constraint is a parsed constraint object. Schemas replace their constraint
+// objects with *constraint values when they are created (see
+// compileConstraints), so each is only parsed once.
+type constraint struct {
+	typ string   // "string", "number", "integer", "boolean", "object", "array", or "" for any type
+	min *float64 // nil if there is no lower bound
+	max *float64 // nil if there is no upper bound
+
+	pattern *regexp.Regexp // nil if strings may have any value
+	enum    []interface{}  // the allowed values, or nil if any value is allowed
+
+	def        interface{} // the value inserted if an optional key is missing
+	hasDefault bool
+
+	message string // the message template of the errors for the value, if not the catalog's
+
+	raw map[string]interface{} // the constraint object, for copying the schema's JSON
+}
+
+// isConstraintObject reports whether obj is a constraint object rather than an
+// ordinary object in the schema, i.e. whether it has any of the keys "$type",
+// "$enum", "$default", and "$message".
+func isConstraintObject(obj map[string]interface{}) bool {
+	for _, key := range []string{constraintTypeKey, constraintEnumKey, constraintDefaultKey, constraintMessageKey} {
+		if _, ok := obj[key]; ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// parseConstraint parses obj as a constraint object, returning false if it is
+// an ordinary object in the schema (see isConstraintObject).
+func parseConstraint(obj map[string]interface{}) (constraint, bool, error) {
+	if !isConstraintObject(obj) {
+		return constraint{}, false, nil
+	}
+
+	typVal, hasType := obj[constraintTypeKey]
+	_, hasEnum := obj[constraintEnumKey]
+	def, hasDefault := obj[constraintDefaultKey]
+
+	c := constraint{def: def, hasDefault: hasDefault, raw: obj}
+	if !hasType && !hasEnum && def != nil {
+		c.typ = jsonTypeName(def) // the type of a value with only a default is that of the default
+	}
+
+	if hasType {
+		var ok bool
+		c.typ, ok = typVal.(string)
+		switch {
+		case !ok:
+			return c, true, fmt.Errorf("jsonbody: value for schema key '%v' must be a string", constraintTypeKey)
+		case c.typ != "string" && c.typ != "number" && c.typ != "integer" && c.typ != "boolean" && c.typ != "object" && c.typ != "array":
+			return c, true, fmt.Errorf("jsonbody: unknown type '%v' in schema", c.typ)
+		}
+	}
+
+	for key, val := range obj {
+		switch key {
+		case constraintTypeKey:
+		case constraintMinKey, constraintMaxKey:
+			n, ok := val.(float64)
+			if !ok {
+				return c, true, fmt.Errorf("jsonbody: value for schema key '%v' must be a number", key)
+			}
+			if c.typ != "number" && c.typ != "integer" {
+				return c, true, fmt.Errorf("jsonbody: schema key '%v' can only be used with type number or integer", key)
+			}
+
+			if key == constraintMinKey {
+				c.min = &n
+			} else {
+				c.max = &n
+			}
+		case constraintPatternKey:
+			pattern, ok := val.(string)
+			if !ok {
+				return c, true, fmt.Errorf("jsonbody: value for schema key '%v' must be a string", key)
+			}
+			if c.typ != "string" {
+				return c, true, fmt.Errorf("jsonbody: schema key '%v' can only be used with type string", key)
+			}
+
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return c, true, fmt.Errorf("jsonbody: invalid pattern in schema: %v", err)
+			}
+			c.pattern = re
+		case constraintEnumKey:
+			values, ok := val.([]interface{})
+			if !ok || len(values) == 0 {
+				return c, true, fmt.Errorf("jsonbody: value for schema key '%v' must be a non-empty array", key)
+			}
+
+			for _, v := range values {
+				typ := jsonTypeName(v)
+				if typ == "object" || typ == "array" {
+					return c, true, fmt.Errorf("jsonbody: values in schema key '%v' must be strings, numbers, booleans, or null", key)
+				}
+				if c.typ != "" && !matchesType(v, c.typ) {
+					return c, true, fmt.Errorf("jsonbody: values in schema key '%v' must be of type %v", key, c.typ)
+				}
+			}
+			c.enum = values
+		case constraintDefaultKey:
+		case constraintMessageKey:
+			message, ok := val.(string)
+			if !ok || message == "" {
+				return c, true, fmt.Errorf("jsonbody: value for schema key '%v' must be a non-empty string", key)
+			}
+			c.message = message
+		default:
+			return c, true, fmt.Errorf("jsonbody: unknown key '%v' in constraint object", key)
+		}
+	}
+
+	if c.min != nil && c.max != nil && *c.min > *c.max {
+		return c, true, fmt.Errorf("jsonbody: value for schema key '%v' must not be greater than '%v'", constraintMinKey, constraintMaxKey)
+	}
+
+	if c.hasDefault && c.def != nil && len(c.validate("", c.def)) > 0 {
+		return c, true, fmt.Errorf("jsonbody: value for schema key '%v' must satisfy the constraint object", constraintDefaultKey)
+	}
+
+	return c, true, nil
+}
+
+// compileConstraints checks that all of the constraint objects in the schema
+// value expected are valid, replacing each with its parsed *constraint. Objects
+// and arrays are updated in place, and the possibly replaced value is returned.
+func compileConstraints(expected interface{}) (interface{}, error) {
+	switch expected := expected.(type) {
+	case []interface{}:
+		for i, elem := range expected {
+			compiled, err := compileConstraints(elem)
+			if err != nil {
+				return nil, err
+			}
+			expected[i] = compiled
+		}
+	case map[string]interface{}:
+		c, ok, err := parseConstraint(expected)
+		if err != nil {
+			return nil, err
+		} else if ok {
+			return &c, nil
+		}
+
+		for key, val := range expected {
+			compiled, err := compileConstraints(val)
+			if err != nil {
+				return nil, err
+			}
+
+			if c, ok := compiled.(*constraint); ok && c.hasDefault && !strings.HasPrefix(key, "?") {
+				return nil, fmt.Errorf("jsonbody: schema key '%v' can only be used for optional keys", constraintDefaultKey)
+			}
+			expected[key] = compiled
+		}
+	}
+
+	return expected, nil
+}
+
+// validate checks actual against the constraint, giving the errors the
+// constraint's message, if any.
+func (c constraint) validate(key string, actual interface{}) []ValidationError {
+	errs := c.check(key, actual)
+	for i := range errs {
+		errs[i].Message = c.message
+	}
+
+	return errs
+}
+
+func (c constraint) check(key string, actual interface{}) []ValidationError {
+	if c.typ != "" && !matchesType(actual, c.typ) {
+		return []ValidationError{wrongType(key, c.typ)}
+	}
+
+	if str, ok := actual.(string); ok && c.pattern != nil && !c.pattern.MatchString(str) {
+		return []ValidationError{{Key: key, Code: CodePatternMismatch, Params: map[string]string{"pattern": c.pattern.String()}}}
+	}
+
+	if n, ok := actual.(float64); ok {
+		if c.min != nil && n < *c.min {
+			return []ValidationError{{Key: key, Code: CodeTooSmall, Params: map[string]string{"min": formatNumber(*c.min)}}}
+		}
+		if c.max != nil && n > *c.max {
+			return []ValidationError{{Key: key, Code: CodeTooLarge, Params: map[string]string{"max": formatNumber(*c.max)}}}
+		}
+	}
+
+	if c.enum != nil && !c.allows(actual) {
+		return []ValidationError{{Key: key, Code: CodeNotInEnum, Params: map[string]string{"values": c.enumString()}}}
+	}
+
+	return []ValidationError{}
+}
+
+// possibleErrors returns the errors that validate can return for key.
+func (c constraint) possibleErrors(key string) []ValidationError {
+	errs := make([]ValidationError, 0)
+	if c.typ != "" {
+		errs = append(errs, wrongType(key, c.typ))
+	}
+	if c.pattern != nil {
+		errs = append(errs, ValidationError{Key: key, Code: CodePatternMismatch, Params: map[string]string{"pattern": c.pattern.String()}})
+	}
+	if c.min != nil {
+		errs = append(errs, ValidationError{Key: key, Code: CodeTooSmall, Params: map[string]string{"min": formatNumber(*c.min)}})
+	}
+	if c.max != nil {
+		errs = append(errs, ValidationError{Key: key, Code: CodeTooLarge, Params: map[string]string{"max": formatNumber(*c.max)}})
+	}
+	if c.enum != nil {
+		errs = append(errs, ValidationError{Key: key, Code: CodeNotInEnum, Params: map[string]string{"values": c.enumString()}})
+	}
+
+	for i := range errs {
+		errs[i].Message = c.message
+	}
+
+	return errs
+}
+
+// allows reports whether actual is one of the values of the "$enum" key.
+func (c constraint) allows(actual interface{}) bool {
+	for _, v := range c.enum {
+		if v == actual {
+			return true
+		}
+	}
+
+	return false
+}
+
+// enumString returns the values of the "$enum" key as a comma-separated list of
+// JSON values, e.g. `"draft", "published"`.
+func (c constraint) enumString() string {
+	values := make([]string, len(c.enum))
+	for i, v := range c.enum {
+		b, _ := json.Marshal(v)
+		values[i] = string(b)
+	}
+
+	return strings.Join(values, ", ")
+}
+
+// matchesType reports whether the decoded value v is of the named type. Since
+// all numbers are decoded as float64, integers are numbers without a fractional
+// part.
+func matchesType(v interface{}, typ string) bool {
+	if typ == "integer" {
+		n, ok := v.(float64)
+		return ok && n == math.Trunc(n) && !math.IsInf(n, 0)
+	}
+
+	return jsonTypeName(v) == typ
+}
+
+func formatNumber(n float64) string {
+	return strconv.FormatFloat(n, 'f', -1, 64)
+}
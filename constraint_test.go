@@ -0,0 +1,143 @@
+package jsonbody
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSchemaValidatesNumberRanges(t *testing.T) {
+	schema := MustParseSchema(`{"age": {"$type": "number", "$min": 0, "$max": 150}, "?score": {"$type": "number", "$min": 0.5}, "tags": [{"$type": "string"}]}`)
+
+	tests := []struct {
+		body string
+		msgs []string
+	}{
+		{`{"age": 0, "tags": []}`, []string{}},
+		{`{"age": 150, "score": 0.5, "tags": ["a"]}`, []string{}},
+		{`{"age": -1, "tags": []}`, []string{"value for key 'age' must be at least 0"}},
+		{`{"age": 151, "tags": []}`, []string{"value for key 'age' must be at most 150"}},
+		{`{"age": 20, "score": 0.25, "tags": []}`, []string{"value for key 'score' must be at least 0.5"}},
+		{`{"age": "20", "tags": [1]}`, []string{"value for key 'age' expected to be of type number", "value for key 'tags[0]' expected to be of type string"}},
+		{`{"tags": []}`, []string{"expected key 'age' missing"}},
+	}
+
+	for _, test := range tests {
+		var body map[string]interface{}
+		assert.Nil(t, json.Unmarshal([]byte(test.body), &body))
+		assert.Equal(t, test.msgs, errorMessages(schema.validate(OrderAlphabetical, body, nil)), test.body)
+	}
+}
+
+func TestSchemaValidatesStringPatterns(t *testing.T) {
+	schema := MustParseSchema(`{"slug": {"$type": "string", "$pattern": "^[a-z0-9-]+$"}}`)
+
+	tests := []struct {
+		body string
+		msgs []string
+	}{
+		{`{"slug": "my-post-2"}`, []string{}},
+		{`{"slug": "My Post"}`, []string{"value for key 'slug' must match the pattern ^[a-z0-9-]+$"}},
+		{`{"slug": 2}`, []string{"value for key 'slug' expected to be of type string"}},
+	}
+
+	for _, test := range tests {
+		var body map[string]interface{}
+		assert.Nil(t, json.Unmarshal([]byte(test.body), &body))
+		assert.Equal(t, test.msgs, errorMessages(schema.validate(OrderAlphabetical, body, nil)), test.body)
+	}
+}
+
+func TestSchemaValidatesEnums(t *testing.T) {
+	schema := MustParseSchema(`{"status": {"$enum": ["draft", "published", "archived"]}, "?priority": {"$type": "number", "$enum": [1, 2, 3]}}`)
+
+	tests := []struct {
+		body string
+		msgs []string
+	}{
+		{`{"status": "draft", "priority": 2}`, []string{}},
+		{`{"status": "deleted"}`, []string{`value for key 'status' must be one of "draft", "published", "archived"`}},
+		{`{"status": 1, "priority": 4}`, []string{"value for key 'priority' must be one of 1, 2, 3", `value for key 'status' must be one of "draft", "published", "archived"`}},
+		{`{"status": "draft", "priority": "1"}`, []string{"value for key 'priority' expected to be of type number"}},
+	}
+
+	for _, test := range tests {
+		var body map[string]interface{}
+		assert.Nil(t, json.Unmarshal([]byte(test.body), &body))
+		assert.Equal(t, test.msgs, errorMessages(schema.validate(OrderAlphabetical, body, nil)), test.body)
+	}
+}
+
+func TestSchemaValidatesIntegers(t *testing.T) {
+	schema := MustParseSchema(`{"count": {"$type": "integer", "$min": 1}, "?sizes": [{"$type": "integer", "$enum": [1, 2]}]}`)
+
+	tests := []struct {
+		body string
+		msgs []string
+	}{
+		{`{"count": 3, "sizes": [1, 2.0]}`, []string{}},
+		{`{"count": 3.5}`, []string{"value for key 'count' expected to be of type integer"}},
+		{`{"count": "3"}`, []string{"value for key 'count' expected to be of type integer"}},
+		{`{"count": 0}`, []string{"value for key 'count' must be at least 1"}},
+		{`{"count": 1e3, "sizes": [1.5]}`, []string{"value for key 'sizes[0]' expected to be of type integer"}},
+	}
+
+	for _, test := range tests {
+		var body map[string]interface{}
+		assert.Nil(t, json.Unmarshal([]byte(test.body), &body))
+		assert.Equal(t, test.msgs, errorMessages(schema.validate(OrderAlphabetical, body, nil)), test.body)
+	}
+}
+
+func TestParseSchemaRejectsInvalidConstraints(t *testing.T) {
+	for _, schemaJSON := range []string{
+		`{"a": {"$type": 1}}`,
+		`{"a": {"$type": "date"}}`,
+		`{"a": {"$type": "number", "$min": "0"}}`,
+		`{"a": {"$type": "string", "$max": 10}}`,
+		`{"a": {"$type": "number", "$min": 10, "$max": 0}}`,
+		`{"a": [{"$type": "number", "$step": 1}]}`,
+		`{"a": {"$type": "string", "$pattern": "("}}`,
+		`{"a": {"$type": "string", "$pattern": 1}}`,
+		`{"a": {"$type": "number", "$pattern": "^1$"}}`,
+		`{"a": {"$enum": []}}`,
+		`{"a": {"$enum": "draft"}}`,
+		`{"a": {"$enum": [{}]}}`,
+		`{"a": {"$type": "string", "$enum": ["a", 1]}}`,
+		`{"a": {"$type": "integer", "$enum": [1.5]}}`,
+		`{"a": {"$type": "string", "$message": ""}}`,
+		`{"a": {"$type": "string", "$message": 1}}`,
+	} {
+		_, err := ParseSchema(schemaJSON)
+		assert.NotNil(t, err, schemaJSON)
+	}
+}
+
+func TestSchemaUsesCustomMessages(t *testing.T) {
+	schema := MustParseSchema(`{"email": {"$type": "string", "$pattern": "@", "$message": "'{key}' must be a valid email address"}, "age": 0}`)
+
+	errs := schema.Validate(context.Background(), bodyMap(`{"email": "nope", "age": "1"}`))
+	assert.Equal(t, []string{"value for key 'age' expected to be of type number", "'email' must be a valid email address"}, errorMessages(errs))
+	assert.Equal(t, CodePatternMismatch, errs[1].Code)
+
+	errs = schema.Validate(context.Background(), bodyMap(`{"email": 1, "age": 1}`))
+	assert.Equal(t, []string{"'email' must be a valid email address"}, errorMessages(errs))
+	assert.Equal(t, "'email' must be a valid email address", newTestTranslations().Translate("fr", errs[0]))
+
+	errs = schema.Validate(context.Background(), bodyMap(`{"age": 1}`))
+	assert.Equal(t, []string{"expected key 'email' missing"}, errorMessages(errs))
+}
+
+func TestParseSchemaCompilesConstraintsOnce(t *testing.T) {
+	schema := MustParseSchema(`{"age": {"$type": "integer", "$min": 0}, "tags": [{"$pattern": "^[a-z]+$", "$type": "string"}]}`)
+
+	assert.IsType(t, &constraint{}, schema.body["age"])
+	assert.IsType(t, &constraint{}, schema.body["tags"].([]interface{})[0])
+
+	extended, err := ExtendSchema(schema, `{"?name": ""}`)
+	assert.Nil(t, err)
+	assert.True(t, schema.body["age"] != extended.body["age"]) // copied, not shared
+	assert.Equal(t, []string{"value for key 'age' must be at least 0"}, errorMessages(extended.validate(OrderAlphabetical, bodyMap(`{"age": -1, "tags": []}`), nil)))
+}
@@ -0,0 +1,93 @@
+package jsonbody
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// cookieSchema is a schema for the JSON stored in a cookie, registered with
+// WithCookieSchema.
+type cookieSchema struct {
+	name     string
+	optional bool
+	schema   *Schema
+}
+
+type cookieContextKey struct{}
+
+// CookieJSON returns the parsed JSON object stored in the named cookie of r, if
+// the cookie was validated by the middleware using WithCookieSchema.
+func CookieJSON(r *http.Request, name string) (map[string]interface{}, bool) {
+	cookies, _ := r.Context().Value(cookieContextKey{}).(map[string]map[string]interface{})
+	val, ok := cookies[name]
+	return val, ok
+}
+
+// validateCookies checks the cookies registered with WithCookieSchema, returning
+// the errors found and r with the parsed cookies added to its context.
+func (m *middleware) validateCookies(r *http.Request) ([]ValidationError, *http.Request) {
+	errs := make([]ValidationError, 0)
+	if len(m.cookies) == 0 {
+		return errs, r
+	}
+
+	parsed := make(map[string]map[string]interface{})
+	for _, c := range m.cookies {
+		cookie, err := r.Cookie(c.name)
+		if err != nil {
+			if !c.optional {
+				errs = append(errs, ValidationError{Key: c.name, Code: CodeMissingCookie})
+			}
+			continue
+		}
+
+		val, ok := decodeCookieJSON(cookie.Value)
+		if !ok {
+			errs = append(errs, ValidationError{Key: c.name, Code: CodeInvalidCookie})
+			continue
+		}
+
+		v := validator{order: m.order, keyOrder: c.schema.keyOrder}
+		cookieErrs := v.validateObject(c.name, "", c.schema.body, val)
+		if len(cookieErrs) > 0 {
+			errs = append(errs, cookieErrs...)
+			continue
+		}
+
+		parsed[c.name] = val
+	}
+
+	return errs, r.WithContext(context.WithValue(r.Context(), cookieContextKey{}, parsed))
+}
+
+// decodeCookieJSON decodes the JSON object in a cookie value, which may be
+// stored as is, percent-encoded, or base64-encoded (since raw JSON contains
+// characters that aren't allowed in cookie values).
+func decodeCookieJSON(value string) (map[string]interface{}, bool) {
+	candidates := []string{value}
+	if unescaped, err := url.QueryUnescape(value); err == nil {
+		candidates = append(candidates, unescaped)
+	}
+	for _, enc := range []*base64.Encoding{base64.RawURLEncoding, base64.URLEncoding, base64.StdEncoding} {
+		if decoded, err := enc.DecodeString(value); err == nil {
+			candidates = append(candidates, string(decoded))
+		}
+	}
+
+	for _, c := range candidates {
+		if !strings.HasPrefix(strings.TrimSpace(c), "{") {
+			continue
+		}
+
+		var val map[string]interface{}
+		if json.Unmarshal([]byte(c), &val) == nil {
+			return val, true
+		}
+	}
+
+	return nil, false
+}
@@ -0,0 +1,81 @@
+package jsonbody
+
+import (
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestDecodeCookieJSONAcceptsEncodings(t *testing.T) {
+	value := `{"theme":"dark"}`
+	for _, encoded := range []string{
+		url.QueryEscape(value),
+		base64.RawURLEncoding.EncodeToString([]byte(value)),
+		base64.StdEncoding.EncodeToString([]byte(value)),
+	} {
+		val, ok := decodeCookieJSON(encoded)
+		assert.True(t, ok, encoded)
+		assert.Equal(t, map[string]interface{}{"theme": "dark"}, val)
+	}
+}
+
+func TestDecodeCookieJSONRejectsNonObjects(t *testing.T) {
+	_, ok := decodeCookieJSON("abc")
+	assert.False(t, ok)
+}
+
+func TestServeHTTPValidatesCookie(t *testing.T) {
+	next := &mockHandler{}
+	next.On("ServeHTTP", mock.Anything, mock.Anything).Return()
+	handler := NewMiddleware("", WithCookieSchema("prefs", `{"theme": "", "?fontSize": 0}`))(next)
+
+	tests := []struct {
+		cookie string
+		status int
+		body   string
+	}{
+		{"", 400, `{"errors":["expected cookie 'prefs' missing"]}`},
+		{"nope", 400, `{"errors":["cookie 'prefs' expected to contain a JSON object"]}`},
+		{url.QueryEscape(`{"fontSize": "big"}`), 400, `{"errors":["value for key 'prefs.fontSize' expected to be of type number","expected key 'prefs.theme' missing"]}`},
+	}
+
+	for _, test := range tests {
+		recorder := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		if test.cookie != "" {
+			r.AddCookie(&http.Cookie{Name: "prefs", Value: test.cookie})
+		}
+
+		handler.ServeHTTP(recorder, r)
+		assert.Equal(t, test.status, recorder.Code)
+		assert.Equal(t, test.body, recorder.Body.String())
+	}
+}
+
+func TestServeHTTPExposesCookieJSON(t *testing.T) {
+	var prefs map[string]interface{}
+	handler := NewMiddleware("", WithCookieSchema("prefs", `{"theme": ""}`), WithCookieSchema("?session", `{}`))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		prefs, _ = CookieJSON(r, "prefs")
+		_, ok := CookieJSON(r, "session")
+		assert.False(t, ok)
+	}))
+
+	recorder := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.AddCookie(&http.Cookie{Name: "prefs", Value: url.QueryEscape(`{"theme": "dark"}`)})
+	handler.ServeHTTP(recorder, r)
+
+	assert.Equal(t, 200, recorder.Code)
+	assert.Equal(t, map[string]interface{}{"theme": "dark"}, prefs)
+}
+
+func TestWithCookieSchemaPanicsIfSchemaNotObject(t *testing.T) {
+	assert.Panics(t, func() { WithCookieSchema("prefs", `[""]`) })
+	assert.Panics(t, func() { WithCookieSchema("prefs", `""`) })
+	assert.NotPanics(t, func() { WithCookieSchema("prefs", `{"theme": ""}`) })
+}
@@ -0,0 +1,84 @@
+package jsonbody
+
+import (
+	"crypto/subtle"
+	"net/http"
+)
+
+// CSRFOptions configures the double-submit CSRF protection enabled by
+// WithCSRF. The client must send the value of the cookie named Cookie in either
+// the header named Header or the top-level body key named Field. At least one
+// of Header and Field must be set. If both are, the header is used when it is
+// present.
+type CSRFOptions struct {
+	// Cookie is the name of the cookie holding the token. It defaults to
+	// "csrf_token".
+	Cookie string
+
+	// Header is the name of the header in which the token may be sent, e.g.
+	// "X-CSRF-Token".
+	Header string
+
+	// Field is the name of the top-level body key in which the token may be
+	// sent, e.g. "_csrf". Schemas should allow the key, e.g. as "?_csrf": "".
+	Field string
+}
+
+// defaultCSRFCookie is the cookie used when CSRFOptions.Cookie is empty.
+const defaultCSRFCookie = "csrf_token"
+
+// needsBody reports whether the body of r has to be decoded to find its CSRF
+// token, because the token isn't in a header.
+func (o *CSRFOptions) needsBody(r *http.Request) bool {
+	if o == nil || o.Field == "" || isSafeMethod(r.Method) {
+		return false
+	}
+
+	return o.Header == "" || r.Header.Get(o.Header) == ""
+}
+
+// checkCSRF reports whether r, whose body has been decoded into body, carries
+// a token matching its CSRF cookie. Requests with safe methods, which shouldn't
+// change any state, are always accepted.
+func (o *CSRFOptions) checkCSRF(r *http.Request, body interface{}) bool {
+	if o == nil {
+		return true
+	}
+
+	if isSafeMethod(r.Method) {
+		return true
+	}
+
+	name := o.Cookie
+	if name == "" {
+		name = defaultCSRFCookie
+	}
+
+	cookie, err := r.Cookie(name)
+	if err != nil || cookie.Value == "" {
+		return false
+	}
+
+	token := ""
+	if o.Header != "" {
+		token = r.Header.Get(o.Header)
+	}
+	if token == "" && o.Field != "" {
+		if obj, ok := body.(map[string]interface{}); ok {
+			token, _ = obj[o.Field].(string)
+		}
+	}
+
+	return token != "" && subtle.ConstantTimeCompare([]byte(token), []byte(cookie.Value)) == 1
+}
+
+// isSafeMethod reports whether method is one of the methods that shouldn't
+// change any state, which don't need CSRF protection.
+func isSafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	}
+
+	return false
+}
@@ -0,0 +1,123 @@
+package jsonbody
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServeHTTPChecksCSRFToken(t *testing.T) {
+	schema := `{"title": "", "?_csrf": ""}`
+	opts := CSRFOptions{Header: "X-CSRF-Token", Field: "_csrf"}
+
+	for _, tc := range []struct {
+		method string
+		cookie string
+		header string
+		body   string
+		code   int
+	}{
+		{http.MethodPost, "abc", "abc", `{"title": "a"}`, http.StatusOK},
+		{http.MethodPost, "abc", "", `{"title": "a", "_csrf": "abc"}`, http.StatusOK},
+		{http.MethodPost, "abc", "abd", `{"title": "a", "_csrf": "abc"}`, http.StatusForbidden},
+		{http.MethodPost, "abc", "", `{"title": "a"}`, http.StatusForbidden},
+		{http.MethodPost, "", "abc", `{"title": "a"}`, http.StatusForbidden},
+		{http.MethodPut, "abc", "", `{"title": "a", "_csrf": 1}`, http.StatusForbidden},
+		{http.MethodGet, "", "", `{"title": "a"}`, http.StatusOK},
+	} {
+		called := false
+		handler := NewMiddleware(schema, WithCSRF(opts))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			called = true
+		}))
+
+		req := httptest.NewRequest(tc.method, "/", strings.NewReader(tc.body))
+		req.Header.Set("Content-Type", "application/json")
+		if tc.cookie != "" {
+			req.AddCookie(&http.Cookie{Name: "csrf_token", Value: tc.cookie})
+		}
+		if tc.header != "" {
+			req.Header.Set("X-CSRF-Token", tc.header)
+		}
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+
+		assert.Equal(t, tc.code, recorder.Code, tc)
+		assert.Equal(t, tc.code == http.StatusOK, called, tc)
+		if tc.code == http.StatusForbidden {
+			assert.JSONEq(t, `{"errors": ["CSRF token missing or invalid"]}`, recorder.Body.String())
+		}
+	}
+}
+
+func TestServeHTTPChecksCSRFTokenWithCustomCookie(t *testing.T) {
+	handler := NewMiddleware("", WithCSRF(CSRFOptions{Cookie: "xsrf", Header: "X-XSRF-Token"}))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	req := httptest.NewRequest(http.MethodDelete, "/posts/1", nil)
+	req.AddCookie(&http.Cookie{Name: "xsrf", Value: "t"})
+	req.Header.Set("X-XSRF-Token", "t")
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, req)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+
+	req = httptest.NewRequest(http.MethodDelete, "/posts/1", nil)
+	req.AddCookie(&http.Cookie{Name: "csrf_token", Value: "t"})
+	req.Header.Set("X-XSRF-Token", "t")
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, req)
+	assert.Equal(t, http.StatusForbidden, recorder.Code)
+}
+
+func TestWithCSRFPanicsWithoutTokenSource(t *testing.T) {
+	assert.Panics(t, func() { WithCSRF(CSRFOptions{Cookie: "csrf"}) })
+}
+
+func TestServeHTTPChecksCSRFTokenWhenValidationIsSkipped(t *testing.T) {
+	control := NewEnforcementControl()
+	control.SetEnforcement("/off", "", EnforcementOff)
+
+	var raw string
+	handler := NewMiddleware(`{"title": ""}`,
+		WithCSRF(CSRFOptions{Header: "X-CSRF-Token", Field: "_csrf"}),
+		WithEnforcementControl(control),
+	)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		raw = string(b)
+	}))
+
+	for _, tc := range []struct {
+		path   string
+		skip   bool
+		header string
+		body   string
+		code   int
+	}{
+		{"/", true, "", `{"title": 1}`, http.StatusForbidden},
+		{"/off", false, "", `{"title": 1}`, http.StatusForbidden},
+		{"/off", false, "wrong", `{"title": 1}`, http.StatusForbidden},
+		{"/", true, "abc", `not json`, http.StatusOK},
+		{"/off", false, "", `{"_csrf": "abc"}`, http.StatusOK},
+		{"/off", false, "", `not json`, http.StatusForbidden},
+	} {
+		raw = ""
+		req := httptest.NewRequest(http.MethodPost, tc.path, strings.NewReader(tc.body))
+		if tc.skip {
+			req = req.WithContext(SkipValidation(req.Context()))
+		}
+		req.AddCookie(&http.Cookie{Name: "csrf_token", Value: "abc"})
+		if tc.header != "" {
+			req.Header.Set("X-CSRF-Token", tc.header)
+		}
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+
+		assert.Equal(t, tc.code, recorder.Code, tc)
+		if tc.code == http.StatusOK {
+			// the handler can still read the whole body
+			assert.Equal(t, tc.body, raw, tc)
+		}
+	}
+}
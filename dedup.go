@@ -0,0 +1,102 @@
+package jsonbody
+
+import (
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// dedupWindow remembers the requests in progress and the requests that
+// completed within a sliding window of time.
+type dedupWindow struct {
+	window   time.Duration
+	clientID func(*http.Request) string
+
+	mu        sync.Mutex
+	seen      map[string]dedupEntry // request key -> entry
+	lastSweep time.Time
+}
+
+type dedupEntry struct {
+	inFlight bool
+	expires  time.Time
+}
+
+// check reports whether the request identified by key is in progress or
+// completed within the window. If it isn't, the request is recorded as in
+// progress, and done must be called once the handler has responded to it.
+func (d *dedupWindow) check(key string) (duplicate bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := time.Now()
+	if d.seen == nil {
+		d.seen = make(map[string]dedupEntry)
+	}
+
+	if now.Sub(d.lastSweep) > d.window {
+		for k, entry := range d.seen {
+			if !entry.inFlight && now.After(entry.expires) {
+				delete(d.seen, k)
+			}
+		}
+		d.lastSweep = now
+	}
+
+	if entry, ok := d.seen[key]; ok && (entry.inFlight || !now.After(entry.expires)) {
+		return true
+	}
+
+	d.seen[key] = dedupEntry{inFlight: true}
+	return false
+}
+
+// done records that the handler has responded to the request identified by
+// key. If it succeeded, identical requests are rejected for the window;
+// otherwise (e.g. because of a server error) the request is forgotten so that
+// it can be retried.
+func (d *dedupWindow) done(key string, succeeded bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if succeeded {
+		d.seen[key] = dedupEntry{expires: time.Now().Add(d.window)}
+	} else {
+		delete(d.seen, key)
+	}
+}
+
+// serveDeduplicated calls the next handler through serveNext, unless an
+// identical request from the same client is in progress or succeeded within the
+// window, in which case it sends a 409 error response.
+func (m *middleware) serveDeduplicated(writer Writer, r *http.Request, hash string) {
+	key := m.dedup.clientID(r) + " " + hash
+	if m.dedup.check(key) {
+		writer.writeErrors(http.StatusConflict, ValidationError{Code: CodeDuplicateRequest})
+		return
+	}
+
+	stats := &statsWriter{ResponseWriter: writer.ResponseWriter}
+	writer.ResponseWriter = stats
+	returned := false
+	defer func() {
+		// server errors and panics aren't recorded so that the request can be
+		// retried
+		m.dedup.done(key, returned && stats.status < 500)
+	}()
+
+	m.serveNext(writer, r, hash)
+	returned = true
+}
+
+// RemoteIP returns the IP address of the client that sent r, as reported by
+// r.RemoteAddr. It is the default client identifier for WithDeduplication.
+func RemoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
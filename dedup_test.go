@@ -0,0 +1,104 @@
+package jsonbody
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestDedupWindowDetectsDuplicatesWithinWindow(t *testing.T) {
+	d := &dedupWindow{window: time.Hour}
+	assert.False(t, d.check("a"))
+	assert.True(t, d.check("a"))
+	assert.False(t, d.check("b"))
+}
+
+func TestDedupWindowForgetsExpiredRequests(t *testing.T) {
+	d := &dedupWindow{window: -time.Second}
+	assert.False(t, d.check("a"))
+	d.done("a", true)
+	assert.False(t, d.check("a"))
+}
+
+func TestRemoteIPStripsPort(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPost, "/", nil)
+	r.RemoteAddr = "10.0.0.1:5000"
+	assert.Equal(t, "10.0.0.1", RemoteIP(r))
+}
+
+func TestServeHTTPSends409ForDuplicateRequest(t *testing.T) {
+	next := &mockHandler{}
+	next.On("ServeHTTP", mock.Anything, mock.Anything).Return()
+	handler := NewMiddleware("", WithDeduplication(time.Minute, nil))(next)
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"a": 1}`)))
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"a": 1}`)))
+
+	assert.Equal(t, 409, recorder.Code)
+	next.AssertNumberOfCalls(t, "ServeHTTP", 1)
+}
+
+func TestServeHTTPForgetsRequestsThatFailWithServerError(t *testing.T) {
+	calls := 0
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	})
+	handler := NewMiddleware("", WithDeduplication(time.Minute, nil))(next)
+
+	for _, status := range []int{502, 200, 409} {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"a": 1}`)))
+		assert.Equal(t, status, recorder.Code)
+	}
+	assert.Equal(t, 2, calls)
+}
+
+func TestServeHTTPRejectsDuplicateOfRequestInProgress(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+	})
+	handler := NewMiddleware("", WithDeduplication(time.Minute, nil))(next)
+
+	done := make(chan struct{})
+	go func() {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"a": 1}`)))
+		close(done)
+	}()
+	<-started
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"a": 1}`)))
+	assert.Equal(t, 409, recorder.Code)
+
+	close(release)
+	<-done
+}
+
+func TestServeHTTPDoesNotDeduplicateRequestsWithIdempotencyKeys(t *testing.T) {
+	next := &jsonHandler{}
+	next.On("ServeHTTP", mock.Anything, mock.Anything).Return()
+	handler := NewMiddleware("",
+		WithDeduplication(time.Minute, nil),
+		WithIdempotencyKeys(NewMemoryCache(), time.Minute),
+	)(next)
+
+	for i := 0; i < 2; i++ {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, idempotentRequest("key", `{"a": 1}`))
+		assert.Equal(t, 200, recorder.Code)
+		assert.JSONEq(t, `{"calls": 1}`, recorder.Body.String())
+	}
+}
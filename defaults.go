@@ -0,0 +1,45 @@
+package jsonbody
+
+import "strings"
+
+// applyDefaults inserts the values of the "$default" keys in the schema into
+// body for the optional keys that it is missing.
+func (s *Schema) applyDefaults(body interface{}) {
+	if s == nil || body == nil {
+		return
+	}
+
+	for _, expected := range s.withoutGrace().values() {
+		applyValueDefaults(expected, body)
+	}
+}
+
+func applyObjectDefaults(expected map[string]interface{}, actual map[string]interface{}) {
+	for key, expectedVal := range expected {
+		key = strings.TrimPrefix(key, "?")
+		actualVal, ok := actual[key]
+		if ok {
+			applyValueDefaults(expectedVal, actualVal)
+			continue
+		}
+
+		if c, ok := expectedVal.(*constraint); ok && c.hasDefault {
+			actual[key] = copyJSONValue(c.def)
+		}
+	}
+}
+
+func applyValueDefaults(expected interface{}, actual interface{}) {
+	switch expected := expected.(type) {
+	case map[string]interface{}:
+		if actualObj, ok := actual.(map[string]interface{}); ok {
+			applyObjectDefaults(expected, actualObj)
+		}
+	case []interface{}:
+		if actualArr, ok := actual.([]interface{}); ok && len(expected) > 0 {
+			for _, elem := range actualArr {
+				applyValueDefaults(expected[0], elem)
+			}
+		}
+	}
+}
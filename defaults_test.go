@@ -0,0 +1,49 @@
+package jsonbody
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApplyDefaultsInsertsMissingOptionalKeys(t *testing.T) {
+	schema := MustParseSchema(`{"?limit": {"$default": 20}, "?sort": {"$enum": ["asc", "desc"], "$default": "asc"}, "?filter": {"?tags": {"$default": []}}, "items": [{"?qty": {"$type": "integer", "$default": 1}}]}`)
+	body := decodeObject(t, `{"limit": 5, "filter": {}, "items": [{}, {"qty": 3}]}`)
+
+	schema.applyDefaults(body)
+
+	assert.Equal(t, decodeObject(t, `{"limit": 5, "sort": "asc", "filter": {"tags": []}, "items": [{"qty": 1}, {"qty": 3}]}`), body)
+}
+
+func TestDefaultsAreValidatedLikeOtherValues(t *testing.T) {
+	schema := MustParseSchema(`{"?limit": {"$default": 20}}`)
+	assert.Equal(t, []string{"value for key 'limit' expected to be of type number"}, errorMessages(schema.validate(OrderAlphabetical, decodeObject(t, `{"limit": "5"}`), nil)))
+}
+
+func TestParseSchemaRejectsInvalidDefaults(t *testing.T) {
+	for _, schemaJSON := range []string{
+		`{"limit": {"$default": 20}}`,
+		`{"?limit": {"$type": "string", "$default": 20}}`,
+		`{"?limit": {"$type": "integer", "$min": 1, "$default": 0}}`,
+		`{"?sort": {"$enum": ["asc"], "$default": "desc"}}`,
+	} {
+		_, err := ParseSchema(schemaJSON)
+		assert.NotNil(t, err, schemaJSON)
+	}
+}
+
+func TestServeHTTPInsertsDefaults(t *testing.T) {
+	var body map[string]interface{}
+	handler := NewMiddleware(`{"?limit": {"$default": 20}}`)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body = r.Body.(Reader).JSON()
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{}`))
+	req.Header.Set("Content-Type", "application/json")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	assert.Equal(t, map[string]interface{}{"limit": 20.0}, body)
+}
@@ -0,0 +1,76 @@
+package jsonbody
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+)
+
+// denyRules rejects request bodies containing keys or string values that match
+// any of its patterns, anywhere in the body.
+type denyRules struct {
+	keys   []*regexp.Regexp
+	values []*regexp.Regexp
+}
+
+// compileDenyPatterns compiles patterns so that they must match an entire key
+// or value.
+func compileDenyPatterns(patterns []string) []*regexp.Regexp {
+	compiled := make([]*regexp.Regexp, len(patterns))
+	for i, p := range patterns {
+		compiled[i] = regexp.MustCompile("^(?:" + p + ")$")
+	}
+
+	return compiled
+}
+
+// check returns an error for each denied key or value in body. Keys of the same
+// object are checked in alphabetical order.
+func (d *denyRules) check(body interface{}) []ValidationError {
+	errs := make([]ValidationError, 0)
+	if d == nil {
+		return errs
+	}
+
+	var walk func(key string, v interface{})
+	walk = func(key string, v interface{}) {
+		switch v := v.(type) {
+		case map[string]interface{}:
+			keys := make([]string, 0, len(v))
+			for k := range v {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+
+			for _, k := range keys {
+				childKey := joinKey(key, k)
+				if matchesAny(d.keys, k) {
+					errs = append(errs, ValidationError{Key: childKey, Code: CodeDeniedKey})
+					continue
+				}
+				walk(childKey, v[k])
+			}
+		case []interface{}:
+			for i, elem := range v {
+				walk(fmt.Sprintf("%v[%v]", key, i), elem)
+			}
+		case string:
+			if matchesAny(d.values, v) {
+				errs = append(errs, ValidationError{Key: key, Code: CodeDeniedValue})
+			}
+		}
+	}
+	walk("", body)
+
+	return errs
+}
+
+func matchesAny(patterns []*regexp.Regexp, s string) bool {
+	for _, p := range patterns {
+		if p.MatchString(s) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,47 @@
+package jsonbody
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestDenyRulesCheckFindsNestedKeysAndValues(t *testing.T) {
+	d := &denyRules{
+		keys:   compileDenyPatterns([]string{"__.*__"}),
+		values: compileDenyPatterns([]string{`\$\{.*\}`}),
+	}
+
+	errs := d.check(bodyMap(`{"a": {"__proto__": {}}, "b": ["ok", "${jndi:x}"], "my__key__s": "fine"}`))
+	assert.Equal(t, []string{
+		"key 'a.__proto__' is not allowed",
+		"value for key 'b[1]' is not allowed",
+	}, errorMessages(errs))
+}
+
+func TestDenyRulesCheckAllowsNilRules(t *testing.T) {
+	var d *denyRules
+	assert.Equal(t, 0, len(d.check(bodyMap(`{"__proto__": 1}`))))
+}
+
+func TestWithDeniedKeysPanicsIfPatternInvalid(t *testing.T) {
+	assert.Panics(t, func() { WithDeniedKeys("(") })
+}
+
+func TestServeHTTPSends400IfBodyHasDeniedKey(t *testing.T) {
+	next := &mockHandler{}
+	next.On("ServeHTTP", mock.Anything, mock.Anything).Return()
+	handler := NewMiddleware(`{}`, WithDeniedKeys("__.*__"))(next)
+
+	recorder := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"__proto__": {"admin": true}}`))
+	r.Header.Set("Content-Type", "application/json")
+	handler.ServeHTTP(recorder, r)
+
+	assert.Equal(t, 400, recorder.Code)
+	assert.Equal(t, `{"errors":["key '__proto__' is not allowed"]}`, recorder.Body.String())
+}
@@ -0,0 +1,115 @@
+package jsonbody
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// ChangeKind is the kind of a Change.
+type ChangeKind string
+
+const (
+	// ChangeAdded is for keys that are only in the new body.
+	ChangeAdded ChangeKind = "added"
+
+	// ChangeRemoved is for keys that are only in the old body.
+	ChangeRemoved ChangeKind = "removed"
+
+	// ChangeUpdated is for keys whose values differ between the bodies.
+	ChangeUpdated ChangeKind = "updated"
+)
+
+// Change describes a difference between two request bodies found by Diff.
+type Change struct {
+	// Key is the path of the changed value, in the same format as
+	// ValidationError.Key, e.g. "author.name".
+	Key string
+
+	Kind ChangeKind
+
+	// Old and New are the values before and after the change. Old is nil for
+	// added values and New is nil for removed values.
+	Old interface{}
+	New interface{}
+}
+
+// Diff compares two decoded request bodies (e.g. the stored version of a
+// resource and the body of an update request), returning the changes between
+// them sorted by key. This is useful for audit trails like "user changed title
+// and tags".
+//
+// Only the keys in schema are compared, so unknown keys in the bodies are
+// ignored. Objects in the schema are compared key by key, while other values
+// (including arrays and objects whose contents are unconstrained) are compared
+// as a whole. If schema is nil, all keys are compared.
+func Diff(oldBody map[string]interface{}, newBody map[string]interface{}, schema *Schema) []Change {
+	changes := make([]Change, 0)
+	if schema == nil {
+		diffObject(&changes, "", nil, oldBody, newBody)
+	} else {
+		diffObject(&changes, "", schema.body, oldBody, newBody)
+	}
+
+	sort.SliceStable(changes, func(i, j int) bool {
+		return changes[i].Key < changes[j].Key
+	})
+
+	return changes
+}
+
+// diffObject appends the changes between the objects oldObj and newObj to
+// changes, comparing the keys in expected, or all keys if expected is nil.
+func diffObject(changes *[]Change, key string, expected map[string]interface{}, oldObj map[string]interface{}, newObj map[string]interface{}) {
+	keys := make(map[string]interface{})
+	if expected == nil {
+		for k := range oldObj {
+			keys[k] = nil
+		}
+		for k := range newObj {
+			keys[k] = nil
+		}
+	} else {
+		for k, v := range expected {
+			keys[strings.TrimPrefix(k, "?")] = v
+		}
+	}
+
+	for k, expectedVal := range keys {
+		newKey := joinKey(key, k)
+		oldVal, inOld := oldObj[k]
+		newVal, inNew := newObj[k]
+
+		switch {
+		case !inOld && !inNew:
+		case !inOld:
+			*changes = append(*changes, Change{Key: newKey, Kind: ChangeAdded, New: newVal})
+		case !inNew:
+			*changes = append(*changes, Change{Key: newKey, Kind: ChangeRemoved, Old: oldVal})
+		default:
+			diffValue(changes, newKey, expectedVal, oldVal, newVal, expected == nil)
+		}
+	}
+}
+
+// diffValue appends the changes between oldVal and newVal to changes. If
+// anyKeys is set, objects are compared key by key regardless of expected.
+func diffValue(changes *[]Change, key string, expected interface{}, oldVal interface{}, newVal interface{}, anyKeys bool) {
+	oldObj, oldIsObj := oldVal.(map[string]interface{})
+	newObj, newIsObj := newVal.(map[string]interface{})
+	if oldIsObj && newIsObj {
+		if anyKeys {
+			diffObject(changes, key, nil, oldObj, newObj)
+			return
+		}
+
+		if expectedObj, ok := expected.(map[string]interface{}); ok && len(expectedObj) > 0 {
+			diffObject(changes, key, expectedObj, oldObj, newObj)
+			return
+		}
+	}
+
+	if !reflect.DeepEqual(oldVal, newVal) {
+		*changes = append(*changes, Change{Key: key, Kind: ChangeUpdated, Old: oldVal, New: newVal})
+	}
+}
@@ -0,0 +1,43 @@
+package jsonbody
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func decodeObject(t *testing.T, s string) map[string]interface{} {
+	var obj map[string]interface{}
+	assert.Nil(t, json.Unmarshal([]byte(s), &obj))
+	return obj
+}
+
+func TestDiffComparesSchemaKeys(t *testing.T) {
+	schema := MustParseSchema(`{"title": "", "?subtitle": "", "tags": [""], "author": {"name": "", "?bio": ""}, "meta": {}}`)
+	oldBody := decodeObject(t, `{"title": "a", "subtitle": "s", "tags": ["x"], "author": {"name": "n", "id": 1}, "meta": {"v": 1}, "internal": 1}`)
+	newBody := decodeObject(t, `{"title": "b", "tags": ["x", "y"], "author": {"name": "n", "bio": "hi", "id": 2}, "meta": {"v": 2}, "internal": 2}`)
+
+	assert.Equal(t, []Change{
+		{Key: "author.bio", Kind: ChangeAdded, New: "hi"},
+		{Key: "meta", Kind: ChangeUpdated, Old: map[string]interface{}{"v": 1.0}, New: map[string]interface{}{"v": 2.0}},
+		{Key: "subtitle", Kind: ChangeRemoved, Old: "s"},
+		{Key: "tags", Kind: ChangeUpdated, Old: []interface{}{"x"}, New: []interface{}{"x", "y"}},
+		{Key: "title", Kind: ChangeUpdated, Old: "a", New: "b"},
+	}, Diff(oldBody, newBody, schema))
+}
+
+func TestDiffComparesAllKeysWithoutSchema(t *testing.T) {
+	oldBody := decodeObject(t, `{"a": 1, "b": {"c": true}}`)
+	newBody := decodeObject(t, `{"a": 1, "b": {"c": false}}`)
+
+	assert.Equal(t, []Change{
+		{Key: "b.c", Kind: ChangeUpdated, Old: true, New: false},
+	}, Diff(oldBody, newBody, nil))
+}
+
+func TestDiffReturnsNoChangesForEqualBodies(t *testing.T) {
+	body := decodeObject(t, `{"a": [1, {"b": 2}]}`)
+
+	assert.Equal(t, []Change{}, Diff(body, body, MustParseSchema(`{"a": []}`)))
+}
@@ -0,0 +1,131 @@
+package jsonbody
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// writeTracker detects handlers that write a response both through the Writer's
+// JSON methods and directly through the embedded http.ResponseWriter, or that
+// call WriteHeader more than once. Such calls are logged along with the call
+// sites involved, or cause a panic if strict is set.
+type writeTracker struct {
+	http.ResponseWriter
+	strict bool
+
+	mu         sync.Mutex
+	headerSite string // the call site that sent the response header
+	headerJSON bool   // whether the header was sent by one of the Writer's JSON methods
+	inJSON     int    // the number of the Writer's JSON methods in progress
+}
+
+func (t *writeTracker) WriteHeader(statusCode int) {
+	if statusCode >= 100 && statusCode <= 199 && statusCode != http.StatusSwitchingProtocols {
+		t.ResponseWriter.WriteHeader(statusCode)
+		return
+	}
+
+	t.mu.Lock()
+	if first := t.headerSite; first != "" {
+		t.mu.Unlock()
+		t.report(fmt.Sprintf("superfluous WriteHeader(%v) call at %v; the response header was already sent at %v", statusCode, callSite(), first))
+	} else {
+		t.headerSite = callSite()
+		t.headerJSON = t.inJSON > 0
+		t.mu.Unlock()
+	}
+
+	t.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (t *writeTracker) Write(b []byte) (int, error) {
+	t.mu.Lock()
+	switch {
+	case t.headerSite == "":
+		t.headerSite = callSite()
+		t.headerJSON = t.inJSON > 0
+		t.mu.Unlock()
+	case t.headerJSON && t.inJSON == 0:
+		first := t.headerSite
+		t.mu.Unlock()
+		t.report(fmt.Sprintf("Write call at %v after the response was written as JSON at %v", callSite(), first))
+	default:
+		t.mu.Unlock()
+	}
+
+	return t.ResponseWriter.Write(b)
+}
+
+// Unwrap returns the underlying http.ResponseWriter for http.ResponseController.
+func (t *writeTracker) Unwrap() http.ResponseWriter {
+	return t.ResponseWriter
+}
+
+// beginJSON and endJSON mark the writes in between as made by one of the
+// Writer's JSON methods. They do nothing on a nil *writeTracker.
+func (t *writeTracker) beginJSON() {
+	if t == nil {
+		return
+	}
+
+	t.mu.Lock()
+	t.inJSON++
+	t.mu.Unlock()
+}
+
+func (t *writeTracker) endJSON() {
+	if t == nil {
+		return
+	}
+
+	t.mu.Lock()
+	t.inJSON--
+	t.mu.Unlock()
+}
+
+func (t *writeTracker) report(msg string) {
+	if t.strict {
+		panic("jsonbody: " + msg)
+	}
+
+	log.Println("jsonbody: " + msg)
+}
+
+// callSite returns the location of the innermost caller outside of the Writer
+// and the http.ResponseWriter wrappers, which is usually in the handler.
+func callSite() string {
+	pcs := make([]uintptr, 32)
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(2, pcs)])
+	for {
+		frame, more := frames.Next()
+		if !isWriterFrame(frame.Function) {
+			return fmt.Sprintf("%v:%v (%v)", frame.File, frame.Line, frame.Function)
+		}
+
+		if !more {
+			return "unknown location"
+		}
+	}
+}
+
+// isWriterFrame reports whether function is a method of one of the package's
+// http.ResponseWriter implementations.
+func isWriterFrame(function string) bool {
+	const pkg = "github.com/jasonccox/jsonbody."
+	if !strings.HasPrefix(function, pkg) {
+		return false
+	}
+
+	name := strings.TrimPrefix(function, pkg)
+	for _, prefix := range []string{"(*", "Writer.", "headWriter."} {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,76 @@
+package jsonbody
+
+import (
+	"bytes"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteDiagnosticsLogsDirectWriteAfterWriteJSON(t *testing.T) {
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	defer log.SetOutput(os.Stderr)
+
+	handler := NewMiddleware("", WithWriteDiagnostics(false))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writer := w.(Writer)
+		writer.WriteJSON(200, map[string]int{"a": 1})
+		writer.Write([]byte("oops"))
+	}))
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	assert.Equal(t, 200, recorder.Code)
+	assert.Contains(t, logs.String(), "Write call at ")
+	assert.Contains(t, logs.String(), "doublewrite_test.go")
+	assert.Contains(t, logs.String(), "after the response was written as JSON")
+}
+
+func TestWriteDiagnosticsLogsSuperfluousWriteHeader(t *testing.T) {
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	defer log.SetOutput(os.Stderr)
+
+	handler := NewMiddleware("", WithWriteDiagnostics(false))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+		writer := w.(Writer)
+		writer.WriteJSON(200, map[string]int{"a": 1})
+	}))
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	assert.Equal(t, http.StatusAccepted, recorder.Code)
+	assert.Contains(t, logs.String(), "superfluous WriteHeader(200) call at ")
+	assert.Equal(t, 2, strings.Count(logs.String(), "doublewrite_test.go"))
+}
+
+func TestWriteDiagnosticsAllowsSingleWrites(t *testing.T) {
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	defer log.SetOutput(os.Stderr)
+
+	handler := NewMiddleware("", WithWriteDiagnostics(true))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writer := w.(Writer)
+		writer.WriteJSON(200, map[string]int{"a": 1})
+	}))
+
+	recorder := httptest.NewRecorder()
+	assert.NotPanics(t, func() { handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil)) })
+	assert.Equal(t, "", logs.String())
+}
+
+func TestWriteDiagnosticsPanicsInStrictMode(t *testing.T) {
+	handler := NewMiddleware("", WithWriteDiagnostics(true))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("text"))
+		w.WriteHeader(500)
+	}))
+
+	assert.Panics(t, func() { handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil)) })
+}
@@ -0,0 +1,224 @@
+package jsonbody
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strings"
+)
+
+// writerConfig holds the settings that the middleware applies to every Writer it
+// creates. A Writer with a nil config uses the defaults.
+type writerConfig struct {
+	stringNumbers  map[string]bool
+	timeFormat     TimeFormat
+	durationFormat DurationFormat
+	canonical      bool
+	sortKeys       bool
+
+	signer          Signer
+	signatureHeader string
+
+	headers      http.Header // sent with every JSON response
+	translations Translator
+	errorObjects bool   // whether errors are sent as objects rather than strings
+	problemType  string // the type of problem details documents, or "" if they aren't sent
+
+	errorFormatter ErrorFormatter
+	stream         StreamOptions
+	appendJSON     bool // whether AppendJSON is enabled
+	pretty         bool // whether JSON responses are always indented
+	bindErrors     bool // whether unrepresentable numbers are reported with 400 responses when binding
+}
+
+// transforms reports whether the config requires response bodies to be modified
+// after they are marshaled.
+func (c *writerConfig) transforms() bool {
+	return c != nil && (len(c.stringNumbers) > 0 || c.canonical || c.sortKeys)
+}
+
+// convertsTimes reports whether the config requires times or durations to be
+// encoded differently than encoding/json does.
+func (c *writerConfig) convertsTimes() bool {
+	return c != nil && (c.timeFormat != TimeRFC3339 || c.durationFormat != DurationNanoseconds)
+}
+
+// encode marshals body as JSON, applying the Writer's configuration and the
+// request's field selection. Fields are only selected for successful (2xx)
+// responses so that error bodies are always sent in full.
+func (w *Writer) encode(statusCode int, body interface{}) ([]byte, error) {
+	selectFields := w.fields != nil && statusCode >= 200 && statusCode < 300
+	original := body
+
+	if w.config.convertsTimes() {
+		generic, err := toGeneric(reflect.ValueOf(body), w.config.timeFormat, w.config.durationFormat)
+		if err != nil {
+			return nil, err
+		}
+		body = generic
+	}
+
+	encoded, err := json.Marshal(body)
+	if err != nil || (!w.config.transforms() && !selectFields) {
+		return encoded, err
+	}
+
+	// Decoding into maps loses the order of keys, so they are sorted when the
+	// body is encoded again below.
+
+	dec := json.NewDecoder(bytes.NewReader(encoded))
+	dec.UseNumber()
+
+	var val interface{}
+	err = dec.Decode(&val)
+	if err != nil {
+		return nil, err
+	}
+
+	if selectFields {
+		val = w.selectFields(original, val)
+	}
+
+	if w.config != nil && len(w.config.stringNumbers) > 0 {
+		val = stringifyNumbers("", val, w.config.stringNumbers)
+	}
+
+	if w.config != nil && w.config.canonical {
+		return canonicalJSON(val)
+	}
+
+	return json.Marshal(val)
+}
+
+// indent returns encoded, a complete JSON response body, indented with two
+// spaces if WithPrettyJSON is used or the client asked for indented JSON (see
+// WithPrettyParam). Otherwise, or if the body is canonical JSON, whose
+// whitespace is fixed, encoded is returned unchanged.
+func (w *Writer) indent(encoded []byte) ([]byte, error) {
+	if !w.pretty && (w.config == nil || !w.config.pretty) {
+		return encoded, nil
+	}
+
+	if w.config != nil && w.config.canonical {
+		return encoded, nil
+	}
+
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, encoded, "", "  "); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// stringifyNumbers replaces the numbers found at the given paths with strings
+// containing the same digits. In paths, "[]" refers to every element of an
+// array, e.g. "items[].id".
+func stringifyNumbers(key string, val interface{}, paths map[string]bool) interface{} {
+	switch val := val.(type) {
+	case json.Number:
+		if paths[key] {
+			return val.String()
+		}
+	case []interface{}:
+		for i, elem := range val {
+			val[i] = stringifyNumbers(key+"[]", elem, paths)
+		}
+	case map[string]interface{}:
+		for k, elem := range val {
+			val[k] = stringifyNumbers(joinKey(key, k), elem, paths)
+		}
+	}
+
+	return val
+}
+
+// fieldSelection is a tree of the paths requested in a field selection. A nil
+// fieldSelection for a key means that the entire value should be kept.
+type fieldSelection map[string]fieldSelection
+
+// parseFieldSelection parses a comma-separated list of paths, such as
+// "id,author.name", into a fieldSelection. It returns nil if the list is empty.
+func parseFieldSelection(list string) fieldSelection {
+	var fields fieldSelection
+	for _, path := range strings.Split(list, ",") {
+		path = strings.TrimSpace(path)
+		if path == "" {
+			continue
+		}
+
+		if fields == nil {
+			fields = make(fieldSelection)
+		}
+
+		node := fields
+		keys := strings.Split(path, ".")
+		for i, key := range keys {
+			child, ok := node[key]
+			if ok && child == nil {
+				break // the entire value is already selected
+			}
+
+			if i == len(keys)-1 {
+				node[key] = nil
+				break
+			}
+
+			if !ok {
+				child = make(fieldSelection)
+				node[key] = child
+			}
+			node = child
+		}
+	}
+
+	return fields
+}
+
+// selectFields applies the Writer's field selection to val, the decoded JSON of
+// body. The envelopes sent by WriteErrorsWithData and WriteMultiStatus are
+// kept, and the selection is applied to the data inside them.
+func (w *Writer) selectFields(body interface{}, val interface{}) interface{} {
+	obj, isObj := val.(map[string]interface{})
+
+	switch body.(type) {
+	case dataEnvelope:
+		if isObj {
+			obj["data"] = w.fields.apply(obj["data"])
+		}
+		return val
+	case multiStatusEnvelope:
+		results, _ := obj["results"].([]interface{})
+		for _, result := range results {
+			if result, ok := result.(map[string]interface{}); ok && result["data"] != nil {
+				result["data"] = w.fields.apply(result["data"])
+			}
+		}
+		return val
+	}
+
+	return w.fields.apply(val)
+}
+
+// apply removes everything not selected by f from the decoded JSON value val.
+// Selections apply to each element of an array.
+func (f fieldSelection) apply(val interface{}) interface{} {
+	switch val := val.(type) {
+	case []interface{}:
+		for i, elem := range val {
+			val[i] = f.apply(elem)
+		}
+	case map[string]interface{}:
+		for k, elem := range val {
+			child, ok := f[k]
+			if !ok {
+				delete(val, k)
+			} else if child != nil {
+				val[k] = child.apply(elem)
+			}
+		}
+	}
+
+	return val
+}
@@ -0,0 +1,79 @@
+package jsonbody
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteJSONStringifiesConfiguredNumbers(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	w := Writer{
+		ResponseWriter: recorder,
+		config:         &writerConfig{stringNumbers: map[string]bool{"id": true, "items[].price": true}},
+	}
+
+	err := w.WriteJSON(200, map[string]interface{}{
+		"id":    int64(12345678901234567),
+		"count": 2,
+		"items": []map[string]interface{}{{"price": 1.25}},
+	})
+	assert.Nil(t, err)
+
+	assert.Equal(t, `{"count":2,"id":"12345678901234567","items":[{"price":"1.25"}]}`, recorder.Body.String())
+}
+
+func TestParseFieldSelectionBuildsTree(t *testing.T) {
+	fields := parseFieldSelection("id, author.name,author.email,tags,tags.x,")
+	assert.Equal(t, fieldSelection{
+		"id":     nil,
+		"author": fieldSelection{"name": nil, "email": nil},
+		"tags":   nil,
+	}, fields)
+}
+
+func TestParseFieldSelectionReturnsNilIfEmpty(t *testing.T) {
+	assert.Nil(t, parseFieldSelection(""))
+}
+
+func TestWriteJSONSelectsFields(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	w := Writer{ResponseWriter: recorder, fields: parseFieldSelection("id,author.name")}
+
+	err := w.WriteJSON(200, []map[string]interface{}{{
+		"id":     1,
+		"title":  "hi",
+		"author": map[string]string{"name": "jo", "email": "jo@example.com"},
+	}})
+	assert.Nil(t, err)
+
+	assert.Equal(t, `[{"author":{"name":"jo"},"id":1}]`, recorder.Body.String())
+}
+
+func TestFieldSelectionKeepsEnvelopes(t *testing.T) {
+	post := map[string]interface{}{"id": 1, "title": "hi"}
+
+	recorder := httptest.NewRecorder()
+	w := Writer{ResponseWriter: recorder, fields: parseFieldSelection("id")}
+	assert.Nil(t, w.WriteErrorsWithData(207, post, "title was truncated"))
+	assert.Equal(t, `{"data":{"id":1},"errors":["title was truncated"]}`, recorder.Body.String())
+
+	recorder = httptest.NewRecorder()
+	w = Writer{ResponseWriter: recorder, fields: parseFieldSelection("id")}
+	assert.Nil(t, w.WriteMultiStatus([]ItemResult{
+		{ID: "1", Status: 201, Data: post},
+		{ID: "2", Status: 400, Errors: []string{"expected key 'title' missing"}},
+	}))
+	assert.Equal(t, `{"results":[{"data":{"id":1},"id":"1","status":201},{"errors":["expected key 'title' missing"],"id":"2","status":400}]}`, recorder.Body.String())
+}
+
+func TestWriteErrorsIgnoresFieldSelection(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	w := Writer{ResponseWriter: recorder, fields: parseFieldSelection("id")}
+
+	err := w.WriteErrors(400, "oops")
+	assert.Nil(t, err)
+
+	assert.Equal(t, `{"errors":["oops"]}`, recorder.Body.String())
+}
@@ -0,0 +1,96 @@
+package jsonbody
+
+import (
+	"strings"
+	"sync"
+)
+
+// EnforcementMode determines what the middleware does with requests to a route.
+type EnforcementMode int
+
+const (
+	// EnforcementEnforce is the default mode, in which invalid requests are
+	// rejected.
+	EnforcementEnforce EnforcementMode = iota
+
+	// EnforcementObserve validates requests as usual, but logs the errors that
+	// would have rejected them and passes them to the handler anyway. Requests
+	// that can't be parsed at all (e.g. invalid JSON) are still rejected.
+	EnforcementObserve
+
+	// EnforcementOff passes requests to the handler without parsing or
+	// validating their bodies, as if SkipValidation had been used. The
+	// middleware's other protections, such as the handler timeout, idempotency
+	// keys, and deduplication, still apply.
+	EnforcementOff
+)
+
+// EnforcementControl switches validation on and off for routes while the
+// server is running, e.g. during an incident, without redeploying. Pass it to
+// the middleware with WithEnforcementControl; it is safe for concurrent use.
+type EnforcementControl struct {
+	mu     sync.RWMutex
+	routes []enforcementRoute
+}
+
+type enforcementRoute struct {
+	method   string
+	segments []string
+	mode     EnforcementMode
+}
+
+// NewEnforcementControl creates an EnforcementControl in which every route is
+// in EnforcementEnforce mode.
+func NewEnforcementControl() *EnforcementControl {
+	return &EnforcementControl{}
+}
+
+// SetEnforcement sets the mode for requests with the given method (or any
+// method, if method is "") and a path matching route. Routes are path patterns
+// like those of WithRouteSchema, e.g. "/posts/{id}". If several routes match a
+// request, the one with the most literal segments wins, and routes for a
+// specific method win over routes for any method.
+func (c *EnforcementControl) SetEnforcement(route string, method string, mode EnforcementMode) {
+	method = strings.ToUpper(method)
+	segments := splitPath(route)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for i, r := range c.routes {
+		if r.method == method && strings.Join(r.segments, "/") == strings.Join(segments, "/") {
+			c.routes[i].mode = mode
+			return
+		}
+	}
+
+	c.routes = append(c.routes, enforcementRoute{method: method, segments: segments, mode: mode})
+}
+
+// mode returns the mode for requests with the given method and path.
+func (c *EnforcementControl) mode(method string, path string) EnforcementMode {
+	if c == nil {
+		return EnforcementEnforce
+	}
+
+	segments := splitPath(path)
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	mode := EnforcementEnforce
+	bestLiterals, bestMethod := -1, false
+	for _, r := range c.routes {
+		if (r.method != "" && r.method != method) || len(r.segments) != len(segments) {
+			continue
+		}
+
+		literals, ok := matchSegments(r.segments, segments)
+		specific := r.method != ""
+		if ok && (literals > bestLiterals || (literals == bestLiterals && specific && !bestMethod)) {
+			mode, bestLiterals, bestMethod = r.mode, literals, specific
+		}
+	}
+
+	return mode
+}
@@ -0,0 +1,83 @@
+package jsonbody
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestEnforcementControlMatchesMostSpecificRoute(t *testing.T) {
+	c := NewEnforcementControl()
+	c.SetEnforcement("/posts/{id}", "", EnforcementObserve)
+	c.SetEnforcement("/posts/{id}", "put", EnforcementOff)
+	c.SetEnforcement("/posts/drafts", "", EnforcementOff)
+
+	assert.Equal(t, EnforcementObserve, c.mode(http.MethodPost, "/posts/1"))
+	assert.Equal(t, EnforcementOff, c.mode(http.MethodPut, "/posts/1"))
+	assert.Equal(t, EnforcementOff, c.mode(http.MethodPost, "/posts/drafts"))
+	assert.Equal(t, EnforcementEnforce, c.mode(http.MethodPost, "/users/1"))
+
+	c.SetEnforcement("/posts/{id}", "PUT", EnforcementEnforce)
+	assert.Equal(t, EnforcementEnforce, c.mode(http.MethodPut, "/posts/1"))
+
+	var nilControl *EnforcementControl
+	assert.Equal(t, EnforcementEnforce, nilControl.mode(http.MethodPut, "/posts/1"))
+}
+
+func TestServeHTTPAppliesEnforcementMode(t *testing.T) {
+	control := NewEnforcementControl()
+	called := false
+	handler := NewMiddleware(`{"title": ""}`, WithEnforcementControl(control))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+
+	send := func(body string) *httptest.ResponseRecorder {
+		called = false
+		req := httptest.NewRequest(http.MethodPost, "/posts", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	assert.Equal(t, 400, send(`{}`).Code)
+	assert.False(t, called)
+
+	control.SetEnforcement("/posts", http.MethodPost, EnforcementObserve)
+	assert.Equal(t, 200, send(`{}`).Code)
+	assert.True(t, called)
+	assert.Equal(t, 400, send(`{`).Code)
+
+	control.SetEnforcement("/posts", http.MethodPost, EnforcementOff)
+	assert.Equal(t, 200, send(`{`).Code)
+	assert.True(t, called)
+}
+
+func TestServeHTTPKeepsProtectionsWithEnforcementOff(t *testing.T) {
+	control := NewEnforcementControl()
+	control.SetEnforcement("/payments", "", EnforcementOff)
+
+	next := &jsonHandler{}
+	next.On("ServeHTTP", mock.Anything, mock.Anything).Return()
+	handler := NewMiddleware(`{"amount": 0}`,
+		WithEnforcementControl(control),
+		WithIdempotencyKeys(nil, time.Hour),
+	)(next)
+
+	for i := 0; i < 2; i++ {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, idempotentRequest("abc", `not json`))
+		assert.Equal(t, `{"calls":1}`, recorder.Body.String())
+	}
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, idempotentRequest("abc", `other`))
+	assert.Equal(t, http.StatusConflict, recorder.Code)
+
+	next.AssertNumberOfCalls(t, "ServeHTTP", 1)
+}
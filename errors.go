@@ -0,0 +1,151 @@
+package jsonbody
+
+import "strings"
+
+// ValidationError describes a single problem with a request. Its message is
+// produced from the template in the message catalog for its Code, so that it
+// can be translated (see Translations).
+type ValidationError struct {
+	// Key is the key of the invalid value in the body, e.g. "author.tags[0]",
+	// or "" if the error isn't about a specific value.
+	Key string
+
+	// Code identifies the kind of error. See the Code constants.
+	Code string
+
+	// Params holds the values substituted into the message template in addition
+	// to the key, e.g. the expected type for CodeWrongType.
+	Params map[string]string
+
+	// Namespace is the error code prefix of the schema the request was
+	// validated against (see Schema.ErrorCodePrefix), e.g. "POSTS_". It
+	// distinguishes the errors of different endpoints without changing their
+	// Code, so their messages are still looked up by Code.
+	Namespace string
+
+	// Message, if not "", is the message template used instead of the one in
+	// the message catalog for Code, in every language. It is set from the
+	// "$message" key of the constraint object that produced the error.
+	Message string
+
+	// Severity determines what the middleware does about the error according
+	// to its Policy. Errors found by a schema have the severity configured for
+	// their key in the schema's "$severity" key, or SeverityError by default.
+	Severity Severity
+}
+
+// Codes of the errors in the message catalog. The default (English) message
+// template for each code is shown in its comment. Templates refer to the error's
+// Key as {key} and to its Params by name.
+const (
+	CodeMissingKey               = "missing_key"                 // expected key '{key}' missing
+	CodeWrongType                = "wrong_type"                  // value for key '{key}' expected to be of type {type}
+	CodeInvalidValue             = "invalid_value"               // value for key '{key}' is invalid: {reason}
+	CodeTooSmall                 = "too_small"                   // value for key '{key}' must be at least {min}
+	CodeTooLarge                 = "too_large"                   // value for key '{key}' must be at most {max}
+	CodePatternMismatch          = "pattern_mismatch"            // value for key '{key}' must match the pattern {pattern}
+	CodeNotInEnum                = "not_in_enum"                 // value for key '{key}' must be one of {values}
+	CodeDeniedKey                = "denied_key"                  // key '{key}' is not allowed
+	CodeUnknownKey               = "unknown_key"                 // key '{key}' is not in the schema
+	CodeDeprecatedKey            = "deprecated_key"              // key '{key}' is deprecated; use '{replacement}' instead
+	CodeDeniedValue              = "denied_value"                // value for key '{key}' is not allowed
+	CodePII                      = "pii"                         // value for key '{key}' appears to contain personal data ({kind})
+	CodeValidationTimeout        = "validation_timeout"          // validation of value for key '{key}' timed out
+	CodeMissingParam             = "missing_param"               // expected query parameter '{key}' missing
+	CodeParamWrongType           = "param_wrong_type"            // value for query parameter '{key}' expected to be of type {type}
+	CodeMissingCookie            = "missing_cookie"              // expected cookie '{key}' missing
+	CodeInvalidCookie            = "invalid_cookie"              // cookie '{key}' expected to contain a JSON object
+	CodeExpectedBody             = "expected_body"               // expected a JSON body
+	CodeBodyWrongType            = "body_wrong_type"             // body expected to be of type {type}
+	CodeInvalidJSON              = "invalid_json"                // body is not acceptable JSON: {reason}
+	CodeContentType              = "content_type"                // content type must be application/json
+	CodeUnsupportedCharset       = "unsupported_charset"         // charset '{charset}' is not supported
+	CodeNotAcceptable            = "not_acceptable"              // the response can only be sent as {types}
+	CodeUnsupportedVersion       = "unsupported_version"         // media type {type} is not supported
+	CodeBodyTooLarge             = "body_too_large"              // body must be at most {max} bytes
+	CodeLengthMismatch           = "length_mismatch"             // body length does not match its Content-Length of {length} bytes
+	CodeDuplicateRequest         = "duplicate_request"           // an identical request was received recently
+	CodeIdempotencyKeyReused     = "idempotency_key_reused"      // Idempotency-Key has already been used for a different request
+	CodeIdempotencyKeyInProgress = "idempotency_key_in_progress" // a request with the same Idempotency-Key is already in progress
+	CodeSchemaUnavailable        = "schema_unavailable"          // the schema for this request is currently unavailable
+	CodeOverloaded               = "overloaded"                  // the server is temporarily overloaded
+	CodeValidationUnavailable    = "validation_unavailable"      // validation is temporarily unavailable
+	CodeTimeout                  = "timeout"                     // the request timed out
+	CodePreconditionFailed       = "precondition_failed"         // precondition failed: the resource has been modified
+	CodeCSRFMismatch             = "csrf_mismatch"               // CSRF token missing or invalid
+)
+
+// messageCatalog maps each error code to its default message template.
+var messageCatalog = map[string]string{
+	CodeMissingKey:               "expected key '{key}' missing",
+	CodeWrongType:                "value for key '{key}' expected to be of type {type}",
+	CodeInvalidValue:             "value for key '{key}' is invalid: {reason}",
+	CodeTooSmall:                 "value for key '{key}' must be at least {min}",
+	CodeTooLarge:                 "value for key '{key}' must be at most {max}",
+	CodePatternMismatch:          "value for key '{key}' must match the pattern {pattern}",
+	CodeNotInEnum:                "value for key '{key}' must be one of {values}",
+	CodeDeniedKey:                "key '{key}' is not allowed",
+	CodeUnknownKey:               "key '{key}' is not in the schema",
+	CodeDeprecatedKey:            "key '{key}' is deprecated; use '{replacement}' instead",
+	CodeDeniedValue:              "value for key '{key}' is not allowed",
+	CodePII:                      "value for key '{key}' appears to contain personal data ({kind})",
+	CodeValidationTimeout:        "validation of value for key '{key}' timed out",
+	CodeMissingParam:             "expected query parameter '{key}' missing",
+	CodeParamWrongType:           "value for query parameter '{key}' expected to be of type {type}",
+	CodeMissingCookie:            "expected cookie '{key}' missing",
+	CodeInvalidCookie:            "cookie '{key}' expected to contain a JSON object",
+	CodeExpectedBody:             "expected a JSON body",
+	CodeBodyWrongType:            "body expected to be of type {type}",
+	CodeInvalidJSON:              "body is not acceptable JSON: {reason}",
+	CodeContentType:              "content type must be application/json",
+	CodeUnsupportedCharset:       "charset '{charset}' is not supported",
+	CodeNotAcceptable:            "the response can only be sent as {types}",
+	CodeUnsupportedVersion:       "media type {type} is not supported",
+	CodeBodyTooLarge:             "body must be at most {max} bytes",
+	CodeLengthMismatch:           "body length does not match its Content-Length of {length} bytes",
+	CodeDuplicateRequest:         "an identical request was received recently",
+	CodeIdempotencyKeyReused:     "Idempotency-Key has already been used for a different request",
+	CodeIdempotencyKeyInProgress: "a request with the same Idempotency-Key is already in progress",
+	CodeSchemaUnavailable:        "the schema for this request is currently unavailable",
+	CodeOverloaded:               "the server is temporarily overloaded",
+	CodeValidationUnavailable:    "validation is temporarily unavailable",
+	CodeTimeout:                  "the request timed out",
+	CodePreconditionFailed:       "precondition failed: the resource has been modified",
+	CodeCSRFMismatch:             "CSRF token missing or invalid",
+}
+
+// QualifiedCode returns the error's Code prefixed with its Namespace, e.g.
+// "POSTS_missing_key".
+func (e ValidationError) QualifiedCode() string {
+	return e.Namespace + e.Code
+}
+
+// Error returns the error's message from the default message catalog.
+func (e ValidationError) Error() string {
+	if e.Message != "" {
+		return e.Format(e.Message)
+	}
+
+	return e.Format(messageCatalog[e.Code])
+}
+
+// Format substitutes the error's key and params into template, which uses the
+// same placeholders as the message catalog, e.g. "la clé '{key}' est manquante".
+func (e ValidationError) Format(template string) string {
+	replacements := []string{"{key}", e.Key}
+	for name, value := range e.Params {
+		replacements = append(replacements, "{"+name+"}", value)
+	}
+
+	return strings.NewReplacer(replacements...).Replace(template)
+}
+
+// errorMessages returns the default messages of errs.
+func errorMessages(errs []ValidationError) []string {
+	msgs := make([]string, len(errs))
+	for i, e := range errs {
+		msgs[i] = e.Error()
+	}
+
+	return msgs
+}
@@ -0,0 +1,45 @@
+package jsonbody
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidationErrorFormatsDefaultMessage(t *testing.T) {
+	e := ValidationError{Key: "a.b", Code: CodeWrongType, Params: map[string]string{"type": "string"}}
+	assert.Equal(t, "value for key 'a.b' expected to be of type string", e.Error())
+}
+
+func TestMessageCatalogHasMessageForEveryCode(t *testing.T) {
+	for code, template := range messageCatalog {
+		assert.NotEmpty(t, template, code)
+	}
+}
+
+func TestQualifiedCodeAddsNamespace(t *testing.T) {
+	e := ValidationError{Code: CodeMissingKey, Namespace: "POSTS_"}
+	assert.Equal(t, "POSTS_missing_key", e.QualifiedCode())
+}
+
+func TestWriteErrorsAppliesWriterNamespace(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	w := Writer{ResponseWriter: recorder, codeNamespace: "POSTS_"}
+
+	errs := []ValidationError{{Code: CodeMissingKey}, {Code: CodeTimeout, Namespace: "OTHER_"}}
+	err := w.writeErrors(400, errs...)
+	assert.Nil(t, err)
+
+	assert.Equal(t, "POSTS_missing_key", errs[0].QualifiedCode())
+	assert.Equal(t, "OTHER_timeout", errs[1].QualifiedCode())
+}
+
+func TestWriteErrorsSendsObjectsIfConfigured(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	w := Writer{ResponseWriter: recorder, config: &writerConfig{errorObjects: true}, codeNamespace: "POSTS_"}
+
+	err := w.writeErrors(400, ValidationError{Key: "author.name", Code: CodeMissingKey}, ValidationError{Code: CodeTimeout})
+	assert.Nil(t, err)
+	assert.Equal(t, `{"errors":[{"field":"author.name","code":"POSTS_missing_key","message":"expected key 'author.name' missing"},{"code":"POSTS_timeout","message":"the request timed out"}]}`, recorder.Body.String())
+}
@@ -0,0 +1,192 @@
+package jsonbody
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ExtendSchema derives a new schema from base, e.g. to create the schema for v2
+// of an endpoint from the schema for v1. overridesJSON is a JSON object that is
+// merged into base:
+//
+//   - keys that aren't in base are added
+//   - keys that are in base are replaced, which changes their type (or, with or
+//     without a leading question mark, whether they are optional)
+//   - keys set to null are removed
+//   - objects are merged recursively, so nested keys can be changed without
+//     repeating the rest of the object (an empty object replaces the object,
+//     allowing any contents)
+//   - constraint objects (e.g. {"$type": "integer", "$min": 1}) aren't merged;
+//     they replace, or are replaced by, the value in base as a whole
+//
+// For example, given a base of {"title": "", "body": "", "author": {"id": 0}},
+// the overrides {"body": null, "?tags": [""], "author": {"id": ""}} produce
+// {"title": "", "?tags": [""], "author": {"id": ""}}.
+//
+// Metadata keys like "$schemaName" are replaced in the same way, and "$query" is
+// merged like an object. Keys keep their declared order, with added keys placed
+// after those from base. base itself is not modified, and the result is parsed
+// once, so it is as efficient as a schema parsed with ParseSchema.
+func ExtendSchema(base *Schema, overridesJSON string) (*Schema, error) {
+	overrides, err := parseSchema(overridesJSON)
+	if err != nil {
+		return nil, err
+	}
+
+	if base == nil {
+		return ParseSchema(overridesJSON)
+	}
+
+	if overrides == nil {
+		return base, nil
+	}
+
+	if len(base.allOf) > 0 {
+		return nil, errors.New("jsonbody: schemas combined with AllOf cannot be extended")
+	}
+
+	if base.array != nil || base.scalar != nil {
+		return nil, errors.New("jsonbody: array and scalar schemas cannot be extended")
+	}
+
+	overrideOrder, err := parseKeyOrder(overridesJSON)
+	if err != nil {
+		return nil, err
+	}
+
+	merged := base.toJSONValue()
+	mergeSchemaObject(merged, overrides)
+
+	keyOrder := make(map[string][]string)
+	for path, keys := range base.keyOrder {
+		keyOrder[path] = append([]string(nil), keys...)
+	}
+	for path, keys := range overrideOrder {
+		keyOrder[path] = mergeKeyOrder(keyOrder[path], keys)
+	}
+
+	schema, err := newSchema(merged, keyOrder)
+	if err != nil {
+		return nil, fmt.Errorf("jsonbody: failed to extend schema: %v", err)
+	}
+
+	return schema, nil
+}
+
+// toJSONValue returns a deep copy of the schema's decoded JSON, including its
+// metadata and query schema.
+func (s *Schema) toJSONValue() map[string]interface{} {
+	obj := make(map[string]interface{})
+	for k, v := range s.body {
+		obj[k] = copyJSONValue(v)
+	}
+
+	if s.query != nil {
+		obj[schemaQueryKey] = copyJSONValue(s.query)
+	}
+
+	if s.severities != nil {
+		severities := make(map[string]interface{}, len(s.severities))
+		for path, severity := range s.severities {
+			severities[path] = severity.String()
+		}
+		obj[schemaSeverityKey] = severities
+	}
+
+	if s.aliases != nil {
+		aliases := make(map[string]interface{}, len(s.aliases))
+		for path, name := range s.aliases {
+			aliases[path] = name
+		}
+		obj[schemaAliasesKey] = aliases
+	}
+
+	for key, val := range map[string]string{
+		schemaNameKey:            s.meta.name,
+		schemaDescriptionKey:     s.meta.description,
+		schemaErrorCodePrefixKey: s.meta.errorCodePrefix,
+	} {
+		if val != "" {
+			obj[key] = val
+		}
+	}
+
+	return obj
+}
+
+func copyJSONValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case *constraint:
+		return copyJSONValue(v.raw)
+	case map[string]interface{}:
+		obj := make(map[string]interface{}, len(v))
+		for k, elem := range v {
+			obj[k] = copyJSONValue(elem)
+		}
+		return obj
+	case []interface{}:
+		arr := make([]interface{}, len(v))
+		for i, elem := range v {
+			arr[i] = copyJSONValue(elem)
+		}
+		return arr
+	}
+
+	return v
+}
+
+// mergeSchemaObject merges the overrides into the schema object dst as described
+// by ExtendSchema.
+func mergeSchemaObject(dst map[string]interface{}, overrides map[string]interface{}) {
+	for key, override := range overrides {
+		name := strings.TrimPrefix(key, "?")
+		existingKey := name
+		existing, ok := dst[name]
+		if !ok {
+			existingKey = "?" + name
+			existing, ok = dst[existingKey]
+		}
+
+		if override == nil {
+			delete(dst, existingKey)
+			continue
+		}
+
+		existingObj, existingIsObj := existing.(map[string]interface{})
+		overrideObj, overrideIsObj := override.(map[string]interface{})
+		if ok && existingIsObj && overrideIsObj && len(overrideObj) > 0 &&
+			!isConstraintObject(existingObj) && !isConstraintObject(overrideObj) {
+			mergeSchemaObject(existingObj, overrideObj)
+			override = existingObj
+		}
+
+		if ok {
+			delete(dst, existingKey)
+		}
+		dst[key] = override
+	}
+}
+
+// mergeKeyOrder adds the keys of an object declared in the overrides to the keys
+// declared in the base, replacing keys whose optionality changed in place.
+func mergeKeyOrder(base []string, overrides []string) []string {
+	for _, key := range overrides {
+		name := strings.TrimPrefix(key, "?")
+
+		replaced := false
+		for i, existing := range base {
+			if strings.TrimPrefix(existing, "?") == name {
+				base[i] = key
+				replaced = true
+				break
+			}
+		}
+
+		if !replaced {
+			base = append(base, key)
+		}
+	}
+
+	return base
+}
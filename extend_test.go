@@ -0,0 +1,96 @@
+package jsonbody
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExtendSchemaAddsRemovesAndRetypesKeys(t *testing.T) {
+	base := MustParseSchema(`{"$schemaName": "CreatePostV1", "title": "", "body": "", "author": {"id": 0, "name": ""}}`)
+
+	extended, err := ExtendSchema(base, `{"$schemaName": "CreatePostV2", "body": null, "?tags": [""], "author": {"id": ""}}`)
+	assert.Nil(t, err)
+
+	assert.Equal(t, "CreatePostV2", extended.Name())
+	assert.Equal(t, map[string]interface{}{
+		"title":  "",
+		"?tags":  []interface{}{""},
+		"author": map[string]interface{}{"id": "", "name": ""},
+	}, extended.body)
+}
+
+func TestExtendSchemaNotModifyBase(t *testing.T) {
+	base := MustParseSchema(`{"author": {"id": 0}}`)
+
+	_, err := ExtendSchema(base, `{"author": {"id": ""}}`)
+	assert.Nil(t, err)
+
+	assert.Equal(t, map[string]interface{}{"author": map[string]interface{}{"id": float64(0)}}, base.body)
+}
+
+func TestExtendSchemaChangesOptionality(t *testing.T) {
+	base := MustParseSchema(`{"a": "", "title": "", "z": ""}`)
+
+	extended, err := ExtendSchema(base, `{"?title": ""}`)
+	assert.Nil(t, err)
+
+	assert.Equal(t, map[string]interface{}{"a": "", "?title": "", "z": ""}, extended.body)
+	assert.Equal(t, []string{"a", "?title", "z"}, extended.keyOrder[""])
+}
+
+func TestExtendSchemaKeepsDeclarationOrder(t *testing.T) {
+	base := MustParseSchema(`{"c": "", "a": ""}`)
+
+	extended, err := ExtendSchema(base, `{"b": "", "a": 0}`)
+	assert.Nil(t, err)
+
+	errs := extended.validate(OrderDeclaration, map[string]interface{}{}, nil)
+	assert.Equal(t, []string{
+		"expected key 'c' missing",
+		"expected key 'a' missing",
+		"expected key 'b' missing",
+	}, errorMessages(errs))
+}
+
+func TestExtendSchemaMergesQuery(t *testing.T) {
+	base := MustParseSchema(`{"$query": {"page": 0}}`)
+
+	extended, err := ExtendSchema(base, `{"$query": {"?sort": ""}}`)
+	assert.Nil(t, err)
+
+	errs := extended.validate(OrderAlphabetical, nil, url.Values{"sort": {"asc"}})
+	assert.Equal(t, []string{"expected query parameter 'page' missing"}, errorMessages(errs))
+}
+
+func TestExtendSchemaReplacesObjectWithConstraint(t *testing.T) {
+	base := MustParseSchema(`{"author": {"id": 0}}`)
+
+	extended, err := ExtendSchema(base, `{"author": {"$type": "string"}}`)
+	assert.Nil(t, err)
+
+	errs := extended.validate(OrderAlphabetical, bodyMap(`{"author": "turtle"}`), nil)
+	assert.Empty(t, errs)
+
+	extended, err = ExtendSchema(extended, `{"author": {"id": 0}}`)
+	assert.Nil(t, err)
+
+	errs = extended.validate(OrderAlphabetical, bodyMap(`{"author": {"id": "x"}}`), nil)
+	assert.Equal(t, []string{"value for key 'author.id' expected to be of type number"}, errorMessages(errs))
+}
+
+func TestExtendSchemaReplacesConstraints(t *testing.T) {
+	base := MustParseSchema(`{"?n": {"$type": "integer", "$min": 1}}`)
+
+	extended, err := ExtendSchema(base, `{"?n": {"$type": "string"}}`)
+	assert.Nil(t, err)
+
+	errs := extended.validate(OrderAlphabetical, bodyMap(`{"n": "one"}`), nil)
+	assert.Empty(t, errs)
+}
+
+func TestExtendSchemaReturnsErrIfOverridesInvalid(t *testing.T) {
+	_, err := ExtendSchema(MustParseSchema(`{}`), `not json`)
+	assert.NotNil(t, err)
+}
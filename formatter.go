@@ -0,0 +1,60 @@
+package jsonbody
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+)
+
+// ErrorFormatter produces the bodies of the error responses sent by the
+// middleware, allowing them to use an existing error envelope. FormatErrors is
+// given the status code of the response and the errors to report, which is
+// empty for internal server errors, and returns the body and its Content-Type.
+// The default messages of the errors can be produced with their Error method.
+type ErrorFormatter interface {
+	FormatErrors(statusCode int, errs []ValidationError) (body []byte, contentType string, err error)
+}
+
+// ErrorFormatterFunc is an adapter allowing an ordinary function to be used as
+// an ErrorFormatter.
+type ErrorFormatterFunc func(statusCode int, errs []ValidationError) ([]byte, string, error)
+
+// FormatErrors calls f(statusCode, errs).
+func (f ErrorFormatterFunc) FormatErrors(statusCode int, errs []ValidationError) ([]byte, string, error) {
+	return f(statusCode, errs)
+}
+
+// writeFormattedErrors sends errs in the body produced by the configured
+// ErrorFormatter.
+func (w *Writer) writeFormattedErrors(statusCode int, errs []ValidationError) error {
+	if w.written {
+		return errors.New("method has already been called once and cannot be called again")
+	}
+
+	body, contentType, err := w.config.errorFormatter.FormatErrors(statusCode, errs)
+	if err != nil {
+		log.Println(fmt.Errorf("jsonbody: failed to format errors: %v", err))
+		return errors.New("formatting the errors failed")
+	}
+
+	w.writes.beginJSON()
+	defer w.writes.endJSON()
+
+	w.setDefaultHeaders()
+	w.Header().Set("Content-Type", contentType)
+	if w.head {
+		w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	}
+	w.WriteHeader(statusCode)
+
+	_, err = w.Write(body)
+	if err != nil {
+		log.Println(fmt.Errorf("jsonbody: failed to write body: %v", err))
+		return errors.New("sending the response body failed")
+	}
+
+	w.written = true
+
+	return nil
+}
@@ -0,0 +1,46 @@
+package jsonbody
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServeHTTPUsesErrorFormatter(t *testing.T) {
+	formatter := ErrorFormatterFunc(func(statusCode int, errs []ValidationError) ([]byte, string, error) {
+		codes := make([]string, len(errs))
+		for i, e := range errs {
+			codes[i] = e.QualifiedCode() + ":" + e.Key
+		}
+		return []byte(strings.Join(codes, ",")), "text/plain", nil
+	})
+	handler := NewMiddleware(`{"title": "", "body": ""}`, WithErrorFormatter(formatter), WithProblemDetails(""))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	recorder := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{}`))
+	r.Header.Set("Content-Type", "application/json")
+	handler.ServeHTTP(recorder, r)
+
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	assert.Equal(t, "text/plain", recorder.Header().Get("Content-Type"))
+	assert.Equal(t, "missing_key:body,missing_key:title", recorder.Body.String())
+}
+
+func TestWriteServerErrorUsesErrorFormatter(t *testing.T) {
+	var gotErrs []ValidationError
+	formatter := ErrorFormatterFunc(func(statusCode int, errs []ValidationError) ([]byte, string, error) {
+		gotErrs = errs
+		return []byte(`<error status="500"/>`), "application/xml", nil
+	})
+
+	recorder := httptest.NewRecorder()
+	w := Writer{ResponseWriter: recorder, config: &writerConfig{errorFormatter: formatter}}
+	w.writeServerError()
+
+	assert.Equal(t, http.StatusInternalServerError, recorder.Code)
+	assert.Equal(t, `<error status="500"/>`, recorder.Body.String())
+	assert.Empty(t, gotErrs)
+}
@@ -0,0 +1,91 @@
+package jsonbody
+
+import (
+	"fmt"
+	"io/fs"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// NewMiddlewareFromFS creates a middleware like NewMiddlewareFromBundle, but
+// loads each route's schema from a separate file in fsys (typically an
+// embed.FS). All files matching glob (see fs.Glob) are loaded, and each file's
+// method and path pattern are taken from its name: the method, followed by an
+// underscore, followed by the path with its slashes replaced by underscores,
+// with the extension removed. An underscore that is part of the path is written
+// as two underscores. For example:
+//
+//	POST_posts.json              POST /posts
+//	PUT_posts_{id}.json          PUT /posts/{id}
+//	POST_users_{id}_avatar       POST /users/{id}/avatar
+//	GET_user__groups_{id}.json   GET /user_groups/{id}
+func NewMiddlewareFromFS(fsys fs.FS, glob string, opts ...Option) (func(next http.Handler) http.Handler, error) {
+	routes, err := loadSchemaFS(fsys, glob)
+	if err != nil {
+		return nil, err
+	}
+
+	opts = append([]Option{withRoutes(routes)}, opts...)
+	return NewMiddleware("", opts...), nil
+}
+
+func loadSchemaFS(fsys fs.FS, glob string) (*routeTable, error) {
+	names, err := fs.Glob(fsys, glob)
+	if err != nil {
+		return nil, fmt.Errorf("jsonbody: failed to list schema files: %v", err)
+	}
+
+	routes := &routeTable{}
+	for _, name := range names {
+		method, pattern, err := routeFromFileName(name)
+		if err != nil {
+			return nil, err
+		}
+
+		schemaJSON, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return nil, fmt.Errorf("jsonbody: failed to read schema file %v: %v", name, err)
+		}
+
+		schema, err := ParseSchema(string(schemaJSON))
+		if err != nil {
+			return nil, fmt.Errorf("jsonbody: invalid schema in %v: %v", name, err)
+		}
+
+		routes.add(method, pattern, schema)
+	}
+
+	return routes, nil
+}
+
+// routeFromFileName returns the method and path pattern encoded in the name of
+// a schema file, e.g. "schemas/PUT_posts_{id}.json" -> "PUT", "/posts/{id}". In
+// the path, "__" stands for a literal underscore and "_" for a slash.
+func routeFromFileName(name string) (method string, pattern string, err error) {
+	base := path.Base(name)
+	base = strings.TrimSuffix(base, path.Ext(base))
+
+	parts := strings.SplitN(base, "_", 2)
+	if len(parts) != 2 || parts[0] == "" {
+		return "", "", fmt.Errorf("jsonbody: schema file name %v must be of the form METHOD_path", name)
+	}
+
+	var b strings.Builder
+	b.WriteByte('/')
+	for p := parts[1]; p != ""; {
+		switch {
+		case strings.HasPrefix(p, "__"):
+			b.WriteByte('_')
+			p = p[2:]
+		case p[0] == '_':
+			b.WriteByte('/')
+			p = p[1:]
+		default:
+			b.WriteByte(p[0])
+			p = p[1:]
+		}
+	}
+
+	return parts[0], b.String(), nil
+}
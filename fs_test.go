@@ -0,0 +1,55 @@
+package jsonbody
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestRouteFromFileNameParsesConvention(t *testing.T) {
+	method, pattern, err := routeFromFileName("schemas/PUT_posts_{id}.json")
+	assert.Nil(t, err)
+	assert.Equal(t, "PUT", method)
+	assert.Equal(t, "/posts/{id}", pattern)
+
+	method, pattern, err = routeFromFileName("GET_user__groups_{group__id}_")
+	assert.Nil(t, err)
+	assert.Equal(t, "GET", method)
+	assert.Equal(t, "/user_groups/{group_id}/", pattern)
+
+	_, _, err = routeFromFileName("posts.json")
+	assert.NotNil(t, err)
+}
+
+func TestNewMiddlewareFromFSLoadsSchemas(t *testing.T) {
+	fsys := fstest.MapFS{
+		"schemas/POST_posts.json":     {Data: []byte(`{"title": ""}`)},
+		"schemas/PUT_posts_{id}.json": {Data: []byte(`{"?title": ""}`)},
+		"schemas/README.md":           {Data: []byte(`not a schema`)},
+	}
+
+	mw, err := NewMiddlewareFromFS(fsys, "schemas/*.json")
+	assert.Nil(t, err)
+
+	next := &mockHandler{}
+	next.On("ServeHTTP", mock.Anything, mock.Anything).Return()
+
+	recorder := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/posts", strings.NewReader(`{}`))
+	r.Header.Set("Content-Type", "application/json")
+	mw(next).ServeHTTP(recorder, r)
+
+	assert.Equal(t, 400, recorder.Code)
+}
+
+func TestNewMiddlewareFromFSReturnsErrIfSchemaInvalid(t *testing.T) {
+	fsys := fstest.MapFS{"POST_posts.json": {Data: []byte(`not json`)}}
+
+	_, err := NewMiddlewareFromFS(fsys, "*.json")
+	assert.NotNil(t, err)
+}
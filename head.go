@@ -0,0 +1,19 @@
+package jsonbody
+
+import "net/http"
+
+// headWriter discards the body of a response to a HEAD request, while still
+// reporting writes as successful so that handlers don't need to special-case
+// HEAD.
+type headWriter struct {
+	http.ResponseWriter
+}
+
+func (hw headWriter) Write(b []byte) (int, error) {
+	return len(b), nil
+}
+
+// Unwrap returns the underlying http.ResponseWriter for http.ResponseController.
+func (hw headWriter) Unwrap() http.ResponseWriter {
+	return hw.ResponseWriter
+}
@@ -0,0 +1,51 @@
+package jsonbody
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestServeHTTPAutoHeadDiscardsBodyAndKeepsHeaders(t *testing.T) {
+	handler := NewMiddleware("", WithAutoHead())(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writer := w.(Writer)
+		writer.WithHeader("ETag", `"v1"`).WriteJSON(200, map[string]string{"key": "value"})
+	}))
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodHead, "/posts/1", nil))
+
+	assert.Equal(t, 200, recorder.Code)
+	assert.Equal(t, 0, recorder.Body.Len())
+	assert.Equal(t, "15", recorder.Header().Get("Content-Length"))
+	assert.Equal(t, `"v1"`, recorder.Header().Get("ETag"))
+}
+
+func TestServeHTTPAutoHeadUsesGetRouteSchema(t *testing.T) {
+	mw, err := NewMiddlewareFromBundle(strings.NewReader(`{"GET /posts/{id}": null, "HEAD /posts/{id}": {"title": ""}}`), WithAutoHead())
+	assert.Nil(t, err)
+
+	next := &jsonHandler{}
+	next.On("ServeHTTP", mock.Anything, mock.Anything).Return()
+
+	recorder := httptest.NewRecorder()
+	mw(next).ServeHTTP(recorder, httptest.NewRequest(http.MethodHead, "/posts/1", nil))
+
+	assert.Equal(t, 200, recorder.Code)
+	assert.Equal(t, 0, recorder.Body.Len())
+}
+
+func TestServeHTTPWritesBodyForHeadWithoutAutoHead(t *testing.T) {
+	next := &jsonHandler{}
+	next.On("ServeHTTP", mock.Anything, mock.Anything).Return()
+	handler := NewMiddleware("")(next)
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodHead, "/posts/1", nil))
+
+	assert.NotEqual(t, 0, recorder.Body.Len())
+}
@@ -0,0 +1,30 @@
+package jsonbody
+
+// WithHeader sets a header on the response and returns the Writer, so that it
+// can be chained before the body is written:
+//
+//	writer.WithHeader("Location", "/posts/1").WriteJSON(http.StatusCreated, post)
+//
+// Like other headers, it has no effect once the response has been written.
+func (w *Writer) WithHeader(key, value string) *Writer {
+	w.Header().Set(key, value)
+	return w
+}
+
+// setDefaultHeaders sets the headers configured with WithResponseHeader on the
+// response, unless the handler has already set them.
+func (w *Writer) setDefaultHeaders() {
+	if w.config == nil {
+		return
+	}
+
+	for key, values := range w.config.headers {
+		if _, ok := w.Header()[key]; ok {
+			continue
+		}
+
+		for _, v := range values {
+			w.Header().Add(key, v)
+		}
+	}
+}
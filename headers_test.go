@@ -0,0 +1,50 @@
+package jsonbody
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithHeaderSetsHeaderAndChains(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	w := Writer{ResponseWriter: recorder}
+
+	err := w.WithHeader("Location", "/posts/1").WithHeader("X-Id", "1").WriteJSON(201, "hi")
+	assert.Nil(t, err)
+
+	assert.Equal(t, "/posts/1", recorder.Header().Get("Location"))
+	assert.Equal(t, "1", recorder.Header().Get("X-Id"))
+	assert.True(t, w.written)
+}
+
+func TestWriteJSONSendsConfiguredHeaders(t *testing.T) {
+	m := &middleware{}
+	WithNoStore()(m)
+	WithNoSniff()(m)
+
+	recorder := httptest.NewRecorder()
+	w := Writer{ResponseWriter: recorder, config: &m.writerConfig}
+
+	err := w.WriteErrors(400, "bad")
+	assert.Nil(t, err)
+
+	assert.Equal(t, "no-store", recorder.Header().Get("Cache-Control"))
+	assert.Equal(t, "nosniff", recorder.Header().Get("X-Content-Type-Options"))
+}
+
+func TestWriteJSONNotOverrideHandlerHeaders(t *testing.T) {
+	m := &middleware{}
+	WithNoStore()(m)
+
+	recorder := httptest.NewRecorder()
+	w := Writer{ResponseWriter: recorder, config: &m.writerConfig}
+
+	w.Header().Set("Cache-Control", "max-age=60")
+	err := w.WriteJSON(200, "hi")
+	assert.Nil(t, err)
+
+	assert.Equal(t, []string{"max-age=60"}, recorder.Header()[http.CanonicalHeaderKey("Cache-Control")])
+}
@@ -0,0 +1,66 @@
+package jsonbody
+
+import (
+	"net/http"
+)
+
+const idempotencyKeyHeader = "Idempotency-Key"
+
+// serveIdempotent handles a request with an Idempotency-Key header. The first
+// response for a key is stored; retries with the same key and body receive the
+// stored response without calling the handler, while requests that reuse a key
+// with a different body (or while the first request is still in progress) are
+// rejected with a 409. Keys are scoped to the client that sent them, so clients
+// that happen to choose the same key don't receive each other's responses.
+func (m *middleware) serveIdempotent(writer Writer, r *http.Request, hash string) {
+	clientID := m.idempotencyClientID
+	if clientID == nil {
+		clientID = RemoteIP
+	}
+	key := clientID(r) + " " + r.Header.Get(idempotencyKeyHeader)
+
+	if m.replayIdempotent(writer, key, hash) {
+		return
+	}
+
+	if _, inFlight := m.idempotencyKeys.LoadOrStore(key, struct{}{}); inFlight {
+		writer.writeErrors(http.StatusConflict, ValidationError{Code: CodeIdempotencyKeyInProgress})
+		return
+	}
+	defer m.idempotencyKeys.Delete(key)
+
+	// The first request may have stored its response and released the key
+	// between the check above and LoadOrStore, so the store is checked again
+	// before calling the handler.
+	if m.replayIdempotent(writer, key, hash) {
+		return
+	}
+
+	rec := &responseRecorder{ResponseWriter: writer.ResponseWriter}
+	writer.ResponseWriter = rec
+	m.serveNext(writer, r, hash)
+
+	// server errors are not stored so that the request can be retried
+	if rec.statusCode != 0 && rec.statusCode < 500 {
+		resp := rec.response()
+		resp.RequestHash = hash
+		m.idempotencyStore.Set(key, resp, m.idempotencyTTL)
+	}
+}
+
+// replayIdempotent sends the response stored for key, or a 409 if it was stored
+// for a different request, and reports whether there was one.
+func (m *middleware) replayIdempotent(writer Writer, key string, hash string) bool {
+	resp, ok := m.idempotencyStore.Get(key)
+	if !ok {
+		return false
+	}
+
+	if resp.RequestHash != hash {
+		writer.writeErrors(http.StatusConflict, ValidationError{Code: CodeIdempotencyKeyReused})
+		return true
+	}
+
+	replayResponse(writer.ResponseWriter, resp)
+	return true
+}
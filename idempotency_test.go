@@ -0,0 +1,124 @@
+package jsonbody
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func idempotentRequest(key string, body string) *http.Request {
+	r := httptest.NewRequest(http.MethodPost, "/payments", strings.NewReader(body))
+	r.Header.Set("Idempotency-Key", key)
+	return r
+}
+
+func TestServeHTTPReplaysResponseForSameIdempotencyKey(t *testing.T) {
+	next := &jsonHandler{}
+	next.On("ServeHTTP", mock.Anything, mock.Anything).Return()
+	handler := NewMiddleware("", WithIdempotencyKeys(nil, time.Hour))(next)
+
+	for i := 0; i < 2; i++ {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, idempotentRequest("abc", `{"amount": 5}`))
+		assert.Equal(t, `{"calls":1}`, recorder.Body.String())
+	}
+
+	next.AssertNumberOfCalls(t, "ServeHTTP", 1)
+}
+
+func TestServeHTTPSends409IfIdempotencyKeyReusedWithDifferentBody(t *testing.T) {
+	next := &jsonHandler{}
+	next.On("ServeHTTP", mock.Anything, mock.Anything).Return()
+	handler := NewMiddleware("", WithIdempotencyKeys(nil, time.Hour))(next)
+
+	handler.ServeHTTP(httptest.NewRecorder(), idempotentRequest("abc", `{"amount": 5}`))
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, idempotentRequest("abc", `{"amount": 6}`))
+
+	assert.Equal(t, 409, recorder.Code)
+	next.AssertNumberOfCalls(t, "ServeHTTP", 1)
+}
+
+func TestServeHTTPIgnoresIdempotencyIfNoKey(t *testing.T) {
+	next := &jsonHandler{}
+	next.On("ServeHTTP", mock.Anything, mock.Anything).Return()
+	handler := NewMiddleware("", WithIdempotencyKeys(nil, time.Hour))(next)
+
+	for i := 0; i < 2; i++ {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{}`)))
+	}
+
+	next.AssertNumberOfCalls(t, "ServeHTTP", 2)
+}
+
+func TestServeHTTPScopesIdempotencyKeysByClient(t *testing.T) {
+	next := &jsonHandler{}
+	next.On("ServeHTTP", mock.Anything, mock.Anything).Return()
+	handler := NewMiddleware("", WithIdempotencyKeys(nil, time.Hour))(next)
+
+	for _, addr := range []string{"192.0.2.1:1234", "192.0.2.2:1234", "192.0.2.1:5678"} {
+		r := idempotentRequest("abc", `{"amount": 5}`)
+		r.RemoteAddr = addr
+		handler.ServeHTTP(httptest.NewRecorder(), r)
+	}
+
+	next.AssertNumberOfCalls(t, "ServeHTTP", 2)
+}
+
+func TestServeHTTPUsesIdempotencyClientID(t *testing.T) {
+	next := &jsonHandler{}
+	next.On("ServeHTTP", mock.Anything, mock.Anything).Return()
+	clientID := func(r *http.Request) string { return r.Header.Get("X-User") }
+	handler := NewMiddleware("", WithIdempotencyClientID(clientID), WithIdempotencyKeys(nil, time.Hour))(next)
+
+	for _, user := range []string{"alice", "bob", "alice"} {
+		r := idempotentRequest("abc", `{"amount": 5}`)
+		r.Header.Set("X-User", user)
+		handler.ServeHTTP(httptest.NewRecorder(), r)
+	}
+
+	next.AssertNumberOfCalls(t, "ServeHTTP", 2)
+}
+
+// lateStore is a ResponseCache whose first Get misses even if a response has
+// been stored, simulating a response stored by a concurrent request between
+// the middleware's first check and its claim of the key.
+type lateStore struct {
+	*MemoryCache
+	hash string
+	gets int
+}
+
+func (s *lateStore) Get(key string) (*CachedResponse, bool) {
+	s.gets++
+	if s.gets == 1 {
+		s.MemoryCache.Set(key, &CachedResponse{StatusCode: 201, Body: []byte(`{"stored":true}`), RequestHash: s.hash}, time.Hour)
+		return nil, false
+	}
+
+	return s.MemoryCache.Get(key)
+}
+
+func TestServeHTTPChecksIdempotencyStoreAfterClaimingKey(t *testing.T) {
+	next := &jsonHandler{}
+	next.On("ServeHTTP", mock.Anything, mock.Anything).Return()
+
+	r := idempotentRequest("abc", `{"amount": 5}`)
+	hash := requestHash(r, []byte(`{"amount": 5}`))
+
+	store := &lateStore{MemoryCache: NewMemoryCache(), hash: hash}
+	handler := NewMiddleware("", WithIdempotencyKeys(store, time.Hour))(next)
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, r)
+
+	assert.Equal(t, 201, recorder.Code)
+	assert.Equal(t, `{"stored":true}`, recorder.Body.String())
+	next.AssertNotCalled(t, "ServeHTTP", mock.Anything, mock.Anything)
+}
@@ -0,0 +1,42 @@
+package jsonbody
+
+import (
+	"errors"
+	"net/http"
+)
+
+// WriteInformational sends an informational (1xx) response, such as 100
+// Continue or 103 Early Hints, along with the headers currently set on the
+// Writer. Unlike the final response, any number of informational responses may
+// be sent, but only before WriteJSON or WriteErrors is called. 101 Switching
+// Protocols is not supported, since it requires hijacking the connection.
+func (w *Writer) WriteInformational(statusCode int) error {
+	if w.written {
+		return errors.New("informational responses cannot be sent after the final response")
+	}
+
+	if statusCode < 100 || statusCode > 199 || statusCode == http.StatusSwitchingProtocols {
+		return errors.New("status code must be an informational (1xx) status other than 101")
+	}
+
+	w.ResponseWriter.WriteHeader(statusCode)
+	return nil
+}
+
+// WriteEarlyHints sends a 103 Early Hints response with the given Link header
+// values, e.g. "</style.css>; rel=preload; as=style", allowing clients to start
+// loading resources before the final response is ready. The Link headers are
+// also included in the final response.
+func (w *Writer) WriteEarlyHints(links ...string) error {
+	for _, link := range links {
+		w.Header().Add("Link", link)
+	}
+
+	return w.WriteInformational(http.StatusEarlyHints)
+}
+
+// Unwrap returns the underlying http.ResponseWriter, allowing an
+// http.ResponseController to access its optional methods (like Flush).
+func (w Writer) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
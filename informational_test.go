@@ -0,0 +1,48 @@
+package jsonbody
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteEarlyHintsSendsInformationalResponse(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		w := Writer{ResponseWriter: rw}
+		assert.Nil(t, w.WriteEarlyHints("</style.css>; rel=preload; as=style"))
+		assert.Nil(t, w.WriteJSON(200, "done"))
+	}))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL)
+	assert.Nil(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, 200, resp.StatusCode)
+	assert.Equal(t, "</style.css>; rel=preload; as=style", resp.Header.Get("Link"))
+}
+
+func TestWriteInformationalReturnsErrIfNotInformational(t *testing.T) {
+	w := Writer{ResponseWriter: httptest.NewRecorder()}
+
+	assert.NotNil(t, w.WriteInformational(200))
+	assert.NotNil(t, w.WriteInformational(101))
+}
+
+func TestWriteInformationalReturnsErrAfterFinalResponse(t *testing.T) {
+	w := Writer{ResponseWriter: httptest.NewRecorder()}
+	w.WriteJSON(200, "done")
+
+	assert.NotNil(t, w.WriteInformational(100))
+}
+
+func TestResponseControllerUnwrapsWriter(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	w := Writer{ResponseWriter: recorder}
+
+	err := http.NewResponseController(w).Flush()
+	assert.Nil(t, err)
+	assert.True(t, recorder.Flushed)
+}
@@ -4,13 +4,17 @@ package jsonbody
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
-	"io/ioutil"
 	"log"
 	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 )
 
 // NewMiddleware creates a middleware that converts the request body to a map and
@@ -60,81 +64,567 @@ import (
 // 		                    // but the elements can be of any type
 //		...
 //	}
-func NewMiddleware(schemaJSON string) func(next http.Handler) http.Handler {
-	schemaMap, err := parseSchema(schemaJSON)
-	if err != nil {
-		panic("jsonbody: unexpected error while parsing schemaJSON: " + err.Error())
-	}
+//
+// A value in the schema may instead be a constraint object, whose "$type" key
+// names the expected type ("string", "number", "integer", "boolean", "object",
+// or "array"), and whose other keys constrain the value further. An integer is
+// a number without a fractional part, e.g. 3 or 3.0 but not 3.5. Numbers and
+// integers can be given inclusive bounds with "$min" and "$max", and strings can
+// be required to match a regular expression (in the syntax of package regexp)
+// with "$pattern". Values of any type can be limited to a set of strings,
+// numbers, booleans, or null with "$enum", in which case "$type" may be omitted.
+// Optional keys can be given a default value with "$default", which is inserted
+// into the body returned by Reader.JSON (but not the raw body) when the key is
+// missing. If there is no "$type" or "$enum", the type is that of the default.
+// Finally, "$message" replaces the messages of the errors reported for a value
+// that doesn't satisfy the constraint object (but not of the error for a missing
+// key) with the given text, which may refer to the key as {key}.
+// 	{
+//		"age": {"$type": "integer", "$min": 0, "$max": 150},
+//		"slug": {"$type": "string", "$pattern": "^[a-z0-9-]+$"},
+//		"status": {"$enum": ["draft", "published", "archived"]},
+//		"?limit": {"$default": 20},
+//		"email": {"$type": "string", "$message": "a valid email address is required"}
+//	}
+//
+// The schema may also be a top-level array, such as [{"id": 0}], in which case
+// the request body must be an array whose elements all match the schema's single
+// element. Such bodies are available to handlers via Reader.JSONArray().
+// Likewise, a schema that is a bare string, number, or boolean, such as "", 0,
+// or false, requires the body to be a value of that type, which is available via
+// Reader.JSONValue(). Array and scalar schemas cannot carry the top-level "$"
+// keys described below.
+//
+// The schema may also be given a name and description using the top-level keys
+// "$schemaName" and "$description". These keys are not validated against the
+// request body; the name is included in log messages about the schema, is
+// available to handlers via Reader.SchemaName(), and can be sent in the
+// X-Schema response header using the WithSchemaHeader option. Similarly, the
+// "$errorCodePrefix" key sets a prefix (e.g. "POSTS_") for the codes of errors
+// reported for the schema; see ValidationError.
+// 	{
+//		"$schemaName": "CreatePostV2",
+//		"$description": "creates a new blog post",
+//		"title": "",
+//		...
+//	}
+//
+// The request's query parameters may be validated by adding a "$query" key to
+// the schema, using the same syntax as the body. Query parameters can be
+// strings, numbers, booleans, or arrays of those (for repeated parameters), and
+// may be constrained with constraint objects of those types, e.g. to allow only
+// the values in "$enum". A schema with no keys other than "$query" and the
+// metadata keys accepts any body.
+// 	{
+//		"$query": {
+//			"page": 0,        // query must contain a "page" parameter that is a number
+//			"?tag": [""],     // query may contain any number of "tag" parameters
+//			"?sort": {"$enum": ["asc", "desc"]} // "sort" must be "asc" or "desc", if present
+//		}
+//	}
+//
+// Errors for some keys can be given a lower severity with the "$severity" key,
+// which maps the paths of keys (or their parents) to "error", "warn", or "info".
+// By default, only errors reject the request; see Policy and WithPolicy.
+// 	{
+//		"$severity": {"nickname": "warn", "author.bio": "info"},
+//		...
+//	}
+//
+// Keys can be renamed without breaking older clients by mapping the paths of
+// their old names to their new ones with the "$aliases" key. Before the body is
+// validated, the old keys are renamed, and a CodeDeprecatedKey error with the
+// severity "warn" is reported for each old key that was used. The severity can
+// be changed with the "$severity" key, e.g. to "info" to only log the use of the
+// old key.
+// 	{
+//		"$aliases": {"userName": "username", "author.fullName": "name"},
+//		"username": "",
+//		...
+//	}
+//
+// The middleware's behavior can be further customized by passing Options.
+func NewMiddleware(schemaJSON string, opts ...Option) func(next http.Handler) http.Handler {
+	schema := MustParseSchema(schemaJSON)
 
 	return func(next http.Handler) http.Handler {
-		return &middleware{
+		m := &middleware{
 			next:   next,
-			schema: schemaMap,
+			schema: schema,
+		}
+
+		for _, opt := range opts {
+			opt(m)
 		}
+
+		return m
 	}
 }
 
 var (
 	errServerErr = errors.New("an unexpected error occurred")
 	errBadBody   = errors.New("the body of the request was bad")
+
+	errLengthMismatch = errors.New("the body's length differs from its Content-Length")
 )
 
 type middleware struct {
-	next   http.Handler
-	schema map[string]interface{}
+	next          http.Handler
+	schema        *Schema
+	methodSchemas map[string]*Schema // overrides schema for the requests with each method
+
+	order        ErrorOrder
+	schemaHeader bool
+	writerConfig writerConfig
+	fieldsParam  string
+	fieldsHeader string
+	prettyParam  string
+
+	cache     ResponseCache
+	cacheTTL  time.Duration
+	cacheVary func(*http.Request) string
+
+	idempotencyStore    ResponseCache
+	idempotencyTTL      time.Duration
+	idempotencyKeys     sync.Map // keys of in-flight requests
+	idempotencyClientID func(*http.Request) string
+
+	dedup    *dedupWindow
+	async    asyncConfig
+	resolver *resolverConfig
+	routes   *routeTable
+
+	maxBodySize    int64
+	handlerTimeout time.Duration
+	breaker        *circuitBreaker
+	routePatterns  *routeTable // routes without schemas, from WithRoutePatterns
+	autoHead       bool
+	cookies        []cookieSchema
+	parseOptions   ParseOptions
+	csrf           *CSRFOptions
+	statuses       *statusContracts
+	deny           *denyRules
+	validators     []Validator
+
+	memoryGuard      *MemoryGuard
+	memoryRetryAfter time.Duration
+
+	spillThreshold int64
+	spillDir       string
+
+	transcodeMaxSize int64
+
+	writeDiagnostics bool
+	strictWrites     bool
+
+	accessLog *accessLogger
+	policy    Policy
+
+	enforceAccept    bool
+	acceptAlternates []string
+	contentTypes     map[string]bool // accepted in addition to application/json and +json types
+	orderedJSON      bool
+
+	versions *schemaVersions
+
+	strictKeys     bool
+	coerce         bool
+	pathValidators []pathValidator
+	enforcement    *EnforcementControl
 }
 
 func (m *middleware) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	writer := Writer{ResponseWriter: w}
+	if m.accessLog == nil {
+		m.serve(w, r, nil)
+		return
+	}
+
+	entry := m.accessLog.begin(r)
+	stats := &statsWriter{ResponseWriter: w}
+	m.serve(stats, r, entry)
+	m.accessLog.finish(entry, stats)
+}
+
+// serve validates r and passes it to the next handler, recording details of the
+// request in entry if access logging is enabled.
+func (m *middleware) serve(w http.ResponseWriter, r *http.Request, entry *accessLogEntry) {
+	writer := Writer{ResponseWriter: w, config: &m.writerConfig, acceptEncoding: r.Header.Get("Accept-Encoding")}
+
+	if m.fieldsParam != "" {
+		writer.fields = parseFieldSelection(r.URL.Query().Get(m.fieldsParam))
+	} else if m.fieldsHeader != "" {
+		writer.fields = parseFieldSelection(r.Header.Get(m.fieldsHeader))
+	}
+
+	if m.prettyParam != "" {
+		writer.pretty, _ = strconv.ParseBool(r.URL.Query().Get(m.prettyParam))
+	}
 
-	if m.schema != nil && r.Header.Get("Content-Type") != "application/json" {
-		writer.WriteErrors(http.StatusBadRequest, "content type must be application/json")
+	if m.writerConfig.translations != nil {
+		writer.locale = m.writerConfig.translations.Negotiate(r.Header.Get("Accept-Language"))
+	}
+
+	if m.autoHead && r.Method == http.MethodHead {
+		writer.ResponseWriter = headWriter{w}
+		writer.head = true
+	}
+
+	if m.writerConfig.appendJSON {
+		writer.appended = &appendState{}
+	}
+
+	if m.writeDiagnostics {
+		writer.writes = &writeTracker{ResponseWriter: writer.ResponseWriter, strict: m.strictWrites}
+		writer.ResponseWriter = writer.writes
+	}
+
+	mode := m.enforcement.mode(r.Method, r.URL.Path)
+	if mode == EnforcementOff || skipsValidation(r.Context()) {
+		m.serveUnvalidated(writer, r, entry)
+		return
+	}
+
+	route := r.Method + " " + m.routePattern(r)
+	bypass := false // whether to skip the subsystems protected by the circuit breaker
+	if m.breaker != nil {
+		allowed, trial := m.breaker.allow(route)
+		if trial != 0 {
+			defer m.breaker.endTrial(route, trial)
+		}
+		if !allowed {
+			if m.breaker.config.Policy == CircuitReject {
+				writer.writeErrors(http.StatusServiceUnavailable, ValidationError{Code: CodeValidationUnavailable})
+				return
+			}
+			bypass = true
+		}
+	}
+
+	schema, params, ok := m.selectSchema(r, route, bypass)
+	if len(params) > 0 {
+		r = r.WithContext(context.WithValue(r.Context(), pathParamsContextKey{}, params))
+	}
+
+	if !ok {
+		writer.writeErrors(http.StatusServiceUnavailable, ValidationError{Code: CodeSchemaUnavailable})
+		return
+	}
+
+	if m.versions != nil {
+		t, ok, fromAccept, status, verr := m.versions.negotiate(r)
+		if status != 0 {
+			writer.writeErrors(status, verr)
+			return
+		}
+
+		if fromAccept {
+			writer.Header().Add("Vary", "Accept")
+		}
+
+		if ok {
+			schema = m.versions.schemas[t.Version]
+			writer.mediaType = t.String()
+			r = r.WithContext(withMediaType(r.Context(), t))
+		}
+	}
+
+	writer.codeNamespace = schema.ErrorCodePrefix()
+	entry.setSchema(schema.Name())
+
+	if m.schemaHeader && schema.Name() != "" {
+		writer.Header().Set("X-Schema", schema.Name())
+	}
+
+	// These checks only use the request's headers, so they are done before the
+	// body is read. This way, clients that send "Expect: 100-continue" don't
+	// transmit bodies that will be rejected anyway.
+	if status, verr := m.checkHeaders(r, schema); status != 0 {
+		if expectsContinue(r) {
+			writer.Header().Set("Connection", "close")
+		}
+
+		writer.writeErrors(status, verr)
+		return
+	}
+
+	// The body is released once the handler is done with it, which may be after
+	// serve returns if the handler times out (see callNext).
+	resources := newBodyResources()
+	defer resources.release()
+	r = r.WithContext(withBodyResources(r.Context(), resources))
+
+	release, ok := m.acquireMemory(&writer, r)
+	if !ok {
 		return
 	}
+	resources.add(release)
 
-	body, err := decodeBody(r)
+	var timing Timing
+	body, err := m.decodeBody(r, &timing)
+	if spilled, ok := r.Body.(spilledBody); ok {
+		resources.add(spilled.remove)
+	}
+	var perr *parseError
+	var maxErr *http.MaxBytesError
 	switch {
 	case err == errBadBody:
-		writer.WriteErrors(http.StatusBadRequest, "expected a JSON body")
+		writer.writeErrors(http.StatusBadRequest, ValidationError{Code: CodeExpectedBody})
+		return
+	case err == errLengthMismatch:
+		writer.writeErrors(http.StatusBadRequest, ValidationError{
+			Code:   CodeLengthMismatch,
+			Params: map[string]string{"length": strconv.FormatInt(r.ContentLength, 10)},
+		})
+		return
+	case errors.As(err, &maxErr):
+		writer.writeErrors(http.StatusRequestEntityTooLarge, bodyTooLarge(maxErr.Limit))
+		return
+	case errors.As(err, &perr):
+		writer.writeErrors(http.StatusBadRequest, ValidationError{Code: CodeInvalidJSON, Params: map[string]string{"reason": perr.reason}})
 		return
 	case err == errServerErr:
 		fallthrough
 	case err != nil:
-		log.Println(fmt.Errorf("jsonbody: failed to decode body: %v", err))
-		writer.WriteHeader(http.StatusInternalServerError)
+		log.Println(fmt.Errorf("%vfailed to decode body: %v", schema.logPrefix(), err))
+		writer.writeServerError()
+		return
+	}
+
+	if !m.csrf.checkCSRF(r, body) {
+		writer.writeErrors(http.StatusForbidden, ValidationError{Code: CodeCSRFMismatch})
 		return
 	}
 
-	errs := validateReqBody(m.schema, body)
+	validateStart := time.Now()
+	if m.coerce {
+		body = schema.coerce(body)
+	}
+	errs := schema.applyAliases(body)
+	errs = append(errs, schema.validate(m.order, body, r.URL.Query())...)
+	if m.strictKeys {
+		errs = append(errs, schema.unknownKeys(body)...)
+	}
+	errs = append(errs, m.runPathValidators(body, errs)...)
+	errs = append(errs, m.deny.check(body)...)
+	errs = append(errs, m.runValidators(r.Context(), body)...)
+	cookieErrs, r := m.validateCookies(r)
+	errs = append(errs, cookieErrs...)
+	errs = m.enforce(&writer, schema, errs, mode == EnforcementObserve)
+	if len(errs) == 0 && len(m.async.validators) > 0 && !bypass {
+		var timedOut bool
+		errs, timedOut = m.async.run(r.Context(), body)
+		m.breaker.record(route, !timedOut)
+		errs = m.enforce(&writer, schema, errs, mode == EnforcementObserve)
+	}
+	timing.Validate = time.Since(validateStart)
+
 	if len(errs) > 0 {
-		writer.WriteErrors(http.StatusBadRequest, errs...)
+		writer.writeErrors(http.StatusBadRequest, errs...)
 		return
 	}
+	schema.applyDefaults(body)
+
+	var hash string
+	if m.dedup != nil || m.cache != nil || m.idempotencyStore != nil {
+		hash, err = requestHashFromBody(r)
+		if err != nil {
+			log.Println(fmt.Errorf("%vfailed to read body: %v", schema.logPrefix(), err))
+			writer.writeServerError()
+			return
+		}
+	}
 
 	reader := Reader{
 		ReadCloser: r.Body,
 		json:       body,
+		schemaName: schema.Name(),
 	}
+	if _, ok := body.(map[string]interface{}); ok && m.orderedJSON {
+		reader.ordered = NewOrderedMap()
+		if err := reader.Unmarshal(reader.ordered); err != nil {
+			log.Println(fmt.Errorf("%vfailed to decode ordered body: %v", schema.logPrefix(), err))
+			writer.writeServerError()
+			return
+		}
+	}
+	entry.setRequestBytes(reader.Size())
 	r.Body = reader
+	r.GetBody = reader.GetBody
+	r = r.WithContext(withTiming(r.Context(), timing))
+	writer.timing = timing
+
+	m.serveProtected(writer, r, hash)
+}
+
+// serveProtected applies idempotency keys or deduplication to r, whose method,
+// URL, and body are identified by hash, then calls the next handler through
+// serveNext. Requests with idempotency keys aren't deduplicated, since their
+// retries should receive the stored response rather than an error.
+func (m *middleware) serveProtected(writer Writer, r *http.Request, hash string) {
+	if m.idempotencyStore != nil && r.Header.Get(idempotencyKeyHeader) != "" {
+		m.serveIdempotent(writer, r, hash)
+		return
+	}
+
+	if m.dedup != nil {
+		m.serveDeduplicated(writer, r, hash)
+		return
+	}
+
+	m.serveNext(writer, r, hash)
+}
+
+// selectSchema returns the schema to validate r against, or false if r should be
+// rejected because its schema couldn't be resolved. If bypass is set, the
+// schema resolver is not used. If the schema was selected from the middleware's
+// routes, the values of the route's path parameters are also returned.
+func (m *middleware) selectSchema(r *http.Request, route string, bypass bool) (schema *Schema, params map[string]string, ok bool) {
+	method := r.Method
+	if m.autoHead && method == http.MethodHead {
+		method = http.MethodGet
+	}
+
+	schema = m.schema
+	if methodSchema, ok := m.methodSchemas[method]; ok {
+		schema = methodSchema
+	}
+
+	if m.routes != nil {
+		if routeSchema, routeParams, ok := m.routes.matchParams(method, r.URL.Path); ok {
+			schema, params = routeSchema, routeParams
+		}
+	}
+
+	if m.resolver == nil || m.resolver.resolver == nil || bypass {
+		return schema, params, true
+	}
 
-	m.next.ServeHTTP(writer, r)
+	pattern := m.routePattern(r)
+	resolved, err := m.resolver.resolve(r, pattern)
+	m.breaker.record(route, err == nil)
+
+	switch {
+	case err != nil:
+		schema, ok = m.resolver.fallback(r, pattern, err, schema)
+		return schema, params, ok
+	case resolved != nil:
+		return resolved, params, true
+	}
+
+	return schema, params, true
+}
+
+// checkHeaders checks the headers of r before its body is read, returning the
+// status and error to reject it with, or 0 if it should be accepted.
+func (m *middleware) checkHeaders(r *http.Request, schema *Schema) (int, ValidationError) {
+	if !schema.acceptsAnyBody() && !m.isJSONContentType(r.Header.Get("Content-Type")) {
+		return http.StatusBadRequest, ValidationError{Code: CodeContentType}
+	}
+
+	if status, verr := m.checkCharset(r); status != 0 {
+		return status, verr
+	}
+
+	if m.enforceAccept && !acceptsAny(r.Header.Get("Accept"), m.acceptableTypes()) {
+		return http.StatusNotAcceptable, ValidationError{
+			Code:   CodeNotAcceptable,
+			Params: map[string]string{"types": strings.Join(m.acceptableTypes(), ", ")},
+		}
+	}
+
+	if m.maxBodySize > 0 && r.ContentLength > m.maxBodySize {
+		return http.StatusRequestEntityTooLarge, bodyTooLarge(m.maxBodySize)
+	}
+
+	return 0, ValidationError{}
+}
+
+// bodyTooLarge returns the error sent for bodies larger than max bytes.
+func bodyTooLarge(max int64) ValidationError {
+	return ValidationError{
+		Code:   CodeBodyTooLarge,
+		Params: map[string]string{"max": strconv.FormatInt(max, 10)},
+	}
+}
+
+// expectsContinue reports whether the client is waiting for a 100 Continue
+// response before sending the body of r.
+func expectsContinue(r *http.Request) bool {
+	return strings.EqualFold(r.Header.Get("Expect"), "100-continue")
 }
 
-func decodeBody(r *http.Request) (map[string]interface{}, error) {
+// serveNext calls the next handler, or sends its cached response if caching is
+// enabled.
+func (m *middleware) serveNext(writer Writer, r *http.Request, hash string) {
+	if m.cache != nil {
+		m.serveCached(writer, r, hash)
+		return
+	}
+
+	m.callNext(writer, r)
+}
+
+// decodeBody reads and parses the request body, recording how long each step
+// took in timing. Bodies larger than the spill threshold are written to a
+// temporary file instead of being read into memory; r.Body is then a
+// spilledBody, which the caller must remove.
+func (m *middleware) decodeBody(r *http.Request, timing *Timing) (interface{}, error) {
 	if r.ContentLength == 0 {
+		r.Body = bodyBuffer{bytes.NewReader(nil)}
 		return nil, nil // validateReqBody will determine whether an empty body is an error or not
 	}
 
-	body := make([]byte, r.ContentLength)
-	defer r.Body.Close()
-	_, err := r.Body.Read(body)
-	if err != nil && err != io.EOF {
+	if m.maxBodySize > 0 {
+		// enforce the limit on the bytes actually read, not just the
+		// Content-Length header, which the checks before reading rely on
+		r.Body = http.MaxBytesReader(nil, r.Body, m.maxBodySize)
+	}
+
+	transcode := m.transcoder(r)
+	if transcode != nil && r.ContentLength < 0 {
+		// the size of bodies of unknown length can't be checked before reading
+		r.Body = http.MaxBytesReader(nil, r.Body, m.transcodeMaxSize)
+	}
+
+	readStart := time.Now()
+	body, spilled, err := m.readBody(r, transcode == nil)
+	timing.Read = time.Since(readStart)
+	if isTooLarge(err) || err == errLengthMismatch {
+		return nil, err
+	} else if err != nil {
 		log.Println(fmt.Errorf("jsonbody: failed to read entire body: %v", err))
 		return nil, errServerErr
 	}
 
+	parseStart := time.Now()
+	defer func() { timing.Parse = time.Since(parseStart) }()
+
+	if spilled != nil {
+		r.Body = *spilled
+		bodyJSON, err := decodeSpilled(*spilled, m.parseOptions)
+		if err != nil {
+			return nil, err
+		}
+
+		return nonNull(bodyJSON)
+	}
+
+	if len(body) == 0 {
+		// a body of unknown length (e.g. chunked) turned out to be empty
+		r.Body = bodyBuffer{bytes.NewReader(nil)}
+		return nil, nil
+	}
+
+	if transcode != nil {
+		body, err = transcode(body)
+		if err != nil {
+			log.Println(fmt.Errorf("jsonbody: failed to transcode body: %v", err))
+			return nil, errBadBody
+		}
+	}
+
 	// reset body in case future handlers want to read it
-	r.Body = ioutil.NopCloser(bytes.NewBuffer(body))
+	r.Body = bodyBuffer{bytes.NewReader(body)}
 
 	var bodyJSON interface{}
 	err = json.Unmarshal(body, &bodyJSON)
@@ -143,5 +633,96 @@ func decodeBody(r *http.Request) (map[string]interface{}, error) {
 		return nil, errBadBody
 	}
 
-	return bodyJSON.(map[string]interface{}), nil
+	err = m.parseOptions.check(body)
+	if err != nil {
+		return nil, err
+	}
+
+	return nonNull(bodyJSON)
+}
+
+// nonNull returns the decoded body unless it is null, which can't be
+// distinguished from an empty body once decoded.
+func nonNull(bodyJSON interface{}) (interface{}, error) {
+	if bodyJSON == nil {
+		log.Println("jsonbody: body is null")
+		return nil, errBadBody
+	}
+
+	return bodyJSON, nil
+}
+
+// readBody reads the body of r into memory or, if canSpill is set and it is
+// larger than the spill threshold, into a temporary file. Bodies of unknown
+// length (e.g. chunked ones) are read until they end.
+func (m *middleware) readBody(r *http.Request, canSpill bool) ([]byte, *spilledBody, error) {
+	defer r.Body.Close()
+	spill := canSpill && m.spillThreshold > 0
+
+	if r.ContentLength > 0 {
+		if spill && r.ContentLength > m.spillThreshold {
+			spilled, err := spillBody(r.Body, m.spillDir)
+			if err == nil && spilled.size() != r.ContentLength {
+				spilled.remove()
+				return nil, nil, errLengthMismatch
+			}
+			return nil, &spilled, err
+		}
+
+		body := make([]byte, r.ContentLength)
+		n, err := readFull(r.Body, body)
+		if err == io.EOF {
+			err = nil
+		}
+		if err == nil && (n < len(body) || !atEOF(r.Body)) {
+			// the client sent fewer or more bytes than it declared, e.g.
+			// because the upload was truncated
+			err = errLengthMismatch
+		}
+		return body, nil, err
+	}
+
+	if !spill {
+		body, err := io.ReadAll(r.Body)
+		return body, nil, err
+	}
+
+	// read up to the threshold into memory, then spill if there's more
+	body, err := io.ReadAll(io.LimitReader(r.Body, m.spillThreshold+1))
+	if err != nil || int64(len(body)) <= m.spillThreshold {
+		return body, nil, err
+	}
+
+	spilled, err := spillBody(io.MultiReader(bytes.NewReader(body), r.Body), m.spillDir)
+	return nil, &spilled, err
+}
+
+// readFull reads into body until it is full, returning the number of bytes read
+// and the first error encountered, including io.EOF if the body ended early.
+// (Unlike io.ReadFull, it doesn't discard an error returned along with the last
+// bytes.)
+func readFull(r io.Reader, body []byte) (int, error) {
+	var err error
+	read := 0
+	for read < len(body) && err == nil {
+		var n int
+		n, err = r.Read(body[read:])
+		read += n
+	}
+
+	return read, err
+}
+
+// atEOF reports whether r has no more bytes to read.
+func atEOF(r io.Reader) bool {
+	var extra [1]byte
+	_, err := io.ReadFull(r, extra[:])
+	return err == io.EOF
+}
+
+// isTooLarge reports whether err occurred because the body was larger than the
+// maximum size.
+func isTooLarge(err error) bool {
+	var maxErr *http.MaxBytesError
+	return errors.As(err, &maxErr)
 }
@@ -2,6 +2,7 @@ package jsonbody
 
 import (
 	"errors"
+	"fmt"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
@@ -48,7 +49,7 @@ func TestServeHTTPSends400IfWrongContentTypeAndSchemaSet(t *testing.T) {
 	next.On("ServeHTTP", mock.Anything, mock.Anything).Return()
 	mw := &middleware{
 		next:   next,
-		schema: make(map[string]interface{}),
+		schema: &Schema{body: make(map[string]interface{})},
 	}
 
 	recorder := httptest.NewRecorder()
@@ -64,7 +65,7 @@ func TestServeHTTPSendsErrorsIfWrongContentTypeAndSchemaSet(t *testing.T) {
 	next.On("ServeHTTP", mock.Anything, mock.Anything).Return()
 	mw := &middleware{
 		next:   next,
-		schema: make(map[string]interface{}),
+		schema: &Schema{body: make(map[string]interface{})},
 	}
 
 	recorder := httptest.NewRecorder()
@@ -83,7 +84,7 @@ func TestServeHTTPNotCallNextIfWrongContentTypeAndSchemaSet(t *testing.T) {
 	next.On("ServeHTTP", mock.Anything, mock.Anything).Return()
 	mw := &middleware{
 		next:   next,
-		schema: make(map[string]interface{}),
+		schema: &Schema{body: make(map[string]interface{})},
 	}
 
 	recorder := httptest.NewRecorder()
@@ -112,7 +113,7 @@ func TestServeHTTPSends400IfBodyEmptyAndSchemaSet(t *testing.T) {
 	next.On("ServeHTTP", mock.Anything, mock.Anything).Return()
 	mw := &middleware{
 		next:   next,
-		schema: make(map[string]interface{}),
+		schema: &Schema{body: make(map[string]interface{})},
 	}
 
 	recorder := httptest.NewRecorder()
@@ -128,7 +129,7 @@ func TestServeHTTPSendsErrorsIfBodyEmptyAndSchemaSet(t *testing.T) {
 	next.On("ServeHTTP", mock.Anything, mock.Anything).Return()
 	mw := &middleware{
 		next:   next,
-		schema: make(map[string]interface{}),
+		schema: &Schema{body: make(map[string]interface{})},
 	}
 
 	recorder := httptest.NewRecorder()
@@ -146,7 +147,7 @@ func TestServeHTTPNotCallNextIfBodyEmptyAndSchemaSet(t *testing.T) {
 	next := &mockHandler{}
 	mw := &middleware{
 		next:   next,
-		schema: make(map[string]interface{}),
+		schema: &Schema{body: make(map[string]interface{})},
 	}
 
 	next.On("ServeHTTP", mock.Anything, mock.Anything).Return()
@@ -256,7 +257,7 @@ func TestServeHTTPCallsNextCorrectly(t *testing.T) {
 func TestServeHTTPSends400IfBodyNotMatchSchema(t *testing.T) {
 	next := &mockHandler{}
 	next.On("ServeHTTP", mock.Anything, mock.Anything).Return()
-	schema, _ := parseSchema(`{ "s": "" }`)
+	schema, _ := ParseSchema(`{ "s": "" }`)
 	mw := middleware{
 		next:   next,
 		schema: schema,
@@ -271,7 +272,7 @@ func TestServeHTTPSends400IfBodyNotMatchSchema(t *testing.T) {
 func TestServeHTTPSendsErrorsIfBodyNotMatchSchema(t *testing.T) {
 	next := &mockHandler{}
 	next.On("ServeHTTP", mock.Anything, mock.Anything).Return()
-	schema, _ := parseSchema(`{ "s": "" }`)
+	schema, _ := ParseSchema(`{ "s": "" }`)
 	mw := middleware{
 		next:   next,
 		schema: schema,
@@ -285,7 +286,7 @@ func TestServeHTTPSendsErrorsIfBodyNotMatchSchema(t *testing.T) {
 
 func TestServeHTTPNotCallNextIfBodyNotMatchSchema(t *testing.T) {
 	next := &mockHandler{}
-	schema, _ := parseSchema(`{ "s": "" }`)
+	schema, _ := ParseSchema(`{ "s": "" }`)
 	mw := middleware{
 		next:   next,
 		schema: schema,
@@ -318,7 +319,7 @@ func TestNewMiddlewareAddsParsedSchemaToHandler(t *testing.T) {
 	next := &mockHandler{}
 	handler := mw(next).(*middleware)
 
-	expectedSchema, _ := parseSchema(`{"schema": "s"}`)
+	expectedSchema, _ := ParseSchema(`{"schema": "s"}`)
 	assert.Equal(t, expectedSchema, handler.schema)
 }
 
@@ -337,3 +338,187 @@ func TestNewMiddlewarePanicsIfInvalidSchema(t *testing.T) {
 
 	assert.Panics(t, shouldPanic)
 }
+
+func TestNewMiddlewareAppliesOptions(t *testing.T) {
+	mw := NewMiddleware(`{"b": "", "a": ""}`, WithErrorOrder(OrderDeclaration))
+	next := &mockHandler{}
+	handler := mw(next).(*middleware)
+
+	assert.Equal(t, OrderDeclaration, handler.order)
+	assert.Equal(t, []string{"b", "a"}, handler.schema.keyOrder[""])
+}
+
+func TestServeHTTPSendsSchemaHeaderIfEnabled(t *testing.T) {
+	mw := NewMiddleware(`{"$schemaName": "CreatePost", "s": ""}`, WithSchemaHeader())
+	next := &mockHandler{}
+	next.On("ServeHTTP", mock.Anything, mock.Anything).Return()
+
+	recorder := httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"s": "hi"}`))
+	request.Header.Set("Content-Type", "application/json")
+	mw(next).ServeHTTP(recorder, request)
+
+	assert.Equal(t, "CreatePost", recorder.Header().Get("X-Schema"))
+}
+
+func TestServeHTTPPassesSchemaNameToReader(t *testing.T) {
+	mw := NewMiddleware(`{"$schemaName": "CreatePost", "$description": "creates a post"}`, WithErrorOrder(OrderDeclaration))
+	next := &mockHandler{}
+	next.On("ServeHTTP", mock.Anything, mock.Anything).Return()
+
+	recorder := httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{}`))
+	request.Header.Set("Content-Type", "application/json")
+	mw(next).ServeHTTP(recorder, request)
+
+	reader := next.Calls[0].Arguments.Get(1).(*http.Request).Body.(Reader)
+	assert.Equal(t, "CreatePost", reader.SchemaName())
+}
+
+func TestServeHTTPPassesFieldSelectionToWriter(t *testing.T) {
+	mw := NewMiddleware("", WithFieldSelection("fields"))
+	next := &mockHandler{}
+	next.On("ServeHTTP", mock.Anything, mock.Anything).Return()
+
+	recorder := httptest.NewRecorder()
+	mw(next).ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/?fields=id", nil))
+
+	writer := next.Calls[0].Arguments.Get(0).(Writer)
+	assert.Equal(t, fieldSelection{"id": nil}, writer.fields)
+}
+
+func TestServeHTTPRejectsExpectContinueBeforeReadingBody(t *testing.T) {
+	next := &mockHandler{}
+	next.On("ServeHTTP", mock.Anything, mock.Anything).Return()
+	handler := NewMiddleware(`{}`, WithMaxBodySize(10))(next)
+
+	reader := mockReader{}
+	request := httptest.NewRequest(http.MethodPost, "/", &reader)
+	request.ContentLength = 11
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("Expect", "100-continue")
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+
+	assert.Equal(t, 413, recorder.Code)
+	assert.Equal(t, "close", recorder.Header().Get("Connection"))
+	reader.AssertNotCalled(t, "Read", mock.Anything)
+}
+
+func TestServeHTTPEnforcesMaxBodySizeWhileReading(t *testing.T) {
+	called := false
+	handler := NewMiddleware(`{}`, WithMaxBodySize(10), WithSpillToDisk(1, t.TempDir()))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+
+	request := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"a": "longer than claimed"}`))
+	request.ContentLength = 8
+	request.Header.Set("Content-Type", "application/json")
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+
+	assert.Equal(t, 413, recorder.Code)
+	assert.Equal(t, `{"errors":["body must be at most 10 bytes"]}`, recorder.Body.String())
+	assert.False(t, called)
+}
+
+func TestServeHTTPRejectsContentLengthMismatch(t *testing.T) {
+	handler := NewMiddleware(`{}`)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	spillingHandler := NewMiddleware(`{}`, WithSpillToDisk(1, t.TempDir()))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	for _, tc := range []struct {
+		handler       http.Handler
+		body          string
+		contentLength int64
+	}{
+		{handler, `{"a": 1}`, 20},
+		{handler, `{"a": 1}`, 2},
+		{spillingHandler, `{"a": 1}`, 20},
+		{spillingHandler, `{"a": 1}`, 4},
+	} {
+		request := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tc.body))
+		request.ContentLength = tc.contentLength
+		request.Header.Set("Content-Type", "application/json")
+
+		recorder := httptest.NewRecorder()
+		tc.handler.ServeHTTP(recorder, request)
+
+		assert.Equal(t, 400, recorder.Code)
+		assert.Equal(t, fmt.Sprintf(`{"errors":["body length does not match its Content-Length of %v bytes"]}`, tc.contentLength), recorder.Body.String())
+	}
+}
+
+func TestServeHTTPUsesMethodSchema(t *testing.T) {
+	mw := NewMiddleware(`{"title": ""}`, WithMethodSchema("patch", `{"?title": ""}`), WithMethodSchema("DELETE", ""))
+	handler := mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	for _, tc := range []struct {
+		method string
+		body   string
+		code   int
+	}{
+		{http.MethodPost, `{}`, 400},
+		{http.MethodPut, `{"title": "a"}`, 200},
+		{http.MethodPatch, `{}`, 200},
+		{http.MethodPatch, `{"title": 1}`, 400},
+		{http.MethodDelete, ``, 200},
+	} {
+		req := httptest.NewRequest(tc.method, "/", strings.NewReader(tc.body))
+		req.Header.Set("Content-Type", "application/json")
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+		assert.Equal(t, tc.code, recorder.Code, tc.method+" "+tc.body)
+	}
+}
+
+func TestServeHTTPAcceptsTopLevelArray(t *testing.T) {
+	var got []interface{}
+	handler := NewMiddleware(`[{"id": 0}]`)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Body.(Reader).JSONArray()
+		assert.Nil(t, r.Body.(Reader).JSON())
+	}))
+
+	recorder := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`[{"id": 1}, {"id": 2}]`))
+	r.Header.Set("Content-Type", "application/json")
+	handler.ServeHTTP(recorder, r)
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, []interface{}{map[string]interface{}{"id": 1.0}, map[string]interface{}{"id": 2.0}}, got)
+}
+
+func TestServeHTTPRejectsScalarBody(t *testing.T) {
+	handler := NewMiddleware(`[0]`)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	recorder := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`42`))
+	r.Header.Set("Content-Type", "application/json")
+	handler.ServeHTTP(recorder, r)
+
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+}
+
+func TestServeHTTPAcceptsTopLevelScalar(t *testing.T) {
+	var got interface{}
+	handler := NewMiddleware(`""`)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Body.(Reader).JSONValue()
+	}))
+
+	recorder := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`"hello"`))
+	r.Header.Set("Content-Type", "application/json")
+	handler.ServeHTTP(recorder, r)
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "hello", got)
+
+	recorder = httptest.NewRecorder()
+	r = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"a": 1}`))
+	r.Header.Set("Content-Type", "application/json")
+	handler.ServeHTTP(recorder, r)
+
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	assert.Equal(t, `{"errors":["body expected to be of type string"]}`, recorder.Body.String())
+}
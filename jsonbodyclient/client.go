@@ -0,0 +1,134 @@
+// Package jsonbodyclient helps Go clients of services using the jsonbody
+// middleware handle its error responses, by decoding the error envelope into
+// typed Go errors.
+package jsonbodyclient
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// maxErrorBodySize is the most of an error response body that is read.
+const maxErrorBodySize = 1 << 20
+
+// Error is a single error from the error envelope. Errors sent as plain
+// strings only have a Message.
+type Error struct {
+	// Field is the key of the invalid value in the request body, e.g.
+	// "author.tags[0]", or "" if the error isn't about a specific value.
+	Field string `json:"field,omitempty"`
+
+	// Code identifies the kind of error, e.g. jsonbody.CodeMissingKey, or ""
+	// if the server didn't send one.
+	Code string `json:"code,omitempty"`
+
+	// Message describes the error.
+	Message string `json:"message"`
+}
+
+func (e Error) Error() string {
+	return e.Message
+}
+
+// UnmarshalJSON decodes either a message string or an error object.
+func (e *Error) UnmarshalJSON(data []byte) error {
+	var msg string
+	if err := json.Unmarshal(data, &msg); err == nil {
+		*e = Error{Message: msg}
+		return nil
+	}
+
+	type plain Error
+	var p plain
+	if err := json.Unmarshal(data, &p); err != nil {
+		return err
+	}
+
+	*e = Error(p)
+	return nil
+}
+
+// ResponseError is returned by CheckResponse for error responses. If the body
+// was the middleware's error envelope, Errors holds its errors; otherwise,
+// Errors is empty and Body holds (the beginning of) the body.
+type ResponseError struct {
+	StatusCode int
+	Errors     []Error
+	Body       []byte
+}
+
+func (e *ResponseError) Error() string {
+	if len(e.Errors) == 0 {
+		return fmt.Sprintf("jsonbodyclient: request failed with status %v", e.StatusCode)
+	}
+
+	msgs := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		msgs[i] = err.Message
+	}
+
+	return fmt.Sprintf("jsonbodyclient: request failed with status %v: %v", e.StatusCode, strings.Join(msgs, "; "))
+}
+
+// HasCode reports whether any of the errors has the given code.
+func (e *ResponseError) HasCode(code string) bool {
+	for _, err := range e.Errors {
+		if err.Code == code {
+			return true
+		}
+	}
+
+	return false
+}
+
+// FieldErrors returns the errors about the given field of the request body.
+func (e *ResponseError) FieldErrors(field string) []Error {
+	var errs []Error
+	for _, err := range e.Errors {
+		if err.Field == field {
+			errs = append(errs, err)
+		}
+	}
+
+	return errs
+}
+
+// IsValidation reports whether the response rejected the request itself (a
+// 4xx status) rather than reporting a server problem.
+func (e *ResponseError) IsValidation() bool {
+	return e.StatusCode >= 400 && e.StatusCode < 500
+}
+
+// CheckResponse returns nil if resp has a 2xx or 3xx status. Otherwise, it
+// reads resp's body and returns a *ResponseError describing it. The body is
+// not closed.
+//
+// The error envelope is a JSON object whose "errors" key holds an array of
+// errors, each either a message string or an object like
+// {"field": "title", "code": "missing_key", "message": "..."}.
+func CheckResponse(resp *http.Response) error {
+	if resp.StatusCode < 400 {
+		return nil
+	}
+
+	respErr := &ResponseError{StatusCode: resp.StatusCode}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize))
+	if err != nil {
+		return respErr
+	}
+
+	var envelope struct {
+		Errors []Error `json:"errors"`
+	}
+	if err := json.Unmarshal(body, &envelope); err != nil || len(envelope.Errors) == 0 {
+		respErr.Body = body
+		return respErr
+	}
+
+	respErr.Errors = envelope.Errors
+	return respErr
+}
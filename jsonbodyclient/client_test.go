@@ -0,0 +1,66 @@
+package jsonbodyclient
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jasonccox/jsonbody"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckResponseAcceptsSuccess(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	recorder.WriteHeader(http.StatusCreated)
+
+	assert.Nil(t, CheckResponse(recorder.Result()))
+}
+
+func TestCheckResponseDecodesStringErrors(t *testing.T) {
+	handler := jsonbody.NewMiddleware(`{"title": ""}`)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{}`))
+	req.Header.Set("Content-Type", "application/json")
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, req)
+
+	err := CheckResponse(recorder.Result())
+
+	var respErr *ResponseError
+	assert.True(t, errors.As(err, &respErr))
+	assert.Equal(t, 400, respErr.StatusCode)
+	assert.True(t, respErr.IsValidation())
+	assert.Equal(t, []Error{{Message: "expected key 'title' missing"}}, respErr.Errors)
+	assert.Equal(t, "jsonbodyclient: request failed with status 400: expected key 'title' missing", err.Error())
+}
+
+func TestCheckResponseDecodesStructuredErrors(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	recorder.WriteHeader(http.StatusBadRequest)
+	recorder.WriteString(`{"errors": [{"field": "title", "code": "missing_key", "message": "expected key 'title' missing"}, "other"]}`)
+
+	err := CheckResponse(recorder.Result())
+
+	var respErr *ResponseError
+	assert.True(t, errors.As(err, &respErr))
+	assert.True(t, respErr.HasCode(jsonbody.CodeMissingKey))
+	assert.False(t, respErr.HasCode(jsonbody.CodeWrongType))
+	assert.Equal(t, []Error{{Field: "title", Code: "missing_key", Message: "expected key 'title' missing"}}, respErr.FieldErrors("title"))
+	assert.Equal(t, Error{Message: "other"}, respErr.Errors[1])
+}
+
+func TestCheckResponseKeepsOtherBodies(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	recorder.WriteHeader(http.StatusBadGateway)
+	recorder.WriteString("upstream unavailable")
+
+	err := CheckResponse(recorder.Result())
+
+	var respErr *ResponseError
+	assert.True(t, errors.As(err, &respErr))
+	assert.False(t, respErr.IsValidation())
+	assert.Empty(t, respErr.Errors)
+	assert.Equal(t, "upstream unavailable", string(respErr.Body))
+	assert.Equal(t, "jsonbodyclient: request failed with status 502", err.Error())
+}
@@ -0,0 +1,155 @@
+package jsonbodyclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/jasonccox/jsonbody"
+)
+
+// Option customizes the behavior of Post.
+type Option func(*config)
+
+type config struct {
+	client         *http.Client
+	requestSchema  *jsonbody.Schema
+	responseSchema *jsonbody.Schema
+}
+
+// WithHTTPClient causes Post to send its request with client instead of
+// http.DefaultClient.
+func WithHTTPClient(client *http.Client) Option {
+	return func(c *config) {
+		c.client = client
+	}
+}
+
+// WithRequestSchema causes Post to check the request body against schemaJSON
+// (in the format described for jsonbody.NewMiddleware) before sending it, so
+// that a request the server would reject isn't sent at all. It panics if
+// schemaJSON is invalid.
+func WithRequestSchema(schemaJSON string) Option {
+	schema := jsonbody.MustParseSchema(schemaJSON)
+	return func(c *config) {
+		c.requestSchema = schema
+	}
+}
+
+// WithResponseSchema causes Post to check the body of successful responses
+// against schemaJSON (in the format described for jsonbody.NewMiddleware)
+// before decoding it. It panics if schemaJSON is invalid.
+func WithResponseSchema(schemaJSON string) Option {
+	schema := jsonbody.MustParseSchema(schemaJSON)
+	return func(c *config) {
+		c.responseSchema = schema
+	}
+}
+
+// SchemaError is returned by Post when the request or response body doesn't
+// match its schema.
+type SchemaError struct {
+	// Response is true if the response body didn't match, or false if the
+	// request body didn't (in which case the request wasn't sent).
+	Response bool
+
+	Errors []jsonbody.ValidationError
+}
+
+func (e *SchemaError) Error() string {
+	which := "request"
+	if e.Response {
+		which = "response"
+	}
+
+	msgs := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		msgs[i] = err.Error()
+	}
+
+	return fmt.Sprintf("jsonbodyclient: %v body does not match schema: %v", which, strings.Join(msgs, "; "))
+}
+
+// Post encodes body as JSON and sends it to url in a POST request, decoding the
+// JSON body of a successful response into out (unless out is nil or the
+// response has no body). If the response has an error status, the error
+// returned by CheckResponse is returned. The request and response bodies can be
+// checked against the same schemas the server uses with WithRequestSchema and
+// WithResponseSchema, in which case mismatches are returned as a *SchemaError.
+func Post(ctx context.Context, url string, body interface{}, out interface{}, opts ...Option) error {
+	c := config{client: http.DefaultClient}
+	for _, opt := range opts {
+		opt(&c)
+	}
+
+	reqBody, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("jsonbodyclient: failed to encode request body: %w", err)
+	}
+
+	if err := validate(c.requestSchema, reqBody, false); err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(reqBody))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if err := CheckResponse(resp); err != nil {
+		return err
+	}
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("jsonbodyclient: failed to read response body: %w", err)
+	}
+
+	if len(bytes.TrimSpace(respBody)) == 0 {
+		return nil
+	}
+
+	if err := validate(c.responseSchema, respBody, true); err != nil {
+		return err
+	}
+
+	if out == nil {
+		return nil
+	}
+
+	if err := json.Unmarshal(respBody, out); err != nil {
+		return fmt.Errorf("jsonbodyclient: failed to decode response body: %w", err)
+	}
+
+	return nil
+}
+
+// validate checks the encoded body against schema, if there is one.
+func validate(schema *jsonbody.Schema, body []byte, response bool) error {
+	if schema == nil {
+		return nil
+	}
+
+	var decoded interface{}
+	if err := json.Unmarshal(body, &decoded); err != nil {
+		return fmt.Errorf("jsonbodyclient: failed to decode body: %w", err)
+	}
+
+	if errs := schema.Validate(context.Background(), decoded); len(errs) > 0 {
+		return &SchemaError{Response: response, Errors: errs}
+	}
+
+	return nil
+}
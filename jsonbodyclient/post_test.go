@@ -0,0 +1,78 @@
+package jsonbodyclient
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jasonccox/jsonbody"
+	"github.com/stretchr/testify/assert"
+)
+
+const createPostSchema = `{"title": ""}`
+
+func newPostServer(response string) *httptest.Server {
+	return httptest.NewServer(jsonbody.NewMiddleware(createPostSchema)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(response))
+	})))
+}
+
+func TestPostSendsBodyAndDecodesResponse(t *testing.T) {
+	server := newPostServer(`{"id": 7}`)
+	defer server.Close()
+
+	var out struct {
+		ID int `json:"id"`
+	}
+	err := Post(context.Background(), server.URL, map[string]string{"title": "hi"}, &out,
+		WithHTTPClient(server.Client()),
+		WithRequestSchema(createPostSchema),
+		WithResponseSchema(`{"id": 0}`),
+	)
+
+	assert.Nil(t, err)
+	assert.Equal(t, 7, out.ID)
+}
+
+func TestPostChecksRequestBodyBeforeSending(t *testing.T) {
+	called := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	defer server.Close()
+
+	err := Post(context.Background(), server.URL, map[string]int{"title": 1}, nil, WithRequestSchema(createPostSchema))
+
+	var schemaErr *SchemaError
+	assert.True(t, errors.As(err, &schemaErr))
+	assert.False(t, schemaErr.Response)
+	assert.Equal(t, "jsonbodyclient: request body does not match schema: value for key 'title' expected to be of type string", err.Error())
+	assert.False(t, called)
+}
+
+func TestPostChecksResponseBody(t *testing.T) {
+	server := newPostServer(`{"id": "7"}`)
+	defer server.Close()
+
+	err := Post(context.Background(), server.URL, map[string]string{"title": "hi"}, nil, WithResponseSchema(`{"id": 0}`))
+
+	var schemaErr *SchemaError
+	assert.True(t, errors.As(err, &schemaErr))
+	assert.True(t, schemaErr.Response)
+}
+
+func TestPostReturnsResponseError(t *testing.T) {
+	server := newPostServer(`{}`)
+	defer server.Close()
+
+	err := Post(context.Background(), server.URL, map[string]string{}, nil)
+
+	var respErr *ResponseError
+	assert.True(t, errors.As(err, &respErr))
+	assert.Equal(t, 400, respErr.StatusCode)
+	assert.Equal(t, []Error{{Message: "expected key 'title' missing"}}, respErr.Errors)
+}
@@ -0,0 +1,459 @@
+// Package jsonbodytest provides helpers for testing handlers that use the
+// jsonbody middleware.
+package jsonbodytest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// OpenAPI is a parsed OpenAPI 3 document (in JSON), against whose response
+// schemas recorded handler responses can be verified. This closes the loop on
+// response validation: WriteValidated checks the rules in Go struct tags, while
+// OpenAPI checks what was actually sent against the published contract.
+//
+// The supported schema keywords are type, nullable, properties, required,
+// additionalProperties (as a boolean or a schema), items, enum, minimum,
+// maximum, minLength, maxLength, minItems, maxItems, allOf, anyOf, oneOf, and
+// local $refs like "#/components/schemas/Post".
+type OpenAPI struct {
+	doc   map[string]interface{}
+	paths []openAPIPath
+}
+
+type openAPIPath struct {
+	template string
+	segments []string
+	item     map[string]interface{}
+}
+
+// Mismatch describes a part of a response that doesn't match the response
+// schema in the OpenAPI document.
+type Mismatch struct {
+	// Pointer is the JSON Pointer (RFC 6901) to the mismatched value in the
+	// response body, e.g. "/author/tags/0", or "" for the whole body.
+	Pointer string
+
+	// Message describes the mismatch.
+	Message string
+}
+
+func (m Mismatch) String() string {
+	if m.Pointer == "" {
+		return "(body): " + m.Message
+	}
+
+	return m.Pointer + ": " + m.Message
+}
+
+// LoadOpenAPI parses an OpenAPI 3 document in JSON.
+func LoadOpenAPI(docJSON []byte) (*OpenAPI, error) {
+	var doc map[string]interface{}
+	if err := json.Unmarshal(docJSON, &doc); err != nil {
+		return nil, fmt.Errorf("jsonbodytest: failed to decode OpenAPI document: %v", err)
+	}
+
+	paths, _ := doc["paths"].(map[string]interface{})
+	o := &OpenAPI{doc: doc}
+	for template, item := range paths {
+		itemObj, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("jsonbodytest: path item for '%v' must be an object", template)
+		}
+
+		o.paths = append(o.paths, openAPIPath{template: template, segments: splitPath(template), item: itemObj})
+	}
+
+	// prefer the templates with the most literal segments, like the middleware's routes
+	sort.Slice(o.paths, func(i, j int) bool {
+		return literalSegments(o.paths[i].segments) > literalSegments(o.paths[j].segments)
+	})
+
+	return o, nil
+}
+
+// Verify checks the response recorded in rec for a request with the given method
+// and path against the matching response schema in the document. It returns an
+// error if the document doesn't describe the response at all, e.g. because the
+// path, method, or status code is missing from it.
+func (o *OpenAPI) Verify(method string, path string, rec *httptest.ResponseRecorder) ([]Mismatch, error) {
+	operation, err := o.operation(method, path)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := o.response(operation, rec.Code)
+	if err != nil {
+		return nil, err
+	}
+
+	content, _ := response["content"].(map[string]interface{})
+	if len(content) == 0 {
+		if rec.Body.Len() > 0 {
+			return []Mismatch{{Message: "the response has a body, but the document describes none"}}, nil
+		}
+		return []Mismatch{}, nil
+	}
+
+	contentType := rec.Header().Get("Content-Type")
+	if i := strings.Index(contentType, ";"); i >= 0 {
+		contentType = contentType[:i]
+	}
+	contentType = strings.TrimSpace(contentType)
+
+	media, ok := content[contentType].(map[string]interface{})
+	if !ok {
+		return []Mismatch{{Message: fmt.Sprintf("content type '%v' is not described by the document", contentType)}}, nil
+	}
+
+	var body interface{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		return []Mismatch{{Message: "the response body is not valid JSON"}}, nil
+	}
+
+	schema, ok := media["schema"]
+	if !ok {
+		return []Mismatch{}, nil
+	}
+
+	v := verifier{doc: o.doc}
+	return v.verify("", schema, body), nil
+}
+
+// Check is like Verify, but reports the mismatches (or the error) as failures of
+// t. It returns true if there were none.
+func (o *OpenAPI) Check(t testing.TB, req *http.Request, rec *httptest.ResponseRecorder) bool {
+	t.Helper()
+
+	mismatches, err := o.Verify(req.Method, req.URL.Path, rec)
+	if err != nil {
+		t.Errorf("%v %v: %v", req.Method, req.URL.Path, err)
+		return false
+	}
+
+	for _, m := range mismatches {
+		t.Errorf("%v %v: response %v does not match the OpenAPI document: %v", req.Method, req.URL.Path, rec.Code, m)
+	}
+
+	return len(mismatches) == 0
+}
+
+// Replay sends each request to handler and checks its response with Check.
+func (o *OpenAPI) Replay(t testing.TB, handler http.Handler, requests ...*http.Request) bool {
+	t.Helper()
+
+	ok := true
+	for _, req := range requests {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if !o.Check(t, req, rec) {
+			ok = false
+		}
+	}
+
+	return ok
+}
+
+func (o *OpenAPI) operation(method string, path string) (map[string]interface{}, error) {
+	segments := splitPath(path)
+	for _, p := range o.paths {
+		if !matchSegments(p.segments, segments) {
+			continue
+		}
+
+		operation, ok := p.item[strings.ToLower(method)].(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("method %v is not described for path '%v'", method, p.template)
+		}
+
+		return operation, nil
+	}
+
+	return nil, fmt.Errorf("path '%v' is not described by the document", path)
+}
+
+func (o *OpenAPI) response(operation map[string]interface{}, status int) (map[string]interface{}, error) {
+	responses, _ := operation["responses"].(map[string]interface{})
+	code := strconv.Itoa(status)
+	for _, key := range []string{code, code[:1] + "XX", code[:1] + "xx", "default"} {
+		if response, ok := responses[key].(map[string]interface{}); ok {
+			resolved, err := resolveRef(o.doc, response)
+			if err != nil {
+				return nil, err
+			}
+
+			return resolved, nil
+		}
+	}
+
+	return nil, fmt.Errorf("status %v is not described by the document", status)
+}
+
+// verifier checks values against the schemas in an OpenAPI document.
+type verifier struct {
+	doc map[string]interface{}
+}
+
+func (v verifier) verify(pointer string, schemaVal interface{}, value interface{}) []Mismatch {
+	schema, ok := schemaVal.(map[string]interface{})
+	if !ok {
+		return []Mismatch{} // e.g. the boolean schema true
+	}
+
+	schema, err := resolveRef(v.doc, schema)
+	if err != nil {
+		return []Mismatch{{Pointer: pointer, Message: err.Error()}}
+	}
+
+	mismatches := make([]Mismatch, 0)
+	fail := func(format string, args ...interface{}) {
+		mismatches = append(mismatches, Mismatch{Pointer: pointer, Message: fmt.Sprintf(format, args...)})
+	}
+
+	for _, part := range asSlice(schema["allOf"]) {
+		mismatches = append(mismatches, v.verify(pointer, part, value)...)
+	}
+
+	for _, keyword := range []string{"anyOf", "oneOf"} {
+		parts := asSlice(schema[keyword])
+		if len(parts) == 0 {
+			continue
+		}
+
+		matched := 0
+		for _, part := range parts {
+			if len(v.verify(pointer, part, value)) == 0 {
+				matched++
+			}
+		}
+
+		if matched == 0 || (keyword == "oneOf" && matched > 1) {
+			fail("value must match %v of the schemas in %v, but matches %v", map[string]string{"anyOf": "at least one", "oneOf": "exactly one"}[keyword], keyword, matched)
+		}
+	}
+
+	if value == nil {
+		if typ, _ := schema["type"].(string); typ != "" && typ != "null" && schema["nullable"] != true {
+			fail("value must be of type %v, not null", typ)
+		}
+		return mismatches
+	}
+
+	if enum, ok := schema["enum"].([]interface{}); ok && !containsJSON(enum, value) {
+		fail("value must be one of %v", encode(enum))
+	}
+
+	typ, _ := schema["type"].(string)
+	if typ != "" && !hasType(value, typ) {
+		fail("value must be of type %v, not %v", typ, typeOf(value))
+		return mismatches
+	}
+
+	switch value := value.(type) {
+	case map[string]interface{}:
+		mismatches = append(mismatches, v.verifyObject(pointer, schema, value)...)
+	case []interface{}:
+		if n, ok := schema["minItems"].(float64); ok && float64(len(value)) < n {
+			fail("array must have at least %v items", n)
+		}
+		if n, ok := schema["maxItems"].(float64); ok && float64(len(value)) > n {
+			fail("array must have at most %v items", n)
+		}
+		if items, ok := schema["items"]; ok {
+			for i, elem := range value {
+				mismatches = append(mismatches, v.verify(pointer+"/"+strconv.Itoa(i), items, elem)...)
+			}
+		}
+	case string:
+		length := float64(len([]rune(value)))
+		if n, ok := schema["minLength"].(float64); ok && length < n {
+			fail("string must be at least %v characters long", n)
+		}
+		if n, ok := schema["maxLength"].(float64); ok && length > n {
+			fail("string must be at most %v characters long", n)
+		}
+	case float64:
+		if n, ok := schema["minimum"].(float64); ok && value < n {
+			fail("number must be at least %v", n)
+		}
+		if n, ok := schema["maximum"].(float64); ok && value > n {
+			fail("number must be at most %v", n)
+		}
+	}
+
+	return mismatches
+}
+
+func (v verifier) verifyObject(pointer string, schema map[string]interface{}, value map[string]interface{}) []Mismatch {
+	mismatches := make([]Mismatch, 0)
+	properties, _ := schema["properties"].(map[string]interface{})
+
+	for _, key := range asSlice(schema["required"]) {
+		name, _ := key.(string)
+		if _, ok := value[name]; !ok {
+			mismatches = append(mismatches, Mismatch{Pointer: pointer + "/" + escapePointer(name), Message: "required property is missing"})
+		}
+	}
+
+	keys := make([]string, 0, len(value))
+	for k := range value {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		child := pointer + "/" + escapePointer(k)
+		if propSchema, ok := properties[k]; ok {
+			mismatches = append(mismatches, v.verify(child, propSchema, value[k])...)
+			continue
+		}
+
+		switch additional := schema["additionalProperties"].(type) {
+		case bool:
+			if !additional {
+				mismatches = append(mismatches, Mismatch{Pointer: child, Message: "property is not allowed"})
+			}
+		case map[string]interface{}:
+			mismatches = append(mismatches, v.verify(child, additional, value[k])...)
+		}
+	}
+
+	return mismatches
+}
+
+// resolveRef follows the local $ref in schema, if there is one.
+func resolveRef(doc map[string]interface{}, schema map[string]interface{}) (map[string]interface{}, error) {
+	for depth := 0; depth < 32; depth++ {
+		ref, ok := schema["$ref"].(string)
+		if !ok {
+			return schema, nil
+		}
+
+		if !strings.HasPrefix(ref, "#/") {
+			return nil, fmt.Errorf("only local references are supported, not '%v'", ref)
+		}
+
+		var target interface{} = doc
+		for _, token := range strings.Split(ref[2:], "/") {
+			obj, ok := target.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("reference '%v' cannot be resolved", ref)
+			}
+			target, ok = obj[unescapePointer(token)]
+			if !ok {
+				return nil, fmt.Errorf("reference '%v' cannot be resolved", ref)
+			}
+		}
+
+		schema, ok = target.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("reference '%v' does not refer to an object", ref)
+		}
+	}
+
+	return nil, errors.New("too many nested references")
+}
+
+func hasType(value interface{}, typ string) bool {
+	switch typ {
+	case "integer":
+		n, ok := value.(float64)
+		return ok && n == math.Trunc(n)
+	default:
+		return typeOf(value) == typ
+	}
+}
+
+func typeOf(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	default:
+		return "object"
+	}
+}
+
+func containsJSON(values []interface{}, value interface{}) bool {
+	encoded := encode(value)
+	for _, v := range values {
+		if encode(v) == encoded {
+			return true
+		}
+	}
+
+	return false
+}
+
+func encode(v interface{}) string {
+	b, _ := json.Marshal(v)
+	return string(b)
+}
+
+func asSlice(v interface{}) []interface{} {
+	s, _ := v.([]interface{})
+	return s
+}
+
+func escapePointer(token string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(token)
+}
+
+func unescapePointer(token string) string {
+	return strings.NewReplacer("~1", "/", "~0", "~").Replace(token)
+}
+
+func splitPath(path string) []string {
+	return strings.Split(strings.Trim(path, "/"), "/")
+}
+
+func literalSegments(segments []string) int {
+	n := 0
+	for _, seg := range segments {
+		if !isParam(seg) {
+			n++
+		}
+	}
+
+	return n
+}
+
+func isParam(segment string) bool {
+	return strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}")
+}
+
+func matchSegments(pattern []string, path []string) bool {
+	if len(pattern) != len(path) {
+		return false
+	}
+
+	for i, seg := range pattern {
+		if isParam(seg) {
+			if path[i] == "" {
+				return false
+			}
+			continue
+		}
+
+		if seg != path[i] {
+			return false
+		}
+	}
+
+	return true
+}
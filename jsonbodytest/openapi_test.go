@@ -0,0 +1,134 @@
+package jsonbodytest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jasonccox/jsonbody"
+	"github.com/stretchr/testify/assert"
+)
+
+const testDoc = `{
+	"openapi": "3.0.3",
+	"paths": {
+		"/posts/{id}": {
+			"get": {
+				"responses": {
+					"200": {
+						"content": {
+							"application/json": {"schema": {"$ref": "#/components/schemas/Post"}}
+						}
+					},
+					"4XX": {"$ref": "#/components/responses/Error"}
+				}
+			}
+		},
+		"/posts/latest": {
+			"get": {"responses": {"204": {"description": "no content"}}}
+		}
+	},
+	"components": {
+		"schemas": {
+			"Post": {
+				"type": "object",
+				"required": ["id", "title"],
+				"additionalProperties": false,
+				"properties": {
+					"id": {"type": "integer", "minimum": 1},
+					"title": {"type": "string", "maxLength": 5},
+					"status": {"type": "string", "enum": ["draft", "published"]},
+					"tags": {"type": "array", "items": {"type": "string"}},
+					"editor": {"type": "string", "nullable": true}
+				}
+			}
+		},
+		"responses": {
+			"Error": {
+				"content": {
+					"application/json": {
+						"schema": {"type": "object", "required": ["errors"], "properties": {"errors": {"type": "array", "items": {"type": "string"}}}}
+					}
+				}
+			}
+		}
+	}
+}`
+
+func record(status int, body interface{}) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	w := jsonbody.Writer{ResponseWriter: rec}
+	w.WriteJSON(status, body)
+	return rec
+}
+
+func TestVerifyReportsMismatchesWithPointers(t *testing.T) {
+	doc, err := LoadOpenAPI([]byte(testDoc))
+	assert.Nil(t, err)
+
+	mismatches, err := doc.Verify(http.MethodGet, "/posts/1", record(200, map[string]interface{}{
+		"id":     1.5,
+		"status": "deleted",
+		"tags":   []interface{}{"a", 2},
+		"a/b":    true,
+		"editor": nil,
+	}))
+	assert.Nil(t, err)
+
+	msgs := make([]string, len(mismatches))
+	for i, m := range mismatches {
+		msgs[i] = m.String()
+	}
+	assert.Equal(t, []string{
+		"/title: required property is missing",
+		"/a~1b: property is not allowed",
+		"/id: value must be of type integer, not number",
+		`/status: value must be one of ["draft","published"]`,
+		"/tags/1: value must be of type string, not number",
+	}, msgs)
+}
+
+func TestVerifyAcceptsMatchingResponses(t *testing.T) {
+	doc, err := LoadOpenAPI([]byte(testDoc))
+	assert.Nil(t, err)
+
+	mismatches, err := doc.Verify(http.MethodGet, "/posts/1", record(200, map[string]interface{}{"id": 1, "title": "hi"}))
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(mismatches))
+
+	mismatches, err = doc.Verify(http.MethodGet, "/posts/1", record(404, map[string][]string{"errors": {"not found"}}))
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(mismatches))
+
+	rec := httptest.NewRecorder()
+	rec.WriteHeader(204)
+	mismatches, err = doc.Verify(http.MethodGet, "/posts/latest", rec)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(mismatches))
+}
+
+func TestVerifyReturnsErrorForUndescribedResponses(t *testing.T) {
+	doc, err := LoadOpenAPI([]byte(testDoc))
+	assert.Nil(t, err)
+
+	_, err = doc.Verify(http.MethodGet, "/users", record(200, nil))
+	assert.NotNil(t, err)
+
+	_, err = doc.Verify(http.MethodDelete, "/posts/1", record(200, nil))
+	assert.NotNil(t, err)
+
+	_, err = doc.Verify(http.MethodGet, "/posts/1", record(500, nil))
+	assert.NotNil(t, err)
+}
+
+func TestReplayChecksHandlerResponses(t *testing.T) {
+	doc, err := LoadOpenAPI([]byte(testDoc))
+	assert.Nil(t, err)
+
+	handler := jsonbody.NewMiddleware("")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writer := w.(jsonbody.Writer)
+		writer.WriteJSON(200, map[string]interface{}{"id": 7, "title": "hello"})
+	}))
+
+	assert.True(t, doc.Replay(t, handler, httptest.NewRequest(http.MethodGet, "/posts/7", nil)))
+}
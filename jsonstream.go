@@ -0,0 +1,178 @@
+package jsonbody
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"reflect"
+	"time"
+)
+
+// WriteJSONStream sends body as JSON in the same way as WriteJSON, but encodes
+// it straight to the response rather than building the whole response in
+// memory first. Slices and arrays are encoded one element at a time, so a
+// multi-megabyte list of results only needs memory for the list itself and one
+// encoded element. Other values are encoded with a json.Encoder.
+//
+// The status code and headers are sent before the body is encoded, so encoding
+// errors partway through can't be reported to the client; they are logged and
+// returned. The StreamOptions' Compress and FlushInterval settings apply, but
+// data is only flushed to the client before the end of the body if
+// FlushInterval is positive. If the response has to be transformed after it is
+// encoded (e.g. because of WithStringNumbers, WithSortedKeys, field selection,
+// indentation, or signing), WriteJSONStream is the same as WriteJSON.
+func (w *Writer) WriteJSONStream(statusCode int, body interface{}) error {
+	if w.written {
+		return errors.New("method has already been called once and cannot be called again")
+	}
+
+	selectFields := w.fields != nil && statusCode >= 200 && statusCode < 300
+	pretty := w.pretty || (w.config != nil && w.config.pretty)
+	if w.config.transforms() || selectFields || pretty || (w.config != nil && w.config.signer != nil) {
+		return w.WriteJSON(statusCode, body)
+	}
+
+	if w.config.convertsTimes() {
+		generic, err := toGeneric(reflect.ValueOf(body), w.config.timeFormat, w.config.durationFormat)
+		if err != nil {
+			log.Println(fmt.Errorf("jsonbody: failed to encode body: %v", err))
+			return errors.New("encoding the response body as JSON failed")
+		}
+		body = generic
+	}
+
+	w.writes.beginJSON()
+	defer w.writes.endJSON()
+
+	var opts StreamOptions
+	if w.config != nil {
+		opts = w.config.stream
+	}
+
+	w.setDefaultHeaders()
+	w.Header().Set("Content-Type", w.jsonContentType())
+	w.Header().Del("Content-Length")
+
+	var dst io.Writer = w.ResponseWriter
+	var gz *gzip.Writer
+	if opts.Compress {
+		w.Header().Add("Vary", "Accept-Encoding")
+		if acceptsGzip(w.acceptEncoding) {
+			w.Header().Set("Content-Encoding", "gzip")
+			gz = gzip.NewWriter(w.ResponseWriter)
+			dst = gz
+		}
+	}
+
+	w.WriteHeader(statusCode)
+	w.written = true
+
+	buf := bufio.NewWriter(dst)
+	rc := http.NewResponseController(w.ResponseWriter)
+	flush := func() error {
+		if err := buf.Flush(); err != nil {
+			return err
+		}
+		if gz != nil {
+			if err := gz.Flush(); err != nil {
+				return err
+			}
+		}
+		if err := rc.Flush(); err != nil && !errors.Is(err, http.ErrNotSupported) {
+			return err
+		}
+		return nil
+	}
+
+	lastFlush := time.Now()
+	afterElement := func() error {
+		if opts.FlushInterval <= 0 || time.Since(lastFlush) < opts.FlushInterval {
+			return nil
+		}
+		lastFlush = time.Now()
+		return flush()
+	}
+
+	err := encodeStream(buf, body, afterElement)
+	if err == nil {
+		err = buf.Flush()
+	}
+	if err == nil && gz != nil {
+		err = gz.Close()
+	}
+	if err != nil {
+		log.Println(fmt.Errorf("jsonbody: failed to stream body: %v", err))
+		return errors.New("streaming the response body failed")
+	}
+
+	return nil
+}
+
+// encodeStream writes body to out as JSON, writing the elements of slices and
+// arrays one at a time and calling afterElement after each one.
+func encodeStream(out io.Writer, body interface{}, afterElement func() error) error {
+	enc := json.NewEncoder(out)
+
+	val := reflect.ValueOf(body)
+	if !streamsElements(val) {
+		return enc.Encode(body)
+	}
+
+	if _, err := io.WriteString(out, "["); err != nil {
+		return err
+	}
+
+	for i := 0; i < val.Len(); i++ {
+		if i > 0 {
+			if _, err := io.WriteString(out, ","); err != nil {
+				return err
+			}
+		}
+
+		// Encode adds a newline after each element, which is insignificant
+		// whitespace in JSON.
+		if err := enc.Encode(val.Index(i).Interface()); err != nil {
+			return err
+		}
+
+		if err := afterElement(); err != nil {
+			return err
+		}
+	}
+
+	_, err := io.WriteString(out, "]")
+	return err
+}
+
+// streamsElements reports whether val is a slice or array that encoding/json
+// would encode as a JSON array of its elements, so that they can be encoded one
+// at a time.
+func streamsElements(val reflect.Value) bool {
+	switch val.Kind() {
+	case reflect.Slice:
+		if val.IsNil() {
+			return false // encoded as null
+		}
+	case reflect.Array:
+	default:
+		return false
+	}
+
+	typ := val.Type()
+	if typ.Elem().Kind() == reflect.Uint8 && val.Kind() == reflect.Slice {
+		return false // encoded as a base64 string
+	}
+
+	for _, iface := range []reflect.Type{jsonMarshalerType, textMarshalerType} {
+		if typ.Implements(iface) || reflect.PointerTo(typ).Implements(iface) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,97 @@
+package jsonbody
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type upperList []string
+
+func (l upperList) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]int{"count": len(l)})
+}
+
+func TestWriteJSONStreamEncodesLikeWriteJSON(t *testing.T) {
+	for _, body := range []interface{}{
+		[]map[string]int{{"a": 1}, {"b": 2}},
+		[2]string{"x", "y"},
+		[]int{},
+		[]int(nil),
+		[]byte("hi"),
+		upperList{"a", "b"},
+		map[string]interface{}{"items": []int{1, 2}},
+		"text",
+	} {
+		recorder := httptest.NewRecorder()
+		w := Writer{ResponseWriter: recorder}
+
+		assert.Nil(t, w.WriteJSONStream(http.StatusCreated, body))
+		assert.Equal(t, http.StatusCreated, recorder.Code)
+		assert.Equal(t, "application/json", recorder.Header().Get("Content-Type"))
+
+		expected, _ := json.Marshal(body)
+		assert.JSONEq(t, string(expected), recorder.Body.String(), "%v", body)
+	}
+}
+
+func TestWriteJSONStreamCanOnlyBeCalledOnce(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	w := Writer{ResponseWriter: recorder}
+
+	assert.Nil(t, w.WriteJSONStream(http.StatusOK, []int{1}))
+	assert.NotNil(t, w.WriteJSONStream(http.StatusOK, []int{2}))
+	assert.NotNil(t, w.WriteJSON(http.StatusOK, []int{2}))
+}
+
+func TestWriteJSONStreamCompressesAndFlushes(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	w := Writer{
+		ResponseWriter: recorder,
+		config:         &writerConfig{stream: StreamOptions{Compress: true, FlushInterval: time.Nanosecond}},
+		acceptEncoding: "gzip",
+	}
+
+	assert.Nil(t, w.WriteJSONStream(http.StatusOK, []string{"a", "b"}))
+	assert.True(t, recorder.Flushed)
+	assert.Equal(t, "gzip", recorder.Header().Get("Content-Encoding"))
+
+	gz, err := gzip.NewReader(recorder.Body)
+	assert.Nil(t, err)
+	body, err := io.ReadAll(gz)
+	assert.Nil(t, err)
+	assert.JSONEq(t, `["a", "b"]`, string(body))
+}
+
+func TestWriteJSONStreamDoesNotFlushByDefault(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	w := Writer{ResponseWriter: recorder}
+
+	assert.Nil(t, w.WriteJSONStream(http.StatusOK, []string{"a", "b"}))
+	assert.False(t, recorder.Flushed)
+}
+
+func TestWriteJSONStreamAppliesTransforms(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	w := Writer{
+		ResponseWriter: recorder,
+		config:         &writerConfig{stringNumbers: map[string]bool{"[].id": true}},
+	}
+
+	assert.Nil(t, w.WriteJSONStream(http.StatusOK, []map[string]int{{"id": 1}}))
+	assert.Equal(t, `[{"id":"1"}]`, recorder.Body.String())
+}
+
+func TestWriteJSONStreamReturnsEncodingErr(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	w := Writer{ResponseWriter: recorder}
+
+	assert.NotNil(t, w.WriteJSONStream(http.StatusOK, []interface{}{1, make(chan int)}))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+}
@@ -0,0 +1,98 @@
+package jsonbody
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// LintWarning describes a suspicious construct in a schema found by LintSchema.
+type LintWarning struct {
+	// Key is the path of the construct in the schema, e.g. "author.tags", with
+	// "[]" denoting the elements of an array, or "" for the top level.
+	Key string
+
+	Message string
+}
+
+func (w LintWarning) String() string {
+	if w.Key == "" {
+		return "(schema): " + w.Message
+	}
+
+	return w.Key + ": " + w.Message
+}
+
+// LintSchema returns warnings about constructs in the schema that are valid but
+// probably mistakes, sorted by key, to catch schema authoring bugs early (e.g.
+// in a test that lints every schema of a service). It warns about:
+//
+//   - optional markers that have no effect because the key is also declared
+//     without one, or that are doubled, like "??name"
+//   - optional markers on keys starting with "$", which are validated as
+//     ordinary body keys rather than treated as schema keywords
+//   - arrays with more than one element, since only the first is used
+//   - empty objects, which accept any contents, where an object with typed keys
+//     was probably intended
+func LintSchema(schema *Schema) []LintWarning {
+	warnings := make([]LintWarning, 0)
+	if schema == nil {
+		return warnings
+	}
+
+	for _, body := range schema.values() {
+		lintValue(&warnings, "", body)
+	}
+	if schema.query != nil {
+		lintValue(&warnings, schemaQueryKey, schema.query)
+	}
+	for _, part := range schema.allOf {
+		if part.query != nil {
+			lintValue(&warnings, schemaQueryKey, part.query)
+		}
+	}
+
+	sort.SliceStable(warnings, func(i, j int) bool {
+		return warnings[i].Key < warnings[j].Key
+	})
+
+	return warnings
+}
+
+func lintValue(warnings *[]LintWarning, key string, expected interface{}) {
+	switch expected := expected.(type) {
+	case []interface{}:
+		if len(expected) > 1 {
+			*warnings = append(*warnings, LintWarning{Key: key, Message: fmt.Sprintf("array has %v elements, but only the first is used to validate elements", len(expected))})
+		}
+		if len(expected) > 0 {
+			lintValue(warnings, key+"[]", expected[0])
+		}
+	case map[string]interface{}:
+		if len(expected) == 0 {
+			if key != "" {
+				*warnings = append(*warnings, LintWarning{Key: key, Message: "empty object accepts any contents; add keys to validate them"})
+			}
+			return
+		}
+
+		for k, val := range expected {
+			name := strings.TrimPrefix(k, "?")
+			newKey := joinKey(key, name)
+			if name != k {
+				switch {
+				case strings.HasPrefix(name, "?"):
+					*warnings = append(*warnings, LintWarning{Key: newKey, Message: fmt.Sprintf("key '%v' has more than one optional marker; the rest are part of its name", k)})
+				case strings.HasPrefix(name, "$"):
+					*warnings = append(*warnings, LintWarning{Key: newKey, Message: fmt.Sprintf("key '%v' is validated as an ordinary body key, not a schema keyword", k)})
+				}
+
+				if _, ok := expected[name]; ok {
+					*warnings = append(*warnings, LintWarning{Key: newKey, Message: fmt.Sprintf("optional marker on '%v' has no effect because the key is also declared as required", k)})
+				}
+			}
+
+			lintValue(warnings, newKey, val)
+		}
+	}
+}
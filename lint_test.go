@@ -0,0 +1,39 @@
+package jsonbody
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLintSchemaFindsSuspiciousConstructs(t *testing.T) {
+	schema := MustParseSchema(`{
+		"title": "",
+		"?title": "",
+		"??nickname": "",
+		"author": {"?$ref": ""},
+		"tags": ["", 0],
+		"meta": {},
+		"age": {"$type": "object"},
+		"$query": {"page": [0, ""]}
+	}`)
+
+	var msgs []string
+	for _, w := range LintSchema(schema) {
+		msgs = append(msgs, w.String())
+	}
+
+	assert.Equal(t, []string{
+		"$query.page: array has 2 elements, but only the first is used to validate elements",
+		"?nickname: key '??nickname' has more than one optional marker; the rest are part of its name",
+		"author.$ref: key '?$ref' is validated as an ordinary body key, not a schema keyword",
+		"meta: empty object accepts any contents; add keys to validate them",
+		"tags: array has 2 elements, but only the first is used to validate elements",
+		"title: optional marker on '?title' has no effect because the key is also declared as required",
+	}, msgs)
+}
+
+func TestLintSchemaReturnsNoWarningsForCleanSchema(t *testing.T) {
+	assert.Equal(t, []LintWarning{}, LintSchema(MustParseSchema(`{"title": "", "?tags": [""], "author": {"name": ""}}`)))
+	assert.Equal(t, []LintWarning{}, LintSchema(nil))
+}
@@ -0,0 +1,127 @@
+package jsonbody
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+)
+
+// WriteWhenReady holds the response of a long-poll request until data is
+// available. It calls wait, which should block until there is data to send or
+// ctx is done, and sends the data it returns in the same way as WriteJSON. If
+// ctx is done first (e.g. because it has a deadline or the client went away), or
+// if wait returns nil data, a 204 No Content response is sent instead. If wait
+// returns an error, nothing more is sent and the error is returned, allowing the
+// handler to send an error response.
+//
+// If the StreamOptions' KeepAliveInterval is positive, a newline (insignificant
+// whitespace before the JSON body) is sent that often while waiting, which
+// keeps proxies from closing the idle connection. The status code and headers
+// are sent along with the first newline, so once one has been sent, a timeout
+// or nil data results in a JSON null body with statusCode, and errors from wait
+// can no longer be reported to the client.
+func (w *Writer) WriteWhenReady(ctx context.Context, statusCode int, wait func(ctx context.Context) (interface{}, error)) error {
+	if w.written {
+		return errors.New("method has already been called once and cannot be called again")
+	}
+
+	type result struct {
+		body interface{}
+		err  error
+	}
+	done := make(chan result, 1) // buffered so that wait can return after a timeout
+	go func() {
+		body, err := wait(ctx)
+		done <- result{body, err}
+	}()
+
+	var keepAlive <-chan time.Time
+	if w.config != nil && w.config.stream.KeepAliveInterval > 0 {
+		ticker := time.NewTicker(w.config.stream.KeepAliveInterval)
+		defer ticker.Stop()
+		keepAlive = ticker.C
+	}
+
+	started := false
+	for {
+		var body interface{}
+		select {
+		case res := <-done:
+			if res.err != nil {
+				return res.err
+			}
+			body = res.body
+		case <-ctx.Done():
+		case <-keepAlive:
+			if err := w.sendKeepAlive(statusCode, !started); err != nil {
+				return err
+			}
+			started = true
+			continue
+		}
+
+		switch {
+		case started:
+			return w.finishKeepAlive(statusCode, body)
+		case body == nil:
+			w.setDefaultHeaders()
+			w.WriteHeader(http.StatusNoContent)
+			w.written = true
+			return nil
+		default:
+			return w.WriteJSON(statusCode, body)
+		}
+	}
+}
+
+// sendKeepAlive sends a newline to keep a long-poll connection open, first
+// sending the status code and headers if first is set.
+func (w *Writer) sendKeepAlive(statusCode int, first bool) error {
+	w.writes.beginJSON()
+	defer w.writes.endJSON()
+
+	if first {
+		w.setDefaultHeaders()
+		w.Header().Set("Content-Type", w.jsonContentType())
+		w.Header().Del("Content-Length")
+		w.WriteHeader(statusCode)
+		w.written = true
+	}
+
+	if _, err := w.Write([]byte("\n")); err != nil {
+		log.Println(fmt.Errorf("jsonbody: failed to write keep-alive: %v", err))
+		return errors.New("sending the keep-alive failed")
+	}
+
+	if err := http.NewResponseController(w.ResponseWriter).Flush(); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return err
+	}
+
+	return nil
+}
+
+// finishKeepAlive sends body after the status code and headers have been sent
+// by sendKeepAlive.
+func (w *Writer) finishKeepAlive(statusCode int, body interface{}) error {
+	w.writes.beginJSON()
+	defer w.writes.endJSON()
+
+	bytes, err := w.encode(statusCode, body)
+	if err == nil {
+		bytes, err = w.indent(bytes)
+	}
+	if err != nil {
+		log.Println(fmt.Errorf("jsonbody: failed to encode body: %v", err))
+		return errors.New("encoding the response body as JSON failed")
+	}
+
+	if _, err := w.Write(bytes); err != nil {
+		log.Println(fmt.Errorf("jsonbody: failed to write body: %v", err))
+		return errors.New("sending the response body failed")
+	}
+
+	return nil
+}
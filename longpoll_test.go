@@ -0,0 +1,68 @@
+package jsonbody
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteWhenReadySendsDataWhenAvailable(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	w := Writer{ResponseWriter: recorder}
+
+	err := w.WriteWhenReady(context.Background(), http.StatusOK, func(ctx context.Context) (interface{}, error) {
+		return map[string]int{"seq": 1}, nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, `{"seq":1}`, recorder.Body.String())
+}
+
+func TestWriteWhenReadySendsNoContentOnTimeout(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	w := Writer{ResponseWriter: recorder}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	err := w.WriteWhenReady(ctx, http.StatusOK, func(ctx context.Context) (interface{}, error) {
+		<-ctx.Done()
+		return nil, nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusNoContent, recorder.Code)
+	assert.Empty(t, recorder.Body.String())
+	assert.True(t, w.written)
+}
+
+func TestWriteWhenReadyReturnsWaitErr(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	w := Writer{ResponseWriter: recorder}
+
+	expected := errors.New("broken")
+	err := w.WriteWhenReady(context.Background(), http.StatusOK, func(ctx context.Context) (interface{}, error) {
+		return nil, expected
+	})
+	assert.Equal(t, expected, err)
+	assert.False(t, w.written)
+}
+
+func TestWriteWhenReadySendsKeepAlives(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	w := Writer{ResponseWriter: recorder, config: &writerConfig{stream: StreamOptions{KeepAliveInterval: 5 * time.Millisecond}}}
+
+	err := w.WriteWhenReady(context.Background(), http.StatusOK, func(ctx context.Context) (interface{}, error) {
+		time.Sleep(30 * time.Millisecond)
+		return []int{1}, nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "application/json", recorder.Header().Get("Content-Type"))
+	assert.Regexp(t, "^\n+\\[1\\]$", recorder.Body.String())
+	assert.True(t, recorder.Flushed)
+}
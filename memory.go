@@ -0,0 +1,75 @@
+package jsonbody
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+)
+
+// MemoryGuard limits the total number of bytes of request bodies buffered in
+// memory at once by the middlewares it is passed to with WithMemoryGuard. This
+// protects the process from running out of memory when many large requests
+// arrive at once. A single MemoryGuard is typically shared by all of a
+// process's middlewares.
+type MemoryGuard struct {
+	limit int64
+	used  int64 // accessed atomically
+}
+
+// NewMemoryGuard creates a MemoryGuard that allows up to limit bytes of request
+// bodies to be buffered at once.
+func NewMemoryGuard(limit int64) *MemoryGuard {
+	return &MemoryGuard{limit: limit}
+}
+
+// InUse returns the number of bytes currently reserved for buffered request
+// bodies, e.g. for reporting as a metric.
+func (g *MemoryGuard) InUse() int64 {
+	return atomic.LoadInt64(&g.used)
+}
+
+// tryAcquire reserves n bytes, returning false if that would exceed the limit.
+func (g *MemoryGuard) tryAcquire(n int64) bool {
+	for {
+		used := atomic.LoadInt64(&g.used)
+		if used+n > g.limit {
+			return false
+		}
+
+		if atomic.CompareAndSwapInt64(&g.used, used, used+n) {
+			return true
+		}
+	}
+}
+
+// release frees n bytes reserved by tryAcquire.
+func (g *MemoryGuard) release(n int64) {
+	atomic.AddInt64(&g.used, -n)
+}
+
+// acquireMemory reserves memory for the body of r with the middleware's
+// MemoryGuard, if it has one. If the body is larger than the guard's whole
+// limit, it can never be reserved, so a 413 error response is sent and false is
+// returned. If the limit would only be exceeded because of other requests, a 503
+// error response is sent and false is returned. Otherwise, the returned function
+// must be called to release the memory once the request has been handled.
+func (m *middleware) acquireMemory(writer *Writer, r *http.Request) (release func(), ok bool) {
+	if m.memoryGuard == nil || r.ContentLength <= 0 {
+		return func() {}, true
+	}
+
+	if r.ContentLength > m.memoryGuard.limit {
+		writer.writeErrors(http.StatusRequestEntityTooLarge, bodyTooLarge(m.memoryGuard.limit))
+		return nil, false
+	}
+
+	if !m.memoryGuard.tryAcquire(r.ContentLength) {
+		writer.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(m.memoryRetryAfter.Seconds()))))
+		writer.writeErrors(http.StatusServiceUnavailable, ValidationError{Code: CodeOverloaded})
+		return nil, false
+	}
+
+	n := r.ContentLength
+	return func() { m.memoryGuard.release(n) }, true
+}
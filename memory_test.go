@@ -0,0 +1,56 @@
+package jsonbody
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryGuardAcquireAndRelease(t *testing.T) {
+	g := NewMemoryGuard(10)
+
+	assert.True(t, g.tryAcquire(6))
+	assert.False(t, g.tryAcquire(5))
+	assert.Equal(t, int64(6), g.InUse())
+
+	g.release(6)
+	assert.True(t, g.tryAcquire(10))
+}
+
+func TestServeHTTPSends503IfMemoryGuardFull(t *testing.T) {
+	guard := NewMemoryGuard(20)
+
+	var inUse int64
+	handler := NewMiddleware("", WithMemoryGuard(guard, 1500*time.Millisecond))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		inUse = guard.InUse()
+	}))
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"a": 1}`)))
+	assert.Equal(t, 200, recorder.Code)
+	assert.Equal(t, int64(8), inUse)
+	assert.Equal(t, int64(0), guard.InUse())
+
+	assert.True(t, guard.tryAcquire(15)) // another request is using most of the memory
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"a": 1}`)))
+	assert.Equal(t, 503, recorder.Code)
+	assert.Equal(t, "2", recorder.Header().Get("Retry-After"))
+	assert.Equal(t, `{"errors":["the server is temporarily overloaded"]}`, recorder.Body.String())
+	guard.release(15)
+}
+
+func TestServeHTTPSends413IfBodyExceedsMemoryGuardLimit(t *testing.T) {
+	guard := NewMemoryGuard(20)
+	handler := NewMiddleware("", WithMemoryGuard(guard, time.Second))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"a": "this body is too long"}`)))
+	assert.Equal(t, 413, recorder.Code)
+	assert.Empty(t, recorder.Header().Get("Retry-After"))
+	assert.Equal(t, int64(0), guard.InUse())
+}
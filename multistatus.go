@@ -0,0 +1,57 @@
+package jsonbody
+
+import "net/http"
+
+// ItemResult is the result of one item of a batch request, sent by
+// Writer.WriteMultiStatus.
+type ItemResult struct {
+	// ID identifies the item, e.g. its index or a client-provided ID. It is
+	// omitted if "".
+	ID string `json:"id,omitempty"`
+
+	// Status is the HTTP status code for the item, e.g. 201 or 400.
+	Status int `json:"status"`
+
+	// Data is the response body for the item, omitted if nil.
+	Data interface{} `json:"data,omitempty"`
+
+	// Errors are the error messages for the item, omitted if empty.
+	Errors []string `json:"errors,omitempty"`
+}
+
+// WriteMultiStatus sends the results of the items of a batch request in the
+// same way as WriteJSON, in the following structure:
+//
+//	{
+//		"results": [
+//			{"id": "1", "status": 201, "data": {...}},
+//			{"id": "2", "status": 400, "errors": ["expected key 'title' missing"]}
+//		]
+//	}
+//
+// The status code of the response is 207 (Multi-Status) if the items' statuses
+// differ. If they are all the same, it is that status instead, so that clients
+// can tell at a glance that the whole batch succeeded or failed. It is 200 if
+// there are no results.
+func (w *Writer) WriteMultiStatus(results []ItemResult) error {
+	if results == nil {
+		results = []ItemResult{}
+	}
+
+	status := http.StatusOK
+	for i, result := range results {
+		if i == 0 {
+			status = result.Status
+		} else if result.Status != status {
+			status = http.StatusMultiStatus
+			break
+		}
+	}
+
+	return w.WriteJSON(status, multiStatusEnvelope{results})
+}
+
+// multiStatusEnvelope is the response body sent by WriteMultiStatus.
+type multiStatusEnvelope struct {
+	Results []ItemResult `json:"results"`
+}
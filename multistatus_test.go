@@ -0,0 +1,43 @@
+package jsonbody
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteMultiStatusSends207IfStatusesDiffer(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	w := Writer{ResponseWriter: recorder}
+
+	err := w.WriteMultiStatus([]ItemResult{
+		{ID: "1", Status: 201, Data: map[string]int{"id": 7}},
+		{ID: "2", Status: 400, Errors: []string{"expected key 'title' missing"}},
+	})
+	assert.Nil(t, err)
+
+	assert.Equal(t, 207, recorder.Code)
+	assert.Equal(t, `{"results":[{"id":"1","status":201,"data":{"id":7}},{"id":"2","status":400,"errors":["expected key 'title' missing"]}]}`, recorder.Body.String())
+}
+
+func TestWriteMultiStatusSendsCommonStatus(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	w := Writer{ResponseWriter: recorder}
+
+	err := w.WriteMultiStatus([]ItemResult{{Status: 201}, {Status: 201}})
+	assert.Nil(t, err)
+
+	assert.Equal(t, 201, recorder.Code)
+}
+
+func TestWriteMultiStatusSends200IfNoResults(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	w := Writer{ResponseWriter: recorder}
+
+	err := w.WriteMultiStatus(nil)
+	assert.Nil(t, err)
+
+	assert.Equal(t, 200, recorder.Code)
+	assert.Equal(t, `{"results":[]}`, recorder.Body.String())
+}
@@ -0,0 +1,850 @@
+package jsonbody
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Option configures the middleware created by NewMiddleware.
+type Option func(*middleware)
+
+// WithErrorOrder sets the order in which validation errors are reported. By
+// default, errors are reported in OrderAlphabetical order.
+func WithErrorOrder(order ErrorOrder) Option {
+	return func(m *middleware) {
+		m.order = order
+	}
+}
+
+// WithStrictKeys causes requests whose bodies contain keys that aren't in the
+// schema to be rejected, like json.Decoder.DisallowUnknownFields, so that typos
+// like "emial" are caught instead of silently ignored. The contents of empty
+// objects and constraint objects in the schema are still unrestricted.
+func WithStrictKeys() Option {
+	return func(m *middleware) {
+		m.strictKeys = true
+	}
+}
+
+// WithCoercion causes strings in request bodies to be accepted where the schema
+// expects numbers or booleans if they can be converted, e.g. "42" or "true",
+// which many clients (like form-to-JSON bridges) send. The converted values
+// replace the strings in the body returned by Reader.JSON, but not in the raw
+// body.
+func WithCoercion() Option {
+	return func(m *middleware) {
+		m.coerce = true
+	}
+}
+
+// WithEnforcementControl lets control switch validation between enforcing,
+// observing, and off for routes while the server is running. See
+// EnforcementControl.
+func WithEnforcementControl(control *EnforcementControl) Option {
+	return func(m *middleware) {
+		m.enforcement = control
+	}
+}
+
+// WithSchemaHeader causes the middleware to send the schema's name (set by its
+// "$schemaName" key) in the X-Schema header of every response.
+func WithSchemaHeader() Option {
+	return func(m *middleware) {
+		m.schemaHeader = true
+	}
+}
+
+// WithStringNumbers causes Writers passed to the handler to encode the numbers
+// at the given paths of response bodies as strings, e.g. 12345678901234567 is
+// sent as "12345678901234567". This avoids precision loss for clients (like
+// JavaScript) that represent all numbers as 64-bit floats. Paths use the same
+// format as the keys in validation errors, except that "[]" refers to every
+// element of an array, e.g. "items[].id".
+//
+// Since the response body must be re-encoded to apply this option, object keys
+// in the response are sorted alphabetically.
+func WithStringNumbers(paths ...string) Option {
+	return func(m *middleware) {
+		if m.writerConfig.stringNumbers == nil {
+			m.writerConfig.stringNumbers = make(map[string]bool)
+		}
+
+		for _, path := range paths {
+			m.writerConfig.stringNumbers[path] = true
+		}
+	}
+}
+
+// WithTimeFormat sets how time.Time values (and pointers to them) are encoded in
+// response bodies written by the Writer, regardless of where they appear in the
+// body. By default, times are encoded as RFC 3339 strings.
+//
+// The rest of the body is encoded as encoding/json would encode it, following
+// the same rules for struct tags, embedded structs, and marshalers.
+func WithTimeFormat(format TimeFormat) Option {
+	return func(m *middleware) {
+		m.writerConfig.timeFormat = format
+	}
+}
+
+// WithDurationFormat sets how time.Duration values are encoded in response
+// bodies written by the Writer, regardless of where they appear in the body. By
+// default, durations are encoded as a number of nanoseconds.
+//
+// The rest of the body is encoded as with WithTimeFormat.
+func WithDurationFormat(format DurationFormat) Option {
+	return func(m *middleware) {
+		m.writerConfig.durationFormat = format
+	}
+}
+
+// WithCanonicalJSON causes Writers passed to the handler to encode response
+// bodies as canonical JSON, as defined by RFC 8785 (the JSON Canonicalization
+// Scheme). Canonical JSON has its object keys sorted and its numbers and strings
+// formatted consistently, so the same value always produces the same bytes. This
+// is useful for responses that clients hash or sign. Canonical responses are
+// never indented, even if WithPrettyJSON or WithPrettyParam is used.
+func WithCanonicalJSON() Option {
+	return func(m *middleware) {
+		m.writerConfig.canonical = true
+	}
+}
+
+// WithSortedKeys causes Writers passed to the handler to encode every object in
+// response bodies with its keys sorted, including objects encoded from structs,
+// whose fields are otherwise written in the order they are declared. This gives
+// responses a stable form that clients can diff or cache. Use an OrderedMap
+// instead to choose the order of the keys in an object.
+func WithSortedKeys() Option {
+	return func(m *middleware) {
+		m.writerConfig.sortKeys = true
+	}
+}
+
+// WithResponseSignature causes Writers passed to the handler to sign the
+// encoded body of each JSON response with signer and send the base64-encoded
+// signature in the given header (e.g. "X-Signature"). Since the signature covers
+// the exact bytes sent, clients can verify it without re-encoding the body.
+func WithResponseSignature(header string, signer Signer) Option {
+	return func(m *middleware) {
+		m.writerConfig.signer = signer
+		m.writerConfig.signatureHeader = header
+	}
+}
+
+// WithCookieSchema causes the middleware to validate the JSON object stored in
+// the named cookie against schemaJSON, which uses the same format as the schema
+// passed to NewMiddleware. The cookie's value may be raw, percent-encoded, or
+// base64-encoded JSON. Errors are reported along with the body's errors, using
+// the cookie's name as the first part of each key. The parsed object is
+// available to the handler through CookieJSON.
+//
+// If name begins with a question mark, the cookie is optional. Like
+// NewMiddleware, WithCookieSchema panics if schemaJSON is invalid. Since cookies
+// must hold JSON objects, it also panics if schemaJSON is an array or scalar
+// schema.
+func WithCookieSchema(name string, schemaJSON string) Option {
+	schema := MustParseSchema(schemaJSON)
+	if schema == nil {
+		schema = &Schema{}
+	}
+	if schema.array != nil || schema.scalar != nil {
+		panic("jsonbody: schema for cookie " + name + " must be an object schema")
+	}
+
+	return func(m *middleware) {
+		m.cookies = append(m.cookies, cookieSchema{
+			name:     strings.TrimPrefix(name, "?"),
+			optional: strings.HasPrefix(name, "?"),
+			schema:   schema,
+		})
+	}
+}
+
+// WithCSRF enables double-submit CSRF protection for browser-facing APIs.
+// Requests with methods other than GET, HEAD, OPTIONS, and TRACE are rejected
+// with a 403 error response unless they carry a token, in a header or body key,
+// equal to the value of a cookie set by the server. See CSRFOptions. The check
+// is done even for requests whose validation is skipped or turned off. WithCSRF
+// panics if neither opts.Header nor opts.Field is set.
+func WithCSRF(opts CSRFOptions) Option {
+	if opts.Header == "" && opts.Field == "" {
+		panic("jsonbody: CSRFOptions must set Header or Field")
+	}
+
+	return func(m *middleware) {
+		m.csrf = &opts
+	}
+}
+
+// WithParseOptions enables stricter parsing of request bodies. See ParseOptions.
+func WithParseOptions(opts ParseOptions) Option {
+	return func(m *middleware) {
+		m.parseOptions = opts
+	}
+}
+
+// WithAllOf adds schemas that requests must satisfy in addition to the schema
+// passed to NewMiddleware, e.g. an envelope schema shared by every endpoint. See
+// AllOf. Like NewMiddleware, WithAllOf panics if any of the schemas are invalid.
+func WithAllOf(schemasJSON ...string) Option {
+	schemas := make([]*Schema, len(schemasJSON))
+	for i, schemaJSON := range schemasJSON {
+		schemas[i] = MustParseSchema(schemaJSON)
+	}
+
+	return func(m *middleware) {
+		m.schema = AllOf(append([]*Schema{m.schema}, schemas...)...)
+	}
+}
+
+// WithDeniedKeys causes the middleware to reject request bodies containing a key
+// that matches any of the given regular expressions, anywhere in the body, e.g.
+// "__.*__" to block keys like "__proto__". Patterns must match the entire key.
+// WithDeniedKeys panics if a pattern is invalid.
+func WithDeniedKeys(patterns ...string) Option {
+	compiled := compileDenyPatterns(patterns)
+
+	return func(m *middleware) {
+		if m.deny == nil {
+			m.deny = &denyRules{}
+		}
+		m.deny.keys = append(m.deny.keys, compiled...)
+	}
+}
+
+// WithDeniedValues is like WithDeniedKeys, but rejects request bodies containing
+// a string value that matches any of the given regular expressions.
+func WithDeniedValues(patterns ...string) Option {
+	compiled := compileDenyPatterns(patterns)
+
+	return func(m *middleware) {
+		if m.deny == nil {
+			m.deny = &denyRules{}
+		}
+		m.deny.values = append(m.deny.values, compiled...)
+	}
+}
+
+// WithValidators adds validators that are run, in order, after the request's
+// schema. Their errors are sent in the same 400 response body as schema
+// validation errors.
+func WithValidators(validators ...Validator) Option {
+	return func(m *middleware) {
+		m.validators = append(m.validators, validators...)
+	}
+}
+
+// WithPathValidator registers a validator that is called with the value at path
+// in each request body, after it has been validated against the schema, for
+// domain-specific checks like whether an email address is deliverable. Paths use
+// the same format as in WithAsyncValidator, e.g. "author.email" or
+// "items[].sku". The validator is not called if the body doesn't contain the
+// path, or if the value already failed validation against the schema. If it
+// returns an error, the error's message is sent in the same 400 response body
+// as schema validation errors.
+func WithPathValidator(path string, validate func(value interface{}) error) Option {
+	return func(m *middleware) {
+		m.pathValidators = append(m.pathValidators, pathValidator{path: path, validate: validate})
+	}
+}
+
+// WithStreamOptions configures the streaming responses written by
+// Writer.WriteNDJSONStream. See StreamOptions.
+func WithStreamOptions(opts StreamOptions) Option {
+	return func(m *middleware) {
+		m.writerConfig.stream = opts
+	}
+}
+
+// WithMemoryGuard causes the middleware to reserve memory for each request body
+// from guard before reading it, based on its Content-Length, and release it once
+// the handler returns. If guard's limit would be exceeded, a 503 error response
+// is sent with a Retry-After header of retryAfter (rounded up to whole seconds),
+// unless the body alone is larger than the limit, in which case retrying can't
+// help, so a 413 error response is sent instead.
+// Bodies of unknown length (e.g. chunked ones) aren't reserved, so they should
+// be limited with WithMaxBodySize.
+func WithMemoryGuard(guard *MemoryGuard, retryAfter time.Duration) Option {
+	return func(m *middleware) {
+		m.memoryGuard = guard
+		m.memoryRetryAfter = retryAfter
+	}
+}
+
+// WithSpillToDisk causes the middleware to write request bodies whose
+// Content-Length is greater than threshold bytes to a temporary file in dir (or
+// the default directory for temporary files if dir is ""), as
+// mime/multipart does for large file uploads, rather than holding them in
+// memory for the lifetime of the request. The body is still parsed and
+// validated as usual, and the Reader passed to the handler reads (and seeks)
+// the file transparently. The file is removed once the handler returns.
+func WithSpillToDisk(threshold int64, dir string) Option {
+	return func(m *middleware) {
+		m.spillThreshold = threshold
+		m.spillDir = dir
+	}
+}
+
+// WithCharsetTranscoding causes the middleware to accept request bodies in
+// charsets other than UTF-8, as indicated by the charset parameter of the
+// Content-Type header (e.g. "application/json; charset=ISO-8859-1"). Such bodies
+// are converted to UTF-8 before they are parsed, and the Reader passed to the
+// handler reads the converted body. ISO-8859-1 and UTF-16 (big- or
+// little-endian, with or without a byte order mark) are supported; bodies in
+// other charsets are rejected with a 415 error response. Since conversion
+// requires the whole body to be held in memory, bodies that need converting and
+// whose Content-Length is greater than maxSize bytes are rejected with a 413
+// error response.
+//
+// Without this option, a charset parameter in the Content-Type header must be
+// UTF-8; the media type itself is checked as usual (see WithContentTypes).
+func WithCharsetTranscoding(maxSize int64) Option {
+	return func(m *middleware) {
+		m.transcodeMaxSize = maxSize
+	}
+}
+
+// WithWriteDiagnostics causes the middleware to detect handlers that write a
+// response both with the Writer's JSON methods and directly with its embedded
+// http.ResponseWriter (e.g. calling Write after WriteJSON), or that call
+// WriteHeader more than once. Each such call is logged along with the call sites
+// of both writes. If strict is true, it panics instead, which makes these bugs
+// fail tests rather than going unnoticed.
+func WithWriteDiagnostics(strict bool) Option {
+	return func(m *middleware) {
+		m.writeDiagnostics = true
+		m.strictWrites = strict
+	}
+}
+
+// WithResponseStatuses declares the status codes that the handler may send for
+// requests with the given method (or any method, if method is "") and a path
+// matching pathPattern, which is written like those of WithRouteSchema. It can be
+// used several times for the same route. With WithAutoHead, HEAD requests are
+// checked against the statuses declared for GET. When the handler sends any other status (e.g. a 500 where only
+// 200 and 404 are documented), the status and its call site are logged, or,
+// if strict diagnostics are enabled with WithWriteDiagnostics(true), the
+// middleware panics. Statuses sent by the middleware itself, such as 400
+// validation errors, and informational (1xx) statuses aren't checked.
+func WithResponseStatuses(method string, pathPattern string, statuses ...int) Option {
+	return func(m *middleware) {
+		if m.statuses == nil {
+			m.statuses = &statusContracts{}
+		}
+		m.statuses.add(method, pathPattern, statuses)
+	}
+}
+
+// WithBindErrors changes how NewTypedMiddleware and Handle respond when a valid
+// number in the body can't be stored in its Go field, e.g. 1.5 or 1e30 for an
+// int field. By default, such bodies get a 500 response, as for any other
+// disagreement between the schema and the Go type. With WithBindErrors, they
+// get a 400 response with an error of code CodeInvalidValue whose key is the
+// number's key and whose reason says whether the number isn't an integer or is
+// out of range.
+func WithBindErrors() Option {
+	return func(m *middleware) {
+		m.writerConfig.bindErrors = true
+	}
+}
+
+// WithAppendJSON allows handlers to write their responses in several pieces
+// with Writer.AppendJSON. WriteJSON and WriteErrors can still only be called
+// once.
+func WithAppendJSON() Option {
+	return func(m *middleware) {
+		m.writerConfig.appendJSON = true
+	}
+}
+
+// WithAccessLog causes the middleware to write an access log to out, with one
+// line per request containing a JSON object like:
+//
+//	{"time":"2023-01-02T15:04:05.123Z","method":"POST","path":"/posts","remote_addr":"192.0.2.1:1234","status":201,"request_bytes":52,"response_bytes":87,"duration_ms":1.25,"schema":"createPost"}
+//
+// Since the middleware already wraps the response, this avoids another layer of
+// middleware (which would hide the Writer from the handler) just for logging.
+// "request_bytes" is the size of the body as passed to the handler (see
+// Reader.Size), and "schema" is omitted if the schema has no name. Lines are
+// written after the response is complete, and writes to out are serialized.
+func WithAccessLog(out io.Writer) Option {
+	return func(m *middleware) {
+		m.accessLog = &accessLogger{out: out}
+	}
+}
+
+// WithMethodSchema causes the middleware to validate requests with the given
+// method (e.g. "PATCH") against the schema in schemaJSON instead of the schema
+// passed to NewMiddleware. This way, a single handler can validate create and
+// update payloads differently. Passing "" for schemaJSON accepts any body for
+// the method. It panics if schemaJSON is invalid.
+func WithMethodSchema(method string, schemaJSON string) Option {
+	schema := MustParseSchema(schemaJSON)
+
+	return func(m *middleware) {
+		if m.methodSchemas == nil {
+			m.methodSchemas = make(map[string]*Schema)
+		}
+		m.methodSchemas[strings.ToUpper(method)] = schema
+	}
+}
+
+// WithRouteSchema causes the middleware to validate requests with the given
+// method and a path matching pathPattern against the schema in schemaJSON, so
+// that a single middleware can serve all of a router's routes. As in
+// NewMiddlewareFromBundle, path segments written as "{name}" match any single
+// segment, and their values are available from PathParams. Requests that don't
+// match any route are validated against the schema passed to NewMiddleware (or
+// WithMethodSchema). It panics if schemaJSON is invalid.
+func WithRouteSchema(method string, pathPattern string, schemaJSON string) Option {
+	schema := MustParseSchema(schemaJSON)
+
+	return func(m *middleware) {
+		if m.routes == nil {
+			m.routes = &routeTable{}
+		}
+		m.routes.add(method, pathPattern, schema)
+	}
+}
+
+// WithRoutePatterns declares the path patterns of the server's routes, e.g.
+// "/posts/{id}", for any method, without attaching schemas to them. The
+// middleware identifies requests' routes by the pattern they match (or by
+// their path, if they match none) when resolving schemas with
+// WithSchemaResolver and tracking failures with WithCircuitBreaker, so
+// declaring the patterns of routes whose paths contain IDs keeps each ID from
+// being treated as a separate route. Routes registered with WithRouteSchema or
+// NewMiddlewareFromBundle don't need to be declared again.
+func WithRoutePatterns(patterns ...string) Option {
+	return func(m *middleware) {
+		if m.routePatterns == nil {
+			m.routePatterns = &routeTable{}
+		}
+		for _, pattern := range patterns {
+			m.routePatterns.add("", pattern, nil)
+		}
+	}
+}
+
+// WithPolicy sets the actions the middleware takes for validation errors of
+// each severity. See Policy for the defaults.
+func WithPolicy(policy Policy) Option {
+	return func(m *middleware) {
+		m.policy = policy
+	}
+}
+
+// WithAcceptEnforcement causes the middleware to reject requests whose Accept
+// header doesn't allow application/json or any of the alternates (e.g.
+// "application/x-ndjson" for handlers that stream) with a 406 error response,
+// before the handler is called. Requests without an Accept header are accepted.
+func WithAcceptEnforcement(alternates ...string) Option {
+	return func(m *middleware) {
+		m.enforceAccept = true
+		m.acceptAlternates = alternates
+	}
+}
+
+// WithContentTypes causes the middleware to accept request bodies with any of
+// the given media types (e.g. "text/json"), in addition to application/json and
+// types with a +json suffix. Parameters in the Content-Type header are ignored
+// when matching.
+func WithContentTypes(mediaTypes ...string) Option {
+	return func(m *middleware) {
+		if m.contentTypes == nil {
+			m.contentTypes = make(map[string]bool)
+		}
+
+		for _, mediaType := range mediaTypes {
+			m.contentTypes[strings.ToLower(mediaType)] = true
+		}
+	}
+}
+
+// WithOrderedJSON causes the middleware to also decode object request bodies
+// into an OrderedMap, which handlers can retrieve with Reader.OrderedJSON() to
+// learn the original order of the keys, e.g. to verify a signature or to echo
+// the body back unchanged. Since this parses the body a second time, it is
+// disabled by default.
+func WithOrderedJSON() Option {
+	return func(m *middleware) {
+		m.orderedJSON = true
+	}
+}
+
+// WithSchemaVersions enables versioning through vendor media types (see
+// VendorMediaType). Requests whose Content-Type is a media type of vendor, like
+// "application/vnd.acme.post.v2+json", are validated against the schema for
+// its version in schemas, which maps versions to schemas in the format
+// described for NewMiddleware; versions that aren't in schemas are rejected
+// with a 415 error response. For requests without such a body (e.g. GET
+// requests), the most preferred supported version in the Accept header is used.
+// Other requests are validated as usual.
+//
+// The negotiated media type is available to handlers from RequestMediaType,
+// and the Writer sends it as the Content-Type of JSON responses. It panics if
+// any of the schemas is invalid.
+func WithSchemaVersions(vendor string, schemas map[int]string) Option {
+	versions := &schemaVersions{vendor: vendor, schemas: make(map[int]*Schema, len(schemas))}
+	for version, schemaJSON := range schemas {
+		versions.schemas[version] = MustParseSchema(schemaJSON)
+	}
+
+	return func(m *middleware) {
+		m.versions = versions
+	}
+}
+
+// WithResponseHeader causes Writers passed to the handler to send the given
+// header with every JSON response (including error responses), unless the
+// handler sets the header itself. It may be passed more than once to send
+// multiple headers, or multiple values of the same header.
+func WithResponseHeader(key, value string) Option {
+	return func(m *middleware) {
+		if m.writerConfig.headers == nil {
+			m.writerConfig.headers = make(http.Header)
+		}
+
+		m.writerConfig.headers.Add(key, value)
+	}
+}
+
+// WithNoStore sends "Cache-Control: no-store" with every JSON response, so that
+// clients and proxies don't cache API responses. It is a shortcut for
+// WithResponseHeader.
+func WithNoStore() Option {
+	return WithResponseHeader("Cache-Control", "no-store")
+}
+
+// WithNoSniff sends "X-Content-Type-Options: nosniff" with every JSON response,
+// so that browsers don't interpret responses as a content type other than JSON.
+// It is a shortcut for WithResponseHeader.
+func WithNoSniff() Option {
+	return WithResponseHeader("X-Content-Type-Options", "nosniff")
+}
+
+// WithTranslations causes the middleware to send the messages of the errors it
+// reports in the language that best matches each request's Accept-Language
+// header, using translations. The selected locale is sent in the
+// Content-Language header of error responses.
+func WithTranslations(translations *Translations) Option {
+	if translations == nil {
+		return WithTranslator(nil)
+	}
+
+	return WithTranslator(translations)
+}
+
+// WithTranslator is like WithTranslations, but uses any Translator to select
+// the language of error responses and produce their messages.
+func WithTranslator(translator Translator) Option {
+	return func(m *middleware) {
+		m.writerConfig.translations = translator
+	}
+}
+
+// WithErrorObjects causes the errors in the error responses sent by the
+// middleware to be objects rather than strings, so that clients can map them to
+// form fields programmatically:
+//
+//	{
+//		"errors": [
+//			{"field": "author.name", "code": "missing_key", "message": "expected key 'author.name' missing"}
+//		]
+//	}
+//
+// The field is omitted for errors that aren't about a specific value, and the
+// code includes the schema's error code prefix, if any (see
+// ValidationError.QualifiedCode).
+func WithErrorObjects() Option {
+	return func(m *middleware) {
+		m.writerConfig.errorObjects = true
+	}
+}
+
+// WithProblemDetails causes the middleware to send its error responses as
+// problem details documents, as defined by RFC 7807, with the Content-Type
+// application/problem+json:
+//
+//	{
+//		"type": "about:blank",
+//		"title": "Bad Request",
+//		"status": 400,
+//		"detail": "expected key 'title' missing",
+//		"errors": [ <list of errors> ]
+//	}
+//
+// The type is typeURI, or "about:blank" if it is "", and the title is the
+// standard text for the status code. The detail is the error's message if there
+// is only one error, and is omitted otherwise. The errors extension member lists
+// the errors in the same form as the default envelope (see WithErrorObjects).
+// Internal server errors are also sent as problem details documents, without
+// errors, rather than with an empty body.
+func WithProblemDetails(typeURI string) Option {
+	if typeURI == "" {
+		typeURI = "about:blank"
+	}
+
+	return func(m *middleware) {
+		m.writerConfig.problemType = typeURI
+	}
+}
+
+// WithErrorFormatter causes the middleware to send the bodies produced by
+// formatter in its error responses, instead of the usual error envelope. This
+// takes precedence over WithErrorObjects and WithProblemDetails.
+func WithErrorFormatter(formatter ErrorFormatter) Option {
+	return func(m *middleware) {
+		m.writerConfig.errorFormatter = formatter
+	}
+}
+
+// WithPrettyJSON causes Writers passed to the handler to indent JSON response
+// bodies, which is useful while debugging. Streamed responses (e.g. from
+// WriteNDJSONStream or AppendJSON) are still written compactly. See
+// WithPrettyParam for indenting only the responses that clients ask for.
+func WithPrettyJSON() Option {
+	return func(m *middleware) {
+		m.writerConfig.pretty = true
+	}
+}
+
+// WithPrettyParam is like WithPrettyJSON, but only indents the responses to
+// requests whose given query parameter (typically "pretty") is true, e.g.
+// "?pretty=true". Other responses are sent compactly.
+func WithPrettyParam(queryParam string) Option {
+	return func(m *middleware) {
+		m.prettyParam = queryParam
+	}
+}
+
+// WithFieldSelection allows clients to request a sparse fieldset by listing the
+// paths they want in the given query parameter (typically "fields"), e.g.
+// "?fields=id,author.name". Writers passed to the handler remove all other keys
+// from successful (2xx) response bodies before sending them. Selections apply to
+// each element of an array, and requests without the parameter receive the full
+// body. For responses sent with WriteErrorsWithData or WriteMultiStatus, the
+// selection applies to the data inside the envelope, which is kept intact.
+//
+// Since the response body must be re-encoded to apply this option, object keys
+// in the response are sorted alphabetically.
+func WithFieldSelection(queryParam string) Option {
+	return func(m *middleware) {
+		m.fieldsParam = queryParam
+	}
+}
+
+// WithFieldSelectionHeader is like WithFieldSelection, but reads the list of
+// paths from the given request header instead of a query parameter.
+func WithFieldSelectionHeader(header string) Option {
+	return func(m *middleware) {
+		m.fieldsHeader = header
+	}
+}
+
+// WithResponseCache causes the middleware to cache successful (2xx) responses
+// from the handler for ttl, keyed by the request's method, URL, and a hash of
+// its body. When an identical request arrives before the response expires, the
+// cached response is sent without calling the handler. This is intended for
+// idempotent endpoints, such as searches that accept their query as a POST body.
+//
+// So that responses aren't sent to the wrong clients or in the wrong form, the
+// key also includes the request's Authorization, Cookie, Accept,
+// Accept-Encoding, Accept-Language, and Content-Type headers and the header
+// set with WithFieldSelectionHeader. Use WithCacheVary if responses depend on
+// anything else, such as a header set by an authenticating proxy.
+//
+// If cache is nil, a new MemoryCache is used.
+func WithResponseCache(cache ResponseCache, ttl time.Duration) Option {
+	return func(m *middleware) {
+		if cache == nil {
+			cache = NewMemoryCache()
+		}
+
+		m.cache = cache
+		m.cacheTTL = ttl
+	}
+}
+
+// WithCacheVary adds the value returned by vary for each request to the key of
+// the responses cached by WithResponseCache, so that requests for which vary
+// returns different values don't share cached responses.
+func WithCacheVary(vary func(*http.Request) string) Option {
+	return func(m *middleware) {
+		m.cacheVary = vary
+	}
+}
+
+// WithIdempotencyKeys enables support for the Idempotency-Key request header.
+// The first response (other than a 5xx) for each key is stored in store for ttl.
+// If the request is retried with the same key, method, URL, and body, the stored
+// response is sent without calling the handler. If the key is reused for a
+// different request, or while the first request is still being handled, a 409
+// error response is sent. Requests without the header are unaffected.
+//
+// Keys are scoped to the client that sent them, which is identified by its IP
+// address unless WithIdempotencyClientID is used.
+//
+// If store is nil, a new MemoryCache is used. The store must not be shared with
+// WithResponseCache.
+func WithIdempotencyKeys(store ResponseCache, ttl time.Duration) Option {
+	return func(m *middleware) {
+		if store == nil {
+			store = NewMemoryCache()
+		}
+
+		m.idempotencyStore = store
+		m.idempotencyTTL = ttl
+	}
+}
+
+// WithIdempotencyClientID sets how WithIdempotencyKeys identifies clients, so
+// that each client's keys are kept separate. clientID should return the same
+// value for every request from a client, e.g. the authenticated user's ID. By
+// default, RemoteIP is used.
+func WithIdempotencyClientID(clientID func(*http.Request) string) Option {
+	return func(m *middleware) {
+		m.idempotencyClientID = clientID
+	}
+}
+
+// WithDeduplication causes the middleware to reject a request with a 409 error
+// response if the same client sent a request with the same method, URL, and body
+// that is still in progress or that completed within the given window, e.g.
+// because a user double-tapped a submit button on a flaky connection. Requests
+// that fail with a server error (5xx) aren't remembered, so they can be retried.
+// Clients are identified by the result of clientID; if it is nil, RemoteIP is
+// used.
+//
+// Unlike WithIdempotencyKeys, deduplication doesn't require any cooperation from
+// clients, but the duplicate request receives an error rather than the original
+// response. If both are enabled, requests with an Idempotency-Key header are
+// handled by WithIdempotencyKeys only.
+func WithDeduplication(window time.Duration, clientID func(*http.Request) string) Option {
+	return func(m *middleware) {
+		if clientID == nil {
+			clientID = RemoteIP
+		}
+
+		m.dedup = &dedupWindow{window: window, clientID: clientID}
+	}
+}
+
+// WithAsyncValidator registers a validator that is called with the value at path
+// in each request body that passes schema validation. Paths use the same format
+// as the keys in validation errors, except that "[]" refers to every element of
+// an array, e.g. "items[].categoryId", or "[].categoryId" for a body that is an
+// array. The validator is not called if the body doesn't contain the path.
+//
+// Validators are run concurrently (see WithAsyncValidation), and the errors they
+// return are sent in the same 400 response body as schema validation errors.
+func WithAsyncValidator(path string, validator AsyncValidator) Option {
+	return func(m *middleware) {
+		m.async.validators = append(m.async.validators, asyncValidatorEntry{path: path, validate: validator})
+	}
+}
+
+// WithAsyncValidation limits how validators registered with WithAsyncValidator
+// are run. If timeout is positive, the context passed to the validators is
+// canceled after timeout, and validators that don't finish in time are reported
+// as errors. If concurrency is positive, at most that many validators are run at
+// once for each request. By default, there is no timeout (other than the
+// request's context), and at most 8 validators are run at once.
+func WithAsyncValidation(timeout time.Duration, concurrency int) Option {
+	return func(m *middleware) {
+		m.async.timeout = timeout
+		m.async.concurrency = concurrency
+	}
+}
+
+// WithSchemaResolver causes the middleware to look up the schema for each
+// request using resolver, falling back to the schema passed to NewMiddleware if
+// the resolver returns a nil *Schema. The tenant ID passed to the resolver is
+// the result of tenantID, or "" if tenantID is nil. If ttl is positive, resolved
+// schemas are cached in memory for ttl for each combination of tenant, route,
+// and method. Routes are identified by their patterns (see WithRoutePatterns),
+// so requests to e.g. /posts/1 and /posts/2 share a cached schema.
+//
+// If the resolver returns an error, a 503 error response is sent, unless a
+// different policy is set with WithSchemaFallback.
+func WithSchemaResolver(resolver SchemaResolver, tenantID func(*http.Request) string, ttl time.Duration) Option {
+	return func(m *middleware) {
+		if m.resolver == nil {
+			m.resolver = &resolverConfig{}
+		}
+
+		m.resolver.resolver = resolver
+		m.resolver.tenantID = tenantID
+		m.resolver.ttl = ttl
+	}
+}
+
+// WithSchemaFallback sets how requests are handled when the SchemaResolver set
+// by WithSchemaResolver fails. Every failure is logged, and if onFallback isn't
+// nil, it is also called with the request and the resolver's error, e.g. to
+// update a metric. It has no effect without WithSchemaResolver.
+func WithSchemaFallback(policy FallbackPolicy, onFallback func(r *http.Request, err error)) Option {
+	return func(m *middleware) {
+		if m.resolver == nil {
+			m.resolver = &resolverConfig{}
+		}
+
+		m.resolver.policy = policy
+		m.resolver.onFallback = onFallback
+	}
+}
+
+// WithMaxBodySize causes the middleware to reject requests whose Content-Length
+// is greater than n bytes with a 413 error response. The check is done before
+// the body is read, so clients that send "Expect: 100-continue" are rejected
+// before they transmit the body, and no memory is allocated for it. The limit
+// is also enforced while the body is read, in case it is longer than its
+// Content-Length claims.
+func WithMaxBodySize(n int64) Option {
+	return func(m *middleware) {
+		m.maxBodySize = n
+	}
+}
+
+// WithHandlerTimeout limits how long the handler may take to respond. The
+// request passed to the handler has a context that is canceled after timeout.
+// If the handler hasn't started writing its response by then, a 504 error
+// response is sent, and anything the handler writes afterward is discarded
+// (writes fail with http.ErrHandlerTimeout). If the handler has already started
+// writing, it is allowed to finish.
+func WithHandlerTimeout(timeout time.Duration) Option {
+	return func(m *middleware) {
+		m.handlerTimeout = timeout
+	}
+}
+
+// WithCircuitBreaker protects the middleware's validation subsystems that can
+// fail independently of the request (the schema resolver set by
+// WithSchemaResolver and timeouts of async validators set by
+// WithAsyncValidator). When they fail cb.Threshold times in a row for a route,
+// the route's circuit opens, and requests to it are handled according to
+// cb.Policy until cb.Cooldown has elapsed and a trial request succeeds.
+func WithCircuitBreaker(cb CircuitBreaker) Option {
+	return func(m *middleware) {
+		if cb.Threshold <= 0 {
+			cb.Threshold = 1
+		}
+
+		m.breaker = &circuitBreaker{config: cb}
+	}
+}
+
+// WithAutoHead causes HEAD requests to be handled like GET requests (including
+// using the schema of the GET route set with NewMiddlewareFromBundle or
+// NewMiddlewareFromFS), except that the response body is discarded. The
+// handler is still called, and the headers of its response, including the
+// Content-Length computed by WriteJSON, are sent as they would be for a GET
+// request.
+func WithAutoHead() Option {
+	return func(m *middleware) {
+		m.autoHead = true
+	}
+}
@@ -0,0 +1,164 @@
+package jsonbody
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+)
+
+// OrderedMap is a JSON object that remembers the order in which its keys were
+// set. When encoded, for example by Writer.WriteJSON, its keys are written in
+// that order rather than the sorted order that encoding/json uses for maps. The
+// zero value is an empty map ready to use.
+//
+// Options that modify response bodies after they are encoded, such as
+// WithStringNumbers and WithSortedKeys, as well as field selection, cause the
+// keys to be sorted instead.
+type OrderedMap struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+// NewOrderedMap returns an empty OrderedMap.
+func NewOrderedMap() *OrderedMap {
+	return &OrderedMap{}
+}
+
+// Set sets the value for key. A new key is added after all existing keys, while
+// an existing key keeps its position.
+func (m *OrderedMap) Set(key string, value interface{}) {
+	if m.values == nil {
+		m.values = make(map[string]interface{})
+	}
+
+	if _, ok := m.values[key]; !ok {
+		m.keys = append(m.keys, key)
+	}
+	m.values[key] = value
+}
+
+// Get returns the value for key and whether it was present.
+func (m *OrderedMap) Get(key string) (interface{}, bool) {
+	val, ok := m.values[key]
+	return val, ok
+}
+
+// Delete removes key from the map, if present.
+func (m *OrderedMap) Delete(key string) {
+	if _, ok := m.values[key]; !ok {
+		return
+	}
+
+	delete(m.values, key)
+	for i, k := range m.keys {
+		if k == key {
+			m.keys = append(m.keys[:i], m.keys[i+1:]...)
+			break
+		}
+	}
+}
+
+// Keys returns the keys of the map in order.
+func (m *OrderedMap) Keys() []string {
+	return append([]string(nil), m.keys...)
+}
+
+// Len returns the number of keys in the map.
+func (m *OrderedMap) Len() int {
+	return len(m.keys)
+}
+
+// MarshalJSON encodes the map as a JSON object with its keys in order.
+func (m OrderedMap) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, key := range m.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+
+		k, err := json.Marshal(key)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(k)
+		buf.WriteByte(':')
+
+		v, err := json.Marshal(m.values[key])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(v)
+	}
+	buf.WriteByte('}')
+
+	return buf.Bytes(), nil
+}
+
+// UnmarshalJSON decodes a JSON object into the map, keeping its keys in the
+// order they appear. Nested objects are decoded as *OrderedMap, and other values
+// as by json.Unmarshal into an interface{}. If a key appears more than once, the
+// last value is kept at the position of the first.
+func (m *OrderedMap) UnmarshalJSON(data []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+
+	if tok != json.Delim('{') {
+		return errors.New("jsonbody: cannot decode non-object into OrderedMap")
+	}
+
+	*m = OrderedMap{}
+	return m.decodeObject(dec)
+}
+
+// decodeObject decodes the members of an object whose opening brace has been
+// read from dec.
+func (m *OrderedMap) decodeObject(dec *json.Decoder) error {
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+
+		val, err := decodeOrderedValue(dec)
+		if err != nil {
+			return err
+		}
+		m.Set(tok.(string), val)
+	}
+
+	_, err := dec.Token() // the closing brace
+	return err
+}
+
+// decodeOrderedValue decodes the next value from dec, decoding objects as
+// *OrderedMap.
+func decodeOrderedValue(dec *json.Decoder) (interface{}, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch tok {
+	case json.Delim('{'):
+		obj := NewOrderedMap()
+		return obj, obj.decodeObject(dec)
+	case json.Delim('['):
+		arr := make([]interface{}, 0)
+		for dec.More() {
+			elem, err := decodeOrderedValue(dec)
+			if err != nil {
+				return nil, err
+			}
+			arr = append(arr, elem)
+		}
+
+		_, err := dec.Token() // the closing bracket
+		return arr, err
+	}
+
+	return tok, nil
+}
@@ -0,0 +1,91 @@
+package jsonbody
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOrderedMapKeepsInsertionOrder(t *testing.T) {
+	m := NewOrderedMap()
+	m.Set("z", 1)
+	m.Set("a", "x")
+	m.Set("m", true)
+	m.Set("z", 2)
+	m.Delete("a")
+
+	val, ok := m.Get("z")
+	assert.True(t, ok)
+	assert.Equal(t, 2, val)
+	assert.Equal(t, []string{"z", "m"}, m.Keys())
+	assert.Equal(t, 2, m.Len())
+
+	b, err := m.MarshalJSON()
+	assert.Nil(t, err)
+	assert.Equal(t, `{"z":2,"m":true}`, string(b))
+}
+
+func TestWriteJSONWritesOrderedMapInOrder(t *testing.T) {
+	var inner OrderedMap
+	inner.Set("b", time.Duration(1500)*time.Millisecond)
+	inner.Set("a", nil)
+
+	m := NewOrderedMap()
+	m.Set("second", 2)
+	m.Set("first", &inner)
+
+	recorder := httptest.NewRecorder()
+	w := Writer{ResponseWriter: recorder}
+	assert.Nil(t, w.WriteJSON(200, m))
+	assert.Equal(t, `{"second":2,"first":{"b":1500000000,"a":null}}`, recorder.Body.String())
+
+	recorder = httptest.NewRecorder()
+	w = Writer{ResponseWriter: recorder, config: &writerConfig{durationFormat: DurationMillis}}
+	assert.Nil(t, w.WriteJSON(200, m))
+	assert.Equal(t, `{"second":2,"first":{"b":1500,"a":null}}`, recorder.Body.String())
+}
+
+func TestWriteJSONSortsKeysIfConfigured(t *testing.T) {
+	m := NewOrderedMap()
+	m.Set("z", struct {
+		Y int `json:"y"`
+		X int `json:"x"`
+	}{1, 2})
+	m.Set("a", 0)
+
+	recorder := httptest.NewRecorder()
+	w := Writer{ResponseWriter: recorder, config: &writerConfig{sortKeys: true}}
+	assert.Nil(t, w.WriteJSON(200, m))
+	assert.Equal(t, `{"a":0,"z":{"x":2,"y":1}}`, recorder.Body.String())
+}
+
+func TestOrderedMapUnmarshalKeepsKeyOrder(t *testing.T) {
+	var m OrderedMap
+	err := json.Unmarshal([]byte(`{"z": 1, "a": {"y": [true, {"c": null, "b": "x"}], "x": 2}, "z": 3}`), &m)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"z", "a"}, m.Keys())
+
+	b, err := json.Marshal(m)
+	assert.Nil(t, err)
+	assert.Equal(t, `{"z":3,"a":{"y":[true,{"c":null,"b":"x"}],"x":2}}`, string(b))
+
+	assert.NotNil(t, json.Unmarshal([]byte(`[1]`), &m))
+}
+
+func TestServeHTTPDecodesOrderedJSONIfConfigured(t *testing.T) {
+	var ordered *OrderedMap
+	handler := NewMiddleware(`{"b": "", "?a": {"$default": 1}}`, WithOrderedJSON())(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ordered = r.Body.(Reader).OrderedJSON()
+	}))
+
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"c": 1, "b": "x"}`))
+	r.Header.Set("Content-Type", "application/json")
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+
+	assert.Equal(t, []string{"c", "b"}, ordered.Keys())
+}
@@ -0,0 +1,156 @@
+package jsonbody
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"strconv"
+	"unicode/utf8"
+)
+
+// ParseOptions tightens how request bodies are parsed, for deployments where
+// inputs that encoding/json tolerates should be rejected. Bodies that violate an
+// enabled option are rejected with a 400 error response. The zero value enables
+// none of them.
+type ParseOptions struct {
+	// RejectLoneSurrogates rejects strings containing a \u escape for a UTF-16
+	// surrogate that isn't part of a valid pair, e.g. "\ud800". By default,
+	// such escapes are replaced with U+FFFD.
+	RejectLoneSurrogates bool
+
+	// RejectControlCharacters rejects strings containing the unescaped control
+	// characters that RFC 8259 permits, i.e. U+007F and U+0080 through U+009F.
+	// (U+0000 through U+001F must always be escaped, so they are always
+	// rejected.)
+	RejectControlCharacters bool
+
+	// RequireObjectOrArray rejects bodies whose top-level value is not an
+	// object or array.
+	RequireObjectOrArray bool
+}
+
+// parseError describes why a body was rejected by the ParseOptions.
+type parseError struct {
+	reason string
+}
+
+func (e *parseError) Error() string {
+	return e.reason
+}
+
+// check returns a *parseError if body violates the enabled options. It assumes
+// body is otherwise valid JSON, since it is also decoded with encoding/json.
+func (o ParseOptions) check(body []byte) error {
+	return o.checkReader(bytes.NewReader(body))
+}
+
+// checkReader is like check, but reads the body from body, so that bodies that
+// aren't held in memory can be checked too.
+func (o ParseOptions) checkReader(body io.Reader) error {
+	if !o.RequireObjectOrArray && !o.RejectLoneSurrogates && !o.RejectControlCharacters {
+		return nil
+	}
+
+	br := bufio.NewReader(body)
+	if o.RequireObjectOrArray {
+		for {
+			c, err := br.ReadByte()
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				return err
+			}
+
+			if c == ' ' || c == '\t' || c == '\n' || c == '\r' {
+				continue
+			}
+
+			if c != '{' && c != '[' {
+				return &parseError{"the top-level value must be an object or array"}
+			}
+			break
+		}
+	}
+
+	if !o.RejectLoneSurrogates && !o.RejectControlCharacters {
+		return nil
+	}
+
+	inString := false
+	for {
+		c, err := br.ReadByte()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		if !inString {
+			if c == '"' {
+				inString = true
+			}
+			continue
+		}
+
+		switch {
+		case c == '"':
+			inString = false
+		case c == '\\':
+			r, ok := peekUnicodeEscape(br)
+			if !ok {
+				br.Discard(1) // the escaped character
+				continue
+			}
+			br.Discard(5)
+
+			if o.RejectLoneSurrogates && r >= 0xD800 && r <= 0xDFFF {
+				low, ok := parseUnicodeEscape(peekBytes(br, 6), 0)
+				if r >= 0xDC00 || !ok || low < 0xDC00 || low > 0xDFFF {
+					return &parseError{"strings must not contain unpaired UTF-16 surrogates"}
+				}
+				br.Discard(6)
+			}
+		case c < utf8.RuneSelf:
+			if o.RejectControlCharacters && c == 0x7F {
+				return &parseError{"strings must not contain unescaped control characters"}
+			}
+		default:
+			br.UnreadByte()
+			r, _, err := br.ReadRune()
+			if err != nil {
+				return err
+			}
+
+			if o.RejectControlCharacters && r >= 0x80 && r <= 0x9F {
+				return &parseError{"strings must not contain unescaped control characters"}
+			}
+		}
+	}
+}
+
+// peekUnicodeEscape parses the \uXXXX escape whose backslash was just read from
+// br, without consuming it, returning false if there isn't one.
+func peekUnicodeEscape(br *bufio.Reader) (rune, bool) {
+	return parseUnicodeEscape(append([]byte{'\\'}, peekBytes(br, 5)...), 0)
+}
+
+// peekBytes returns up to the next n bytes of br without consuming them.
+func peekBytes(br *bufio.Reader, n int) []byte {
+	b, _ := br.Peek(n)
+	return b
+}
+
+// parseUnicodeEscape parses the \uXXXX escape at body[i:], returning false if
+// there isn't one.
+func parseUnicodeEscape(body []byte, i int) (rune, bool) {
+	if i+6 > len(body) || body[i] != '\\' || body[i+1] != 'u' {
+		return 0, false
+	}
+
+	n, err := strconv.ParseUint(string(body[i+2:i+6]), 16, 16)
+	if err != nil {
+		return 0, false
+	}
+
+	return rune(n), true
+}
@@ -0,0 +1,53 @@
+package jsonbody
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestParseOptionsCheckRejectsLoneSurrogates(t *testing.T) {
+	opts := ParseOptions{RejectLoneSurrogates: true}
+
+	assert.NotNil(t, opts.check([]byte(`{"a": "\ud800"}`)))
+	assert.NotNil(t, opts.check([]byte(`{"a": "\udc00\ud800"}`)))
+	assert.NotNil(t, opts.check([]byte(`{"a": "\ud800A"}`)))
+	assert.Nil(t, opts.check([]byte(`{"a": "😀 é \\ud800"}`)))
+	assert.Nil(t, ParseOptions{}.check([]byte(`{"a": "\ud800"}`)))
+}
+
+func TestParseOptionsCheckRejectsControlCharacters(t *testing.T) {
+	opts := ParseOptions{RejectControlCharacters: true}
+
+	assert.NotNil(t, opts.check([]byte("{\"a\": \"x\u007f\"}")))
+	assert.NotNil(t, opts.check([]byte("{\"a\": \"x\u0085\"}")))
+	assert.Nil(t, opts.check([]byte("{\"a\": \"\\u007f é\"}")))
+}
+
+func TestParseOptionsCheckRequiresObjectOrArray(t *testing.T) {
+	opts := ParseOptions{RequireObjectOrArray: true}
+
+	assert.NotNil(t, opts.check([]byte(` "hi"`)))
+	assert.NotNil(t, opts.check([]byte(`5`)))
+	assert.Nil(t, opts.check([]byte(" \n{}")))
+	assert.Nil(t, opts.check([]byte(`[1]`)))
+}
+
+func TestServeHTTPSends400IfParseOptionsViolated(t *testing.T) {
+	next := &mockHandler{}
+	next.On("ServeHTTP", mock.Anything, mock.Anything).Return()
+	handler := NewMiddleware(`{}`, WithParseOptions(ParseOptions{RejectLoneSurrogates: true}))(next)
+
+	recorder := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"name": "\ud800"}`))
+	r.Header.Set("Content-Type", "application/json")
+	handler.ServeHTTP(recorder, r)
+
+	assert.Equal(t, 400, recorder.Code)
+	assert.Equal(t, `{"errors":["body is not acceptable JSON: strings must not contain unpaired UTF-16 surrogates"]}`, recorder.Body.String())
+	next.AssertNotCalled(t, "ServeHTTP", mock.Anything, mock.Anything)
+}
@@ -0,0 +1,105 @@
+package jsonbody
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// MergePatch returns a JSON Merge Patch (RFC 7386) that transforms oldBody into
+// newBody, e.g. for sending an update to another service. Keys removed from
+// oldBody are set to nil (null) in the patch, and objects are patched
+// recursively. Since null means removal in a merge patch, keys whose value is
+// null in newBody are treated as removed; use JSONPatch if that matters.
+func MergePatch(oldBody map[string]interface{}, newBody map[string]interface{}) map[string]interface{} {
+	patch := make(map[string]interface{})
+	for k := range oldBody {
+		if newVal, ok := newBody[k]; !ok || newVal == nil {
+			patch[k] = nil
+		}
+	}
+
+	for k, newVal := range newBody {
+		if newVal == nil {
+			continue
+		}
+
+		oldVal, ok := oldBody[k]
+		oldObj, oldIsObj := oldVal.(map[string]interface{})
+		newObj, newIsObj := newVal.(map[string]interface{})
+		switch {
+		case ok && oldIsObj && newIsObj:
+			if sub := MergePatch(oldObj, newObj); len(sub) > 0 {
+				patch[k] = sub
+			}
+		case !ok || !reflect.DeepEqual(oldVal, newVal):
+			patch[k] = newVal
+		}
+	}
+
+	return patch
+}
+
+// PatchOperation is an operation of a JSON Patch (RFC 6902).
+type PatchOperation struct {
+	Op    string      // "add", "remove", or "replace"
+	Path  string      // a JSON Pointer (RFC 6901), e.g. "/author/name"
+	Value interface{} // the new value, unless Op is "remove"
+}
+
+// MarshalJSON encodes the operation as a JSON Patch operation object, without a
+// value for "remove" operations.
+func (o PatchOperation) MarshalJSON() ([]byte, error) {
+	if o.Op == "remove" {
+		return json.Marshal(map[string]string{"op": o.Op, "path": o.Path})
+	}
+
+	return json.Marshal(map[string]interface{}{"op": o.Op, "path": o.Path, "value": o.Value})
+}
+
+// JSONPatch returns a JSON Patch (RFC 6902) that transforms oldBody into
+// newBody, with operations ordered by path. Objects are patched key by key,
+// while arrays that differ are replaced as a whole.
+func JSONPatch(oldBody map[string]interface{}, newBody map[string]interface{}) []PatchOperation {
+	ops := make([]PatchOperation, 0)
+	jsonPatchObject(&ops, "", oldBody, newBody)
+	return ops
+}
+
+func jsonPatchObject(ops *[]PatchOperation, pointer string, oldObj map[string]interface{}, newObj map[string]interface{}) {
+	keys := make([]string, 0, len(oldObj)+len(newObj))
+	for k := range oldObj {
+		keys = append(keys, k)
+	}
+	for k := range newObj {
+		if _, ok := oldObj[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		path := pointer + "/" + escapePointerToken(k)
+		oldVal, inOld := oldObj[k]
+		newVal, inNew := newObj[k]
+
+		oldSub, oldIsObj := oldVal.(map[string]interface{})
+		newSub, newIsObj := newVal.(map[string]interface{})
+		switch {
+		case !inOld:
+			*ops = append(*ops, PatchOperation{Op: "add", Path: path, Value: newVal})
+		case !inNew:
+			*ops = append(*ops, PatchOperation{Op: "remove", Path: path})
+		case oldIsObj && newIsObj:
+			jsonPatchObject(ops, path, oldSub, newSub)
+		case !reflect.DeepEqual(oldVal, newVal):
+			*ops = append(*ops, PatchOperation{Op: "replace", Path: path, Value: newVal})
+		}
+	}
+}
+
+// escapePointerToken escapes a key for use in a JSON Pointer.
+func escapePointerToken(key string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(key, "~", "~0"), "/", "~1")
+}
@@ -0,0 +1,39 @@
+package jsonbody
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMergePatch(t *testing.T) {
+	oldBody := decodeObject(t, `{"title": "a", "subtitle": "s", "tags": ["x"], "author": {"name": "n", "bio": "b"}, "draft": true}`)
+	newBody := decodeObject(t, `{"title": "b", "tags": ["x", "y"], "author": {"name": "n"}, "draft": true, "views": 0}`)
+
+	patch, err := json.Marshal(MergePatch(oldBody, newBody))
+	assert.Nil(t, err)
+	assert.JSONEq(t, `{"title": "b", "subtitle": null, "tags": ["x", "y"], "author": {"bio": null}, "views": 0}`, string(patch))
+}
+
+func TestMergePatchReturnsEmptyPatchForEqualBodies(t *testing.T) {
+	body := decodeObject(t, `{"a": {"b": [1]}}`)
+
+	assert.Equal(t, map[string]interface{}{}, MergePatch(body, body))
+}
+
+func TestJSONPatch(t *testing.T) {
+	oldBody := decodeObject(t, `{"title": "a", "subtitle": "s", "tags": ["x"], "author": {"name": "n", "bio": "b"}, "a/b": 1}`)
+	newBody := decodeObject(t, `{"title": "b", "tags": ["x", "y"], "author": {"name": "n", "bio": null}, "a/b": 2, "views": 0}`)
+
+	patch, err := json.Marshal(JSONPatch(oldBody, newBody))
+	assert.Nil(t, err)
+	assert.JSONEq(t, `[
+		{"op": "replace", "path": "/a~1b", "value": 2},
+		{"op": "replace", "path": "/author/bio", "value": null},
+		{"op": "remove", "path": "/subtitle"},
+		{"op": "replace", "path": "/tags", "value": ["x", "y"]},
+		{"op": "replace", "path": "/title", "value": "b"},
+		{"op": "add", "path": "/views", "value": 0}
+	]`, string(patch))
+}
@@ -0,0 +1,205 @@
+package jsonbody
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// ErrMissingKey is returned (wrapped) when a path refers to a key or array
+// element that is not present in the request body.
+var ErrMissingKey = errors.New("key missing from body")
+
+// pathSegment is a single step of a path: either an object key or, if isIndex is
+// set, an array index.
+type pathSegment struct {
+	key     string
+	index   int
+	isIndex bool
+}
+
+// parsePath splits a path like "author.tags[0]" into its segments. This is the
+// same format used for keys in validation errors. The empty path refers to the
+// entire body.
+func parsePath(path string) ([]pathSegment, error) {
+	segments := make([]pathSegment, 0)
+	if path == "" {
+		return segments, nil
+	}
+
+	for _, part := range strings.Split(path, ".") {
+		key := part
+		indices := ""
+		if i := strings.Index(part, "["); i >= 0 {
+			key, indices = part[:i], part[i:]
+		}
+
+		if key == "" && (indices == "" || len(segments) > 0) {
+			return nil, fmt.Errorf("jsonbody: invalid path '%v'", path)
+		}
+
+		if key != "" {
+			segments = append(segments, pathSegment{key: key})
+		}
+
+		for indices != "" {
+			end := strings.Index(indices, "]")
+			if indices[0] != '[' || end < 0 {
+				return nil, fmt.Errorf("jsonbody: invalid path '%v'", path)
+			}
+
+			index, err := strconv.Atoi(indices[1:end])
+			if err != nil || index < 0 {
+				return nil, fmt.Errorf("jsonbody: invalid array index in path '%v'", path)
+			}
+
+			segments = append(segments, pathSegment{index: index, isIndex: true})
+			indices = indices[end+1:]
+		}
+	}
+
+	return segments, nil
+}
+
+// lookupPath returns the value found at path in the decoded JSON value body.
+func lookupPath(body interface{}, path string) (interface{}, error) {
+	segments, err := parsePath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	val := body
+	for _, seg := range segments {
+		if seg.isIndex {
+			arr, ok := val.([]interface{})
+			if !ok || seg.index >= len(arr) {
+				return nil, fmt.Errorf("jsonbody: '%v': %w", path, ErrMissingKey)
+			}
+			val = arr[seg.index]
+			continue
+		}
+
+		obj, ok := val.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("jsonbody: '%v': %w", path, ErrMissingKey)
+		}
+
+		val, ok = obj[seg.key]
+		if !ok {
+			return nil, fmt.Errorf("jsonbody: '%v': %w", path, ErrMissingKey)
+		}
+	}
+
+	return val, nil
+}
+
+// Get returns the value at path in the request body as a T. The path uses the
+// same format as the keys in validation errors, e.g. "author.tags[0]".
+//
+// If the key is missing, the returned error wraps ErrMissingKey. If the value
+// cannot be represented as a T, a *BindError is returned. This includes null,
+// unless T is a pointer, interface, map, or slice type, in which case its zero
+// value is returned. Types other than the ones produced by encoding/json (e.g.
+// int or a struct type) are converted in the same way as Reader.BindMap.
+func Get[T any](r Reader, path string) (T, error) {
+	var result T
+
+	val, err := lookupPath(r.json, path)
+	if err != nil {
+		return result, err
+	}
+
+	if typed, ok := val.(T); ok {
+		return typed, nil
+	}
+
+	if val == nil {
+		typ := reflect.TypeOf(&result).Elem()
+		switch typ.Kind() {
+		case reflect.Ptr, reflect.Interface, reflect.Map, reflect.Slice:
+			return result, nil
+		}
+		return result, &BindError{Key: path, Value: "null", Type: typ}
+	}
+
+	err = bindValue(val, &result)
+	if bindErr, ok := err.(*BindError); ok && bindErr.Key == "" {
+		bindErr.Key = path
+	} else if ok {
+		bindErr.Key = joinPath(path, bindErr.Key)
+	}
+
+	return result, err
+}
+
+// joinPath appends the relative path rel to path.
+func joinPath(path string, rel string) string {
+	if strings.HasPrefix(rel, "[") {
+		return path + rel
+	}
+
+	return joinKey(path, rel)
+}
+
+// pathMatch is a value found by matchPath, along with its concrete key.
+type pathMatch struct {
+	key   string
+	value interface{}
+}
+
+// matchPath returns every value in body found at pattern, which is a path in
+// which "[]" may be used to refer to every element of an array, e.g.
+// "items[].id" or, for a body that is an array, "[].id". Matches are returned in
+// the order they appear in the body.
+func matchPath(body interface{}, pattern string) []pathMatch {
+	matches := []pathMatch{{key: "", value: body}}
+	if pattern == "" {
+		return matches
+	}
+
+	for _, part := range strings.Split(pattern, ".") {
+		key := strings.TrimRight(part, "[]")
+		wildcards := (len(part) - len(key)) / 2
+
+		next := make([]pathMatch, 0, len(matches))
+		for _, m := range matches {
+			if key == "" && wildcards > 0 {
+				next = append(next, m) // "[]" applies to the value itself
+				continue
+			}
+
+			obj, ok := m.value.(map[string]interface{})
+			if !ok {
+				continue
+			}
+
+			val, ok := obj[key]
+			if !ok {
+				continue
+			}
+
+			next = append(next, pathMatch{key: joinKey(m.key, key), value: val})
+		}
+
+		for i := 0; i < wildcards; i++ {
+			expanded := make([]pathMatch, 0, len(next))
+			for _, m := range next {
+				arr, ok := m.value.([]interface{})
+				if !ok {
+					continue
+				}
+
+				for j, elem := range arr {
+					expanded = append(expanded, pathMatch{key: fmt.Sprintf("%v[%v]", m.key, j), value: elem})
+				}
+			}
+			next = expanded
+		}
+
+		matches = next
+	}
+
+	return matches
+}
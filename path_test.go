@@ -0,0 +1,92 @@
+package jsonbody
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParsePathSplitsKeysAndIndices(t *testing.T) {
+	segments, err := parsePath("a.b[0][2].c")
+	assert.Nil(t, err)
+	assert.Equal(t, []pathSegment{
+		{key: "a"},
+		{key: "b"},
+		{index: 0, isIndex: true},
+		{index: 2, isIndex: true},
+		{key: "c"},
+	}, segments)
+}
+
+func TestParsePathReturnsErrIfInvalid(t *testing.T) {
+	for _, path := range []string{"a..b", "a[x]", "a[0", "a.[0]", "a[-1]"} {
+		_, err := parsePath(path)
+		assert.NotNil(t, err, path)
+	}
+}
+
+func TestGetReturnsTypedValue(t *testing.T) {
+	r := Reader{json: bodyMap(`{"item": {"price": 4.5, "tags": ["a", "b"]}}`)}
+
+	price, err := Get[float64](r, "item.price")
+	assert.Nil(t, err)
+	assert.Equal(t, 4.5, price)
+
+	tag, err := Get[string](r, "item.tags[1]")
+	assert.Nil(t, err)
+	assert.Equal(t, "b", tag)
+}
+
+func TestGetConvertsToOtherTypes(t *testing.T) {
+	r := Reader{json: bodyMap(`{"count": 3, "tags": ["a"]}`)}
+
+	count, err := Get[int](r, "count")
+	assert.Nil(t, err)
+	assert.Equal(t, 3, count)
+
+	tags, err := Get[[]string](r, "tags")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"a"}, tags)
+}
+
+func TestGetReturnsErrMissingKeyIfMissing(t *testing.T) {
+	r := Reader{json: bodyMap(`{"tags": []}`)}
+
+	_, err := Get[string](r, "name")
+	assert.True(t, errors.Is(err, ErrMissingKey))
+
+	_, err = Get[string](r, "tags[0]")
+	assert.True(t, errors.Is(err, ErrMissingKey))
+}
+
+func TestGetReturnsBindErrorIfWrongType(t *testing.T) {
+	r := Reader{json: bodyMap(`{"items": [{"tags": [5]}]}`)}
+
+	_, err := Get[[]string](r, "items[0].tags")
+	bindErr, ok := err.(*BindError)
+	assert.True(t, ok)
+	assert.Equal(t, "items[0].tags[0]", bindErr.Key)
+}
+
+func TestGetReturnsBindErrorIfNull(t *testing.T) {
+	r := Reader{json: bodyMap(`{"title": null}`)}
+
+	_, err := Get[string](r, "title")
+	bindErr, ok := err.(*BindError)
+	assert.True(t, ok)
+	assert.Equal(t, "title", bindErr.Key)
+	assert.Equal(t, "null", bindErr.Value)
+
+	ptr, err := Get[*string](r, "title")
+	assert.Nil(t, err)
+	assert.Nil(t, ptr)
+
+	val, err := Get[interface{}](r, "title")
+	assert.Nil(t, err)
+	assert.Nil(t, val)
+
+	tags, err := Get[[]string](r, "title")
+	assert.Nil(t, err)
+	assert.Nil(t, tags)
+}
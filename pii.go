@@ -0,0 +1,162 @@
+package jsonbody
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+	"sort"
+)
+
+// PIIPolicy determines what a PIIValidator does when it finds personal data.
+type PIIPolicy int
+
+const (
+	// PIIReject reports personal data as validation errors, so the request is
+	// rejected with a 400 error response.
+	PIIReject PIIPolicy = iota
+
+	// PIILog logs the keys containing personal data (but not the data itself)
+	// without rejecting the request.
+	PIILog
+)
+
+// PIIPattern describes a kind of personal data that a PIIValidator looks for.
+type PIIPattern struct {
+	// Name describes the kind of data in error and log messages, e.g. "email
+	// address".
+	Name string
+
+	// Pattern matches the data within a string value.
+	Pattern *regexp.Regexp
+
+	// Check, if not nil, must return true for a match of Pattern to be
+	// reported, e.g. to verify a checksum.
+	Check func(match string) bool
+}
+
+// DefaultPIIPatterns are the patterns used by a PIIValidator with no Patterns.
+// They detect email addresses, credit card numbers (verified with the Luhn
+// checksum), and US Social Security numbers.
+var DefaultPIIPatterns = []PIIPattern{
+	{
+		Name:    "email address",
+		Pattern: regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`),
+	},
+	{
+		Name:    "credit card number",
+		Pattern: regexp.MustCompile(`\b(?:\d[ -]?){12,18}\d\b`),
+		Check:   luhnValid,
+	},
+	{
+		Name:    "national ID number",
+		Pattern: regexp.MustCompile(`\b\d{3}-\d{2}-\d{4}\b`),
+	},
+}
+
+// PIIValidator is a Validator that looks for personal data in the string values
+// of request bodies, except in the keys that are expected to contain it.
+type PIIValidator struct {
+	// Patterns are the kinds of personal data to look for. If empty,
+	// DefaultPIIPatterns is used.
+	Patterns []PIIPattern
+
+	// AllowedKeys are the keys that may contain personal data, in the same
+	// format as the keys in validation errors, except that "[]" refers to
+	// every element of an array, e.g. "contacts[].email".
+	AllowedKeys []string
+
+	// Policy determines whether personal data is rejected or logged.
+	Policy PIIPolicy
+}
+
+// Validate implements Validator.
+func (v *PIIValidator) Validate(ctx context.Context, body interface{}) []ValidationError {
+	patterns := v.Patterns
+	if len(patterns) == 0 {
+		patterns = DefaultPIIPatterns
+	}
+
+	allowed := make(map[string]bool, len(v.AllowedKeys))
+	for _, k := range v.AllowedKeys {
+		allowed[k] = true
+	}
+
+	errs := make([]ValidationError, 0)
+
+	var walk func(key string, schemaKey string, val interface{})
+	walk = func(key string, schemaKey string, val interface{}) {
+		if allowed[schemaKey] {
+			return
+		}
+
+		switch val := val.(type) {
+		case map[string]interface{}:
+			keys := make([]string, 0, len(val))
+			for k := range val {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+
+			for _, k := range keys {
+				walk(joinKey(key, k), joinKey(schemaKey, k), val[k])
+			}
+		case []interface{}:
+			for i, elem := range val {
+				walk(fmt.Sprintf("%v[%v]", key, i), schemaKey+"[]", elem)
+			}
+		case string:
+			if kind, ok := findPII(patterns, val); ok {
+				errs = append(errs, ValidationError{Key: key, Code: CodePII, Params: map[string]string{"kind": kind}})
+			}
+		}
+	}
+	walk("", "", body)
+
+	if v.Policy == PIILog {
+		for _, e := range errs {
+			log.Printf("jsonbody: %v\n", e.Error())
+		}
+		return []ValidationError{}
+	}
+
+	return errs
+}
+
+// findPII returns the name of the first pattern that matches s.
+func findPII(patterns []PIIPattern, s string) (string, bool) {
+	for _, p := range patterns {
+		for _, match := range p.Pattern.FindAllString(s, -1) {
+			if p.Check == nil || p.Check(match) {
+				return p.Name, true
+			}
+		}
+	}
+
+	return "", false
+}
+
+// luhnValid reports whether the digits in s (ignoring other characters) pass
+// the Luhn checksum used by credit card numbers.
+func luhnValid(s string) bool {
+	sum := 0
+	double := false
+	for i := len(s) - 1; i >= 0; i-- {
+		c := s[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+
+		d := int(c - '0')
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+
+	return sum%10 == 0
+}
@@ -0,0 +1,37 @@
+package jsonbody
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLuhnValid(t *testing.T) {
+	assert.True(t, luhnValid("4111 1111 1111 1111"))
+	assert.True(t, luhnValid("5500-0000-0000-0004"))
+	assert.False(t, luhnValid("4111 1111 1111 1112"))
+}
+
+func TestPIIValidatorFlagsPIIOutsideAllowedKeys(t *testing.T) {
+	v := &PIIValidator{AllowedKeys: []string{"contacts[].email"}}
+
+	errs := v.Validate(context.Background(), bodyMap(`{
+		"contacts": [{"email": "a@example.com", "note": "card 4111 1111 1111 1111"}],
+		"title": "mail me at b@example.com",
+		"ssn": "123-45-6789",
+		"order": "1234 5678 9012 3456"
+	}`))
+	assert.Equal(t, []string{
+		"value for key 'contacts[0].note' appears to contain personal data (credit card number)",
+		"value for key 'ssn' appears to contain personal data (national ID number)",
+		"value for key 'title' appears to contain personal data (email address)",
+	}, errorMessages(errs))
+}
+
+func TestPIIValidatorLogsInsteadOfRejectingIfPolicyLog(t *testing.T) {
+	v := &PIIValidator{Policy: PIILog}
+
+	errs := v.Validate(context.Background(), bodyMap(`{"title": "b@example.com"}`))
+	assert.Equal(t, 0, len(errs))
+}
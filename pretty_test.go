@@ -0,0 +1,76 @@
+package jsonbody
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteJSONIndentsIfPretty(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	w := Writer{ResponseWriter: recorder, config: &writerConfig{pretty: true}}
+
+	assert.Nil(t, w.WriteJSON(http.StatusOK, map[string]interface{}{"a": []int{1}}))
+	assert.Equal(t, "{\n  \"a\": [\n    1\n  ]\n}", recorder.Body.String())
+}
+
+func TestWriteJSONDoesNotIndentCanonicalJSON(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	w := Writer{ResponseWriter: recorder, config: &writerConfig{canonical: true}, pretty: true}
+
+	assert.Nil(t, w.WriteJSON(http.StatusOK, map[string]interface{}{"b": 1, "a": []int{1}}))
+	assert.Equal(t, `{"a":[1],"b":1}`, recorder.Body.String())
+}
+
+func TestWriteJSONStreamIndentsIfPretty(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	w := Writer{ResponseWriter: recorder, pretty: true}
+
+	assert.Nil(t, w.WriteJSONStream(http.StatusOK, []int{1, 2}))
+	assert.Equal(t, "[\n  1,\n  2\n]", recorder.Body.String())
+}
+
+func TestWriteNDJSONStreamIgnoresPretty(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	w := Writer{ResponseWriter: recorder, config: &writerConfig{pretty: true}}
+
+	err := w.WriteNDJSONStream(http.StatusOK, func(send func(v interface{}) error) error {
+		return send(map[string]int{"a": 1})
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "{\"a\":1}\n", recorder.Body.String())
+}
+
+func TestServeHTTPIndentsIfRequested(t *testing.T) {
+	handler := NewMiddleware("", WithPrettyParam("pretty"))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writer := w.(Writer)
+		writer.WriteJSON(http.StatusOK, map[string]int{"a": 1})
+	}))
+
+	for _, tc := range []struct {
+		url  string
+		body string
+	}{
+		{"/", `{"a":1}`},
+		{"/?pretty=false", `{"a":1}`},
+		{"/?pretty=yes", `{"a":1}`},
+		{"/?pretty=true", "{\n  \"a\": 1\n}"},
+		{"/?pretty=1", "{\n  \"a\": 1\n}"},
+	} {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, tc.url, nil))
+		assert.Equal(t, tc.body, recorder.Body.String(), tc.url)
+	}
+}
+
+func TestServeHTTPIndentsErrorsWithPrettyJSON(t *testing.T) {
+	handler := NewMiddleware(`{"title": ""}`, WithPrettyJSON())(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	assert.Equal(t, "{\n  \"errors\": [\n    \"content type must be application/json\"\n  ]\n}", recorder.Body.String())
+}
@@ -0,0 +1,49 @@
+package jsonbody
+
+import "net/http"
+
+// problemMediaType is the Content-Type of problem details documents.
+const problemMediaType = "application/problem+json"
+
+// problem is a problem details document, as defined by RFC 7807, sent when
+// WithProblemDetails is used. Errors is an extension member holding the errors
+// in the same form as in the default error envelope.
+type problem struct {
+	Type   string      `json:"type"`
+	Title  string      `json:"title"`
+	Status int         `json:"status"`
+	Detail string      `json:"detail,omitempty"`
+	Errors interface{} `json:"errors,omitempty"`
+}
+
+// writeProblem sends errs, which have already been translated and converted to
+// the configured form, as a problem details document.
+func (w *Writer) writeProblem(statusCode int, errs interface{}, detail string) error {
+	mediaType := w.mediaType
+	w.mediaType = problemMediaType
+	defer func() { w.mediaType = mediaType }()
+
+	return w.WriteJSON(statusCode, problem{
+		Type:   w.config.problemType,
+		Title:  http.StatusText(statusCode),
+		Status: statusCode,
+		Detail: detail,
+		Errors: errs,
+	})
+}
+
+// writeServerError sends a 500 response, which has no body unless an
+// ErrorFormatter is configured or problem details are enabled.
+func (w *Writer) writeServerError() {
+	if w.config != nil && w.config.errorFormatter != nil {
+		w.writeFormattedErrors(http.StatusInternalServerError, []ValidationError{})
+		return
+	}
+
+	if w.config == nil || w.config.problemType == "" {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.writeProblem(http.StatusInternalServerError, nil, "")
+}
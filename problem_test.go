@@ -0,0 +1,47 @@
+package jsonbody
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServeHTTPSendsProblemDetailsIfConfigured(t *testing.T) {
+	handler := NewMiddleware(`{"title": "", "body": ""}`, WithProblemDetails(""))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	recorder := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"body": ""}`))
+	r.Header.Set("Content-Type", "application/json")
+	handler.ServeHTTP(recorder, r)
+
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	assert.Equal(t, "application/problem+json", recorder.Header().Get("Content-Type"))
+	assert.Equal(t, `{"type":"about:blank","title":"Bad Request","status":400,"detail":"expected key 'title' missing","errors":["expected key 'title' missing"]}`, recorder.Body.String())
+}
+
+func TestWriteErrorsSendsProblemDetailsWithErrorObjects(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	w := Writer{ResponseWriter: recorder, config: &writerConfig{problemType: "https://example.com/probs/invalid", errorObjects: true}}
+
+	err := w.writeErrors(422, ValidationError{Key: "a", Code: CodeMissingKey}, ValidationError{Key: "b", Code: CodeMissingKey})
+	assert.Nil(t, err)
+	assert.Equal(t, `{"type":"https://example.com/probs/invalid","title":"Unprocessable Entity","status":422,"errors":[{"field":"a","code":"missing_key","message":"expected key 'a' missing"},{"field":"b","code":"missing_key","message":"expected key 'b' missing"}]}`, recorder.Body.String())
+}
+
+func TestWriteServerErrorSendsProblemDetailsIfConfigured(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	w := Writer{ResponseWriter: recorder}
+	w.writeServerError()
+	assert.Equal(t, http.StatusInternalServerError, recorder.Code)
+	assert.Empty(t, recorder.Body.String())
+
+	recorder = httptest.NewRecorder()
+	w = Writer{ResponseWriter: recorder, config: &writerConfig{problemType: "about:blank"}}
+	w.writeServerError()
+	assert.Equal(t, http.StatusInternalServerError, recorder.Code)
+	assert.Equal(t, "application/problem+json", recorder.Header().Get("Content-Type"))
+	assert.Equal(t, `{"type":"about:blank","title":"Internal Server Error","status":500}`, recorder.Body.String())
+}
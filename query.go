@@ -0,0 +1,143 @@
+package jsonbody
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// schemaQueryKey is the top-level schema key whose value is the schema for the
+// request's query parameters.
+const schemaQueryKey = "$query"
+
+// extractQuerySchema removes the "$query" key from the top level of the schema
+// and returns its value, checking that it only uses types that query parameters
+// can have. Constraint objects (e.g. {"$enum": ["asc", "desc"]}) may be used
+// for parameters of those types, and are replaced with their parsed
+// *constraint.
+func extractQuerySchema(schema map[string]interface{}) (map[string]interface{}, error) {
+	val, ok := schema[schemaQueryKey]
+	if !ok {
+		return nil, nil
+	}
+	delete(schema, schemaQueryKey)
+
+	query, ok := val.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("jsonbody: value for schema key '%v' must be an object", schemaQueryKey)
+	}
+
+	for key, expected := range query {
+		arr, isArr := expected.([]interface{})
+		if isArr && len(arr) > 0 {
+			expected = arr[0]
+		}
+
+		switch e := expected.(type) {
+		case string, float64, bool, []interface{}:
+		case map[string]interface{}:
+			c, isConstraint, err := parseConstraint(e)
+			if err != nil {
+				return nil, err
+			}
+			if !isConstraint || c.typ == "object" || c.typ == "array" || c.hasDefault {
+				return nil, fmt.Errorf("jsonbody: constraint object for query parameter '%v' in schema must have a string, number, integer, or boolean type and no '%v'", strings.TrimPrefix(key, "?"), constraintDefaultKey)
+			}
+
+			if isArr {
+				arr[0] = &c
+			} else {
+				query[key] = &c
+			}
+		default:
+			return nil, fmt.Errorf("jsonbody: query parameter '%v' in schema must be a string, number, boolean, constraint object, or array of one of those", strings.TrimPrefix(key, "?"))
+		}
+	}
+
+	return query, nil
+}
+
+// validateQuery checks the query parameters of a request against the "$query"
+// schema. Since query parameters are always strings, a parameter matches a
+// number or boolean in the schema if it can be parsed as one. A parameter
+// matches an array if each of its values (e.g. "?tag=a&tag=b") matches the
+// array's element.
+func (v validator) validateQuery(expected map[string]interface{}, actual url.Values) []ValidationError {
+	errs := make([]ValidationError, 0)
+	for _, expectedKey := range v.keys(schemaQueryKey, expected) {
+		expectedVal := expected[expectedKey]
+		optional := strings.HasPrefix(expectedKey, "?")
+		expectedKey = strings.TrimPrefix(expectedKey, "?")
+
+		values, ok := actual[expectedKey]
+		if !ok {
+			if !optional {
+				errs = append(errs, ValidationError{Key: expectedKey, Code: CodeMissingParam})
+			}
+			continue
+		}
+
+		if arr, ok := expectedVal.([]interface{}); ok {
+			if len(arr) == 0 {
+				continue
+			}
+			expectedVal = arr[0]
+		} else {
+			values = values[:1]
+		}
+
+		for _, value := range values {
+			if c, ok := expectedVal.(*constraint); ok {
+				if constraintErrs := checkQueryConstraint(expectedKey, c, value); len(constraintErrs) > 0 {
+					errs = append(errs, constraintErrs...)
+					break
+				}
+				continue
+			}
+
+			if typ, ok := queryValueMatches(expectedVal, value); !ok {
+				errs = append(errs, ValidationError{Key: expectedKey, Code: CodeParamWrongType, Params: map[string]string{"type": typ}})
+				break
+			}
+		}
+	}
+
+	return errs
+}
+
+// checkQueryConstraint checks the query parameter value against c. The value is
+// converted to the constraint's type first or, if it has none, to the "$enum"
+// value it represents, if any.
+func checkQueryConstraint(key string, c *constraint, value string) []ValidationError {
+	var actual interface{} = value
+	if c.typ != "" {
+		actual = coerceScalar(c.typ, value)
+		if !matchesType(actual, c.typ) {
+			return []ValidationError{{Key: key, Code: CodeParamWrongType, Params: map[string]string{"type": c.typ}}}
+		}
+	} else {
+		for _, v := range c.enum {
+			if coerceScalar(jsonTypeName(v), value) == v {
+				actual = v
+				break
+			}
+		}
+	}
+
+	return c.validate(key, actual)
+}
+
+// queryValueMatches reports whether the query parameter value matches the type
+// of expected, along with the name of that type.
+func queryValueMatches(expected interface{}, value string) (string, bool) {
+	switch expected.(type) {
+	case float64:
+		_, err := strconv.ParseFloat(value, 64)
+		return "number", err == nil
+	case bool:
+		return "boolean", value == "true" || value == "false"
+	}
+
+	return "string", true
+}
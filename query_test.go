@@ -0,0 +1,95 @@
+package jsonbody
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestExtractQuerySchemaReturnsErrIfNotObject(t *testing.T) {
+	_, err := ParseSchema(`{"$query": ["page"]}`)
+	assert.NotNil(t, err)
+}
+
+func TestExtractQuerySchemaReturnsErrIfTypeUnsupported(t *testing.T) {
+	_, err := ParseSchema(`{"$query": {"filter": {}}}`)
+	assert.NotNil(t, err)
+}
+
+func TestParseSchemaAcceptsAnyBodyIfOnlyQuery(t *testing.T) {
+	schema, err := ParseSchema(`{"$schemaName": "ListPosts", "$query": {"page": 0}}`)
+	assert.Nil(t, err)
+	assert.True(t, schema.acceptsAnyBody())
+}
+
+func TestValidateQueryReportsErrors(t *testing.T) {
+	schema := MustParseSchema(`{"$query": {"page": 0, "?draft": false, "?tag": [0], "q": ""}}`)
+	query, _ := url.ParseQuery("page=two&draft=yes&tag=1&tag=x")
+
+	errs := schema.validate(OrderDeclaration, nil, query)
+	assert.Equal(t, []string{
+		"value for query parameter 'page' expected to be of type number",
+		"value for query parameter 'draft' expected to be of type boolean",
+		"value for query parameter 'tag' expected to be of type number",
+		"expected query parameter 'q' missing",
+	}, errorMessages(errs))
+}
+
+func TestValidateQueryAcceptsValidParams(t *testing.T) {
+	schema := MustParseSchema(`{"$query": {"page": 0, "?draft": false, "?tag": [""], "?ids": []}}`)
+	query, _ := url.ParseQuery("page=2&tag=a&tag=b&ids=1")
+
+	errs := schema.validate(OrderAlphabetical, nil, query)
+	assert.Equal(t, 0, len(errs))
+}
+
+func TestServeHTTPSends400IfQueryInvalid(t *testing.T) {
+	next := &mockHandler{}
+	next.On("ServeHTTP", mock.Anything, mock.Anything).Return()
+	handler := NewMiddleware(`{"$query": {"page": 0}}`)(next)
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/posts?page=x", nil))
+	assert.Equal(t, 400, recorder.Code)
+	assert.Equal(t, `{"errors":["value for query parameter 'page' expected to be of type number"]}`, recorder.Body.String())
+
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/posts?page=1", nil))
+	assert.Equal(t, 200, recorder.Code)
+}
+
+func TestValidateQueryChecksConstraints(t *testing.T) {
+	schema := MustParseSchema(`{"$query": {"?sort": {"$enum": ["asc", "desc"]}, "?limit": {"$type": "integer", "$min": 1}, "?ids": [{"$enum": [1, 2]}]}}`)
+
+	tests := []struct {
+		query string
+		msgs  []string
+	}{
+		{"sort=asc&limit=10&ids=1&ids=2", []string{}},
+		{"", []string{}},
+		{"sort=up", []string{`value for key 'sort' must be one of "asc", "desc"`}},
+		{"limit=0", []string{"value for key 'limit' must be at least 1"}},
+		{"limit=1.5", []string{"value for query parameter 'limit' expected to be of type integer"}},
+		{"ids=1&ids=3", []string{"value for key 'ids' must be one of 1, 2"}},
+	}
+
+	for _, test := range tests {
+		query, _ := url.ParseQuery(test.query)
+		assert.Equal(t, test.msgs, errorMessages(schema.validate(OrderAlphabetical, nil, query)), test.query)
+	}
+}
+
+func TestExtractQuerySchemaReturnsErrIfConstraintUnsupported(t *testing.T) {
+	for _, schemaJSON := range []string{
+		`{"$query": {"filter": {"$type": "object"}}}`,
+		`{"$query": {"?sort": {"$enum": ["asc"], "$default": "asc"}}}`,
+		`{"$query": {"sort": {"$enum": []}}}`,
+	} {
+		_, err := ParseSchema(schemaJSON)
+		assert.NotNil(t, err, schemaJSON)
+	}
+}
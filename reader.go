@@ -1,16 +1,136 @@
 package jsonbody
 
-import "io"
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+)
 
-// Reader is an extension of a generic io.Reader. It provides a method for
-// retrieving the JSON request body as a map[string]interface{}.
+// Reader is an extension of a generic io.Reader. It provides methods for
+// retrieving the decoded JSON request body. Since the body is buffered by the
+// middleware, Reader also implements io.Seeker, allowing the raw body to be read
+// more than once.
 type Reader struct {
 	io.ReadCloser
-	json map[string]interface{}
+	json       interface{} // the decoded body, or nil if there is none
+	ordered    *OrderedMap // set by WithOrderedJSON for object bodies
+	schemaName string
 }
 
-// JSON returns a a map[string]interface{} representing the request body. See the
-// documentation for encoding/json regarding how the map represents the JSON data.
+// JSON returns a a map[string]interface{} representing the request body, or nil
+// if the body is not an object. See the documentation for encoding/json
+// regarding how the map represents the JSON data.
 func (r Reader) JSON() map[string]interface{} {
+	obj, _ := r.json.(map[string]interface{})
+	return obj
+}
+
+// JSONValue returns the decoded request body, whatever its type: a
+// map[string]interface{}, []interface{}, string, float64, or bool, or nil if
+// there is no body.
+func (r Reader) JSONValue() interface{} {
 	return r.json
 }
+
+// JSONArray returns a []interface{} representing the request body, or nil if the
+// body is not an array.
+func (r Reader) JSONArray() []interface{} {
+	arr, _ := r.json.([]interface{})
+	return arr
+}
+
+// OrderedJSON returns the request body as an OrderedMap, whose keys, like those
+// of the nested objects in it, are in the order they appear in the raw body. It
+// returns nil unless the middleware was created with WithOrderedJSON and the
+// body is an object. Unlike JSON, it doesn't include defaults or values
+// converted by WithCoercion.
+func (r Reader) OrderedJSON() *OrderedMap {
+	return r.ordered
+}
+
+// SchemaName returns the name of the schema the request body was validated
+// against, as set by the schema's "$schemaName" key, or "" if the schema has no
+// name.
+func (r Reader) SchemaName() string {
+	return r.schemaName
+}
+
+// Unmarshal decodes the raw request body into v in the same way as
+// json.Unmarshal, so v's UnmarshalJSON methods and struct tags are honored. The
+// body is read from the beginning and rewound afterward, so Unmarshal can be
+// called more than once and the body can still be read by the handler. Unlike
+// BindMap, it parses the body a second time.
+func (r Reader) Unmarshal(v interface{}) error {
+	if seeker, ok := r.ReadCloser.(io.Seeker); ok {
+		if _, err := seeker.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		defer seeker.Seek(0, io.SeekStart)
+	}
+
+	err := json.NewDecoder(r.ReadCloser).Decode(v)
+	if err == io.EOF {
+		return errors.New("jsonbody: request body is empty")
+	}
+
+	return err
+}
+
+// Seek implements io.Seeker, setting the offset for the next Read of the raw
+// request body.
+func (r Reader) Seek(offset int64, whence int) (int64, error) {
+	seeker, ok := r.ReadCloser.(io.Seeker)
+	if !ok {
+		return 0, errors.New("jsonbody: request body is not seekable")
+	}
+
+	return seeker.Seek(offset, whence)
+}
+
+// Reset rewinds the raw request body so that it can be read again from the
+// beginning.
+func (r Reader) Reset() error {
+	_, err := r.Seek(0, io.SeekStart)
+	return err
+}
+
+// Size returns the size of the raw request body in bytes, or -1 if it is
+// unknown.
+func (r Reader) Size() int64 {
+	switch body := r.ReadCloser.(type) {
+	case bodyBuffer:
+		return body.Reader.Size()
+	case spilledBody:
+		return body.size()
+	}
+
+	return -1
+}
+
+// GetBody returns a new reader of the raw request body, independent of r and of
+// any other reader it returned. It has the signature of http.Request.GetBody,
+// which the middleware sets to it, so that httputil.ReverseProxy and retrying
+// transports can resend the body after a connection failure. The readers it
+// returns must not be used after the handler returns.
+func (r Reader) GetBody() (io.ReadCloser, error) {
+	switch body := r.ReadCloser.(type) {
+	case bodyBuffer:
+		return io.NopCloser(io.NewSectionReader(body.Reader, 0, body.Reader.Size())), nil
+	case spilledBody:
+		return io.NopCloser(io.NewSectionReader(body.File, 0, r.Size())), nil
+	}
+
+	return nil, errors.New("jsonbody: request body is not buffered")
+}
+
+// bodyBuffer holds a request body that has been read into memory. Unlike the
+// value returned by ioutil.NopCloser, it preserves the Seek method of the
+// underlying bytes.Reader.
+type bodyBuffer struct {
+	*bytes.Reader
+}
+
+func (b bodyBuffer) Close() error {
+	return nil
+}
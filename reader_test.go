@@ -0,0 +1,95 @@
+package jsonbody
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSeekAllowsBodyToBeReread(t *testing.T) {
+	r := Reader{ReadCloser: bodyBuffer{bytes.NewReader([]byte(`{"a": 1}`))}}
+
+	ioutil.ReadAll(r)
+	pos, err := r.Seek(2, io.SeekStart)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), pos)
+
+	rest, _ := ioutil.ReadAll(r)
+	assert.Equal(t, `a": 1}`, string(rest))
+}
+
+func TestSeekReturnsErrIfBodyNotSeekable(t *testing.T) {
+	r := Reader{ReadCloser: ioutil.NopCloser(strings.NewReader("{}"))}
+
+	_, err := r.Seek(0, io.SeekStart)
+	assert.NotNil(t, err)
+}
+
+func TestResetRewindsBody(t *testing.T) {
+	r := Reader{ReadCloser: bodyBuffer{bytes.NewReader([]byte(`{}`))}}
+
+	ioutil.ReadAll(r)
+	err := r.Reset()
+	assert.Nil(t, err)
+
+	body, _ := ioutil.ReadAll(r)
+	assert.Equal(t, "{}", string(body))
+}
+
+func TestUnmarshalDecodesBufferedBody(t *testing.T) {
+	r := Reader{ReadCloser: bodyBuffer{bytes.NewReader([]byte(`{"title": "hi", "upvotes": 3}`))}}
+	ioutil.ReadAll(r)
+
+	var post struct {
+		Title   string `json:"title"`
+		Upvotes int    `json:"upvotes"`
+	}
+	assert.Nil(t, r.Unmarshal(&post))
+	assert.Equal(t, "hi", post.Title)
+	assert.Equal(t, 3, post.Upvotes)
+
+	raw, _ := ioutil.ReadAll(r)
+	assert.Equal(t, `{"title": "hi", "upvotes": 3}`, string(raw))
+}
+
+func TestUnmarshalReturnsErrForEmptyOrMismatchedBody(t *testing.T) {
+	var v struct{ A string }
+	r := Reader{ReadCloser: bodyBuffer{bytes.NewReader(nil)}}
+	assert.NotNil(t, r.Unmarshal(&v))
+
+	r = Reader{ReadCloser: bodyBuffer{bytes.NewReader([]byte(`{"A": 1}`))}}
+	assert.NotNil(t, r.Unmarshal(&v))
+}
+
+func TestUnmarshalReadsUnseekableBody(t *testing.T) {
+	var v struct{ A string }
+	r := Reader{ReadCloser: ioutil.NopCloser(strings.NewReader(`{"A": "x"}`))}
+	assert.Nil(t, r.Unmarshal(&v))
+	assert.Equal(t, "x", v.A)
+}
+
+func TestGetBodyReturnsIndependentReaders(t *testing.T) {
+	r := Reader{ReadCloser: bodyBuffer{bytes.NewReader([]byte(`{"a": 1}`))}}
+	ioutil.ReadAll(r)
+
+	first, err := r.GetBody()
+	assert.Nil(t, err)
+	second, err := r.GetBody()
+	assert.Nil(t, err)
+
+	b, _ := ioutil.ReadAll(first)
+	assert.Equal(t, `{"a": 1}`, string(b))
+	b, _ = ioutil.ReadAll(second)
+	assert.Equal(t, `{"a": 1}`, string(b))
+}
+
+func TestGetBodyReturnsErrForUnbufferedBody(t *testing.T) {
+	r := Reader{ReadCloser: ioutil.NopCloser(strings.NewReader(`{}`))}
+
+	_, err := r.GetBody()
+	assert.NotNil(t, err)
+}
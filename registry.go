@@ -0,0 +1,177 @@
+package jsonbody
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// Registry is a SchemaResolver that loads schema documents from a remote HTTP
+// registry, allowing a fleet of services to share centrally managed schemas.
+// Each document is fetched with a GET request and kept in memory; after the
+// refresh interval it is revalidated with a conditional request using its ETag,
+// so unchanged schemas aren't downloaded again. Documents are fetched per route
+// pattern (see WithRoutePatterns) rather than per path, and at most 1024 of
+// them are kept, evicting the least recently fetched.
+//
+// The registry should respond with the schema document (in the same format as
+// the schemaJSON passed to NewMiddleware) and a 200 status, or with a 404 status
+// if it has no schema for the request, in which case the middleware's own
+// schema is used.
+//
+// Use it with the WithSchemaResolver option.
+type Registry struct {
+	baseURL string
+	client  *http.Client
+	refresh time.Duration
+
+	// URL returns the URL of the schema document for the given tenant, route,
+	// and method. The route is a route pattern, e.g. "/posts/{id}", or the
+	// path of a request that matches no pattern. The default returns
+	// <baseURL>/<method><route>, with a "tenant" query parameter if tenantID
+	// isn't "".
+	URL func(tenantID string, route string, method string) string
+
+	// GracePeriod is how long bodies that match the previous version of a
+	// schema are still accepted after the registry sends a new version. See
+	// RotateSchema. By default, new versions take effect immediately.
+	GracePeriod time.Duration
+
+	mu      sync.Mutex
+	entries map[string]*registryEntry
+}
+
+// maxRegistryEntries is the maximum number of schema documents a Registry keeps
+// in memory.
+const maxRegistryEntries = 1024
+
+type registryEntry struct {
+	schema    *Schema
+	etag      string
+	fetchedAt time.Time
+}
+
+// NewRegistry creates a Registry that loads schemas from the registry at
+// baseURL using client, revalidating each schema at most once per refresh
+// interval. If client is nil, http.DefaultClient is used.
+func NewRegistry(baseURL string, client *http.Client, refresh time.Duration) *Registry {
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	return &Registry{
+		baseURL: baseURL,
+		client:  client,
+		refresh: refresh,
+		entries: make(map[string]*registryEntry),
+	}
+}
+
+func (reg *Registry) documentURL(tenantID string, route string, method string) string {
+	if reg.URL != nil {
+		return reg.URL(tenantID, route, method)
+	}
+
+	u := reg.baseURL + "/" + url.PathEscape(method) + route
+	if tenantID != "" {
+		u += "?tenant=" + url.QueryEscape(tenantID)
+	}
+
+	return u
+}
+
+// Resolve implements SchemaResolver.
+func (reg *Registry) Resolve(ctx context.Context, tenantID string, route string, method string) (*Schema, error) {
+	docURL := reg.documentURL(tenantID, route, method)
+
+	reg.mu.Lock()
+	entry, ok := reg.entries[docURL]
+	reg.mu.Unlock()
+
+	if ok && time.Since(entry.fetchedAt) < reg.refresh {
+		return entry.schema, nil
+	}
+
+	updated, err := reg.fetch(ctx, docURL, entry)
+	if err != nil {
+		return nil, err
+	}
+
+	reg.mu.Lock()
+	if _, ok := reg.entries[docURL]; !ok && len(reg.entries) >= maxRegistryEntries {
+		reg.evictOldest()
+	}
+	reg.entries[docURL] = updated
+	reg.mu.Unlock()
+
+	return updated.schema, nil
+}
+
+// evictOldest removes the least recently fetched entry. reg.mu must be held.
+func (reg *Registry) evictOldest() {
+	var oldest string
+	var oldestAt time.Time
+	for docURL, entry := range reg.entries {
+		if oldest == "" || entry.fetchedAt.Before(oldestAt) {
+			oldest, oldestAt = docURL, entry.fetchedAt
+		}
+	}
+
+	delete(reg.entries, oldest)
+}
+
+// fetch downloads the schema document at docURL, revalidating the previous
+// entry if there is one.
+func (reg *Registry) fetch(ctx context.Context, docURL string, prev *registryEntry) (*registryEntry, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, docURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if prev != nil && prev.etag != "" {
+		req.Header.Set("If-None-Match", prev.etag)
+	}
+
+	resp, err := reg.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusNotModified:
+		if prev == nil {
+			return nil, fmt.Errorf("jsonbody: registry sent 304 for unknown schema %v", docURL)
+		}
+		return &registryEntry{schema: prev.schema, etag: prev.etag, fetchedAt: time.Now()}, nil
+	case http.StatusNotFound:
+		return &registryEntry{fetchedAt: time.Now()}, nil
+	case http.StatusOK:
+	default:
+		return nil, fmt.Errorf("jsonbody: registry sent unexpected status %v for schema %v", resp.StatusCode, docURL)
+	}
+
+	doc, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	schema, err := ParseSchema(string(doc))
+	if err != nil {
+		return nil, fmt.Errorf("jsonbody: invalid schema %v: %v", docURL, err)
+	}
+
+	if prev != nil && prev.schema != nil {
+		schema = RotateSchema(prev.schema, schema, reg.GracePeriod)
+	}
+
+	return &registryEntry{
+		schema:    schema,
+		etag:      resp.Header.Get("ETag"),
+		fetchedAt: time.Now(),
+	}, nil
+}
@@ -0,0 +1,97 @@
+package jsonbody
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestRegistryFetchesAndRevalidatesSchemas(t *testing.T) {
+	requests := make([]*http.Request, 0)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r)
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Write([]byte(`{"$schemaName": "CreatePost"}`))
+	}))
+	defer server.Close()
+
+	reg := NewRegistry(server.URL, nil, 0)
+	for i := 0; i < 2; i++ {
+		schema, err := reg.Resolve(context.Background(), "acme", "/posts", "POST")
+		assert.Nil(t, err)
+		assert.Equal(t, "CreatePost", schema.Name())
+	}
+
+	assert.Equal(t, 2, len(requests))
+	assert.Equal(t, "/POST/posts", requests[0].URL.Path)
+	assert.Equal(t, "acme", requests[0].URL.Query().Get("tenant"))
+	assert.Equal(t, `"v1"`, requests[1].Header.Get("If-None-Match"))
+}
+
+func TestRegistryReturnsNilSchemaIfNotFound(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+
+	schema, err := NewRegistry(server.URL, nil, 0).Resolve(context.Background(), "", "/posts", "POST")
+	assert.Nil(t, err)
+	assert.Nil(t, schema)
+}
+
+func TestRegistryReturnsErrOnServerError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	_, err := NewRegistry(server.URL, nil, 0).Resolve(context.Background(), "", "/posts", "POST")
+	assert.NotNil(t, err)
+}
+
+func TestServeHTTPFetchesRegistrySchemasByRoutePattern(t *testing.T) {
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	next := &mockHandler{}
+	next.On("ServeHTTP", mock.Anything, mock.Anything).Return()
+	reg := NewRegistry(server.URL, nil, time.Hour)
+	handler := NewMiddleware("", WithRoutePatterns("/posts/{id}"), WithSchemaResolver(reg, nil, 0))(next)
+
+	for _, path := range []string{"/posts/1", "/posts/2"} {
+		r := httptest.NewRequest(http.MethodPost, path, strings.NewReader(`{}`))
+		r.Header.Set("Content-Type", "application/json")
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, r)
+		assert.Equal(t, 200, recorder.Code)
+	}
+
+	assert.Equal(t, []string{"/POST/posts/{id}"}, paths)
+}
+
+func TestRegistryBoundsEntries(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+
+	reg := NewRegistry(server.URL, nil, time.Hour)
+	for i := 0; i < maxRegistryEntries+10; i++ {
+		_, err := reg.Resolve(context.Background(), "", fmt.Sprint("/posts/", i), "POST")
+		assert.Nil(t, err)
+	}
+
+	assert.Len(t, reg.entries, maxRegistryEntries)
+	assert.NotContains(t, reg.entries, server.URL+"/POST/posts/0")
+}
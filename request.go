@@ -0,0 +1,72 @@
+package jsonbody
+
+import (
+	"log"
+	"net/http"
+	"net/url"
+)
+
+type pathParamsContextKey struct{}
+
+// PathParams returns the values of the path parameters (e.g. "{id}") in the
+// route pattern that matched r, if the middleware selected r's schema from the
+// routes configured with WithRouteSchema, NewMiddlewareFromBundle, or
+// NewMiddlewareFromFS.
+func PathParams(r *http.Request) map[string]string {
+	params, _ := r.Context().Value(pathParamsContextKey{}).(map[string]string)
+	return params
+}
+
+// Request bundles the parts of a request that has passed through the
+// middleware, with the body stored in a value of type T. It is passed to the
+// handler by Handle.
+type Request[T any] struct {
+	// Body is the request body, bound to T in the same way as Reader.BindMap.
+	Body T
+
+	// PathParams holds the values of the route's path parameters; see
+	// PathParams.
+	PathParams map[string]string
+
+	// Query holds the request's query parameters.
+	Query url.Values
+
+	// Header holds the request's headers.
+	Header http.Header
+
+	// HTTP is the underlying request.
+	HTTP *http.Request
+}
+
+// Handle creates an http.Handler that passes each request to handler as a
+// Request[T], giving handlers a single typed entry point. The returned handler
+// must be wrapped by the middleware, which validates the body and query before
+// handler is called; path parameters aren't validated. If the validated body can't be stored in a T (i.e.
+// the schema and T disagree), a 500 response is sent without calling handler;
+// see WithBindErrors for numbers that don't fit in T.
+func Handle[T any](handler func(w Writer, req Request[T])) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writer, ok := w.(Writer)
+		reader, ok2 := r.Body.(Reader)
+		if !ok || !ok2 {
+			log.Println("jsonbody: handler created by Handle must be wrapped by the middleware")
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		req := Request[T]{
+			PathParams: PathParams(r),
+			Query:      r.URL.Query(),
+			Header:     r.Header,
+			HTTP:       r,
+		}
+
+		err := bindValue(reader.json, &req.Body)
+		if err != nil {
+			writer.writeBindError(err)
+			return
+		}
+
+		handler(writer, req)
+	})
+}
@@ -0,0 +1,77 @@
+package jsonbody
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type createComment struct {
+	Text string `json:"text"`
+}
+
+func TestHandlePassesTypedRequest(t *testing.T) {
+	mw, err := NewMiddlewareFromBundle(strings.NewReader(`{"POST /posts/{postID}/comments": {"text": "", "$query": {"?notify": false}}}`))
+	assert.Nil(t, err)
+
+	var got Request[createComment]
+	handler := mw(Handle(func(w Writer, req Request[createComment]) {
+		got = req
+		w.WriteJSON(201, req.Body)
+	}))
+
+	recorder := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/posts/42/comments?notify=true", strings.NewReader(`{"text": "hi"}`))
+	r.Header.Set("Content-Type", "application/json")
+	handler.ServeHTTP(recorder, r)
+
+	assert.Equal(t, 201, recorder.Code)
+	assert.Equal(t, createComment{Text: "hi"}, got.Body)
+	assert.Equal(t, map[string]string{"postID": "42"}, got.PathParams)
+	assert.Equal(t, "true", got.Query.Get("notify"))
+	assert.Equal(t, "application/json", got.Header.Get("Content-Type"))
+	assert.Equal(t, "/posts/42/comments", got.HTTP.URL.Path)
+}
+
+func TestHandleSends500IfBodyCannotBeBound(t *testing.T) {
+	called := false
+	handler := NewMiddleware(`{"text": 0}`)(Handle(func(w Writer, req Request[createComment]) {
+		called = true
+	}))
+
+	recorder := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"text": 1}`))
+	r.Header.Set("Content-Type", "application/json")
+	handler.ServeHTTP(recorder, r)
+
+	assert.Equal(t, 500, recorder.Code)
+	assert.False(t, called)
+}
+
+func TestHandleSends400ForUnrepresentableNumbersWithBindErrors(t *testing.T) {
+	called := false
+	handler := NewMiddleware(`{"count": 0}`, WithBindErrors())(Handle(func(w Writer, req Request[struct {
+		Count uint `json:"count"`
+	}]) {
+		called = true
+	}))
+
+	recorder := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"count": -1}`))
+	r.Header.Set("Content-Type", "application/json")
+	handler.ServeHTTP(recorder, r)
+
+	assert.Equal(t, 400, recorder.Code)
+	assert.JSONEq(t, `{"errors": ["value for key 'count' is invalid: value is out of range"]}`, recorder.Body.String())
+	assert.False(t, called)
+}
+
+func TestHandleSends500IfNotWrapped(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	Handle(func(w Writer, req Request[createComment]) {}).ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	assert.Equal(t, 500, recorder.Code)
+}
@@ -0,0 +1,172 @@
+package jsonbody
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// SchemaResolver looks up the schema to validate a request against at request
+// time, allowing e.g. multi-tenant platforms to serve tenant-customized schemas
+// fetched from a datastore. The route is the route pattern the request's path
+// matches, e.g. "/posts/{id}" (see WithRoutePatterns), or the path itself if
+// it matches none. A nil
+// *Schema with a nil error means that the middleware's own schema should be
+// used.
+type SchemaResolver interface {
+	Resolve(ctx context.Context, tenantID string, route string, method string) (*Schema, error)
+}
+
+// SchemaResolverFunc is an adapter allowing an ordinary function to be used as
+// a SchemaResolver.
+type SchemaResolverFunc func(ctx context.Context, tenantID string, route string, method string) (*Schema, error)
+
+// Resolve calls f(ctx, tenantID, route, method).
+func (f SchemaResolverFunc) Resolve(ctx context.Context, tenantID string, route string, method string) (*Schema, error) {
+	return f(ctx, tenantID, route, method)
+}
+
+// FallbackPolicy determines how the middleware handles requests when its
+// SchemaResolver fails, e.g. because a remote registry is unavailable.
+type FallbackPolicy int
+
+const (
+	// FallbackReject rejects requests with a 503 error response. This is the
+	// default.
+	FallbackReject FallbackPolicy = iota
+
+	// FallbackLastKnownGood validates requests against the last schema the
+	// resolver successfully returned for the same tenant, route, and method
+	// (or against the middleware's own schema, if the resolver last returned a
+	// nil *Schema), rejecting them as FallbackReject does if there isn't one.
+	FallbackLastKnownGood
+
+	// FallbackObserveOnly passes requests to the handler without validating
+	// them.
+	FallbackObserveOnly
+)
+
+type resolverConfig struct {
+	resolver SchemaResolver
+	tenantID func(*http.Request) string
+	ttl      time.Duration
+
+	policy     FallbackPolicy
+	onFallback func(*http.Request, error)
+
+	mu        sync.Mutex
+	cache     map[resolverKey]resolvedSchema
+	lastGood  map[resolverKey]*Schema
+	lastSweep time.Time
+}
+
+// maxResolverEntries is the maximum number of last known good schemas kept by
+// a resolverConfig. Routes are identified by pattern, so this is only reached
+// by requests to many paths that match no pattern.
+const maxResolverEntries = 1024
+
+type resolverKey struct {
+	tenantID string
+	route    string
+	method   string
+}
+
+type resolvedSchema struct {
+	schema  *Schema
+	expires time.Time
+}
+
+// resolve returns the schema for r, whose route pattern is route, using a
+// cached schema if one was resolved for the same tenant, route, and method
+// within the TTL.
+func (c *resolverConfig) resolve(r *http.Request, route string) (*Schema, error) {
+	key := c.key(r, route)
+
+	if c.ttl > 0 {
+		c.mu.Lock()
+		cached, ok := c.cache[key]
+		c.mu.Unlock()
+
+		if ok && time.Now().Before(cached.expires) {
+			return cached.schema, nil
+		}
+	}
+
+	schema, err := c.resolver.Resolve(r.Context(), key.tenantID, key.route, key.method)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	if c.ttl > 0 {
+		if c.cache == nil {
+			c.cache = make(map[resolverKey]resolvedSchema)
+		}
+
+		now := time.Now()
+		if now.Sub(c.lastSweep) > time.Minute {
+			for k, cached := range c.cache {
+				if now.After(cached.expires) {
+					delete(c.cache, k)
+				}
+			}
+			c.lastSweep = now
+		}
+
+		c.cache[key] = resolvedSchema{schema: schema, expires: now.Add(c.ttl)}
+	}
+
+	if c.policy == FallbackLastKnownGood {
+		if c.lastGood == nil {
+			c.lastGood = make(map[resolverKey]*Schema)
+		}
+		if _, ok := c.lastGood[key]; !ok && len(c.lastGood) >= maxResolverEntries {
+			for k := range c.lastGood {
+				delete(c.lastGood, k)
+				break
+			}
+		}
+		c.lastGood[key] = schema
+	}
+	c.mu.Unlock()
+
+	return schema, nil
+}
+
+func (c *resolverConfig) key(r *http.Request, route string) resolverKey {
+	key := resolverKey{route: route, method: r.Method}
+	if c.tenantID != nil {
+		key.tenantID = c.tenantID(r)
+	}
+
+	return key
+}
+
+// fallback applies the fallback policy after the resolver failed with err. It
+// returns the schema to validate r against (nil meaning no validation), or
+// false if r should be rejected. The last known good schema for r, whose route
+// pattern is route, is defaultSchema if the resolver last returned a nil
+// *Schema for it.
+func (c *resolverConfig) fallback(r *http.Request, route string, err error, defaultSchema *Schema) (*Schema, bool) {
+	log.Printf("jsonbody: failed to resolve schema for %v %v: %v\n", r.Method, r.URL.Path, err)
+	if c.onFallback != nil {
+		c.onFallback(r, err)
+	}
+
+	switch c.policy {
+	case FallbackLastKnownGood:
+		c.mu.Lock()
+		schema, ok := c.lastGood[c.key(r, route)]
+		c.mu.Unlock()
+		if ok && schema == nil {
+			schema = defaultSchema
+		}
+		return schema, ok
+	case FallbackObserveOnly:
+		return nil, true
+	}
+
+	return nil, false
+}
@@ -0,0 +1,196 @@
+package jsonbody
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func tenantHeader(r *http.Request) string {
+	return r.Header.Get("X-Tenant")
+}
+
+func TestResolverConfigCachesSchemas(t *testing.T) {
+	calls := 0
+	c := &resolverConfig{
+		resolver: SchemaResolverFunc(func(ctx context.Context, tenantID, route, method string) (*Schema, error) {
+			calls++
+			return MustParseSchema(`{"$schemaName": "` + tenantID + ` ` + method + ` ` + route + `"}`), nil
+		}),
+		tenantID: tenantHeader,
+		ttl:      time.Minute,
+	}
+
+	r := httptest.NewRequest(http.MethodPost, "/posts", nil)
+	r.Header.Set("X-Tenant", "acme")
+
+	for i := 0; i < 2; i++ {
+		schema, err := c.resolve(r, "/posts")
+		assert.Nil(t, err)
+		assert.Equal(t, "acme POST /posts", schema.Name())
+	}
+
+	assert.Equal(t, 1, calls)
+}
+
+func TestServeHTTPValidatesAgainstResolvedSchema(t *testing.T) {
+	next := &mockHandler{}
+	next.On("ServeHTTP", mock.Anything, mock.Anything).Return()
+	resolver := SchemaResolverFunc(func(ctx context.Context, tenantID, route, method string) (*Schema, error) {
+		if tenantID == "acme" {
+			return MustParseSchema(`{"sku": ""}`), nil
+		}
+		return nil, nil
+	})
+	handler := NewMiddleware(`{"name": ""}`, WithSchemaResolver(resolver, tenantHeader, 0))(next)
+
+	recorder := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"name": "x"}`))
+	r.Header.Set("Content-Type", "application/json")
+	r.Header.Set("X-Tenant", "acme")
+	handler.ServeHTTP(recorder, r)
+
+	assert.Equal(t, `{"errors":["expected key 'sku' missing"]}`, recorder.Body.String())
+
+	recorder = httptest.NewRecorder()
+	r = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"name": "x"}`))
+	r.Header.Set("Content-Type", "application/json")
+	handler.ServeHTTP(recorder, r)
+
+	assert.Equal(t, 200, recorder.Code)
+}
+
+func TestServeHTTPSends503IfResolverFails(t *testing.T) {
+	next := &mockHandler{}
+	next.On("ServeHTTP", mock.Anything, mock.Anything).Return()
+	resolver := SchemaResolverFunc(func(ctx context.Context, tenantID, route, method string) (*Schema, error) {
+		return nil, errors.New("datastore down")
+	})
+	handler := NewMiddleware("", WithSchemaResolver(resolver, nil, 0))(next)
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{}`)))
+
+	assert.Equal(t, 503, recorder.Code)
+	next.AssertNotCalled(t, "ServeHTTP", mock.Anything, mock.Anything)
+}
+
+func TestResolverConfigFallsBackToLastKnownGood(t *testing.T) {
+	fail := false
+	c := &resolverConfig{
+		resolver: SchemaResolverFunc(func(ctx context.Context, tenantID, route, method string) (*Schema, error) {
+			if fail {
+				return nil, errors.New("registry down")
+			}
+			return MustParseSchema(`{"$schemaName": "good"}`), nil
+		}),
+		policy: FallbackLastKnownGood,
+	}
+	r := httptest.NewRequest(http.MethodPost, "/posts", nil)
+
+	c.resolve(r, "/posts")
+	fail = true
+	_, err := c.resolve(r, "/posts")
+
+	schema, ok := c.fallback(r, "/posts", err, nil)
+	assert.True(t, ok)
+	assert.Equal(t, "good", schema.Name())
+
+	_, ok = c.fallback(httptest.NewRequest(http.MethodPost, "/other", nil), "/other", err, nil)
+	assert.False(t, ok)
+}
+
+func TestServeHTTPSkipsValidationIfObserveOnlyFallback(t *testing.T) {
+	next := &mockHandler{}
+	next.On("ServeHTTP", mock.Anything, mock.Anything).Return()
+	resolver := SchemaResolverFunc(func(ctx context.Context, tenantID, route, method string) (*Schema, error) {
+		return nil, errors.New("registry down")
+	})
+
+	var fallbackErr error
+	handler := NewMiddleware(`{"name": ""}`,
+		WithSchemaResolver(resolver, nil, 0),
+		WithSchemaFallback(FallbackObserveOnly, func(r *http.Request, err error) { fallbackErr = err }),
+	)(next)
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{}`)))
+
+	assert.Equal(t, 200, recorder.Code)
+	assert.NotNil(t, fallbackErr)
+}
+
+func TestServeHTTPFallsBackToOwnSchemaIfLastKnownGoodIsNil(t *testing.T) {
+	next := &mockHandler{}
+	next.On("ServeHTTP", mock.Anything, mock.Anything).Return()
+	fail := false
+	resolver := SchemaResolverFunc(func(ctx context.Context, tenantID, route, method string) (*Schema, error) {
+		if fail {
+			return nil, errors.New("registry down")
+		}
+		return nil, nil
+	})
+
+	// WithSchemaFallback may be passed before WithSchemaResolver.
+	handler := NewMiddleware(`{"name": ""}`,
+		WithSchemaFallback(FallbackLastKnownGood, nil),
+		WithSchemaResolver(resolver, nil, 0),
+	)(next)
+
+	send := func(body string) int {
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+		return recorder.Code
+	}
+
+	assert.Equal(t, 200, send(`{"name": "a"}`))
+
+	fail = true
+	assert.Equal(t, 400, send(`{}`))
+	assert.Equal(t, 200, send(`{"name": "a"}`))
+}
+
+func TestServeHTTPResolvesSchemasByRoutePattern(t *testing.T) {
+	next := &mockHandler{}
+	next.On("ServeHTTP", mock.Anything, mock.Anything).Return()
+	var routes []string
+	resolver := SchemaResolverFunc(func(ctx context.Context, tenantID, route, method string) (*Schema, error) {
+		routes = append(routes, route)
+		return nil, nil
+	})
+	handler := NewMiddleware("", WithRoutePatterns("/posts/{id}"), WithSchemaResolver(resolver, nil, time.Minute))(next)
+
+	for _, path := range []string{"/posts/1", "/posts/2", "/other"} {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, path, strings.NewReader(`{}`)))
+		assert.Equal(t, 200, recorder.Code)
+	}
+
+	assert.Equal(t, []string{"/posts/{id}", "/other"}, routes)
+}
+
+func TestResolverConfigBoundsLastKnownGood(t *testing.T) {
+	c := &resolverConfig{
+		resolver: SchemaResolverFunc(func(ctx context.Context, tenantID, route, method string) (*Schema, error) {
+			return nil, nil
+		}),
+		policy: FallbackLastKnownGood,
+	}
+
+	r := httptest.NewRequest(http.MethodPost, "/", nil)
+	for i := 0; i < maxResolverEntries*2; i++ {
+		c.resolve(r, fmt.Sprint("/posts/", i))
+	}
+
+	assert.Len(t, c.lastGood, maxResolverEntries)
+}
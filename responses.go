@@ -0,0 +1,41 @@
+package jsonbody
+
+import (
+	"errors"
+	"net/http"
+)
+
+// Created sends body with a 201 Created status code in the same way as
+// WriteJSON, setting the Location header to location unless it is empty.
+func (w *Writer) Created(location string, body interface{}) error {
+	if location != "" && !w.written {
+		w.Header().Set("Location", location)
+	}
+
+	return w.WriteJSON(http.StatusCreated, body)
+}
+
+// NoContent sends a 204 No Content response, which has no body. Like WriteJSON,
+// it can only be called once.
+func (w *Writer) NoContent() error {
+	if w.written {
+		return errors.New("method has already been called once and cannot be called again")
+	}
+
+	w.setDefaultHeaders()
+	w.WriteHeader(http.StatusNoContent)
+	w.written = true
+	return nil
+}
+
+// NotFound sends msg with a 404 Not Found status code in the same way as
+// WriteErrors.
+func (w *Writer) NotFound(msg string) error {
+	return w.WriteErrors(http.StatusNotFound, msg)
+}
+
+// Conflict sends msg with a 409 Conflict status code in the same way as
+// WriteErrors.
+func (w *Writer) Conflict(msg string) error {
+	return w.WriteErrors(http.StatusConflict, msg)
+}
@@ -0,0 +1,52 @@
+package jsonbody
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreatedSetsLocation(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	w := Writer{ResponseWriter: recorder}
+
+	assert.Nil(t, w.Created("/posts/1", map[string]string{"id": "1"}))
+	assert.Equal(t, http.StatusCreated, recorder.Code)
+	assert.Equal(t, "/posts/1", recorder.Header().Get("Location"))
+	assert.JSONEq(t, `{"id": "1"}`, recorder.Body.String())
+
+	recorder = httptest.NewRecorder()
+	w = Writer{ResponseWriter: recorder}
+	assert.Nil(t, w.Created("", "ok"))
+	assert.Equal(t, http.StatusCreated, recorder.Code)
+	assert.Empty(t, recorder.Header().Get("Location"))
+}
+
+func TestNoContentSendsNoBody(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	w := Writer{ResponseWriter: recorder, config: &writerConfig{headers: http.Header{"Cache-Control": {"no-store"}}}}
+
+	assert.Nil(t, w.NoContent())
+	assert.Equal(t, http.StatusNoContent, recorder.Code)
+	assert.Empty(t, recorder.Body.String())
+	assert.Equal(t, "no-store", recorder.Header().Get("Cache-Control"))
+
+	assert.NotNil(t, w.NoContent())
+	assert.NotNil(t, w.WriteJSON(http.StatusOK, "hi"))
+}
+
+func TestErrorHelpersSendErrors(t *testing.T) {
+	for code, write := range map[int]func(w *Writer, msg string) error{
+		http.StatusNotFound: (*Writer).NotFound,
+		http.StatusConflict: (*Writer).Conflict,
+	} {
+		recorder := httptest.NewRecorder()
+		w := Writer{ResponseWriter: recorder}
+
+		assert.Nil(t, write(&w, "nope"))
+		assert.Equal(t, code, recorder.Code)
+		assert.JSONEq(t, `{"errors": ["nope"]}`, recorder.Body.String())
+	}
+}
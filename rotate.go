@@ -0,0 +1,69 @@
+package jsonbody
+
+import (
+	"log"
+	"net/url"
+	"time"
+)
+
+// schemaGrace holds the schema that a schema replaced, which bodies may still
+// match until the grace period ends.
+type schemaGrace struct {
+	previous *Schema
+	until    time.Time
+}
+
+// RotateSchema returns a schema that behaves like next, except that for the
+// grace period after it is called, bodies that are invalid under next but valid
+// under previous are accepted too. This smooths deploys in which clients are
+// updated slightly after servers. Which of the schemas each body matched during
+// the grace period is logged. Registry uses it when its GracePeriod is set, and
+// custom SchemaResolvers can use it when they replace a schema.
+func RotateSchema(previous *Schema, next *Schema, grace time.Duration) *Schema {
+	if next == nil || grace <= 0 {
+		return next
+	}
+
+	rotated := *next
+	rotated.grace = &schemaGrace{
+		previous: previous.withoutGrace(),
+		until:    time.Now().Add(grace),
+	}
+
+	return &rotated
+}
+
+// withoutGrace returns the schema without the grace period added by
+// RotateSchema, so that schemas rotated repeatedly don't form a chain.
+func (s *Schema) withoutGrace() *Schema {
+	if s == nil || s.grace == nil {
+		return s
+	}
+
+	current := *s
+	current.grace = nil
+	return &current
+}
+
+// inGracePeriod reports whether bodies may still match the schema that s
+// replaced.
+func (s *Schema) inGracePeriod() bool {
+	return s.grace != nil && time.Now().Before(s.grace.until)
+}
+
+// validateWithGrace validates the body and query against the schema, falling
+// back to the schema it replaced.
+func (s *Schema) validateWithGrace(order ErrorOrder, body interface{}, query url.Values) []ValidationError {
+	errs := s.withoutGrace().validate(order, body, query)
+	if len(errs) == 0 {
+		log.Printf("%vbody matched the new schema during the rotation grace period\n", s.logPrefix())
+		return errs
+	}
+
+	if len(s.grace.previous.validate(order, body, query)) == 0 {
+		log.Printf("%vbody matched the previous schema during the rotation grace period\n", s.logPrefix())
+		return []ValidationError{}
+	}
+
+	return errs
+}
@@ -0,0 +1,58 @@
+package jsonbody
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRotateSchemaAcceptsPreviousSchemaDuringGracePeriod(t *testing.T) {
+	previous := MustParseSchema(`{"name": ""}`)
+	next := MustParseSchema(`{"fullName": ""}`)
+
+	rotated := RotateSchema(previous, next, time.Minute)
+	assert.Equal(t, 0, len(rotated.validate(OrderAlphabetical, bodyMap(`{"fullName": "a"}`), nil)))
+	assert.Equal(t, 0, len(rotated.validate(OrderAlphabetical, bodyMap(`{"name": "a"}`), nil)))
+	assert.Equal(t, []string{"expected key 'fullName' missing"}, errorMessages(rotated.validate(OrderAlphabetical, bodyMap(`{}`), nil)))
+
+	expired := RotateSchema(previous, next, time.Nanosecond)
+	time.Sleep(time.Millisecond)
+	assert.Equal(t, 1, len(expired.validate(OrderAlphabetical, bodyMap(`{"name": "a"}`), nil)))
+}
+
+func TestRotateSchemaDoesNotChainGracePeriods(t *testing.T) {
+	v1 := MustParseSchema(`{"a": ""}`)
+	v2 := RotateSchema(v1, MustParseSchema(`{"b": ""}`), time.Minute)
+	v3 := RotateSchema(v2, MustParseSchema(`{"c": ""}`), time.Minute)
+
+	assert.Equal(t, 0, len(v3.validate(OrderAlphabetical, bodyMap(`{"b": "x"}`), nil)))
+	assert.Equal(t, 1, len(v3.validate(OrderAlphabetical, bodyMap(`{"a": "x"}`), nil)))
+}
+
+func TestRegistryRotatesSchemasWithGracePeriod(t *testing.T) {
+	version := 1
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if version == 1 {
+			w.Write([]byte(`{"name": ""}`))
+		} else {
+			w.Write([]byte(`{"fullName": ""}`))
+		}
+	}))
+	defer server.Close()
+
+	reg := NewRegistry(server.URL, nil, 0)
+	reg.GracePeriod = time.Minute
+
+	_, err := reg.Resolve(context.Background(), "", "/users", "POST")
+	assert.Nil(t, err)
+
+	version = 2
+	schema, err := reg.Resolve(context.Background(), "", "/users", "POST")
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(schema.validate(OrderAlphabetical, bodyMap(`{"name": "a"}`), nil)))
+	assert.Equal(t, 0, len(schema.validate(OrderAlphabetical, bodyMap(`{"fullName": "a"}`), nil)))
+}
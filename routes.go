@@ -0,0 +1,140 @@
+package jsonbody
+
+import (
+	"net/http"
+	"strings"
+)
+
+// routeTable maps request methods and path patterns to schemas. Patterns are
+// paths in which segments written as "{name}" match any single non-empty path
+// segment, e.g. "/users/{id}".
+type routeTable struct {
+	routes []route
+}
+
+type route struct {
+	method   string
+	segments []string
+	schema   *Schema
+}
+
+// add registers schema for requests with the given method and a path matching
+// pattern, replacing any schema previously registered for them.
+func (t *routeTable) add(method string, pattern string, schema *Schema) {
+	method = strings.ToUpper(method)
+	segments := splitPath(pattern)
+
+	for i, r := range t.routes {
+		if r.method == method && strings.Join(r.segments, "/") == strings.Join(segments, "/") {
+			t.routes[i].schema = schema
+			return
+		}
+	}
+
+	t.routes = append(t.routes, route{method: method, segments: segments, schema: schema})
+}
+
+// match returns the schema registered for the given method and path. If several
+// patterns match, the one with the most literal (non-parameter) segments wins.
+func (t *routeTable) match(method string, path string) (*Schema, bool) {
+	schema, _, ok := t.matchParams(method, path)
+	return schema, ok
+}
+
+// matchParams is like match, but also returns the values of the matching
+// pattern's parameters, keyed by name.
+func (t *routeTable) matchParams(method string, path string) (*Schema, map[string]string, bool) {
+	segments := splitPath(path)
+	best := t.best(method, segments)
+	if best == nil {
+		return nil, nil, false
+	}
+
+	params := make(map[string]string)
+	for i, seg := range best.segments {
+		if strings.HasPrefix(seg, "{") && strings.HasSuffix(seg, "}") {
+			params[seg[1:len(seg)-1]] = segments[i]
+		}
+	}
+
+	return best.schema, params, true
+}
+
+// matchPattern returns the pattern registered for the given method that matches
+// path, e.g. "/users/{id}", choosing between patterns in the same way as match.
+func (t *routeTable) matchPattern(method string, path string) (string, bool) {
+	best := t.best(method, splitPath(path))
+	if best == nil {
+		return "", false
+	}
+
+	return "/" + strings.Join(best.segments, "/"), true
+}
+
+// best returns the route for method (or for any method, if its method is "")
+// whose pattern matches the path segments with the most literal segments, or
+// nil if none matches.
+func (t *routeTable) best(method string, segments []string) *route {
+	var best *route
+	bestLiterals := -1
+	for i := range t.routes {
+		r := &t.routes[i]
+		if (r.method != method && r.method != "") || len(r.segments) != len(segments) {
+			continue
+		}
+
+		literals, ok := matchSegments(r.segments, segments)
+		if ok && literals > bestLiterals {
+			best, bestLiterals = r, literals
+		}
+	}
+
+	return best
+}
+
+// routePattern returns the pattern of the route that r matches among those
+// registered with WithRouteSchema, NewMiddlewareFromBundle, or
+// WithRoutePatterns, e.g. "/posts/{id}", or the path of r's URL if it doesn't
+// match any. It identifies r's route for the parts of the middleware that keep
+// state per route, so that paths containing IDs don't each get their own state.
+func (m *middleware) routePattern(r *http.Request) string {
+	method := r.Method
+	if m.autoHead && method == http.MethodHead {
+		method = http.MethodGet
+	}
+
+	for _, table := range []*routeTable{m.routes, m.routePatterns} {
+		if table == nil {
+			continue
+		}
+		if pattern, ok := table.matchPattern(method, r.URL.Path); ok {
+			return pattern
+		}
+	}
+
+	return r.URL.Path
+}
+
+// matchSegments reports whether the path segments match the pattern segments
+// and, if so, how many of the pattern segments were literals.
+func matchSegments(pattern []string, path []string) (literals int, ok bool) {
+	for i, seg := range pattern {
+		if strings.HasPrefix(seg, "{") && strings.HasSuffix(seg, "}") {
+			if path[i] == "" {
+				return 0, false
+			}
+			continue
+		}
+
+		if seg != path[i] {
+			return 0, false
+		}
+		literals++
+	}
+
+	return literals, true
+}
+
+func splitPath(path string) []string {
+	return strings.Split(strings.Trim(path, "/"), "/")
+}
@@ -0,0 +1,87 @@
+package jsonbody
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRouteTableMatchesPatterns(t *testing.T) {
+	users := MustParseSchema(`{"$schemaName": "users"}`)
+	me := MustParseSchema(`{"$schemaName": "me"}`)
+
+	routes := &routeTable{}
+	routes.add("put", "/users/{id}", users)
+	routes.add("PUT", "/users/me", me)
+
+	schema, ok := routes.match("PUT", "/users/42")
+	assert.True(t, ok)
+	assert.Equal(t, users, schema)
+
+	schema, ok = routes.match("PUT", "/users/me/")
+	assert.True(t, ok)
+	assert.Equal(t, me, schema)
+
+	_, ok = routes.match("POST", "/users/42")
+	assert.False(t, ok)
+
+	_, ok = routes.match("PUT", "/users/42/posts")
+	assert.False(t, ok)
+}
+
+func TestRouteTableAddReplacesExistingRoute(t *testing.T) {
+	routes := &routeTable{}
+	routes.add("POST", "/posts", MustParseSchema(`{"$schemaName": "old"}`))
+	routes.add("POST", "/posts", MustParseSchema(`{"$schemaName": "new"}`))
+
+	schema, _ := routes.match("POST", "/posts")
+	assert.Equal(t, "new", schema.Name())
+	assert.Equal(t, 1, len(routes.routes))
+}
+
+func TestRouteTableMatchParamsReturnsParams(t *testing.T) {
+	routes := &routeTable{}
+	routes.add("GET", "/users/{userID}/posts/{postID}", nil)
+
+	_, params, ok := routes.matchParams("GET", "/users/7/posts/42")
+	assert.True(t, ok)
+	assert.Equal(t, map[string]string{"userID": "7", "postID": "42"}, params)
+}
+
+func TestServeHTTPUsesRouteSchema(t *testing.T) {
+	var id string
+	mw := NewMiddleware(`{"default": ""}`,
+		WithRouteSchema("POST", "/users", `{"name": ""}`),
+		WithRouteSchema("put", "/users/{id}", `{"?name": ""}`),
+	)
+	handler := mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id = PathParams(r)["id"]
+	}))
+
+	for _, tc := range []struct {
+		method string
+		path   string
+		body   string
+		code   int
+	}{
+		{http.MethodPost, "/users", `{"name": "a"}`, 200},
+		{http.MethodPost, "/users", `{}`, 400},
+		{http.MethodPut, "/users/42", `{}`, 200},
+		{http.MethodPost, "/other", `{"name": "a"}`, 400},
+		{http.MethodPost, "/other", `{"default": "a"}`, 200},
+	} {
+		req := httptest.NewRequest(tc.method, tc.path, strings.NewReader(tc.body))
+		req.Header.Set("Content-Type", "application/json")
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+		assert.Equal(t, tc.code, recorder.Code, tc.method+" "+tc.path+" "+tc.body)
+	}
+
+	req := httptest.NewRequest(http.MethodPut, "/users/42", strings.NewReader(`{}`))
+	req.Header.Set("Content-Type", "application/json")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	assert.Equal(t, "42", id)
+}
@@ -0,0 +1,226 @@
+package jsonbody
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/url"
+	"strings"
+)
+
+// Schema is a parsed request body schema. See NewMiddleware for the format of
+// schemas. A nil *Schema accepts any request body (including none at all).
+type Schema struct {
+	body     map[string]interface{} // nil if any body is accepted
+	array    []interface{}          // the schema of a top-level array body, if any
+	scalar   interface{}            // the schema of a top-level string, number, or boolean body, if any
+	query    map[string]interface{}
+	keyOrder map[string][]string
+	meta     schemaMeta
+	allOf    []*Schema // the schemas combined by AllOf, if any
+
+	severities map[string]Severity // the severities of errors for keys, by path
+	aliases    map[string]string   // the names that replace deprecated keys, by path
+	grace      *schemaGrace        // set by RotateSchema
+}
+
+// ParseSchema parses schemaJSON into a Schema. If schemaJSON is "" (the empty
+// string), it returns a nil *Schema, which accepts any request body.
+func ParseSchema(schemaJSON string) (*Schema, error) {
+	trimmed := strings.TrimSpace(schemaJSON)
+	if strings.HasPrefix(trimmed, "[") {
+		return parseArraySchema(schemaJSON)
+	} else if trimmed != "" && !strings.HasPrefix(trimmed, "{") {
+		return parseScalarSchema(schemaJSON)
+	}
+
+	body, err := parseSchema(schemaJSON)
+	if err != nil || body == nil {
+		return nil, err
+	}
+
+	keyOrder, err := parseKeyOrder(schemaJSON)
+	if err != nil {
+		return nil, err
+	}
+
+	return newSchema(body, keyOrder)
+}
+
+// newSchema creates a Schema from its decoded JSON, extracting the metadata and
+// query schema from its top level.
+func newSchema(body map[string]interface{}, keyOrder map[string][]string) (*Schema, error) {
+	meta, err := extractSchemaMeta(body)
+	if err != nil {
+		return nil, err
+	}
+
+	query, err := extractQuerySchema(body)
+	if err != nil {
+		return nil, err
+	}
+
+	severities, err := extractSeverities(body)
+	if err != nil {
+		return nil, err
+	}
+
+	aliases, err := extractAliases(body)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := compileConstraints(body); err != nil {
+		return nil, err
+	}
+
+	if query != nil && len(body) == 0 {
+		body = nil // the schema only describes the query parameters
+	}
+
+	return &Schema{
+		body:     body,
+		query:    query,
+		keyOrder: keyOrder,
+		meta:     meta,
+
+		severities: severities,
+		aliases:    aliases,
+	}, nil
+}
+
+// parseArraySchema parses the schema of a top-level array body.
+func parseArraySchema(schemaJSON string) (*Schema, error) {
+	var array []interface{}
+	if err := json.Unmarshal([]byte(schemaJSON), &array); err != nil {
+		log.Printf("jsonbody: failed to decode schema: %v\n", err)
+		return nil, errors.New("jsonbody: failed to decode schema")
+	}
+
+	keyOrder, err := parseKeyOrder(schemaJSON)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := compileConstraints(array); err != nil {
+		return nil, err
+	}
+
+	return &Schema{array: array, keyOrder: keyOrder}, nil
+}
+
+// parseScalarSchema parses the schema of a top-level string, number, or boolean
+// body.
+func parseScalarSchema(schemaJSON string) (*Schema, error) {
+	var scalar interface{}
+	if err := json.Unmarshal([]byte(schemaJSON), &scalar); err != nil {
+		log.Printf("jsonbody: failed to decode schema: %v\n", err)
+		return nil, errors.New("jsonbody: failed to decode schema")
+	}
+
+	switch scalar.(type) {
+	case string, float64, bool:
+		return &Schema{scalar: scalar}, nil
+	}
+
+	return nil, errors.New("jsonbody: schema must be an object, array, string, number, or boolean")
+}
+
+// MustParseSchema is like ParseSchema, but panics if schemaJSON can't be parsed.
+func MustParseSchema(schemaJSON string) *Schema {
+	s, err := ParseSchema(schemaJSON)
+	if err != nil {
+		panic("jsonbody: unexpected error while parsing schemaJSON: " + err.Error())
+	}
+
+	return s
+}
+
+// Name returns the name of the schema, as set by its "$schemaName" key.
+func (s *Schema) Name() string {
+	if s == nil {
+		return ""
+	}
+
+	return s.meta.name
+}
+
+// Description returns the description of the schema, as set by its
+// "$description" key.
+func (s *Schema) Description() string {
+	if s == nil {
+		return ""
+	}
+
+	return s.meta.description
+}
+
+// ErrorCodePrefix returns the prefix added to the codes of errors reported for
+// requests validated against the schema, as set by its "$errorCodePrefix" key.
+func (s *Schema) ErrorCodePrefix() string {
+	if s == nil {
+		return ""
+	}
+
+	return s.meta.errorCodePrefix
+}
+
+// logPrefix returns the prefix for messages logged about requests validated
+// against the schema, which includes the schema name if there is one.
+func (s *Schema) logPrefix() string {
+	if s.Name() == "" {
+		return "jsonbody: "
+	}
+
+	return "jsonbody: schema " + s.Name() + ": "
+}
+
+// acceptsAnyBody reports whether the schema accepts any request body (including
+// none at all), in which case the content type isn't checked either.
+func (s *Schema) acceptsAnyBody() bool {
+	if s == nil {
+		return true
+	}
+
+	for _, part := range s.allOf {
+		if !part.acceptsAnyBody() {
+			return false
+		}
+	}
+
+	return s.body == nil && s.array == nil && s.scalar == nil
+}
+
+// validate checks the request body and query parameters against the schema,
+// returning a list of errors.
+func (s *Schema) validate(order ErrorOrder, body interface{}, query url.Values) []ValidationError {
+	if s == nil {
+		return []ValidationError{}
+	}
+
+	if s.inGracePeriod() {
+		return s.validateWithGrace(order, body, query)
+	}
+
+	if len(s.allOf) > 0 {
+		return s.validateAllOf(order, body, query)
+	}
+
+	v := validator{
+		order:    order,
+		keyOrder: s.keyOrder,
+	}
+
+	errs := v.validateBody(s, body)
+	if s.query != nil {
+		errs = append(errs, v.validateQuery(s.query, query)...)
+	}
+
+	if s.severities != nil {
+		for i := range errs {
+			errs[i].Severity = severityOf(s.severities, errs[i].Key)
+		}
+	}
+
+	return errs
+}
@@ -0,0 +1,71 @@
+package jsonbody
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSchemaReturnsNilSchemaIfEmpty(t *testing.T) {
+	schema, err := ParseSchema("")
+	assert.Nil(t, err)
+	assert.Nil(t, schema)
+}
+
+func TestParseSchemaExtractsMetadata(t *testing.T) {
+	schema, err := ParseSchema(`{"$schemaName": "CreatePost", "$description": "creates a post", "title": ""}`)
+	assert.Nil(t, err)
+	assert.Equal(t, "CreatePost", schema.Name())
+	assert.Equal(t, "creates a post", schema.Description())
+	assert.Equal(t, map[string]interface{}{"title": ""}, schema.body)
+}
+
+func TestMustParseSchemaPanicsIfInvalid(t *testing.T) {
+	assert.Panics(t, func() { MustParseSchema("not json") })
+}
+
+func TestParseSchemaExtractsErrorCodePrefix(t *testing.T) {
+	schema, err := ParseSchema(`{"$errorCodePrefix": "POSTS_", "title": ""}`)
+	assert.Nil(t, err)
+	assert.Equal(t, "POSTS_", schema.ErrorCodePrefix())
+	assert.Equal(t, map[string]interface{}{"title": ""}, schema.body)
+}
+
+func TestParseSchemaAcceptsTopLevelArray(t *testing.T) {
+	s, err := ParseSchema(`[{"id": 0, "name": ""}]`)
+	assert.Nil(t, err)
+
+	errs := s.Validate(context.Background(), []interface{}{
+		map[string]interface{}{"id": 1.0, "name": "a"},
+		map[string]interface{}{"id": 2.0},
+	})
+	assert.Equal(t, []string{"expected key '[1].name' missing"}, errorMessages(errs))
+
+	errs = s.Validate(context.Background(), bodyMap(`{"id": 1, "name": "a"}`))
+	assert.Equal(t, []string{"body expected to be of type array"}, errorMessages(errs))
+
+	errs = s.Validate(context.Background(), nil)
+	assert.Equal(t, []string{"expected a JSON body"}, errorMessages(errs))
+}
+
+func TestExtendSchemaRejectsArraySchema(t *testing.T) {
+	_, err := ExtendSchema(MustParseSchema(`[0]`), `{"a": ""}`)
+	assert.NotNil(t, err)
+}
+
+func TestParseSchemaAcceptsTopLevelScalar(t *testing.T) {
+	s, err := ParseSchema(`0`)
+	assert.Nil(t, err)
+
+	assert.Empty(t, s.Validate(context.Background(), 42.0))
+
+	errs := s.Validate(context.Background(), "42")
+	assert.Equal(t, []string{"body expected to be of type number"}, errorMessages(errs))
+
+	errs = s.Validate(context.Background(), nil)
+	assert.Equal(t, []string{"expected a JSON body"}, errorMessages(errs))
+
+	_, err = ParseSchema(`null`)
+	assert.NotNil(t, err)
+}
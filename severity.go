@@ -0,0 +1,173 @@
+package jsonbody
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Severity is the severity of a ValidationError, which determines what the
+// middleware does about it according to its Policy.
+type Severity int
+
+const (
+	// SeverityError is the default severity. With the default Policy, errors
+	// cause the request to be rejected.
+	SeverityError Severity = iota
+
+	// SeverityWarn is for problems that clients should fix, but that don't
+	// prevent the request from being handled. With the default Policy, they
+	// are reported in Warning headers on the response.
+	SeverityWarn
+
+	// SeverityInfo is for noteworthy but harmless deviations. With the default
+	// Policy, they are only logged.
+	SeverityInfo
+)
+
+var severityNames = map[Severity]string{
+	SeverityError: "error",
+	SeverityWarn:  "warn",
+	SeverityInfo:  "info",
+}
+
+func (s Severity) String() string {
+	if name, ok := severityNames[s]; ok {
+		return name
+	}
+
+	return "Severity(" + strconv.Itoa(int(s)) + ")"
+}
+
+// Action is what the middleware does about a ValidationError.
+type Action int
+
+const (
+	// ActionReject rejects the request with a 400 error response listing the
+	// error.
+	ActionReject Action = iota
+
+	// ActionWarn passes the request to the handler, adding a Warning header
+	// with the error's message to the response.
+	ActionWarn
+
+	// ActionLog passes the request to the handler, logging the error.
+	ActionLog
+)
+
+// Policy maps the severities of validation errors to the actions the middleware
+// takes for them. Severities that aren't in the map get the default action:
+// ActionReject for SeverityError, ActionWarn for SeverityWarn, and ActionLog for
+// SeverityInfo. Since the policy is separate from the schema, the same schema
+// can be enforced strictly in one environment and leniently in another:
+//
+//	policy := jsonbody.Policy{}
+//	if env == "canary" {
+//		policy[jsonbody.SeverityError] = jsonbody.ActionWarn
+//	}
+//	mw := jsonbody.NewMiddleware(schemaJSON, jsonbody.WithPolicy(policy))
+type Policy map[Severity]Action
+
+func (p Policy) action(s Severity) Action {
+	if action, ok := p[s]; ok {
+		return action
+	}
+
+	switch s {
+	case SeverityWarn:
+		return ActionWarn
+	case SeverityInfo:
+		return ActionLog
+	}
+
+	return ActionReject
+}
+
+const schemaSeverityKey = "$severity"
+
+// extractSeverities removes the "$severity" key from the top level of the
+// schema and returns its value, which maps the paths of keys in the schema
+// (e.g. "author.tags[]", without question marks) to the severities of their
+// errors.
+func extractSeverities(schema map[string]interface{}) (map[string]Severity, error) {
+	val, ok := schema[schemaSeverityKey]
+	if !ok {
+		return nil, nil
+	}
+	delete(schema, schemaSeverityKey)
+
+	obj, ok := val.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("jsonbody: value for schema key '%v' must be an object", schemaSeverityKey)
+	}
+
+	severities := make(map[string]Severity, len(obj))
+	for path, name := range obj {
+		severity, ok := parseSeverity(name)
+		if !ok {
+			return nil, fmt.Errorf("jsonbody: severity for '%v' in schema must be \"error\", \"warn\", or \"info\"", path)
+		}
+		severities[path] = severity
+	}
+
+	return severities, nil
+}
+
+func parseSeverity(v interface{}) (Severity, bool) {
+	for severity, name := range severityNames {
+		if v == name {
+			return severity, true
+		}
+	}
+
+	return 0, false
+}
+
+var arrayIndexPattern = regexp.MustCompile(`\[\d+\]`)
+
+// severityOf returns the severity configured for the error's key or the closest
+// of its parents, or SeverityError if there is none.
+func severityOf(severities map[string]Severity, key string) Severity {
+	path := arrayIndexPattern.ReplaceAllString(key, "[]")
+	for path != "" {
+		if severity, ok := severities[path]; ok {
+			return severity
+		}
+
+		i := strings.LastIndexAny(path, ".[")
+		if i < 0 {
+			break
+		}
+		path = path[:i]
+	}
+
+	return SeverityError
+}
+
+// enforce applies the middleware's policy to errs, adding Warning headers to w
+// or logging the errors that don't reject the request, and returns the ones
+// that do. If observe is set (see EnforcementObserve), errors that would reject
+// the request are logged instead.
+func (m *middleware) enforce(w *Writer, schema *Schema, errs []ValidationError, observe bool) []ValidationError {
+	rejected := make([]ValidationError, 0, len(errs))
+	for _, e := range errs {
+		action := m.policy.action(e.Severity)
+		if observe && action == ActionReject {
+			log.Printf("%vobserved request with error: %v\n", schema.logPrefix(), e.Error())
+			continue
+		}
+
+		switch action {
+		case ActionWarn:
+			w.Header().Add("Warning", `199 - `+strconv.Quote(w.message(e)))
+		case ActionLog:
+			log.Printf("%vaccepted request with %v: %v\n", schema.logPrefix(), e.Severity, e.Error())
+		default:
+			rejected = append(rejected, e)
+		}
+	}
+
+	return rejected
+}
@@ -0,0 +1,66 @@
+package jsonbody
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSeverityOfUsesClosestPath(t *testing.T) {
+	severities := map[string]Severity{
+		"author":        SeverityInfo,
+		"author.tags[]": SeverityWarn,
+	}
+
+	assert.Equal(t, SeverityInfo, severityOf(severities, "author.name"))
+	assert.Equal(t, SeverityWarn, severityOf(severities, "author.tags[3]"))
+	assert.Equal(t, SeverityError, severityOf(severities, "title"))
+}
+
+func TestParseSchemaRejectsInvalidSeverity(t *testing.T) {
+	_, err := ParseSchema(`{"$severity": {"a": "fatal"}, "a": ""}`)
+	assert.NotNil(t, err)
+
+	_, err = ParseSchema(`{"$severity": "warn"}`)
+	assert.NotNil(t, err)
+}
+
+func TestServeHTTPAppliesPolicyToSeverities(t *testing.T) {
+	schema := `{"$severity": {"nickname": "warn", "bio": "info"}, "name": "", "?nickname": "", "?bio": ""}`
+
+	for _, tc := range []struct {
+		policy  Policy
+		body    string
+		code    int
+		warning string
+	}{
+		{nil, `{"name": "a", "nickname": 1, "bio": 2}`, 200, `199 - "value for key 'nickname' expected to be of type string"`},
+		{nil, `{"nickname": 1}`, 400, `199 - "value for key 'nickname' expected to be of type string"`},
+		{Policy{SeverityWarn: ActionReject}, `{"name": "a", "nickname": 1}`, 400, ""},
+		{Policy{SeverityError: ActionWarn}, `{"bio": ""}`, 200, `199 - "expected key 'name' missing"`},
+	} {
+		called := false
+		handler := NewMiddleware(schema, WithPolicy(tc.policy))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			called = true
+		}))
+
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tc.body))
+		req.Header.Set("Content-Type", "application/json")
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+
+		assert.Equal(t, tc.code, recorder.Code, tc.body)
+		assert.Equal(t, tc.code == 200, called, tc.body)
+		assert.Equal(t, tc.warning, recorder.Header().Get("Warning"), tc.body)
+	}
+}
+
+func TestExtendSchemaKeepsSeverities(t *testing.T) {
+	base := MustParseSchema(`{"$severity": {"a": "warn"}, "a": ""}`)
+	extended, err := ExtendSchema(base, `{"b": ""}`)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]Severity{"a": SeverityWarn}, extended.severities)
+}
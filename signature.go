@@ -0,0 +1,38 @@
+package jsonbody
+
+import (
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/sha256"
+)
+
+// Signer computes a detached signature over an encoded response body.
+type Signer interface {
+	Sign(body []byte) ([]byte, error)
+}
+
+// SignerFunc is an adapter allowing an ordinary function to be used as a Signer.
+type SignerFunc func(body []byte) ([]byte, error)
+
+// Sign calls f(body).
+func (f SignerFunc) Sign(body []byte) ([]byte, error) {
+	return f(body)
+}
+
+// HMACSigner returns a Signer that signs response bodies with HMAC-SHA256 using
+// the given key.
+func HMACSigner(key []byte) Signer {
+	return SignerFunc(func(body []byte) ([]byte, error) {
+		mac := hmac.New(sha256.New, key)
+		mac.Write(body)
+		return mac.Sum(nil), nil
+	})
+}
+
+// Ed25519Signer returns a Signer that signs response bodies with the given
+// Ed25519 private key.
+func Ed25519Signer(key ed25519.PrivateKey) Signer {
+	return SignerFunc(func(body []byte) ([]byte, error) {
+		return ed25519.Sign(key, body), nil
+	})
+}
@@ -0,0 +1,59 @@
+package jsonbody
+
+import (
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHMACSignerSignsWithSHA256(t *testing.T) {
+	sig, err := HMACSigner([]byte("key")).Sign([]byte("body"))
+	assert.Nil(t, err)
+
+	mac := hmac.New(sha256.New, []byte("key"))
+	mac.Write([]byte("body"))
+	assert.Equal(t, mac.Sum(nil), sig)
+}
+
+func TestEd25519SignerProducesVerifiableSignature(t *testing.T) {
+	pub, priv, _ := ed25519.GenerateKey(nil)
+
+	sig, err := Ed25519Signer(priv).Sign([]byte("body"))
+	assert.Nil(t, err)
+	assert.True(t, ed25519.Verify(pub, []byte("body"), sig))
+}
+
+func TestWriteJSONSendsSignatureHeader(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	w := Writer{
+		ResponseWriter: recorder,
+		config:         &writerConfig{signer: HMACSigner([]byte("key")), signatureHeader: "X-Signature"},
+	}
+
+	err := w.WriteJSON(200, map[string]string{"a": "b"})
+	assert.Nil(t, err)
+
+	expected, _ := HMACSigner([]byte("key")).Sign([]byte(`{"a":"b"}`))
+	assert.Equal(t, base64.StdEncoding.EncodeToString(expected), recorder.Header().Get("X-Signature"))
+}
+
+func TestWriteJSONReturnsErrIfSigningFails(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	w := Writer{
+		ResponseWriter: recorder,
+		config: &writerConfig{
+			signer:          SignerFunc(func([]byte) ([]byte, error) { return nil, errors.New("error") }),
+			signatureHeader: "X-Signature",
+		},
+	}
+
+	err := w.WriteJSON(200, "hi")
+	assert.NotNil(t, err)
+	assert.Equal(t, 0, recorder.Body.Len())
+}
@@ -0,0 +1,119 @@
+package jsonbody
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+)
+
+type skipContextKey struct{}
+
+// SkipValidation returns a copy of ctx that tells the middleware not to parse or
+// validate the body of the request it belongs to, e.g. for internal
+// service-to-service calls that were already validated upstream. It is intended
+// for use by trusted middleware that runs before jsonbody:
+//
+//	r = r.WithContext(jsonbody.SkipValidation(r.Context()))
+//
+// Since clients can't set context values, they can't use this to bypass
+// validation. The handler is still passed a Writer and a Reader, but the
+// Reader's JSON method returns nil; the raw body can be read from the Reader as
+// usual. Only the schema and validators are skipped: protections such as the
+// body size limit, the memory guard, CSRF protection, the handler timeout,
+// deduplication, idempotency keys, and the response cache still apply.
+func SkipValidation(ctx context.Context) context.Context {
+	return context.WithValue(ctx, skipContextKey{}, true)
+}
+
+// skipsValidation reports whether ctx was created by SkipValidation.
+func skipsValidation(ctx context.Context) bool {
+	skip, _ := ctx.Value(skipContextKey{}).(bool)
+	return skip
+}
+
+// serveUnvalidated handles a request whose body isn't parsed or validated, either
+// because validation is off for its route or because SkipValidation was used.
+// The middleware's other protections still apply to it. Its body is only read
+// before the handler is called if they need it, e.g. to identify duplicate
+// requests.
+func (m *middleware) serveUnvalidated(writer Writer, r *http.Request, entry *accessLogEntry) {
+	resources := newBodyResources()
+	defer resources.release()
+	r = r.WithContext(withBodyResources(r.Context(), resources))
+
+	release, ok := m.acquireMemory(&writer, r)
+	if !ok {
+		return
+	}
+	resources.add(release)
+
+	if m.maxBodySize > 0 {
+		if r.ContentLength > m.maxBodySize {
+			writer.writeErrors(http.StatusRequestEntityTooLarge, bodyTooLarge(m.maxBodySize))
+			return
+		}
+		r.Body = http.MaxBytesReader(nil, r.Body, m.maxBodySize)
+	}
+
+	var hash string
+	needsBody := m.csrf.needsBody(r)
+	if m.dedup != nil || m.cache != nil || m.idempotencyStore != nil || needsBody {
+		body, spilled, err := m.readBody(r, true)
+		var maxErr *http.MaxBytesError
+		switch {
+		case errors.As(err, &maxErr):
+			writer.writeErrors(http.StatusRequestEntityTooLarge, bodyTooLarge(maxErr.Limit))
+			return
+		case err == errLengthMismatch:
+			writer.writeErrors(http.StatusBadRequest, ValidationError{
+				Code:   CodeLengthMismatch,
+				Params: map[string]string{"length": fmt.Sprint(r.ContentLength)},
+			})
+			return
+		case err != nil:
+			log.Println(fmt.Errorf("jsonbody: failed to read body: %v", err))
+			writer.writeServerError()
+			return
+		}
+
+		if spilled != nil {
+			resources.add(spilled.remove)
+			r.Body = *spilled
+		} else {
+			r.Body = bodyBuffer{bytes.NewReader(body)}
+		}
+
+		hash, err = requestHashFromBody(r)
+		if err != nil {
+			log.Println(fmt.Errorf("jsonbody: failed to read body: %v", err))
+			writer.writeServerError()
+			return
+		}
+	}
+
+	var body interface{}
+	if needsBody {
+		// the body is only decoded to find the token, so errors just mean that
+		// there isn't one
+		if seeker, ok := r.Body.(io.ReadSeeker); ok {
+			json.NewDecoder(seeker).Decode(&body)
+			seeker.Seek(0, io.SeekStart)
+		}
+	}
+	if !m.csrf.checkCSRF(r, body) {
+		writer.writeErrors(http.StatusForbidden, ValidationError{Code: CodeCSRFMismatch})
+		return
+	}
+
+	reader := Reader{ReadCloser: r.Body}
+	if size := reader.Size(); size >= 0 {
+		entry.setRequestBytes(size)
+	}
+	r.Body = reader
+	m.serveProtected(writer, r, hash)
+}
@@ -0,0 +1,100 @@
+package jsonbody
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServeHTTPSkipsValidationIfContextSet(t *testing.T) {
+	var raw []byte
+	var body map[string]interface{}
+	handler := NewMiddleware(`{"title": ""}`)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reader := r.Body.(Reader)
+		body = reader.JSON()
+		raw, _ = io.ReadAll(reader)
+		writer := w.(Writer)
+		writer.WriteJSON(200, "ok")
+	}))
+
+	recorder := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`not json`))
+	handler.ServeHTTP(recorder, r.WithContext(SkipValidation(r.Context())))
+
+	assert.Equal(t, 200, recorder.Code)
+	assert.Nil(t, body)
+	assert.Equal(t, "not json", string(raw))
+}
+
+func TestServeHTTPNotSkipValidationByDefault(t *testing.T) {
+	handler := NewMiddleware(`{"title": ""}`)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	recorder := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`not json`))
+	r.Header.Set("X-Skip-Validation", "true")
+	handler.ServeHTTP(recorder, r)
+
+	assert.Equal(t, 400, recorder.Code)
+}
+
+func skippedRequest(body string) *http.Request {
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	return r.WithContext(SkipValidation(r.Context()))
+}
+
+func TestServeHTTPSkipValidationKeepsProtections(t *testing.T) {
+	calls := 0
+	slow := false
+	handler := NewMiddleware(`{"title": ""}`,
+		WithMaxBodySize(10),
+		WithHandlerTimeout(10*time.Millisecond),
+		WithDeduplication(time.Minute, nil),
+	)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if slow {
+			<-r.Context().Done()
+			return
+		}
+		writer := w.(Writer)
+		writer.WriteJSON(200, "ok")
+	}))
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, skippedRequest(`not json, and too long`))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, recorder.Code)
+
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, skippedRequest(`not json`))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, skippedRequest(`not json`))
+	assert.Equal(t, http.StatusConflict, recorder.Code)
+	assert.Equal(t, 1, calls)
+
+	slow = true
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, skippedRequest(`other`))
+	assert.Equal(t, http.StatusGatewayTimeout, recorder.Code)
+}
+
+func TestServeHTTPSkipValidationAppliesMemoryGuard(t *testing.T) {
+	guard := NewMemoryGuard(4)
+	handler := NewMiddleware(`{"title": ""}`, WithMemoryGuard(guard, time.Second))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, skippedRequest(`not json`))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, recorder.Code)
+	assert.Equal(t, int64(0), guard.InUse())
+
+	assert.True(t, guard.tryAcquire(2))
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, skippedRequest(`"x"`))
+	assert.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+	assert.Equal(t, int64(2), guard.InUse())
+}
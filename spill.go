@@ -0,0 +1,94 @@
+package jsonbody
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+)
+
+// spilledBody holds a request body that has been written to a temporary file
+// rather than read into memory. The file is removed by the middleware once the
+// handler returns, so Close does nothing; this way, handlers can close the body
+// as usual and still Seek back to the beginning afterward.
+type spilledBody struct {
+	*os.File
+}
+
+func (b spilledBody) Close() error {
+	return nil
+}
+
+// remove closes and deletes the temporary file.
+func (b spilledBody) remove() {
+	b.File.Close()
+	os.Remove(b.Name())
+}
+
+// spillBody copies body into a temporary file in dir (or the default directory
+// for temporary files if dir is ""), rewound to the beginning.
+func spillBody(body io.Reader, dir string) (spilledBody, error) {
+	f, err := os.CreateTemp(dir, "jsonbody-")
+	if err != nil {
+		return spilledBody{}, err
+	}
+	spilled := spilledBody{f}
+
+	if _, err := io.Copy(f, body); err != nil {
+		spilled.remove()
+		return spilledBody{}, err
+	}
+
+	if err := spilled.rewind(); err != nil {
+		spilled.remove()
+		return spilledBody{}, err
+	}
+
+	return spilled, nil
+}
+
+// size returns the size of the body, or -1 if it is unknown.
+func (b spilledBody) size() int64 {
+	info, err := b.Stat()
+	if err != nil {
+		return -1
+	}
+
+	return info.Size()
+}
+
+func (b spilledBody) rewind() error {
+	_, err := b.Seek(0, io.SeekStart)
+	return err
+}
+
+// decodeSpilled parses the body in the temporary file, leaving the file rewound
+// to the beginning.
+func decodeSpilled(body spilledBody, opts ParseOptions) (interface{}, error) {
+	var bodyJSON interface{}
+	dec := json.NewDecoder(body)
+	err := dec.Decode(&bodyJSON)
+	if err == nil {
+		// like json.Unmarshal, reject anything after the value
+		if _, tokErr := dec.Token(); tokErr != io.EOF {
+			err = errors.New("invalid data after top-level value")
+		}
+	}
+
+	if err != nil {
+		log.Println(fmt.Errorf("jsonbody: failed to decode body: %v", err))
+		return nil, errBadBody
+	}
+
+	if err := body.rewind(); err != nil {
+		return nil, err
+	}
+
+	if err := opts.checkReader(body); err != nil {
+		return nil, err
+	}
+
+	return bodyJSON, body.rewind()
+}
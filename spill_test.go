@@ -0,0 +1,104 @@
+package jsonbody
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServeHTTPSpillsLargeBodiesToDisk(t *testing.T) {
+	dir := t.TempDir()
+
+	var spilled bool
+	var first, second string
+	handler := NewMiddleware(`{"a": ""}`, WithSpillToDisk(16, dir))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, spilled = r.Body.(Reader).ReadCloser.(spilledBody)
+
+		b, _ := ioutil.ReadAll(r.Body)
+		first = string(b)
+		r.Body.Close()
+
+		assert.Nil(t, r.Body.(Reader).Reset())
+		b, _ = ioutil.ReadAll(r.Body)
+		second = string(b)
+
+		assert.Equal(t, "a long enough value", r.Body.(Reader).JSON()["a"])
+
+		replay, err := r.GetBody()
+		assert.Nil(t, err)
+		b, _ = ioutil.ReadAll(replay)
+		assert.Equal(t, second, string(b))
+	}))
+
+	body := `{"a": "a long enough value"}`
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, req)
+
+	assert.Equal(t, 200, recorder.Code)
+	assert.True(t, spilled)
+	assert.Equal(t, body, first)
+	assert.Equal(t, body, second)
+
+	entries, err := os.ReadDir(dir)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(entries))
+}
+
+func TestServeHTTPKeepsSmallBodiesInMemoryWhenSpilling(t *testing.T) {
+	var spilled bool
+	handler := NewMiddleware("", WithSpillToDisk(16, t.TempDir()))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, spilled = r.Body.(Reader).ReadCloser.(spilledBody)
+	}))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"a": 1}`)))
+	assert.False(t, spilled)
+}
+
+func TestServeHTTPValidatesSpilledBodies(t *testing.T) {
+	dir := t.TempDir()
+	handler := NewMiddleware(`{"a": 1}`,
+		WithSpillToDisk(4, dir),
+		WithParseOptions(ParseOptions{RejectLoneSurrogates: true}),
+	)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	for _, tc := range []struct {
+		body string
+		want string
+	}{
+		{`{"a": "x"}`, `{"errors":["value for key 'a' expected to be of type number"]}`},
+		{`{"a": 1} {}`, `{"errors":["expected a JSON body"]}`},
+		{`{"a": 1, "b": "\ud800"}`, `{"errors":["body is not acceptable JSON: strings must not contain unpaired UTF-16 surrogates"]}`},
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tc.body))
+		req.Header.Set("Content-Type", "application/json")
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+
+		assert.Equal(t, 400, recorder.Code, tc.body)
+		assert.Equal(t, tc.want, recorder.Body.String(), tc.body)
+	}
+
+	entries, _ := os.ReadDir(dir)
+	assert.Equal(t, 0, len(entries))
+}
+
+func TestServeHTTPCachesSpilledBodies(t *testing.T) {
+	calls := 0
+	handler := NewMiddleware("", WithSpillToDisk(4, t.TempDir()), WithResponseCache(NewMemoryCache(), time.Minute))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Write([]byte("ok"))
+	}))
+
+	for i := 0; i < 2; i++ {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"a": 1}`)))
+	}
+	assert.Equal(t, 1, calls)
+}
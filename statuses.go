@@ -0,0 +1,133 @@
+package jsonbody
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// statusContracts maps request methods and path patterns to the response status
+// codes their handlers are allowed to send, as registered with
+// WithResponseStatuses.
+type statusContracts struct {
+	routes []statusRoute
+}
+
+type statusRoute struct {
+	method   string
+	segments []string
+	statuses []int
+}
+
+// add declares statuses for requests with the given method and a path matching
+// pattern, in addition to any statuses previously declared for them.
+func (c *statusContracts) add(method string, pattern string, statuses []int) {
+	method = strings.ToUpper(method)
+	segments := splitPath(pattern)
+
+	for i, r := range c.routes {
+		if r.method == method && strings.Join(r.segments, "/") == strings.Join(segments, "/") {
+			c.routes[i].statuses = append(c.routes[i].statuses, statuses...)
+			return
+		}
+	}
+
+	c.routes = append(c.routes, statusRoute{method: method, segments: segments, statuses: statuses})
+}
+
+// match returns the statuses declared for the given method and path, or false if
+// none were. Statuses declared with the method "" apply to every method. If
+// several patterns match, the one with the most literal segments wins, and of
+// those, one for the specific method wins over one for any method.
+func (c *statusContracts) match(method string, path string) ([]int, bool) {
+	if c == nil {
+		return nil, false
+	}
+
+	segments := splitPath(path)
+
+	var best *statusRoute
+	bestLiterals, bestMethod := -1, false
+	for i := range c.routes {
+		r := &c.routes[i]
+		if (r.method != "" && r.method != method) || len(r.segments) != len(segments) {
+			continue
+		}
+
+		literals, ok := matchSegments(r.segments, segments)
+		specific := r.method != ""
+		if ok && (literals > bestLiterals || (literals == bestLiterals && specific && !bestMethod)) {
+			best, bestLiterals, bestMethod = r, literals, specific
+		}
+	}
+
+	if best == nil {
+		return nil, false
+	}
+
+	return best.statuses, true
+}
+
+// statusChecker reports handlers that send a response status that isn't among
+// the statuses declared for their route. Such responses are logged along with
+// the call site that sent the status, or cause a panic if strict is set.
+// Informational (1xx) statuses are ignored.
+type statusChecker struct {
+	http.ResponseWriter
+	route    string
+	statuses []int
+	strict   bool
+
+	mu   sync.Mutex
+	sent bool
+}
+
+func (c *statusChecker) WriteHeader(statusCode int) {
+	c.check(statusCode)
+	c.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (c *statusChecker) Write(b []byte) (int, error) {
+	c.check(http.StatusOK)
+	return c.ResponseWriter.Write(b)
+}
+
+// Unwrap returns the underlying http.ResponseWriter for http.ResponseController.
+func (c *statusChecker) Unwrap() http.ResponseWriter {
+	return c.ResponseWriter
+}
+
+func (c *statusChecker) check(statusCode int) {
+	if statusCode >= 100 && statusCode <= 199 {
+		return
+	}
+
+	c.mu.Lock()
+	if c.sent {
+		c.mu.Unlock()
+		return
+	}
+	c.sent = true
+	c.mu.Unlock()
+
+	for _, s := range c.statuses {
+		if s == statusCode {
+			return
+		}
+	}
+
+	declared := make([]string, len(c.statuses))
+	for i, s := range c.statuses {
+		declared[i] = fmt.Sprint(s)
+	}
+	msg := fmt.Sprintf("jsonbody: handler for %v sent undeclared status %v at %v; declared statuses are %v",
+		c.route, statusCode, callSite(), strings.Join(declared, ", "))
+
+	if c.strict {
+		panic(msg)
+	}
+
+	log.Println(msg)
+}
@@ -0,0 +1,124 @@
+package jsonbody
+
+import (
+	"bytes"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStatusContractsMatchMostSpecificRoute(t *testing.T) {
+	c := &statusContracts{}
+	c.add("get", "/posts/{id}", []int{200, 404})
+	c.add("GET", "/posts/latest", []int{200})
+	c.add("GET", "/posts/{id}", []int{304})
+
+	statuses, ok := c.match(http.MethodGet, "/posts/1")
+	assert.True(t, ok)
+	assert.Equal(t, []int{200, 404, 304}, statuses)
+
+	statuses, ok = c.match(http.MethodGet, "/posts/latest")
+	assert.True(t, ok)
+	assert.Equal(t, []int{200}, statuses)
+
+	_, ok = c.match(http.MethodPost, "/posts/1")
+	assert.False(t, ok)
+}
+
+func TestStatusContractsMatchAnyMethod(t *testing.T) {
+	c := &statusContracts{}
+	c.add("", "/posts/{id}", []int{200})
+	c.add("DELETE", "/posts/{id}", []int{204})
+
+	statuses, ok := c.match(http.MethodPost, "/posts/1")
+	assert.True(t, ok)
+	assert.Equal(t, []int{200}, statuses)
+
+	statuses, ok = c.match(http.MethodDelete, "/posts/1")
+	assert.True(t, ok)
+	assert.Equal(t, []int{204}, statuses)
+}
+
+func TestResponseStatusesLogsUndeclaredStatus(t *testing.T) {
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	defer log.SetOutput(os.Stderr)
+
+	status := http.StatusOK
+	handler := NewMiddleware(`{"title": ""}`, WithResponseStatuses(http.MethodPost, "/posts", http.StatusCreated, http.StatusConflict))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writer := w.(Writer)
+		writer.WriteJSON(status, map[string]int{"a": 1})
+	}))
+
+	for _, tc := range []struct {
+		status int
+		body   string
+		logged bool
+	}{
+		{http.StatusCreated, `{"title": "a"}`, false},
+		{http.StatusConflict, `{"title": "a"}`, false},
+		{http.StatusInternalServerError, `{"title": "a"}`, true},
+		{http.StatusCreated, `{}`, false}, // the middleware's 400 isn't checked
+	} {
+		logs.Reset()
+		status = tc.status
+
+		req := httptest.NewRequest(http.MethodPost, "/posts", strings.NewReader(tc.body))
+		req.Header.Set("Content-Type", "application/json")
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+
+		if tc.logged {
+			assert.Contains(t, logs.String(), "handler for POST /posts sent undeclared status 500 at ")
+			assert.Contains(t, logs.String(), "statuses_test.go")
+			assert.Contains(t, logs.String(), "declared statuses are 201, 409")
+		} else {
+			assert.Empty(t, logs.String(), tc.status)
+		}
+	}
+}
+
+func TestResponseStatusesChecksImplicitStatus(t *testing.T) {
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	defer log.SetOutput(os.Stderr)
+
+	handler := NewMiddleware("", WithResponseStatuses(http.MethodGet, "/", http.StatusNoContent))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("hi"))
+	}))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Contains(t, logs.String(), "sent undeclared status 200")
+}
+
+func TestResponseStatusesPanicsInStrictMode(t *testing.T) {
+	handler := NewMiddleware("", WithWriteDiagnostics(true), WithResponseStatuses(http.MethodGet, "/posts/{id}", http.StatusOK))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+
+	assert.Panics(t, func() {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/posts/1", nil))
+	})
+
+	assert.NotPanics(t, func() {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/other", nil))
+	})
+}
+
+func TestResponseStatusesChecksHeadAgainstGetWithAutoHead(t *testing.T) {
+	handler := NewMiddleware("",
+		WithAutoHead(),
+		WithWriteDiagnostics(true),
+		WithResponseStatuses(http.MethodGet, "/posts/{id}", http.StatusOK),
+	)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+
+	assert.Panics(t, func() {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodHead, "/posts/1", nil))
+	})
+}
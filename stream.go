@@ -0,0 +1,237 @@
+package jsonbody
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// StreamOptions configures the streaming responses written by
+// Writer.WriteNDJSONStream and Writer.WriteJSONStream and the long-poll
+// responses written by Writer.WriteWhenReady. It is set with the
+// WithStreamOptions option.
+type StreamOptions struct {
+	// FlushInterval is how often buffered data is flushed to the client. If it
+	// is 0, data is flushed after every item. Otherwise, data is flushed at
+	// least that often, even while the producer is idle, which keeps proxies
+	// from closing long-running streams.
+	FlushInterval time.Duration
+
+	// Compress causes streams to be gzip-compressed on the fly if the request's
+	// Accept-Encoding header allows it.
+	Compress bool
+
+	// WriteTimeout, if positive, is the write deadline set for sending each
+	// value. If the client doesn't accept the value in time (e.g. because it
+	// stopped reading), send returns a *SlowClientError, so the producer can
+	// stop generating data.
+	WriteTimeout time.Duration
+
+	// AbortSlowClients causes the connection to be closed once a client has
+	// been detected as slow, by panicking with http.ErrAbortHandler after the
+	// producer returns. Otherwise, the handler decides what to do.
+	AbortSlowClients bool
+
+	// KeepAliveInterval, if positive, is how often WriteWhenReady sends a
+	// newline while it waits for data, keeping the connection from appearing
+	// idle.
+	KeepAliveInterval time.Duration
+}
+
+// SlowClientError is returned by the send function of WriteNDJSONStream when a
+// value couldn't be written before the StreamOptions' WriteTimeout.
+type SlowClientError struct {
+	Timeout time.Duration
+	Err     error // the underlying write error
+}
+
+func (e *SlowClientError) Error() string {
+	return fmt.Sprintf("jsonbody: client did not accept stream data within %v: %v", e.Timeout, e.Err)
+}
+
+func (e *SlowClientError) Unwrap() error {
+	return e.Err
+}
+
+// WriteNDJSONStream sends a stream of JSON values as newline-delimited JSON
+// (application/x-ndjson), encoding each value in the same way as WriteJSON.
+// produce is called with a send function that writes a single value; it should
+// call send for each value and return when there are no more (or send returns
+// an error). WriteNDJSONStream returns produce's error, or the error that
+// occurred while writing if produce returned nil.
+//
+// Unlike WriteJSON, the status code and headers are sent before the first value
+// is produced, so errors that occur partway through can't be reported to the
+// client with an error response. See StreamOptions for detecting slow clients.
+func (w *Writer) WriteNDJSONStream(statusCode int, produce func(send func(v interface{}) error) error) error {
+	if w.written {
+		return errors.New("method has already been called once and cannot be called again")
+	}
+
+	w.writes.beginJSON()
+	defer w.writes.endJSON()
+
+	var opts StreamOptions
+	if w.config != nil {
+		opts = w.config.stream
+	}
+
+	w.setDefaultHeaders()
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.Header().Del("Content-Length")
+
+	var dst io.Writer = w.ResponseWriter
+	var gz *gzip.Writer
+	if opts.Compress {
+		w.Header().Add("Vary", "Accept-Encoding")
+		if acceptsGzip(w.acceptEncoding) {
+			w.Header().Set("Content-Encoding", "gzip")
+			gz = gzip.NewWriter(w.ResponseWriter)
+			dst = gz
+		}
+	}
+
+	w.WriteHeader(statusCode)
+	w.written = true
+
+	rc := http.NewResponseController(w.ResponseWriter)
+	var mu sync.Mutex
+	var writeErr error
+	flush := func() {
+		if gz != nil && writeErr == nil {
+			writeErr = gz.Flush()
+		}
+		if err := rc.Flush(); err != nil && !errors.Is(err, http.ErrNotSupported) && writeErr == nil {
+			writeErr = err
+		}
+		writeErr = opts.slowClientErr(writeErr)
+	}
+
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	if opts.FlushInterval > 0 {
+		ticker := time.NewTicker(opts.FlushInterval)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ticker.C:
+					mu.Lock()
+					flush()
+					mu.Unlock()
+				case <-stop:
+					return
+				}
+			}
+		}()
+	}
+
+	send := func(v interface{}) error {
+		line, err := w.encode(statusCode, v)
+		if err != nil {
+			return fmt.Errorf("jsonbody: failed to encode stream value: %v", err)
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		if writeErr != nil {
+			return writeErr
+		}
+
+		if opts.WriteTimeout > 0 {
+			rc.SetWriteDeadline(time.Now().Add(opts.WriteTimeout))
+		}
+
+		_, writeErr = dst.Write(append(line, '\n'))
+		if writeErr == nil && opts.FlushInterval == 0 {
+			flush()
+		}
+
+		writeErr = opts.slowClientErr(writeErr)
+		return writeErr
+	}
+
+	err := produce(send)
+
+	close(stop)
+	wg.Wait()
+
+	if gz != nil && writeErr == nil {
+		writeErr = gz.Close()
+	}
+	if writeErr == nil {
+		flush()
+	}
+
+	if opts.WriteTimeout > 0 {
+		rc.SetWriteDeadline(time.Time{})
+	}
+
+	var slow *SlowClientError
+	if errors.As(writeErr, &slow) {
+		if opts.AbortSlowClients {
+			log.Println(slow)
+			panic(http.ErrAbortHandler)
+		}
+
+		if err == nil {
+			return slow
+		}
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if writeErr != nil {
+		log.Println(fmt.Errorf("jsonbody: failed to write stream: %v", writeErr))
+		return errors.New("sending the response stream failed")
+	}
+
+	return nil
+}
+
+// slowClientErr wraps err in a *SlowClientError if it was caused by the write
+// deadline.
+func (o StreamOptions) slowClientErr(err error) error {
+	var slow *SlowClientError
+	if o.WriteTimeout <= 0 || !errors.Is(err, os.ErrDeadlineExceeded) || errors.As(err, &slow) {
+		return err
+	}
+
+	return &SlowClientError{Timeout: o.WriteTimeout, Err: err}
+}
+
+// acceptsGzip reports whether an Accept-Encoding header value allows gzip.
+func acceptsGzip(acceptEncoding string) bool {
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		fields := strings.Split(part, ";")
+		coding := strings.ToLower(strings.TrimSpace(fields[0]))
+		if coding != "gzip" && coding != "*" {
+			continue
+		}
+
+		q := 1.0
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				q, _ = strconv.ParseFloat(param[2:], 64)
+			}
+		}
+
+		return q > 0
+	}
+
+	return false
+}
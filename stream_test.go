@@ -0,0 +1,149 @@
+package jsonbody
+
+import (
+	"compress/gzip"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAcceptsGzip(t *testing.T) {
+	assert.True(t, acceptsGzip("deflate, gzip;q=0.5"))
+	assert.True(t, acceptsGzip("*"))
+	assert.False(t, acceptsGzip("gzip;q=0"))
+	assert.False(t, acceptsGzip("br"))
+}
+
+func TestWriteNDJSONStreamWritesLines(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	w := Writer{ResponseWriter: recorder}
+
+	err := w.WriteNDJSONStream(200, func(send func(v interface{}) error) error {
+		for i := 0; i < 3; i++ {
+			if err := send(map[string]int{"n": i}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	assert.Nil(t, err)
+
+	assert.Equal(t, "application/x-ndjson", recorder.Header().Get("Content-Type"))
+	assert.Equal(t, "{\"n\":0}\n{\"n\":1}\n{\"n\":2}\n", recorder.Body.String())
+	assert.True(t, recorder.Flushed)
+	assert.NotNil(t, w.WriteJSON(200, "again"))
+}
+
+func TestWriteNDJSONStreamCompressesIfAccepted(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	w := Writer{
+		ResponseWriter: recorder,
+		config:         &writerConfig{stream: StreamOptions{Compress: true, FlushInterval: time.Millisecond}},
+		acceptEncoding: "gzip",
+	}
+
+	err := w.WriteNDJSONStream(200, func(send func(v interface{}) error) error {
+		send("a")
+		time.Sleep(5 * time.Millisecond)
+		return send("b")
+	})
+	assert.Nil(t, err)
+
+	assert.Equal(t, "gzip", recorder.Header().Get("Content-Encoding"))
+	assert.Equal(t, "Accept-Encoding", recorder.Header().Get("Vary"))
+
+	gz, err := gzip.NewReader(recorder.Body)
+	assert.Nil(t, err)
+	body, err := io.ReadAll(gz)
+	assert.Nil(t, err)
+	assert.Equal(t, "\"a\"\n\"b\"\n", string(body))
+}
+
+func TestWriteNDJSONStreamNotCompressIfNotAccepted(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	w := Writer{ResponseWriter: recorder, config: &writerConfig{stream: StreamOptions{Compress: true}}}
+
+	err := w.WriteNDJSONStream(200, func(send func(v interface{}) error) error {
+		return send("a")
+	})
+	assert.Nil(t, err)
+
+	assert.Equal(t, "", recorder.Header().Get("Content-Encoding"))
+	assert.Equal(t, "\"a\"\n", recorder.Body.String())
+}
+
+func TestWriteNDJSONStreamReturnsProducerErr(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	w := Writer{ResponseWriter: recorder}
+
+	producerErr := errors.New("database went away")
+	err := w.WriteNDJSONStream(200, func(send func(v interface{}) error) error {
+		return producerErr
+	})
+	assert.Equal(t, producerErr, err)
+}
+
+// deadlineWriter simulates a client that has stopped reading: writes fail once
+// the write deadline has passed.
+type deadlineWriter struct {
+	*httptest.ResponseRecorder
+	deadline time.Time
+	stalled  bool
+}
+
+func (w *deadlineWriter) SetWriteDeadline(deadline time.Time) error {
+	w.deadline = deadline
+	return nil
+}
+
+func (w *deadlineWriter) Write(b []byte) (int, error) {
+	if w.stalled {
+		time.Sleep(time.Until(w.deadline))
+		return 0, os.ErrDeadlineExceeded
+	}
+	return w.ResponseRecorder.Write(b)
+}
+
+func TestWriteNDJSONStreamReturnsSlowClientError(t *testing.T) {
+	dw := &deadlineWriter{ResponseRecorder: httptest.NewRecorder()}
+	w := Writer{ResponseWriter: dw, config: &writerConfig{stream: StreamOptions{WriteTimeout: time.Millisecond}}}
+
+	var sendErr error
+	sent := 0
+	err := w.WriteNDJSONStream(200, func(send func(v interface{}) error) error {
+		for i := 0; i < 5; i++ {
+			if i == 2 {
+				dw.stalled = true
+			}
+			if sendErr = send(i); sendErr != nil {
+				return nil
+			}
+			sent++
+		}
+		return nil
+	})
+
+	var slow *SlowClientError
+	assert.True(t, errors.As(sendErr, &slow))
+	assert.True(t, errors.Is(sendErr, os.ErrDeadlineExceeded))
+	assert.Equal(t, sendErr, err)
+	assert.Equal(t, 2, sent)
+	assert.True(t, dw.deadline.IsZero())
+}
+
+func TestWriteNDJSONStreamAbortsSlowClient(t *testing.T) {
+	dw := &deadlineWriter{ResponseRecorder: httptest.NewRecorder(), stalled: true}
+	w := Writer{ResponseWriter: dw, config: &writerConfig{stream: StreamOptions{WriteTimeout: time.Millisecond, AbortSlowClients: true}}}
+
+	assert.PanicsWithValue(t, http.ErrAbortHandler, func() {
+		w.WriteNDJSONStream(200, func(send func(v interface{}) error) error {
+			return send(1)
+		})
+	})
+}
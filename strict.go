@@ -0,0 +1,124 @@
+package jsonbody
+
+import (
+	"fmt"
+	"sort"
+)
+
+// unknownKeys returns an error for each key in body that isn't in the schema,
+// for WithStrictKeys. A key is known if it is in any of the schemas combined by
+// AllOf or, during a grace period, in the schema that s replaced. Keys inside
+// empty objects and constraint objects are all known, since those accept any
+// contents.
+func (s *Schema) unknownKeys(body interface{}) []ValidationError {
+	errs := make([]ValidationError, 0)
+	if s == nil || body == nil {
+		return errs
+	}
+
+	checkUnknownNestedKeys(&errs, "", s.values(), body)
+	return errs
+}
+
+// values returns the decoded JSON of the schema's body (an object, array, or
+// scalar), or of the bodies of each of the schemas it combines.
+func (s *Schema) values() []interface{} {
+	if s == nil {
+		return nil
+	}
+
+	var values []interface{}
+	switch {
+	case len(s.allOf) > 0:
+		for _, part := range s.allOf {
+			values = append(values, part.values()...)
+		}
+	case s.array != nil:
+		values = append(values, s.array)
+	case s.scalar != nil:
+		values = append(values, s.scalar)
+	case s.body != nil:
+		values = append(values, s.body)
+	}
+
+	if s.inGracePeriod() {
+		values = append(values, s.grace.previous.values()...)
+	}
+
+	return values
+}
+
+// checkUnknownKeys appends an error to errs for each key in actual that isn't
+// in any of the schema objects in expected.
+func checkUnknownKeys(errs *[]ValidationError, key string, expected []map[string]interface{}, actual map[string]interface{}) {
+	keys := make([]string, 0, len(actual))
+	for k := range actual {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		newKey := joinKey(key, k)
+
+		var known bool
+		var nested []interface{}
+		for _, obj := range expected {
+			for _, schemaKey := range []string{k, "?" + k} {
+				if val, ok := obj[schemaKey]; ok {
+					known = true
+					nested = append(nested, val)
+				}
+			}
+		}
+
+		if !known {
+			*errs = append(*errs, ValidationError{Key: newKey, Code: CodeUnknownKey})
+			continue
+		}
+
+		checkUnknownNestedKeys(errs, newKey, nested, actual[k])
+	}
+}
+
+// checkUnknownNestedKeys checks the keys of the objects in actual, which is the
+// value for a key whose schema values are expected.
+func checkUnknownNestedKeys(errs *[]ValidationError, key string, expected []interface{}, actual interface{}) {
+	switch actual := actual.(type) {
+	case map[string]interface{}:
+		objs := make([]map[string]interface{}, 0, len(expected))
+		for _, val := range expected {
+			if _, isConstraint := val.(*constraint); isConstraint {
+				return // any contents are allowed
+			}
+
+			obj, ok := val.(map[string]interface{})
+			if !ok {
+				continue
+			}
+
+			if len(obj) == 0 {
+				return // any contents are allowed
+			}
+			objs = append(objs, obj)
+		}
+
+		if len(objs) > 0 {
+			checkUnknownKeys(errs, key, objs, actual)
+		}
+	case []interface{}:
+		elems := make([]interface{}, 0, len(expected))
+		for _, val := range expected {
+			if arr, ok := val.([]interface{}); ok && len(arr) > 0 {
+				elems = append(elems, arr[0])
+			}
+		}
+
+		if len(elems) == 0 {
+			return
+		}
+
+		for i, elem := range actual {
+			checkUnknownNestedKeys(errs, fmt.Sprintf("%v[%v]", key, i), elems, elem)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package jsonbody
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnknownKeys(t *testing.T) {
+	schema := MustParseSchema(`{"email": "", "?name": "", "author": {"id": 0}, "tags": [{"label": ""}], "meta": {}, "age": {"$type": "object"}}`)
+	body := decodeObject(t, `{"email": "a", "emial": "b", "author": {"id": 1, "idd": 2}, "tags": [{"label": "x"}, {"lable": "y"}], "meta": {"any": 1}, "age": {"any": 1}}`)
+
+	assert.Equal(t, []string{
+		"key 'author.idd' is not in the schema",
+		"key 'emial' is not in the schema",
+		"key 'tags[1].lable' is not in the schema",
+	}, errorMessages(schema.unknownKeys(body)))
+}
+
+func TestUnknownKeysAllowsKeysOfAnyCombinedSchema(t *testing.T) {
+	schema := AllOf(MustParseSchema(`{"a": 0, "o": {"x": 0}}`), MustParseSchema(`{"b": 0, "o": {"y": 0}}`))
+	body := decodeObject(t, `{"a": 1, "b": 2, "c": 3, "o": {"x": 1, "y": 2, "z": 3}}`)
+
+	assert.Equal(t, []string{
+		"key 'c' is not in the schema",
+		"key 'o.z' is not in the schema",
+	}, errorMessages(schema.unknownKeys(body)))
+}
+
+func TestServeHTTPRejectsUnknownKeysIfStrict(t *testing.T) {
+	called := false
+	handler := NewMiddleware(`{"email": ""}`, WithStrictKeys())(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"email": "a", "emial": "a"}`))
+	req.Header.Set("Content-Type", "application/json")
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, req)
+
+	assert.Equal(t, 400, recorder.Code)
+	assert.Equal(t, `{"errors":["key 'emial' is not in the schema"]}`, recorder.Body.String())
+	assert.False(t, called)
+}
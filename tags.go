@@ -0,0 +1,191 @@
+package jsonbody
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// validateStruct checks the values of v against the rules in the jsonbody struct
+// tags of its fields (and of any nested structs), returning a list of errors in
+// the same format as request body validation errors. Keys are named using the
+// fields' json tags. See Writer.WriteValidated for the supported rules.
+func validateStruct(v interface{}) []string {
+	return validateStructValue("", reflect.ValueOf(v), map[visit]bool{})
+}
+
+// visit identifies a pointer or map being validated, so that values that refer
+// to themselves aren't validated forever.
+type visit struct {
+	ptr uintptr
+	typ reflect.Type
+}
+
+// validateStructValue validates v, which is at key. visiting holds the pointers
+// and maps that v is nested in.
+func validateStructValue(key string, v reflect.Value, visiting map[visit]bool) []string {
+	errs := make([]string, 0)
+
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return errs
+		}
+		if v.Kind() == reflect.Ptr {
+			vis := visit{ptr: v.Pointer(), typ: v.Type()}
+			if visiting[vis] {
+				return errs
+			}
+			visiting[vis] = true
+			defer delete(visiting, vis)
+		}
+		v = v.Elem()
+	}
+
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			errs = append(errs, validateStructValue(fmt.Sprintf("%v[%v]", key, i), v.Index(i), visiting)...)
+		}
+	case reflect.Map:
+		vis := visit{ptr: v.Pointer(), typ: v.Type()}
+		if visiting[vis] {
+			return errs
+		}
+		visiting[vis] = true
+		defer delete(visiting, vis)
+
+		iter := v.MapRange()
+		for iter.Next() {
+			errs = append(errs, validateStructValue(joinKey(key, fmt.Sprint(iter.Key().Interface())), iter.Value(), visiting)...)
+		}
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if f.PkgPath != "" && !f.Anonymous {
+				continue
+			}
+
+			jsonTag := f.Tag.Get("json")
+			if jsonTag == "-" {
+				continue
+			}
+
+			name := strings.Split(jsonTag, ",")[0]
+			fieldVal := v.Field(i)
+
+			if f.Anonymous && name == "" {
+				errs = append(errs, validateStructValue(key, fieldVal, visiting)...)
+				continue
+			}
+
+			if name == "" {
+				name = f.Name
+			}
+
+			fieldKey := joinKey(key, name)
+			errs = append(errs, validateTagRules(fieldKey, f.Tag.Get("jsonbody"), fieldVal)...)
+			errs = append(errs, validateStructValue(fieldKey, fieldVal, visiting)...)
+		}
+	}
+
+	return errs
+}
+
+func validateTagRules(key string, tag string, v reflect.Value) []string {
+	errs := make([]string, 0)
+	if tag == "" {
+		return errs
+	}
+
+	for _, rule := range strings.Split(tag, ",") {
+		name, arg := rule, ""
+		if i := strings.Index(rule, "="); i >= 0 {
+			name, arg = rule[:i], rule[i+1:]
+		}
+
+		switch name {
+		case "required":
+			if isEmptyValue(v) {
+				errs = append(errs, fmt.Sprintf("expected key '%v' missing", key))
+			}
+		case "min", "max":
+			bound, err := strconv.ParseFloat(arg, 64)
+			if err != nil {
+				errs = append(errs, fmt.Sprintf("invalid rule '%v' for key '%v'", rule, key))
+				continue
+			}
+
+			size, isLen, ok := measure(v)
+			if !ok {
+				continue
+			}
+
+			if name == "min" && size < bound {
+				if isLen {
+					errs = append(errs, fmt.Sprintf("value for key '%v' expected to have length of at least %v", key, arg))
+				} else {
+					errs = append(errs, fmt.Sprintf("value for key '%v' expected to be at least %v", key, arg))
+				}
+			} else if name == "max" && size > bound {
+				if isLen {
+					errs = append(errs, fmt.Sprintf("value for key '%v' expected to have length of at most %v", key, arg))
+				} else {
+					errs = append(errs, fmt.Sprintf("value for key '%v' expected to be at most %v", key, arg))
+				}
+			}
+		case "oneof":
+			if (v.Kind() == reflect.Ptr && v.IsNil()) || !v.CanInterface() {
+				continue
+			}
+
+			allowed := strings.Fields(arg)
+			actual := fmt.Sprint(reflect.Indirect(v).Interface())
+			found := false
+			for _, a := range allowed {
+				if a == actual {
+					found = true
+					break
+				}
+			}
+
+			if !found {
+				errs = append(errs, fmt.Sprintf("value for key '%v' expected to be one of [%v]", key, strings.Join(allowed, ", ")))
+			}
+		}
+	}
+
+	return errs
+}
+
+// measure returns the numeric value of v if it is a number, or its length if it
+// is a string (in characters), slice, array, or map. isLen reports which was returned, and ok is
+// false if v is neither.
+func measure(v reflect.Value) (size float64, isLen bool, ok bool) {
+	v = reflect.Indirect(v)
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), false, true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return float64(v.Uint()), false, true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), false, true
+	case reflect.String:
+		return float64(utf8.RuneCountInString(v.String())), true, true
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return float64(v.Len()), true, true
+	}
+
+	return 0, false, false
+}
+
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Slice, reflect.Map:
+		return v.Len() == 0
+	}
+
+	return v.IsZero()
+}
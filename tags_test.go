@@ -0,0 +1,68 @@
+package jsonbody
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type taggedAuthor struct {
+	Name string `json:"name" jsonbody:"required"`
+}
+
+type taggedPost struct {
+	Title   string         `json:"title" jsonbody:"required,max=5"`
+	Status  string         `json:"status" jsonbody:"oneof=draft published"`
+	Upvotes int            `json:"upvotes" jsonbody:"min=0"`
+	Tags    []string       `json:"tags" jsonbody:"min=1"`
+	Authors []taggedAuthor `json:"authors"`
+}
+
+func TestValidateStructReturnsNoErrorsIfValid(t *testing.T) {
+	errs := validateStruct(taggedPost{
+		Title:   "hi",
+		Status:  "draft",
+		Upvotes: 3,
+		Tags:    []string{"a"},
+		Authors: []taggedAuthor{{Name: "jo"}},
+	})
+	assert.Equal(t, 0, len(errs))
+}
+
+func TestValidateStructReturnsErrorsIfInvalid(t *testing.T) {
+	errs := validateStruct(&taggedPost{
+		Title:   "too long",
+		Status:  "deleted",
+		Upvotes: -1,
+		Authors: []taggedAuthor{{}},
+	})
+	assert.Equal(t, []string{
+		"value for key 'title' expected to have length of at most 5",
+		"value for key 'status' expected to be one of [draft, published]",
+		"value for key 'upvotes' expected to be at least 0",
+		"value for key 'tags' expected to have length of at least 1",
+		"expected key 'authors[0].name' missing",
+	}, errs)
+}
+
+func TestValidateStructCountsCharacters(t *testing.T) {
+	errs := validateStruct(taggedPost{
+		Title:   "héllo",
+		Upvotes: 0,
+		Tags:    []string{"a"},
+	})
+	assert.Equal(t, []string{"value for key 'status' expected to be one of [draft, published]"}, errs)
+}
+
+type taggedNode struct {
+	Name string      `json:"name" jsonbody:"required"`
+	Next *taggedNode `json:"next"`
+}
+
+func TestValidateStructStopsAtCycles(t *testing.T) {
+	a := &taggedNode{Name: "a"}
+	b := &taggedNode{Next: a}
+	a.Next = b
+
+	assert.Equal(t, []string{"expected key 'next.name' missing"}, validateStruct(a))
+}
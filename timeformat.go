@@ -0,0 +1,498 @@
+package jsonbody
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// TimeFormat determines how time.Time values are encoded in response bodies.
+type TimeFormat int
+
+const (
+	// TimeRFC3339 encodes times as RFC 3339 strings with sub-second precision,
+	// e.g. "2019-09-24T10:30:00.5Z". This is the default, matching encoding/json.
+	TimeRFC3339 TimeFormat = iota
+
+	// TimeUnixMillis encodes times as the number of milliseconds since the Unix
+	// epoch, e.g. 1569321000500.
+	TimeUnixMillis
+)
+
+// DurationFormat determines how time.Duration values are encoded in response
+// bodies.
+type DurationFormat int
+
+const (
+	// DurationNanoseconds encodes durations as an integer number of
+	// nanoseconds. This is the default, matching encoding/json.
+	DurationNanoseconds DurationFormat = iota
+
+	// DurationMillis encodes durations as an integer number of milliseconds.
+	DurationMillis
+
+	// DurationISO8601 encodes durations as ISO 8601 duration strings, e.g.
+	// "PT1H30M" or "PT0.25S".
+	DurationISO8601
+)
+
+var (
+	timeType          = reflect.TypeOf(time.Time{})
+	orderedMapType    = reflect.TypeOf(OrderedMap{})
+	durationType      = reflect.TypeOf(time.Duration(0))
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+func (f TimeFormat) encode(t time.Time) interface{} {
+	if f == TimeUnixMillis {
+		return t.UnixMilli()
+	}
+
+	return t.Format(time.RFC3339Nano)
+}
+
+func (f DurationFormat) encode(d time.Duration) interface{} {
+	switch f {
+	case DurationMillis:
+		return int64(d / time.Millisecond)
+	case DurationISO8601:
+		return iso8601Duration(d)
+	}
+
+	return int64(d)
+}
+
+// iso8601Duration formats d as an ISO 8601 duration using hours, minutes, and
+// (possibly fractional) seconds.
+func iso8601Duration(d time.Duration) string {
+	if d == 0 {
+		return "PT0S"
+	}
+
+	var b strings.Builder
+	if d < 0 {
+		b.WriteString("-")
+		d = -d
+	}
+	b.WriteString("PT")
+
+	if h := d / time.Hour; h > 0 {
+		fmt.Fprintf(&b, "%dH", h)
+		d -= h * time.Hour
+	}
+
+	if m := d / time.Minute; m > 0 {
+		fmt.Fprintf(&b, "%dM", m)
+		d -= m * time.Minute
+	}
+
+	if d > 0 {
+		b.WriteString(strconv.FormatFloat(d.Seconds(), 'f', -1, 64))
+		b.WriteString("S")
+	}
+
+	return b.String()
+}
+
+// toGeneric converts v into the generic representation produced by decoding JSON
+// (maps, slices, and primitives), following the same rules as encoding/json but
+// encoding times and durations according to the given formats. Structs are
+// converted into OrderedMaps so that their fields keep their declared order.
+func toGeneric(v reflect.Value, tf TimeFormat, df DurationFormat) (interface{}, error) {
+	if !v.IsValid() {
+		return nil, nil
+	}
+
+	switch v.Type() {
+	case timeType:
+		return tf.encode(v.Interface().(time.Time)), nil
+	case durationType:
+		return df.encode(time.Duration(v.Int())), nil
+	}
+
+	if v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil, nil
+		}
+
+		if v.Kind() == reflect.Ptr && (v.Elem().Type() == timeType || v.Elem().Type() == orderedMapType) {
+			return toGeneric(v.Elem(), tf, df)
+		}
+	}
+
+	if v.Type() == orderedMapType {
+		m := v.Interface().(OrderedMap)
+		out := NewOrderedMap()
+		for _, key := range m.keys {
+			elem, err := toGeneric(reflect.ValueOf(m.values[key]), tf, df)
+			if err != nil {
+				return nil, err
+			}
+			out.Set(key, elem)
+		}
+		return out, nil
+	}
+
+	if marshaler, ok := asMarshaler(v, jsonMarshalerType); ok {
+		raw, err := marshaler.(json.Marshaler).MarshalJSON()
+		return json.RawMessage(raw), err
+	}
+
+	if marshaler, ok := asMarshaler(v, textMarshalerType); ok {
+		text, err := marshaler.(encoding.TextMarshaler).MarshalText()
+		return string(text), err
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		return toGeneric(v.Elem(), tf, df)
+
+	case reflect.Slice:
+		if v.IsNil() {
+			return nil, nil
+		}
+
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return v.Interface(), nil // encoded as base64 by encoding/json
+		}
+
+		fallthrough
+	case reflect.Array:
+		arr := make([]interface{}, v.Len())
+		for i := range arr {
+			elem, err := toGeneric(v.Index(i), tf, df)
+			if err != nil {
+				return nil, err
+			}
+			arr[i] = elem
+		}
+		return arr, nil
+
+	case reflect.Map:
+		if v.IsNil() {
+			return nil, nil
+		}
+
+		obj := make(map[string]interface{}, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			key, err := mapKeyString(iter.Key())
+			if err != nil {
+				return nil, err
+			}
+
+			elem, err := toGeneric(iter.Value(), tf, df)
+			if err != nil {
+				return nil, err
+			}
+			obj[key] = elem
+		}
+		return obj, nil
+
+	case reflect.Struct:
+		return structToGeneric(v, tf, df)
+	}
+
+	return v.Interface(), nil
+}
+
+// asMarshaler returns v as an iface (json.Marshaler or encoding.TextMarshaler)
+// if encoding/json would use that method to encode it: either v's type
+// implements iface or v is addressable and its pointer type does.
+func asMarshaler(v reflect.Value, iface reflect.Type) (interface{}, bool) {
+	if v.Type().Implements(iface) && v.CanInterface() {
+		return v.Interface(), true
+	}
+
+	if v.Kind() != reflect.Ptr && v.CanAddr() && reflect.PointerTo(v.Type()).Implements(iface) && v.Addr().CanInterface() {
+		return v.Addr().Interface(), true
+	}
+
+	return nil, false
+}
+
+// mapKeyString returns the object key encoding/json uses for the map key k.
+func mapKeyString(k reflect.Value) (string, error) {
+	if k.Kind() == reflect.String {
+		return k.String(), nil
+	}
+
+	if marshaler, ok := asMarshaler(k, textMarshalerType); ok {
+		if k.Kind() == reflect.Ptr && k.IsNil() {
+			return "", nil
+		}
+		text, err := marshaler.(encoding.TextMarshaler).MarshalText()
+		return string(text), err
+	}
+
+	switch k.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(k.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(k.Uint(), 10), nil
+	}
+
+	return "", fmt.Errorf("unsupported map key type %v", k.Type())
+}
+
+func structToGeneric(v reflect.Value, tf TimeFormat, df DurationFormat) (interface{}, error) {
+	obj := NewOrderedMap()
+
+fields:
+	for _, f := range cachedJSONFields(v.Type()) {
+		fieldVal := v
+		for _, i := range f.index {
+			if fieldVal.Kind() == reflect.Ptr {
+				if fieldVal.IsNil() {
+					continue fields // the field is in a nil embedded struct
+				}
+				fieldVal = fieldVal.Elem()
+			}
+			fieldVal = fieldVal.Field(i)
+		}
+
+		if f.omitEmpty && isEmptyJSONValue(fieldVal) {
+			continue
+		}
+
+		elem, err := toGeneric(fieldVal, tf, df)
+		if err != nil {
+			return nil, err
+		}
+
+		if f.quoted && elem != nil {
+			// durations encoded as ISO 8601 strings are already strings
+			if _, isString := elem.(string); !isString || f.typ.Kind() == reflect.String {
+				quoted, err := json.Marshal(elem)
+				if err != nil {
+					return nil, err
+				}
+				elem = string(quoted)
+			}
+		}
+
+		obj.Set(f.name, elem)
+	}
+
+	return obj, nil
+}
+
+// jsonField is a struct field that encoding/json encodes, possibly one promoted
+// from an embedded struct.
+type jsonField struct {
+	name      string
+	tagged    bool         // whether the name comes from a tag
+	index     []int        // the index sequence for reflect.Value.FieldByIndex
+	typ       reflect.Type // the field's type, or the type it points to
+	omitEmpty bool
+	quoted    bool // whether the value is encoded as a string (the ",string" option)
+}
+
+// jsonFieldsCache holds the results of jsonFields, by struct type.
+var jsonFieldsCache sync.Map // map[reflect.Type][]jsonField
+
+func cachedJSONFields(t reflect.Type) []jsonField {
+	if fields, ok := jsonFieldsCache.Load(t); ok {
+		return fields.([]jsonField)
+	}
+
+	fields, _ := jsonFieldsCache.LoadOrStore(t, jsonFields(t))
+	return fields.([]jsonField)
+}
+
+// jsonFields returns the fields of the struct type t that encoding/json
+// encodes, in the order it encodes them. Like encoding/json, it promotes the
+// fields of embedded structs, and when several fields have the same name, it
+// keeps the least nested one, preferring tagged fields, and drops them all if
+// that doesn't settle it.
+func jsonFields(t reflect.Type) []jsonField {
+	type embedded struct {
+		typ   reflect.Type
+		index []int
+	}
+
+	current := []embedded{}
+	next := []embedded{{typ: t}}
+	var count, nextCount map[reflect.Type]int
+	visited := map[reflect.Type]bool{}
+
+	var fields []jsonField
+	for len(next) > 0 {
+		current, next = next, current[:0]
+		count, nextCount = nextCount, map[reflect.Type]int{}
+
+		for _, e := range current {
+			if visited[e.typ] {
+				continue
+			}
+			visited[e.typ] = true
+
+			for i := 0; i < e.typ.NumField(); i++ {
+				sf := e.typ.Field(i)
+				ft := sf.Type
+				if ft.Name() == "" && ft.Kind() == reflect.Ptr {
+					ft = ft.Elem()
+				}
+
+				if sf.Anonymous {
+					// embedded structs of unexported types may still
+					// have exported fields
+					if sf.PkgPath != "" && ft.Kind() != reflect.Struct {
+						continue
+					}
+				} else if sf.PkgPath != "" {
+					continue
+				}
+
+				tag := sf.Tag.Get("json")
+				if tag == "-" {
+					continue
+				}
+
+				opts := strings.Split(tag, ",")
+				name := opts[0]
+				if !isValidJSONTagName(name) {
+					name = ""
+				}
+
+				index := append(append([]int(nil), e.index...), i)
+
+				if name == "" && sf.Anonymous && ft.Kind() == reflect.Struct {
+					nextCount[ft]++
+					if nextCount[ft] == 1 {
+						next = append(next, embedded{typ: ft, index: index})
+					}
+					continue
+				}
+
+				f := jsonField{name: name, tagged: name != "", index: index, typ: ft}
+				if f.name == "" {
+					f.name = sf.Name
+				}
+				for _, opt := range opts[1:] {
+					switch opt {
+					case "omitempty":
+						f.omitEmpty = true
+					case "string":
+						if ft.Implements(jsonMarshalerType) || ft.Implements(textMarshalerType) ||
+							reflect.PointerTo(ft).Implements(jsonMarshalerType) || reflect.PointerTo(ft).Implements(textMarshalerType) {
+							break // marshalers are never quoted
+						}
+
+						switch ft.Kind() {
+						case reflect.Bool,
+							reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+							reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+							reflect.Float32, reflect.Float64, reflect.String:
+							f.quoted = true
+						}
+					}
+				}
+
+				fields = append(fields, f)
+				if count[e.typ] > 1 {
+					// the struct is embedded more than once at this depth, so
+					// its fields conflict with themselves
+					fields = append(fields, f)
+				}
+			}
+		}
+	}
+
+	sort.Slice(fields, func(i, j int) bool {
+		x, y := fields[i], fields[j]
+		if x.name != y.name {
+			return x.name < y.name
+		}
+		if len(x.index) != len(y.index) {
+			return len(x.index) < len(y.index)
+		}
+		if x.tagged != y.tagged {
+			return x.tagged
+		}
+		return lessIndex(x.index, y.index)
+	})
+
+	dominant := fields[:0]
+	for i := 0; i < len(fields); {
+		j := i + 1
+		for j < len(fields) && fields[j].name == fields[i].name {
+			j++
+		}
+
+		// fields[i] is the least nested, preferring tagged fields; it only
+		// wins if the next one is less nested or untagged
+		if j-i == 1 || len(fields[i].index) < len(fields[i+1].index) || fields[i].tagged != fields[i+1].tagged {
+			dominant = append(dominant, fields[i])
+		}
+		i = j
+	}
+
+	sort.Slice(dominant, func(i, j int) bool {
+		return lessIndex(dominant[i].index, dominant[j].index)
+	})
+
+	return dominant
+}
+
+// lessIndex reports whether the field with index sequence a is declared before
+// the field with index sequence b.
+func lessIndex(a []int, b []int) bool {
+	for k, i := range a {
+		if k >= len(b) {
+			return false
+		}
+		if i != b[k] {
+			return i < b[k]
+		}
+	}
+
+	return len(a) < len(b)
+}
+
+// isValidJSONTagName reports whether encoding/json accepts name as the name in a
+// json struct tag.
+func isValidJSONTagName(name string) bool {
+	if name == "" {
+		return false
+	}
+
+	for _, c := range name {
+		switch {
+		case strings.ContainsRune("!#$%&()*+-./:;<=>?@[]^_{|}~ ", c):
+		case !unicode.IsLetter(c) && !unicode.IsDigit(c):
+			return false
+		}
+	}
+
+	return true
+}
+
+// isEmptyJSONValue reports whether v is considered empty by the omitempty option
+// of encoding/json.
+func isEmptyJSONValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Interface, reflect.Ptr:
+		return v.IsNil()
+	}
+
+	return false
+}
@@ -0,0 +1,134 @@
+package jsonbody
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIso8601DurationFormatsDurations(t *testing.T) {
+	assert.Equal(t, "PT0S", iso8601Duration(0))
+	assert.Equal(t, "PT1H30M", iso8601Duration(90*time.Minute))
+	assert.Equal(t, "PT2M0.25S", iso8601Duration(2*time.Minute+250*time.Millisecond))
+	assert.Equal(t, "-PT5S", iso8601Duration(-5*time.Second))
+}
+
+type timedEvent struct {
+	Name     string        `json:"name"`
+	At       time.Time     `json:"at"`
+	Ended    *time.Time    `json:"ended,omitempty"`
+	Duration time.Duration `json:"duration"`
+}
+
+func TestWriteJSONAppliesTimeAndDurationFormats(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	w := Writer{
+		ResponseWriter: recorder,
+		config:         &writerConfig{timeFormat: TimeUnixMillis, durationFormat: DurationISO8601},
+	}
+
+	err := w.WriteJSON(200, []timedEvent{{
+		Name:     "launch",
+		At:       time.Unix(1569321000, 500*int64(time.Millisecond)),
+		Duration: 90 * time.Second,
+	}})
+	assert.Nil(t, err)
+
+	assert.Equal(t, `[{"name":"launch","at":1569321000500,"duration":"PT1M30S"}]`, recorder.Body.String())
+}
+
+func TestWriteJSONLeavesTimesUnchangedByDefault(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	w := Writer{ResponseWriter: recorder, config: &writerConfig{}}
+
+	at := time.Date(2019, 9, 24, 10, 30, 0, 0, time.UTC)
+	err := w.WriteJSON(200, timedEvent{Name: "launch", At: at, Duration: time.Second})
+	assert.Nil(t, err)
+
+	assert.Equal(t, `{"name":"launch","at":"2019-09-24T10:30:00Z","duration":1000000000}`, recorder.Body.String())
+}
+
+func TestTimeUnixMillisEncodesFarFutureTimes(t *testing.T) {
+	at := time.Date(2300, 1, 1, 0, 0, 0, 0, time.UTC)
+	assert.Equal(t, at.Unix()*1000, TimeUnixMillis.encode(at))
+}
+
+type pointerMarshaler struct{ v string }
+
+func (m *pointerMarshaler) MarshalJSON() ([]byte, error) {
+	return json.Marshal(strings.ToUpper(m.v))
+}
+
+type upperKey struct{ s string }
+
+func (k upperKey) MarshalText() ([]byte, error) {
+	return []byte(strings.ToUpper(k.s)), nil
+}
+
+type base struct {
+	ID    int    `json:"id"`
+	Name  string // conflicts with the field at depth 0
+	Color string
+	Size  string `json:"size"`
+}
+
+type other struct {
+	Color string // conflicts with base.Color at the same depth, so both are dropped
+	Size  string // loses to the tagged base.Size
+}
+
+type hidden struct {
+	Visible string
+}
+
+type encodedTypes struct {
+	Zebra    string             `json:"zebra"`
+	Apple    string             `json:"apple"`
+	Count    int                `json:"count,string"`
+	Ratio    *float64           `json:"ratio,string"`
+	Flag     bool               `json:"flag,string"`
+	Label    string             `json:"label,string"`
+	Elapsed  time.Duration      `json:"elapsed,string"`
+	Marshals []pointerMarshaler `json:"marshals"`
+	Keys     map[upperKey]int   `json:"keys"`
+	Name     string
+	base
+	*other
+	hidden
+}
+
+func TestToGenericMatchesEncodingJSON(t *testing.T) {
+	ratio := 0.5
+	body := &encodedTypes{
+		Zebra:    "z",
+		Apple:    "a",
+		Count:    3,
+		Ratio:    &ratio,
+		Flag:     true,
+		Label:    "hi",
+		Elapsed:  time.Second,
+		Marshals: []pointerMarshaler{{"a"}, {"b"}},
+		Keys:     map[upperKey]int{{"x"}: 1},
+		Name:     "outer",
+		base:     base{ID: 1, Name: "inner", Color: "red", Size: "L"},
+		other:    &other{Color: "blue", Size: "M"},
+		hidden:   hidden{Visible: "yes"},
+	}
+
+	for _, val := range []interface{}{body, []encodedTypes{*body}, encodedTypes{}} {
+		expected, err := json.Marshal(val)
+		assert.Nil(t, err)
+
+		generic, err := toGeneric(reflect.ValueOf(val), TimeRFC3339, DurationNanoseconds)
+		assert.Nil(t, err)
+		actual, err := json.Marshal(generic)
+		assert.Nil(t, err)
+
+		assert.Equal(t, string(expected), string(actual))
+	}
+}
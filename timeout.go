@@ -0,0 +1,230 @@
+package jsonbody
+
+import (
+	"context"
+	"net/http"
+	"sync"
+)
+
+// callNext calls the next handler, enforcing the handler timeout if one is set.
+func (m *middleware) callNext(writer Writer, r *http.Request) {
+	if m.handlerTimeout <= 0 {
+		m.serveHandler(writer, r)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), m.handlerTimeout)
+	defer cancel()
+	r = r.WithContext(ctx)
+
+	tw := &timeoutWriter{
+		ResponseWriter: writer.ResponseWriter,
+		header:         writer.ResponseWriter.Header().Clone(),
+	}
+	writer.ResponseWriter = tw
+
+	// If the handler times out, it keeps running after callNext returns, so it
+	// keeps the request's body (and the memory reserved for it) until it's done.
+	resources := requestResources(r.Context())
+	resources.hold()
+
+	done := make(chan struct{})
+	panicChan := make(chan interface{}, 1)
+	go func() {
+		defer resources.release()
+		defer func() {
+			if p := recover(); p != nil {
+				panicChan <- p
+			}
+		}()
+		m.serveHandler(writer, r)
+		close(done)
+	}()
+
+	select {
+	case p := <-panicChan:
+		panic(p)
+	case <-done:
+		return
+	case <-ctx.Done():
+	}
+
+	tw.mu.Lock()
+	if !tw.wroteHeader {
+		tw.timedOut = true
+		tw.mu.Unlock()
+
+		timeoutResp := Writer{ResponseWriter: tw.ResponseWriter, config: writer.config, locale: writer.locale}
+		timeoutResp.writeErrors(http.StatusGatewayTimeout, ValidationError{Code: CodeTimeout})
+		return
+	}
+	tw.mu.Unlock()
+
+	// the handler has already started responding, so let it finish
+	select {
+	case p := <-panicChan:
+		panic(p)
+	case <-done:
+	}
+}
+
+// bodyResources holds the cleanup functions for resources tied to a request's
+// body, like its spill file and the memory reserved for it with a MemoryGuard.
+// They are run once every holder has released the resources: the middleware
+// when it finishes serving the request, and the handler's goroutine if it is
+// run with a timeout.
+type bodyResources struct {
+	mu      sync.Mutex
+	holders int
+	cleanup []func()
+}
+
+type bodyResourcesContextKey struct{}
+
+// withBodyResources returns a copy of ctx holding res.
+func withBodyResources(ctx context.Context, res *bodyResources) context.Context {
+	return context.WithValue(ctx, bodyResourcesContextKey{}, res)
+}
+
+// requestResources returns the bodyResources held in ctx, or nil if there are
+// none.
+func requestResources(ctx context.Context) *bodyResources {
+	res, _ := ctx.Value(bodyResourcesContextKey{}).(*bodyResources)
+	return res
+}
+
+// newBodyResources returns a bodyResources with a single holder.
+func newBodyResources() *bodyResources {
+	return &bodyResources{holders: 1}
+}
+
+// add registers f to be run when the resources are released.
+func (res *bodyResources) add(f func()) {
+	res.mu.Lock()
+	defer res.mu.Unlock()
+
+	res.cleanup = append(res.cleanup, f)
+}
+
+// hold adds a holder, which must call release when it's done with the resources.
+func (res *bodyResources) hold() {
+	if res == nil {
+		return
+	}
+
+	res.mu.Lock()
+	defer res.mu.Unlock()
+
+	res.holders++
+}
+
+// release removes a holder, running the cleanup functions in reverse order if it
+// was the last one.
+func (res *bodyResources) release() {
+	if res == nil {
+		return
+	}
+
+	res.mu.Lock()
+	res.holders--
+	if res.holders > 0 {
+		res.mu.Unlock()
+		return
+	}
+	cleanup := res.cleanup
+	res.cleanup = nil
+	res.mu.Unlock()
+
+	for i := len(cleanup) - 1; i >= 0; i-- {
+		cleanup[i]()
+	}
+}
+
+// serveHandler calls the next handler, then completes the response if the
+// handler wrote it with AppendJSON. If response statuses are declared for the
+// route, the handler's status is checked against them.
+func (m *middleware) serveHandler(writer Writer, r *http.Request) {
+	method := r.Method
+	if m.autoHead && method == http.MethodHead {
+		method = http.MethodGet // HEAD requests are served by the GET handler
+	}
+
+	if statuses, ok := m.statuses.match(method, r.URL.Path); ok {
+		writer.ResponseWriter = &statusChecker{
+			ResponseWriter: writer.ResponseWriter,
+			route:          r.Method + " " + r.URL.Path,
+			statuses:       statuses,
+			strict:         m.strictWrites,
+		}
+	}
+
+	m.next.ServeHTTP(writer, r)
+	writer.finishAppend()
+}
+
+// timeoutWriter passes writes through to the underlying http.ResponseWriter
+// until the handler times out, after which they fail with
+// http.ErrHandlerTimeout. The handler's headers are kept in a separate map and
+// copied to the underlying http.ResponseWriter when it writes, so that a handler
+// that is still running after the timeout can't modify the headers of the
+// timeout response.
+type timeoutWriter struct {
+	http.ResponseWriter
+	header http.Header
+
+	mu          sync.Mutex
+	wroteHeader bool
+	timedOut    bool
+}
+
+func (tw *timeoutWriter) Header() http.Header {
+	return tw.header
+}
+
+func (tw *timeoutWriter) WriteHeader(statusCode int) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+
+	if tw.timedOut {
+		return
+	}
+
+	tw.copyHeader()
+	if statusCode >= 200 {
+		tw.wroteHeader = true
+	}
+	tw.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (tw *timeoutWriter) Write(b []byte) (int, error) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+
+	if tw.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+
+	if !tw.wroteHeader {
+		tw.copyHeader()
+		tw.wroteHeader = true
+	}
+	return tw.ResponseWriter.Write(b)
+}
+
+func (tw *timeoutWriter) copyHeader() {
+	dst := tw.ResponseWriter.Header()
+	for k := range dst {
+		if _, ok := tw.header[k]; !ok {
+			delete(dst, k)
+		}
+	}
+
+	for k, v := range tw.header {
+		dst[k] = v
+	}
+}
+
+// Unwrap returns the underlying http.ResponseWriter for http.ResponseController.
+func (tw *timeoutWriter) Unwrap() http.ResponseWriter {
+	return tw.ResponseWriter
+}
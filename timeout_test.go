@@ -0,0 +1,87 @@
+package jsonbody
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServeHTTPSends504IfHandlerTimesOut(t *testing.T) {
+	release := make(chan struct{})
+	handlerErr := make(chan error, 1)
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		writer := w.(Writer)
+		handlerErr <- writer.WriteJSON(200, "too late")
+	})
+	handler := NewMiddleware("", WithHandlerTimeout(time.Millisecond))(next)
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", strings.NewReader(`{}`)))
+
+	assert.Equal(t, 504, recorder.Code)
+	assert.Equal(t, `{"errors":["the request timed out"]}`, recorder.Body.String())
+
+	close(release)
+	assert.NotNil(t, <-handlerErr)
+}
+
+func TestServeHTTPLetsStartedResponseFinishAfterTimeout(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(200)
+		<-r.Context().Done()
+		w.Write([]byte("done"))
+	})
+	handler := NewMiddleware("", WithHandlerTimeout(time.Millisecond))(next)
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	assert.Equal(t, 200, recorder.Code)
+	assert.Equal(t, "done", recorder.Body.String())
+}
+
+func TestServeHTTPPropagatesHandlerPanicWithTimeout(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("oops")
+	})
+	handler := NewMiddleware("", WithHandlerTimeout(time.Second))(next)
+
+	assert.Panics(t, func() {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	})
+}
+
+func TestServeHTTPKeepsBodyUntilTimedOutHandlerReturns(t *testing.T) {
+	guard := NewMemoryGuard(1024)
+	release := make(chan struct{})
+	read := make(chan string, 1)
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		b, _ := ioutil.ReadAll(r.Body)
+		read <- string(b)
+	})
+	handler := NewMiddleware(`{"a": ""}`,
+		WithHandlerTimeout(time.Millisecond),
+		WithSpillToDisk(4, t.TempDir()),
+		WithMemoryGuard(guard, time.Second),
+	)(next)
+
+	body := `{"a": "a long enough value"}`
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, req)
+
+	assert.Equal(t, 504, recorder.Code)
+	assert.Equal(t, int64(len(body)), guard.InUse())
+
+	close(release)
+	assert.Equal(t, body, <-read)
+	assert.Eventually(t, func() bool { return guard.InUse() == 0 }, time.Second, time.Millisecond)
+}
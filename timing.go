@@ -0,0 +1,39 @@
+package jsonbody
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// Timing is a breakdown of the latency the middleware added to a request before
+// calling the handler, e.g. for inclusion in application logs.
+type Timing struct {
+	Read     time.Duration // reading the body
+	Parse    time.Duration // parsing the body as JSON
+	Validate time.Duration // validating the body, query, and cookies, including async validators
+}
+
+// Total returns the sum of the durations.
+func (t Timing) Total() time.Duration {
+	return t.Read + t.Parse + t.Validate
+}
+
+type timingContextKey struct{}
+
+// RequestTiming returns the Timing of r, if it was passed to the handler by the
+// middleware. The Writer passed to the handler provides the same Timing.
+func RequestTiming(r *http.Request) (Timing, bool) {
+	t, ok := r.Context().Value(timingContextKey{}).(Timing)
+	return t, ok
+}
+
+// Timing returns the Timing of the request the Writer responds to.
+func (w Writer) Timing() Timing {
+	return w.timing
+}
+
+// withTiming returns a copy of ctx carrying t.
+func withTiming(ctx context.Context, t Timing) context.Context {
+	return context.WithValue(ctx, timingContextKey{}, t)
+}
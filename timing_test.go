@@ -0,0 +1,44 @@
+package jsonbody
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTimingTotal(t *testing.T) {
+	timing := Timing{Read: time.Millisecond, Parse: 2 * time.Millisecond, Validate: 3 * time.Millisecond}
+	assert.Equal(t, 6*time.Millisecond, timing.Total())
+}
+
+func TestServeHTTPExposesTiming(t *testing.T) {
+	var fromContext, fromWriter Timing
+	var ok bool
+	handler := NewMiddleware(`{"id": 0}`, WithAsyncValidator("id", func(ctx context.Context, v interface{}) error {
+		time.Sleep(5 * time.Millisecond)
+		return nil
+	}))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fromContext, ok = RequestTiming(r)
+		fromWriter = w.(Writer).Timing()
+	}))
+
+	recorder := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"id": 1}`))
+	r.Header.Set("Content-Type", "application/json")
+	handler.ServeHTTP(recorder, r)
+
+	assert.True(t, ok)
+	assert.Equal(t, fromContext, fromWriter)
+	assert.True(t, fromContext.Validate >= 5*time.Millisecond, fromContext.Validate)
+	assert.True(t, fromContext.Parse > 0, fromContext.Parse)
+}
+
+func TestRequestTimingReturnsFalseOutsideMiddleware(t *testing.T) {
+	_, ok := RequestTiming(httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.False(t, ok)
+}
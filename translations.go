@@ -0,0 +1,223 @@
+package jsonbody
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Translator produces the messages of errors in the language of each request.
+// Negotiate selects a locale using the request's Accept-Language header, and
+// Translate returns the message for an error in that locale, typically by
+// looking up a template for its Code and passing it to ValidationError.Format.
+// Translations is a Translator backed by bundles of templates; implement
+// Translator to use another source of translations, such as an i18n library.
+type Translator interface {
+	Negotiate(acceptLanguage string) string
+	Translate(locale string, e ValidationError) string
+}
+
+// Translations holds translations of the message catalog (see the Code
+// constants) into other languages, and selects the language of each error
+// response using the request's Accept-Language header. It is enabled with the
+// WithTranslations option.
+//
+// A locale's messages are looked up in the locale's bundle, then in the bundles
+// of its fallbacks (see SetFallback), then in the bundles of its parent locales
+// (e.g. "fr" for "fr-CA"), and finally in the default message catalog, so a
+// bundle only needs to contain the messages that differ from its fallbacks.
+type Translations struct {
+	defaultLocale string
+	bundles       map[string]map[string]string // locale (lowercase) -> code -> template
+	names         map[string]string            // locale (lowercase) -> locale as added
+	fallbacks     map[string][]string
+}
+
+// NewTranslations creates an empty Translations. defaultLocale is the language
+// of the default message catalog, typically "en"; it is selected when a client
+// prefers it or accepts none of the other locales.
+func NewTranslations(defaultLocale string) *Translations {
+	return &Translations{
+		defaultLocale: defaultLocale,
+		bundles:       make(map[string]map[string]string),
+		names:         make(map[string]string),
+		fallbacks:     make(map[string][]string),
+	}
+}
+
+// Add adds the message templates for locale (e.g. "fr-CA"), keyed by error code,
+// to its bundle. Templates use the same placeholders as the default catalog, e.g.
+// "la clé '{key}' est manquante".
+func (t *Translations) Add(locale string, messages map[string]string) {
+	lower := strings.ToLower(locale)
+	if t.bundles[lower] == nil {
+		t.bundles[lower] = make(map[string]string)
+		t.names[lower] = locale
+	}
+
+	for code, template := range messages {
+		t.bundles[lower][code] = template
+	}
+}
+
+// LoadFS adds a bundle for each file in fsys matching glob (see fs.Glob). Each
+// file must contain a JSON object mapping error codes to message templates, and
+// is named after its locale, e.g. "locales/fr-CA.json".
+func (t *Translations) LoadFS(fsys fs.FS, glob string) error {
+	files, err := fs.Glob(fsys, glob)
+	if err != nil {
+		return fmt.Errorf("jsonbody: invalid translation glob '%v': %v", glob, err)
+	}
+
+	for _, file := range files {
+		data, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return fmt.Errorf("jsonbody: failed to read translation bundle '%v': %v", file, err)
+		}
+
+		var messages map[string]string
+		err = json.Unmarshal(data, &messages)
+		if err != nil {
+			return fmt.Errorf("jsonbody: invalid translation bundle '%v': %v", file, err)
+		}
+
+		name := path.Base(file)
+		t.Add(strings.TrimSuffix(name, path.Ext(name)), messages)
+	}
+
+	return nil
+}
+
+// SetFallback sets the locales whose bundles are used, in order, for messages
+// that are missing from locale's bundle, before its parent locales are tried.
+// For example, SetFallback("pt-BR", "pt-PT") uses European Portuguese for
+// messages that have no Brazilian Portuguese translation.
+func (t *Translations) SetFallback(locale string, fallbacks ...string) {
+	t.fallbacks[strings.ToLower(locale)] = fallbacks
+}
+
+// Negotiate returns the locale that best matches the given Accept-Language
+// header value, taking its quality values into account. A language range
+// matches a locale with the same tag or, failing that, the tag's parent locales
+// (e.g. "de-CH" matches "de"). If no locale matches, the default locale is
+// returned.
+func (t *Translations) Negotiate(acceptLanguage string) string {
+	for _, tag := range parseAcceptLanguage(acceptLanguage) {
+		for _, candidate := range parentLocales(tag) {
+			if strings.EqualFold(candidate, t.defaultLocale) {
+				return t.defaultLocale
+			}
+
+			if name, ok := t.names[strings.ToLower(candidate)]; ok {
+				return name
+			}
+		}
+	}
+
+	return t.defaultLocale
+}
+
+// Translate returns the message for e in locale, following the locale's fallback
+// chain. Errors with a custom Message aren't translated.
+func (t *Translations) Translate(locale string, e ValidationError) string {
+	if e.Message != "" {
+		return e.Error()
+	}
+
+	for _, candidate := range t.chain(locale) {
+		if template, ok := t.bundles[candidate][e.Code]; ok {
+			return e.Format(template)
+		}
+	}
+
+	return e.Error()
+}
+
+// chain returns the lowercase locales whose bundles are searched for the
+// messages of locale, in order.
+func (t *Translations) chain(locale string) []string {
+	chain := make([]string, 0)
+	seen := make(map[string]bool)
+
+	var add func(locale string)
+	add = func(locale string) {
+		lower := strings.ToLower(locale)
+		if seen[lower] {
+			return
+		}
+		seen[lower] = true
+		chain = append(chain, lower)
+
+		for _, fallback := range t.fallbacks[lower] {
+			add(fallback)
+		}
+	}
+
+	for _, candidate := range parentLocales(locale) {
+		add(candidate)
+	}
+
+	return chain
+}
+
+// parentLocales returns locale followed by its parent locales, e.g. "zh-Hant-TW",
+// "zh-Hant", and "zh".
+func parentLocales(locale string) []string {
+	locales := []string{locale}
+	for i := strings.LastIndex(locale, "-"); i > 0; i = strings.LastIndex(locale, "-") {
+		locale = locale[:i]
+		locales = append(locales, locale)
+	}
+
+	return locales
+}
+
+// parseAcceptLanguage returns the language tags in an Accept-Language header
+// value in order of preference, omitting the wildcard and tags with a quality
+// value of 0.
+func parseAcceptLanguage(header string) []string {
+	type weighted struct {
+		tag string
+		q   float64
+	}
+
+	tags := make([]weighted, 0)
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(part, ";")
+		tag := strings.TrimSpace(fields[0])
+		if tag == "" || tag == "*" {
+			continue
+		}
+
+		q := 1.0
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				parsed, err := strconv.ParseFloat(param[2:], 64)
+				if err != nil {
+					parsed = 0
+				}
+				q = parsed
+			}
+		}
+
+		if q > 0 {
+			tags = append(tags, weighted{tag: tag, q: q})
+		}
+	}
+
+	sort.SliceStable(tags, func(i, j int) bool {
+		return tags[i].q > tags[j].q
+	})
+
+	result := make([]string, len(tags))
+	for i, w := range tags {
+		result[i] = w.tag
+	}
+
+	return result
+}
@@ -0,0 +1,131 @@
+package jsonbody
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func newTestTranslations() *Translations {
+	tr := NewTranslations("en")
+	tr.Add("fr", map[string]string{
+		CodeMissingKey: "clé attendue '{key}' manquante",
+		CodeWrongType:  "la valeur de la clé '{key}' doit être de type {type}",
+	})
+	tr.Add("fr-CA", map[string]string{
+		CodeMissingKey: "clé '{key}' manquante",
+	})
+	tr.Add("pt-PT", map[string]string{
+		CodeMissingKey: "chave '{key}' em falta",
+	})
+	tr.Add("pt-BR", map[string]string{})
+	tr.SetFallback("pt-BR", "pt-PT")
+	return tr
+}
+
+func TestParseAcceptLanguageOrdersByQuality(t *testing.T) {
+	tags := parseAcceptLanguage("de;q=0.5, fr-CA, *;q=0.1, en;q=0.8, es;q=0")
+	assert.Equal(t, []string{"fr-CA", "en", "de"}, tags)
+}
+
+func TestNegotiateMatchesParentLocale(t *testing.T) {
+	tr := newTestTranslations()
+	assert.Equal(t, "fr", tr.Negotiate("fr-BE"))
+	assert.Equal(t, "fr-CA", tr.Negotiate("FR-ca"))
+}
+
+func TestNegotiatePrefersDefaultLocaleWhenRequested(t *testing.T) {
+	tr := newTestTranslations()
+	assert.Equal(t, "en", tr.Negotiate("en-US, fr;q=0.9"))
+}
+
+func TestNegotiateReturnsDefaultLocaleIfNoMatch(t *testing.T) {
+	tr := newTestTranslations()
+	assert.Equal(t, "en", tr.Negotiate("ja, zh;q=0.5"))
+	assert.Equal(t, "en", tr.Negotiate(""))
+}
+
+func TestTranslateFollowsFallbackChain(t *testing.T) {
+	tr := newTestTranslations()
+	missing := ValidationError{Key: "a", Code: CodeMissingKey}
+	wrongType := wrongType("a", "string")
+
+	assert.Equal(t, "clé 'a' manquante", tr.Translate("fr-CA", missing))
+	assert.Equal(t, "la valeur de la clé 'a' doit être de type string", tr.Translate("fr-CA", wrongType))
+	assert.Equal(t, "chave 'a' em falta", tr.Translate("pt-BR", missing))
+	assert.Equal(t, "expected key 'a' missing", tr.Translate("en", missing))
+	assert.Equal(t, "value for key 'a' expected to be of type string", tr.Translate("pt-BR", wrongType))
+}
+
+func TestLoadFSAddsBundlePerFile(t *testing.T) {
+	fsys := fstest.MapFS{
+		"locales/de.json":    {Data: []byte(`{"missing_key": "Schlüssel '{key}' fehlt"}`)},
+		"locales/README.txt": {Data: []byte(`not a bundle`)},
+	}
+
+	tr := NewTranslations("en")
+	err := tr.LoadFS(fsys, "locales/*.json")
+	assert.Nil(t, err)
+
+	assert.Equal(t, "de", tr.Negotiate("de-AT"))
+	assert.Equal(t, "Schlüssel 'a' fehlt", tr.Translate("de", ValidationError{Key: "a", Code: CodeMissingKey}))
+}
+
+func TestLoadFSReturnsErrIfBundleInvalid(t *testing.T) {
+	fsys := fstest.MapFS{"de.json": {Data: []byte(`["not", "an", "object"]`)}}
+
+	err := NewTranslations("en").LoadFS(fsys, "*.json")
+	assert.NotNil(t, err)
+}
+
+func TestServeHTTPSendsTranslatedErrors(t *testing.T) {
+	next := &mockHandler{}
+	next.On("ServeHTTP", mock.Anything, mock.Anything).Return()
+	handler := NewMiddleware(`{"title": ""}`, WithTranslations(newTestTranslations()))(next)
+
+	recorder := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/posts", strings.NewReader(`{}`))
+	r.Header.Set("Content-Type", "application/json")
+	r.Header.Set("Accept-Language", "fr-CA;q=0.9, ja")
+	handler.ServeHTTP(recorder, r)
+
+	assert.Equal(t, 400, recorder.Code)
+	assert.Equal(t, "fr-CA", recorder.Header().Get("Content-Language"))
+	assert.Equal(t, `{"errors":["clé 'title' manquante"]}`, recorder.Body.String())
+}
+
+type pigLatinTranslator struct{}
+
+func (pigLatinTranslator) Negotiate(acceptLanguage string) string {
+	return "x-pig-latin"
+}
+
+func (pigLatinTranslator) Translate(locale string, e ValidationError) string {
+	return e.Format("eykay '{key}' issingmay")
+}
+
+func TestServeHTTPUsesCustomTranslator(t *testing.T) {
+	next := &mockHandler{}
+	next.On("ServeHTTP", mock.Anything, mock.Anything).Return()
+	handler := NewMiddleware(`{"title": ""}`, WithTranslator(pigLatinTranslator{}))(next)
+
+	recorder := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/posts", strings.NewReader(`{}`))
+	r.Header.Set("Content-Type", "application/json")
+	handler.ServeHTTP(recorder, r)
+
+	assert.Equal(t, 400, recorder.Code)
+	assert.Equal(t, "x-pig-latin", recorder.Header().Get("Content-Language"))
+	assert.Equal(t, `{"errors":["eykay 'title' issingmay"]}`, recorder.Body.String())
+}
+
+func TestWithTranslationsIgnoresNil(t *testing.T) {
+	m := &middleware{}
+	WithTranslations(nil)(m)
+	assert.Nil(t, m.writerConfig.translations)
+}
@@ -0,0 +1,49 @@
+package jsonbody
+
+import (
+	"context"
+	"net/http"
+)
+
+type typedBodyContextKey struct{}
+
+// NewTypedMiddleware creates a middleware like NewMiddleware that also stores
+// each validated body in a value of type T, which handlers retrieve with
+// TypedBody instead of accessing the map returned by Reader.JSON. The body is
+// bound to T in the same way as Reader.BindMap. If the validated body can't be
+// stored in a T (i.e. the schema and T disagree), a 500 response is sent without
+// calling the handler; see WithBindErrors for numbers that don't fit in T.
+func NewTypedMiddleware[T any](schemaJSON string, opts ...Option) func(next http.Handler) http.Handler {
+	mw := NewMiddleware(schemaJSON, opts...)
+
+	return func(next http.Handler) http.Handler {
+		return mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			reader := r.Body.(Reader)
+			body := new(T)
+			if reader.json != nil {
+				if err := bindValue(reader.json, body); err != nil {
+					writer := w.(Writer)
+					writer.writeBindError(err)
+					return
+				}
+			}
+
+			ctx := context.WithValue(r.Context(), typedBodyContextKey{}, body)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		}))
+	}
+}
+
+// TypedBody returns the body of r stored by the middleware created by
+// NewTypedMiddleware[T], or false if there isn't one (e.g. because the
+// middleware was created for a different type). If the request had no body, the
+// zero value of T is returned.
+func TypedBody[T any](r *http.Request) (T, bool) {
+	body, ok := r.Context().Value(typedBodyContextKey{}).(*T)
+	if !ok {
+		var zero T
+		return zero, false
+	}
+
+	return *body, true
+}
@@ -0,0 +1,110 @@
+package jsonbody
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type typedPost struct {
+	Title string   `json:"title"`
+	Tags  []string `json:"tags"`
+}
+
+func TestNewTypedMiddlewareStoresBody(t *testing.T) {
+	var post typedPost
+	var ok, otherOK bool
+	handler := NewTypedMiddleware[typedPost](`{"title": "", "?tags": [""]}`)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		post, ok = TypedBody[typedPost](r)
+		_, otherOK = TypedBody[string](r)
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"title": "hi", "tags": ["a"]}`))
+	req.Header.Set("Content-Type", "application/json")
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, req)
+
+	assert.Equal(t, 200, recorder.Code)
+	assert.True(t, ok)
+	assert.False(t, otherOK)
+	assert.Equal(t, typedPost{Title: "hi", Tags: []string{"a"}}, post)
+}
+
+func TestNewTypedMiddlewareValidatesBeforeBinding(t *testing.T) {
+	called := false
+	handler := NewTypedMiddleware[typedPost](`{"title": ""}`)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"title": 1}`))
+	req.Header.Set("Content-Type", "application/json")
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, req)
+
+	assert.Equal(t, 400, recorder.Code)
+	assert.False(t, called)
+}
+
+func TestNewTypedMiddlewareSends500IfSchemaAndTypeDisagree(t *testing.T) {
+	called := false
+	handler := NewTypedMiddleware[typedPost](`{"tags": ""}`)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"tags": "a"}`))
+	req.Header.Set("Content-Type", "application/json")
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, req)
+
+	assert.Equal(t, 500, recorder.Code)
+	assert.False(t, called)
+}
+
+type typedCounter struct {
+	Counts []int8 `json:"counts"`
+}
+
+func TestNewTypedMiddlewareReportsUnrepresentableNumbers(t *testing.T) {
+	for _, tc := range []struct {
+		opts []Option
+		body string
+		code int
+		resp string
+	}{
+		{nil, `{"counts": [1, 1.5]}`, 500, ``},
+		{[]Option{WithBindErrors()}, `{"counts": [1, 1.5]}`, 400, `{"errors": ["value for key 'counts[1]' is invalid: value is not an integer"]}`},
+		{[]Option{WithBindErrors()}, `{"counts": [300]}`, 400, `{"errors": ["value for key 'counts[0]' is invalid: value is out of range"]}`},
+		{[]Option{WithBindErrors(), WithErrorObjects()}, `{"counts": [300]}`, 400, `{"errors": [{"field": "counts[0]", "code": "invalid_value", "message": "value for key 'counts[0]' is invalid: value is out of range"}]}`},
+		{[]Option{WithBindErrors()}, `{"counts": [1, 2]}`, 200, ``},
+	} {
+		called := false
+		handler := NewTypedMiddleware[typedCounter](`{"counts": [0]}`, tc.opts...)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			called = true
+		}))
+
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tc.body))
+		req.Header.Set("Content-Type", "application/json")
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+
+		assert.Equal(t, tc.code, recorder.Code, tc.body)
+		assert.Equal(t, tc.code == 200, called, tc.body)
+		if tc.resp != "" {
+			assert.JSONEq(t, tc.resp, recorder.Body.String())
+		}
+	}
+}
+
+func TestNewTypedMiddlewareWithBindErrorsSends500ForTypeMismatch(t *testing.T) {
+	handler := NewTypedMiddleware[typedPost](`{"tags": ""}`, WithBindErrors())(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"tags": "a"}`))
+	req.Header.Set("Content-Type", "application/json")
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, req)
+
+	assert.Equal(t, 500, recorder.Code)
+}
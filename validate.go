@@ -5,9 +5,26 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"sort"
 	"strings"
 )
 
+// ErrorOrder determines the order in which validation errors are reported.
+// Regardless of the order, errors for a nested object or array are always
+// reported immediately after the errors for the key containing it (i.e.
+// depth-first).
+type ErrorOrder int
+
+const (
+	// OrderAlphabetical reports the errors for the keys of each object sorted
+	// alphabetically by key.
+	OrderAlphabetical ErrorOrder = iota
+
+	// OrderDeclaration reports the errors for the keys of each object in the
+	// order the keys are declared in the schema.
+	OrderDeclaration
+)
+
 func parseSchema(schemaJSON string) (map[string]interface{}, error) {
 	if schemaJSON == "" {
 		return nil, nil
@@ -23,88 +40,290 @@ func parseSchema(schemaJSON string) (map[string]interface{}, error) {
 	return schemaMap, nil
 }
 
+const (
+	schemaNameKey            = "$schemaName"
+	schemaDescriptionKey     = "$description"
+	schemaErrorCodePrefixKey = "$errorCodePrefix"
+)
+
+// schemaMeta holds the metadata attached to a schema through its top-level
+// "$schemaName", "$description", and "$errorCodePrefix" keys.
+type schemaMeta struct {
+	name            string
+	description     string
+	errorCodePrefix string
+}
+
+// extractSchemaMeta removes the metadata keys from the top level of the schema
+// and returns their values.
+func extractSchemaMeta(schema map[string]interface{}) (schemaMeta, error) {
+	var meta schemaMeta
+	if schema == nil {
+		return meta, nil
+	}
+
+	for key, dest := range map[string]*string{
+		schemaNameKey:            &meta.name,
+		schemaDescriptionKey:     &meta.description,
+		schemaErrorCodePrefixKey: &meta.errorCodePrefix,
+	} {
+		val, ok := schema[key]
+		if !ok {
+			continue
+		}
+
+		str, ok := val.(string)
+		if !ok {
+			return meta, fmt.Errorf("jsonbody: value for schema key '%v' must be a string", key)
+		}
+
+		*dest = str
+		delete(schema, key)
+	}
+
+	return meta, nil
+}
+
+// parseKeyOrder returns the keys of each object in the schema in the order they
+// are declared. The keys of the returned map are the paths to the objects, with
+// "" for the top-level object and "[]" denoting the elements of an array.
+func parseKeyOrder(schemaJSON string) (map[string][]string, error) {
+	if schemaJSON == "" {
+		return nil, nil
+	}
+
+	keyOrder := make(map[string][]string)
+	dec := json.NewDecoder(strings.NewReader(schemaJSON))
+	err := recordKeyOrder(dec, "", keyOrder)
+	if err != nil {
+		log.Printf("jsonbody: failed to decode schema: %v\n", err)
+		return nil, errors.New("jsonbody: failed to decode schema")
+	}
+
+	return keyOrder, nil
+}
+
+func recordKeyOrder(dec *json.Decoder, key string, keyOrder map[string][]string) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+
+	switch tok {
+	case json.Delim('{'):
+		keys := make([]string, 0)
+		for dec.More() {
+			tok, err := dec.Token()
+			if err != nil {
+				return err
+			}
+
+			// a key declared more than once keeps its first position, so that
+			// it is only validated once
+			k := tok.(string)
+			declared := false
+			for _, existing := range keys {
+				if existing == k {
+					declared = true
+					break
+				}
+			}
+			if !declared {
+				keys = append(keys, k)
+			}
+			err = recordKeyOrder(dec, joinKey(key, strings.TrimPrefix(k, "?")), keyOrder)
+			if err != nil {
+				return err
+			}
+		}
+		keyOrder[key] = keys
+	case json.Delim('['):
+		// only the first element of a schema array is used for validation
+		for i := 0; dec.More(); i++ {
+			if i == 0 {
+				err = recordKeyOrder(dec, key+"[]", keyOrder)
+			} else {
+				err = dec.Decode(&json.RawMessage{})
+			}
+
+			if err != nil {
+				return err
+			}
+		}
+	default:
+		return nil
+	}
+
+	_, err = dec.Token() // consume the closing delimiter
+	return err
+}
+
+func joinKey(parent string, key string) string {
+	if parent == "" {
+		return key
+	}
+
+	return parent + "." + key
+}
+
+type validator struct {
+	order    ErrorOrder
+	keyOrder map[string][]string
+}
+
 func validateReqBody(expected map[string]interface{}, actual map[string]interface{}) []string {
+	return errorMessages(validator{}.validateReqBody(expected, actual))
+}
+
+// validateBody checks the decoded body against the object, array, or scalar
+// schema of s.
+func (v validator) validateBody(s *Schema, body interface{}) []ValidationError {
+	if s.scalar != nil {
+		if body == nil {
+			return []ValidationError{{Code: CodeExpectedBody}}
+		}
+
+		if typ := jsonTypeName(s.scalar); jsonTypeName(body) != typ {
+			return []ValidationError{{Code: CodeBodyWrongType, Params: map[string]string{"type": typ}}}
+		}
+
+		return []ValidationError{}
+	}
+
+	if s.array != nil {
+		if body == nil {
+			return []ValidationError{{Code: CodeExpectedBody}}
+		}
+
+		arr, ok := body.([]interface{})
+		if !ok {
+			return []ValidationError{{Code: CodeBodyWrongType, Params: map[string]string{"type": "array"}}}
+		}
+
+		return v.validateArray("", "", s.array, arr)
+	}
+
+	obj, ok := body.(map[string]interface{})
+	if !ok && body != nil && s.body != nil {
+		return []ValidationError{{Code: CodeBodyWrongType, Params: map[string]string{"type": "object"}}}
+	}
+
+	return v.validateReqBody(s.body, obj)
+}
+
+func (v validator) validateReqBody(expected map[string]interface{}, actual map[string]interface{}) []ValidationError {
 	if expected == nil {
-		return []string{}
+		return []ValidationError{}
 	}
 
 	if actual == nil {
-		return []string{"expected a JSON body"}
+		return []ValidationError{{Code: CodeExpectedBody}}
 	}
 
-	return validateObject("", expected, actual)
+	return v.validateObject("", "", expected, actual)
 }
 
-func validateObject(key string, expected map[string]interface{}, actual map[string]interface{}) []string {
+// keys returns the keys of the schema object at schemaKey in the order their
+// errors should be reported.
+func (v validator) keys(schemaKey string, expected map[string]interface{}) []string {
+	if v.order == OrderDeclaration {
+		if declared, ok := v.keyOrder[schemaKey]; ok {
+			keys := make([]string, 0, len(declared))
+			for _, k := range declared {
+				if _, ok := expected[k]; ok { // skip keys that aren't validated, like metadata
+					keys = append(keys, k)
+				}
+			}
+
+			return keys
+		}
+	}
+
+	keys := make([]string, 0, len(expected))
+	for k := range expected {
+		keys = append(keys, k)
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		return strings.TrimPrefix(keys[i], "?") < strings.TrimPrefix(keys[j], "?")
+	})
+
+	return keys
+}
+
+func (v validator) validateObject(key string, schemaKey string, expected map[string]interface{}, actual map[string]interface{}) []ValidationError {
 	if len(expected) == 0 {
-		return []string{}
+		return []ValidationError{}
 	}
 
-	errs := make([]string, 0)
-	for expectedKey, expectedVal := range expected {
+	errs := make([]ValidationError, 0)
+	for _, expectedKey := range v.keys(schemaKey, expected) {
+		expectedVal := expected[expectedKey]
 		optional := strings.HasPrefix(expectedKey, "?")
 		expectedKey = strings.TrimPrefix(expectedKey, "?")
 
-		var newKey string
-		if key == "" {
-			newKey = expectedKey
-		} else {
-			newKey = key + "." + expectedKey
-		}
+		newKey := joinKey(key, expectedKey)
+		newSchemaKey := joinKey(schemaKey, expectedKey)
 
 		actualVal, ok := actual[expectedKey]
 		if !optional && !ok {
-			errs = append(errs, fmt.Sprintf("expected key '%v' missing", newKey))
+			errs = append(errs, ValidationError{Key: newKey, Code: CodeMissingKey})
 		} else if ok {
-			errs = append(errs, validateSingle(newKey, expectedVal, actualVal)...)
+			errs = append(errs, v.validateSingle(newKey, newSchemaKey, expectedVal, actualVal)...)
 		}
 	}
 
 	return errs
 }
 
-func validateSingle(key string, expected interface{}, actual interface{}) []string {
-	errs := make([]string, 0)
+func (v validator) validateSingle(key string, schemaKey string, expected interface{}, actual interface{}) []ValidationError {
+	errs := make([]ValidationError, 0)
 	switch expected := expected.(type) {
 	case string:
 		if _, ok := actual.(string); !ok {
-			errs = append(errs, fmt.Sprintf("value for key '%v' expected to be of type string", key))
+			errs = append(errs, wrongType(key, "string"))
 		}
 	case bool:
 		if _, ok := actual.(bool); !ok {
-			errs = append(errs, fmt.Sprintf("value for key '%v' expected to be of type boolean", key))
+			errs = append(errs, wrongType(key, "boolean"))
 		}
 	case float64:
 		if _, ok := actual.(float64); !ok {
-			errs = append(errs, fmt.Sprintf("value for key '%v' expected to be of type number", key))
+			errs = append(errs, wrongType(key, "number"))
 		}
 	case []interface{}:
 		if actualArray, ok := actual.([]interface{}); !ok {
-			errs = append(errs, fmt.Sprintf("value for key '%v' expected to be of type array", key))
+			errs = append(errs, wrongType(key, "array"))
 		} else {
-			errs = append(errs, validateArray(key, expected, actualArray)...)
+			errs = append(errs, v.validateArray(key, schemaKey, expected, actualArray)...)
 		}
+	case *constraint:
+		errs = append(errs, expected.validate(key, actual)...)
 	case map[string]interface{}:
 		if actualObj, ok := actual.(map[string]interface{}); !ok {
-			errs = append(errs, fmt.Sprintf("value for key '%v' expected to be of type object", key))
+			errs = append(errs, wrongType(key, "object"))
 		} else {
-			errs = append(errs, validateObject(key, expected, actualObj)...)
+			errs = append(errs, v.validateObject(key, schemaKey, expected, actualObj)...)
 		}
 	}
 
 	return errs
 }
 
-func validateArray(key string, expected []interface{}, actual []interface{}) []string {
+func (v validator) validateArray(key string, schemaKey string, expected []interface{}, actual []interface{}) []ValidationError {
 	if len(expected) == 0 {
-		return []string{}
+		return []ValidationError{}
 	}
 
-	errs := make([]string, 0)
+	errs := make([]ValidationError, 0)
 
 	for i, actualVal := range actual {
-		errs = append(errs, validateSingle(fmt.Sprintf("%v[%v]", key, i), expected[0], actualVal)...)
+		errs = append(errs, v.validateSingle(fmt.Sprintf("%v[%v]", key, i), schemaKey+"[]", expected[0], actualVal)...)
 	}
 
 	return errs
 }
+
+func wrongType(key string, typ string) ValidationError {
+	return ValidationError{Key: key, Code: CodeWrongType, Params: map[string]string{"type": typ}}
+}
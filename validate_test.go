@@ -147,3 +147,76 @@ func TestParseSchemaReturnsErrIfNotJSON(t *testing.T) {
 	_, err := parseSchema("not json")
 	assert.NotNil(t, err)
 }
+
+func TestValidateReqBodyOrdersErrorsAlphabeticallyByDefault(t *testing.T) {
+	var expected, actual map[string]interface{}
+	json.Unmarshal([]byte(`{"c": "", "?a": "", "b": { "z": "", "y": "" }}`), &expected)
+	json.Unmarshal([]byte(`{"a": 0, "b": {}}`), &actual)
+
+	errs := validateReqBody(expected, actual)
+	assert.Equal(t, []string{
+		"value for key 'a' expected to be of type string",
+		"expected key 'b.y' missing",
+		"expected key 'b.z' missing",
+		"expected key 'c' missing",
+	}, errs)
+}
+
+func TestValidateReqBodyOrdersErrorsByDeclaration(t *testing.T) {
+	schemaJSON := `{"c": "", "?a": "", "b": [{ "z": "", "y": "" }, "ignored"]}`
+	expected, _ := parseSchema(schemaJSON)
+	keyOrder, _ := parseKeyOrder(schemaJSON)
+
+	var actual map[string]interface{}
+	json.Unmarshal([]byte(`{"a": 0, "b": [{}]}`), &actual)
+
+	v := validator{order: OrderDeclaration, keyOrder: keyOrder}
+	errs := v.validateReqBody(expected, actual)
+	assert.Equal(t, []string{
+		"expected key 'c' missing",
+		"value for key 'a' expected to be of type string",
+		"expected key 'b[0].z' missing",
+		"expected key 'b[0].y' missing",
+	}, errorMessages(errs))
+}
+
+func TestValidateReqBodyReportsRedeclaredKeyOnceByDeclaration(t *testing.T) {
+	schemaJSON := `{"b": "", "a": "", "b": 0}`
+	expected, _ := parseSchema(schemaJSON)
+	keyOrder, _ := parseKeyOrder(schemaJSON)
+	assert.Equal(t, []string{"b", "a"}, keyOrder[""])
+
+	v := validator{order: OrderDeclaration, keyOrder: keyOrder}
+	errs := v.validateReqBody(expected, bodyMap(`{"a": "", "b": true}`))
+	assert.Equal(t, []string{"value for key 'b' expected to be of type number"}, errorMessages(errs))
+}
+
+func TestParseKeyOrderReturnsNilIfSchemaEmpty(t *testing.T) {
+	keyOrder, err := parseKeyOrder("")
+	assert.Nil(t, err)
+	assert.Nil(t, keyOrder)
+}
+
+func TestParseKeyOrderRecordsNestedKeys(t *testing.T) {
+	keyOrder, err := parseKeyOrder(`{"b": 0, "?a": { "d": [{ "f": "", "e": "" }], "c": "" }}`)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string][]string{
+		"":      {"b", "?a"},
+		"a":     {"d", "c"},
+		"a.d[]": {"f", "e"},
+	}, keyOrder)
+}
+
+func TestExtractSchemaMetaRemovesMetaKeys(t *testing.T) {
+	schema, _ := parseSchema(`{"$schemaName": "CreatePost", "$description": "creates a post", "title": ""}`)
+	meta, err := extractSchemaMeta(schema)
+	assert.Nil(t, err)
+	assert.Equal(t, schemaMeta{name: "CreatePost", description: "creates a post"}, meta)
+	assert.Equal(t, map[string]interface{}{"title": ""}, schema)
+}
+
+func TestExtractSchemaMetaReturnsErrIfNameNotString(t *testing.T) {
+	schema, _ := parseSchema(`{"$schemaName": 5}`)
+	_, err := extractSchemaMeta(schema)
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,75 @@
+package jsonbody
+
+import "context"
+
+// Validator checks a decoded request body, returning the problems it finds. The
+// middleware runs the request's schema followed by the validators registered
+// with WithValidators, in order, and reports all of their errors together.
+// Validators allow reusable checks that can't be expressed in a schema (like a
+// profanity filter) to be published as packages.
+//
+// body is the decoded JSON body, as described by encoding/json, or nil if the
+// request has no body.
+type Validator interface {
+	Validate(ctx context.Context, body interface{}) []ValidationError
+}
+
+// ValidatorFunc is an adapter to allow the use of ordinary functions as
+// Validators.
+type ValidatorFunc func(ctx context.Context, body interface{}) []ValidationError
+
+// Validate calls f(ctx, body).
+func (f ValidatorFunc) Validate(ctx context.Context, body interface{}) []ValidationError {
+	return f(ctx, body)
+}
+
+// Validate implements Validator, checking body against the schema. Unlike the
+// middleware, it doesn't check query parameters, since it has no request.
+func (s *Schema) Validate(ctx context.Context, body interface{}) []ValidationError {
+	return s.validate(OrderAlphabetical, body, nil)
+}
+
+// runValidators runs the validators registered with WithValidators.
+func (m *middleware) runValidators(ctx context.Context, body interface{}) []ValidationError {
+	errs := make([]ValidationError, 0)
+	for _, v := range m.validators {
+		errs = append(errs, v.Validate(ctx, body)...)
+	}
+
+	return errs
+}
+
+// pathValidator is a validator registered with WithPathValidator.
+type pathValidator struct {
+	path     string
+	validate func(value interface{}) error
+}
+
+// runPathValidators calls the validators registered with WithPathValidator for
+// the values in body that they apply to, skipping values that already have
+// errors in errs so that validators can rely on the schema's types.
+func (m *middleware) runPathValidators(body interface{}, errs []ValidationError) []ValidationError {
+	pathErrs := make([]ValidationError, 0)
+	if len(m.pathValidators) == 0 || body == nil {
+		return pathErrs
+	}
+
+	invalid := make(map[string]bool, len(errs))
+	for _, e := range errs {
+		invalid[e.Key] = true
+	}
+
+	for _, v := range m.pathValidators {
+		for _, match := range matchPath(body, v.path) {
+			if invalid[match.key] {
+				continue
+			}
+
+			if err := v.validate(match.value); err != nil {
+				pathErrs = append(pathErrs, ValidationError{Key: match.key, Code: CodeInvalidValue, Params: map[string]string{"reason": err.Error()}})
+			}
+		}
+	}
+
+	return pathErrs
+}
@@ -0,0 +1,92 @@
+package jsonbody
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestSchemaValidateImplementsValidator(t *testing.T) {
+	var v Validator = MustParseSchema(`{"title": ""}`)
+
+	errs := v.Validate(context.Background(), bodyMap(`{}`))
+	assert.Equal(t, []string{"expected key 'title' missing"}, errorMessages(errs))
+
+	errs = v.Validate(context.Background(), []interface{}{})
+	assert.Equal(t, []string{"body expected to be of type object"}, errorMessages(errs))
+}
+
+func TestServeHTTPRunsValidatorsInOrder(t *testing.T) {
+	next := &mockHandler{}
+	next.On("ServeHTTP", mock.Anything, mock.Anything).Return()
+
+	var gotBody interface{}
+	first := ValidatorFunc(func(ctx context.Context, body interface{}) []ValidationError {
+		gotBody = body
+		return []ValidationError{{Key: "title", Code: CodeInvalidValue, Params: map[string]string{"reason": "contains profanity"}}}
+	})
+	second := ValidatorFunc(func(ctx context.Context, body interface{}) []ValidationError {
+		return []ValidationError{{Key: "email", Code: CodeInvalidValue, Params: map[string]string{"reason": "looks like PII"}}}
+	})
+	handler := NewMiddleware(`{"title": "", "count": 0}`, WithValidators(first, second))(next)
+
+	recorder := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"title": "darn"}`))
+	r.Header.Set("Content-Type", "application/json")
+	handler.ServeHTTP(recorder, r)
+
+	assert.Equal(t, 400, recorder.Code)
+	assert.Equal(t, map[string]interface{}{"title": "darn"}, gotBody)
+	assert.Equal(t, `{"errors":["expected key 'count' missing","value for key 'title' is invalid: contains profanity","value for key 'email' is invalid: looks like PII"]}`, recorder.Body.String())
+}
+
+func TestServeHTTPRunsPathValidators(t *testing.T) {
+	var checked []interface{}
+	handler := NewMiddleware(`{"author": {"email": ""}, "items": [{"sku": ""}]}`,
+		WithPathValidator("author.email", func(v interface{}) error {
+			checked = append(checked, v)
+			if !strings.Contains(v.(string), "@") {
+				return errors.New("not an email address")
+			}
+			return nil
+		}),
+		WithPathValidator("items[].sku", func(v interface{}) error {
+			checked = append(checked, v)
+			return nil
+		}),
+	)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"author": {"email": "nope"}, "items": [{"sku": 1}, {"sku": "b"}]}`))
+	req.Header.Set("Content-Type", "application/json")
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, req)
+
+	assert.Equal(t, 400, recorder.Code)
+	assert.Equal(t, `{"errors":["value for key 'items[0].sku' expected to be of type string","value for key 'author.email' is invalid: not an email address"]}`, recorder.Body.String())
+	assert.Equal(t, []interface{}{"nope", "b"}, checked)
+}
+
+func TestServeHTTPRunsPathValidatorsOnArrayBodies(t *testing.T) {
+	handler := NewMiddleware(`[{"sku": ""}]`,
+		WithPathValidator("[].sku", func(v interface{}) error {
+			if v == "bad" {
+				return errors.New("unknown SKU")
+			}
+			return nil
+		}),
+	)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`[{"sku": "a"}, {"sku": "bad"}]`))
+	req.Header.Set("Content-Type", "application/json")
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, req)
+
+	assert.Equal(t, 400, recorder.Code)
+	assert.Equal(t, `{"errors":["value for key '[1].sku' is invalid: unknown SKU"]}`, recorder.Body.String())
+}
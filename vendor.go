@@ -0,0 +1,110 @@
+package jsonbody
+
+import (
+	"context"
+	"fmt"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// VendorMediaType is a versioned vendor-specific JSON media type, like
+// "application/vnd.acme.post.v2+json".
+type VendorMediaType struct {
+	Vendor   string // e.g. "acme"
+	Resource string // e.g. "post", or "" if the media type doesn't name one
+	Version  int    // e.g. 2
+}
+
+// ParseVendorMediaType parses a media type of the form
+// "application/vnd.<vendor>[.<resource>].v<version>+json", ignoring any
+// parameters. The resource may contain dots.
+func ParseVendorMediaType(mediaType string) (VendorMediaType, error) {
+	typ, _, err := mime.ParseMediaType(mediaType)
+	if err != nil {
+		return VendorMediaType{}, fmt.Errorf("jsonbody: invalid media type '%v': %v", mediaType, err)
+	}
+
+	const prefix, suffix = "application/vnd.", "+json"
+	if !strings.HasPrefix(typ, prefix) || !strings.HasSuffix(typ, suffix) {
+		return VendorMediaType{}, fmt.Errorf("jsonbody: media type '%v' is not of the form %v<vendor>.v<version>%v", mediaType, prefix, suffix)
+	}
+
+	parts := strings.Split(typ[len(prefix):len(typ)-len(suffix)], ".")
+	if len(parts) < 2 || parts[0] == "" {
+		return VendorMediaType{}, fmt.Errorf("jsonbody: media type '%v' has no vendor or version", mediaType)
+	}
+
+	last := parts[len(parts)-1]
+	version, err := strconv.Atoi(strings.TrimPrefix(last, "v"))
+	if !strings.HasPrefix(last, "v") || err != nil || version < 0 {
+		return VendorMediaType{}, fmt.Errorf("jsonbody: media type '%v' has an invalid version", mediaType)
+	}
+
+	return VendorMediaType{
+		Vendor:   parts[0],
+		Resource: strings.Join(parts[1:len(parts)-1], "."),
+		Version:  version,
+	}, nil
+}
+
+// String returns the media type, e.g. "application/vnd.acme.post.v2+json".
+func (t VendorMediaType) String() string {
+	name := t.Vendor
+	if t.Resource != "" {
+		name += "." + t.Resource
+	}
+
+	return "application/vnd." + name + ".v" + strconv.Itoa(t.Version) + "+json"
+}
+
+// schemaVersions holds the schemas configured with WithSchemaVersions.
+type schemaVersions struct {
+	vendor  string
+	schemas map[int]*Schema
+}
+
+type mediaTypeContextKey struct{}
+
+// RequestMediaType returns the vendor media type negotiated for r by the
+// middleware configured with WithSchemaVersions, or false if there isn't one.
+func RequestMediaType(r *http.Request) (VendorMediaType, bool) {
+	t, ok := r.Context().Value(mediaTypeContextKey{}).(VendorMediaType)
+	return t, ok
+}
+
+// negotiate returns the vendor media type of r's body if it has one, or
+// otherwise the most preferred supported one in its Accept header. fromAccept
+// reports which of them it is. If the body's media type has an unsupported
+// version, an error response is returned.
+func (v *schemaVersions) negotiate(r *http.Request) (t VendorMediaType, ok bool, fromAccept bool, status int, verr ValidationError) {
+	if t, err := ParseVendorMediaType(r.Header.Get("Content-Type")); err == nil && strings.EqualFold(t.Vendor, v.vendor) {
+		if _, ok := v.schemas[t.Version]; !ok {
+			return t, false, false, http.StatusUnsupportedMediaType, ValidationError{
+				Code:   CodeUnsupportedVersion,
+				Params: map[string]string{"type": t.String()},
+			}
+		}
+		return t, true, false, 0, ValidationError{}
+	}
+
+	best := 0.0
+	for _, rng := range parseAccept(r.Header.Get("Accept")) {
+		accepted, err := ParseVendorMediaType(rng.typ)
+		if err != nil || !strings.EqualFold(accepted.Vendor, v.vendor) || rng.quality <= best {
+			continue
+		}
+
+		if _, supported := v.schemas[accepted.Version]; supported {
+			t, ok, best = accepted, true, rng.quality
+		}
+	}
+
+	return t, ok, true, 0, ValidationError{}
+}
+
+// withMediaType returns a copy of ctx carrying the negotiated media type.
+func withMediaType(ctx context.Context, t VendorMediaType) context.Context {
+	return context.WithValue(ctx, mediaTypeContextKey{}, t)
+}
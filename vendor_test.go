@@ -0,0 +1,95 @@
+package jsonbody
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseVendorMediaType(t *testing.T) {
+	typ, err := ParseVendorMediaType("application/vnd.acme.blog.post.v2+json; charset=utf-8")
+	assert.Nil(t, err)
+	assert.Equal(t, VendorMediaType{Vendor: "acme", Resource: "blog.post", Version: 2}, typ)
+	assert.Equal(t, "application/vnd.acme.blog.post.v2+json", typ.String())
+
+	typ, err = ParseVendorMediaType("application/vnd.acme.v10+json")
+	assert.Nil(t, err)
+	assert.Equal(t, VendorMediaType{Vendor: "acme", Version: 10}, typ)
+	assert.Equal(t, "application/vnd.acme.v10+json", typ.String())
+
+	for _, bad := range []string{
+		"application/json",
+		"application/vnd.acme+json",
+		"application/vnd.acme.post+json",
+		"application/vnd.acme.post.vx+json",
+		"application/vnd.acme.post.v2",
+		"text/vnd.acme.post.v2+json",
+	} {
+		_, err := ParseVendorMediaType(bad)
+		assert.NotNil(t, err, bad)
+	}
+}
+
+func TestServeHTTPSelectsSchemaVersion(t *testing.T) {
+	var negotiated VendorMediaType
+	handler := NewMiddleware(`{"a": ""}`, WithSchemaVersions("acme", map[int]string{
+		1: `{"a": ""}`,
+		2: `{"a": 0}`,
+	}))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		negotiated, _ = RequestMediaType(r)
+		writer := w.(Writer)
+		writer.WriteJSON(http.StatusOK, map[string]int{"a": 1})
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"a": 1}`))
+	req.Header.Set("Content-Type", "application/vnd.acme.post.v2+json")
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, req)
+
+	assert.Equal(t, 200, recorder.Code, recorder.Body.String())
+	assert.Equal(t, "application/vnd.acme.post.v2+json", recorder.Header().Get("Content-Type"))
+	assert.Equal(t, VendorMediaType{Vendor: "acme", Resource: "post", Version: 2}, negotiated)
+
+	req = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"a": 1}`))
+	req.Header.Set("Content-Type", "application/vnd.acme.post.v1+json")
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, req)
+
+	assert.Equal(t, 400, recorder.Code)
+
+	req = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"a": 1}`))
+	req.Header.Set("Content-Type", "application/vnd.acme.post.v3+json")
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, req)
+
+	assert.Equal(t, 415, recorder.Code)
+	assert.Equal(t, `{"errors":["media type application/vnd.acme.post.v3+json is not supported"]}`, recorder.Body.String())
+}
+
+func TestServeHTTPNegotiatesVersionFromAccept(t *testing.T) {
+	handler := NewMiddleware("", WithSchemaVersions("acme", map[int]string{
+		1: "",
+		2: "",
+	}))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writer := w.(Writer)
+		writer.WriteJSON(http.StatusOK, map[string]int{"a": 1})
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept", "application/vnd.acme.post.v3+json, application/vnd.acme.post.v1+json;q=0.9, application/vnd.acme.post.v2+json;q=0.5")
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, req)
+
+	assert.Equal(t, 200, recorder.Code, recorder.Body.String())
+	assert.Equal(t, "application/vnd.acme.post.v1+json", recorder.Header().Get("Content-Type"))
+	assert.Equal(t, "Accept", recorder.Header().Get("Vary"))
+
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, req)
+
+	assert.Equal(t, "application/json", recorder.Header().Get("Content-Type"))
+}
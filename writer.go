@@ -1,11 +1,13 @@
 package jsonbody
 
 import (
-	"encoding/json"
+	"encoding/base64"
 	"errors"
 	"fmt"
 	"log"
 	"net/http"
+	"strconv"
+	"strings"
 )
 
 // Writer is an extension of a generic http.ResponseWriter. It provides methods
@@ -14,23 +16,57 @@ import (
 type Writer struct {
 	http.ResponseWriter
 	written bool
+	config  *writerConfig
+	fields  fieldSelection
+	head    bool   // whether the body is discarded because the request is HEAD
+	locale  string // the locale of error messages, if translations are configured
+
+	codeNamespace  string // the namespace of errors sent by the middleware
+	acceptEncoding string // the request's Accept-Encoding header
+	timing         Timing
+	writes         *writeTracker // nil unless write diagnostics are enabled
+	appended       *appendState  // nil unless AppendJSON is enabled
+	mediaType      string        // the Content-Type of JSON responses, if not application/json
+	pretty         bool          // whether the client asked for indented JSON
 }
 
 // WriteJSON encodes an object as JSON and sends it as the response body, along
-// with the Content-Type header. This method or WriteErrors can only be called
-// once, unless they return an error.
+// with statusCode (e.g. http.StatusCreated) and the Content-Type header. The
+// status code is sent after the headers are set, so WriteHeader shouldn't be
+// called first. This method or WriteErrors can only be called once, unless they
+// return an error. See AppendJSON for writing a response in several pieces.
 func (w *Writer) WriteJSON(statusCode int, body interface{}) error {
 	if w.written {
 		return errors.New("method has already been called once and cannot be called again")
 	}
 
-	bytes, err := json.Marshal(body)
+	w.writes.beginJSON()
+	defer w.writes.endJSON()
+
+	bytes, err := w.encode(statusCode, body)
+	if err == nil {
+		bytes, err = w.indent(bytes)
+	}
 	if err != nil {
 		log.Println(fmt.Errorf("jsonbody: failed to encode body: %v", err))
 		return errors.New("encoding the response body as JSON failed")
 	}
 
-	w.Header().Set("Content-Type", "application/json")
+	if w.config != nil && w.config.signer != nil {
+		sig, err := w.config.signer.Sign(bytes)
+		if err != nil {
+			log.Println(fmt.Errorf("jsonbody: failed to sign body: %v", err))
+			return errors.New("signing the response body failed")
+		}
+
+		w.Header().Set(w.config.signatureHeader, base64.StdEncoding.EncodeToString(sig))
+	}
+
+	w.setDefaultHeaders()
+	w.Header().Set("Content-Type", w.jsonContentType())
+	if w.head {
+		w.Header().Set("Content-Length", strconv.Itoa(len(bytes)))
+	}
 	w.WriteHeader(statusCode)
 
 	_, err = w.Write(bytes)
@@ -44,9 +80,20 @@ func (w *Writer) WriteJSON(statusCode int, body interface{}) error {
 	return nil
 }
 
+// jsonContentType returns the Content-Type of JSON responses, which is the
+// negotiated vendor media type if there is one.
+func (w *Writer) jsonContentType() string {
+	if w.mediaType != "" {
+		return w.mediaType
+	}
+
+	return "application/json"
+}
+
 // WriteErrors encodes the given errors as a JSON array assigned to the key "errors"
-// and sends it as the response body. This method or WriteJSON can only be called
-// once, unless they return an error.
+// and sends it as the response body, along with statusCode, in the same way as
+// WriteJSON. This method or WriteJSON can only be called once, unless they
+// return an error.
 func (w *Writer) WriteErrors(statusCode int, errs ...string) error {
 	err := w.WriteJSON(statusCode, map[string][]string{
 		"errors": errs,
@@ -54,3 +101,136 @@ func (w *Writer) WriteErrors(statusCode int, errs ...string) error {
 
 	return err
 }
+
+// WriteErrorsWithData is like WriteErrors, but also sends data (encoded in the
+// same way as by WriteJSON) under the key "data" in the same envelope:
+//
+//	{
+//		"errors": [ <list of error strings> ],
+//		"data": <data>
+//	}
+//
+// This allows responses that are partially successful, such as a 207
+// Multi-Status response to a batch request in which some items failed, to
+// report both the results and the errors.
+func (w *Writer) WriteErrorsWithData(statusCode int, data interface{}, errs ...string) error {
+	if errs == nil {
+		errs = []string{}
+	}
+
+	return w.WriteJSON(statusCode, dataEnvelope{errs, data})
+}
+
+// dataEnvelope is the response body sent by WriteErrorsWithData.
+type dataEnvelope struct {
+	Errors []string    `json:"errors"`
+	Data   interface{} `json:"data"`
+}
+
+// writeErrors sends errs in the same way as WriteErrors, translating their
+// messages into the Writer's locale if translations are configured. If error
+// objects are enabled, each error is sent as an errorObject instead of a string,
+// and if problem details are enabled, the errors are sent in a problem details
+// document instead of the usual envelope. A configured ErrorFormatter takes
+// precedence over all of these.
+func (w *Writer) writeErrors(statusCode int, errs ...ValidationError) error {
+	for i := range errs {
+		if errs[i].Namespace == "" {
+			errs[i].Namespace = w.codeNamespace
+		}
+	}
+
+	if w.config != nil && w.config.errorFormatter != nil {
+		return w.writeFormattedErrors(statusCode, errs)
+	}
+
+	if w.config != nil && w.config.translations != nil {
+		w.Header().Set("Content-Language", w.locale)
+	}
+
+	msgs := make([]string, len(errs))
+	for i, e := range errs {
+		msgs[i] = w.message(e)
+	}
+
+	var body interface{} = msgs
+	if w.config != nil && w.config.errorObjects {
+		objs := make([]errorObject, len(errs))
+		for i, e := range errs {
+			objs[i] = errorObject{Field: e.Key, Code: e.QualifiedCode(), Message: msgs[i]}
+		}
+		body = objs
+	}
+
+	if w.config != nil && w.config.problemType != "" {
+		var detail string
+		if len(msgs) == 1 {
+			detail = msgs[0]
+		}
+
+		return w.writeProblem(statusCode, body, detail)
+	}
+
+	return w.WriteJSON(statusCode, map[string]interface{}{
+		"errors": body,
+	})
+}
+
+// errorObject is the form of an error sent when WithErrorObjects is used.
+type errorObject struct {
+	Field   string `json:"field,omitempty"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// message returns the message for e, translated into the Writer's locale if
+// translations are configured.
+func (w *Writer) message(e ValidationError) string {
+	if w.config == nil || w.config.translations == nil {
+		return e.Error()
+	}
+
+	return w.config.translations.Translate(w.locale, e)
+}
+
+// WriteValidated checks body against the rules in the jsonbody struct tags of
+// its fields before sending it as the response body in the same way as
+// WriteJSON. If body violates any of the rules, nothing is written and a
+// *ResponseValidationError listing the violations is returned, since sending the
+// body would break the API's contract.
+//
+// The supported rules are:
+//
+//	required    the value must not be the zero value (or a nil/empty slice or map)
+//	min=<n>     numbers must be at least n; strings, slices, and maps must have at least n elements
+//	max=<n>     numbers must be at most n; strings, slices, and maps must have at most n elements
+//	oneof=<a b> the value must be one of the space-separated values
+//
+// The elements of strings are their characters, not bytes.
+//
+// For example:
+//
+//	type Post struct {
+//		Title   string `json:"title" jsonbody:"required,max=200"`
+//		Status  string `json:"status" jsonbody:"oneof=draft published"`
+//		Upvotes int    `json:"upvotes" jsonbody:"min=0"`
+//	}
+func (w *Writer) WriteValidated(statusCode int, body interface{}) error {
+	errs := validateStruct(body)
+	if len(errs) > 0 {
+		log.Printf("jsonbody: response body failed validation: %v\n", errs)
+		return &ResponseValidationError{Errors: errs}
+	}
+
+	return w.WriteJSON(statusCode, body)
+}
+
+// ResponseValidationError is returned by WriteValidated when the response body
+// violates the rules in its struct tags.
+type ResponseValidationError struct {
+	Errors []string
+}
+
+func (e *ResponseValidationError) Error() string {
+	return "jsonbody: response body failed validation: " + strings.Join(e.Errors, "; ")
+}
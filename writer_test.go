@@ -192,3 +192,61 @@ func TestWriteErrorsWritesMultipleErrors(t *testing.T) {
 
 	assert.Equal(t, []byte(`{"errors":["error1","error2","error3"]}`), mockRW.lastBytes)
 }
+
+func TestWriteErrorsWithDataWritesBoth(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	w := Writer{ResponseWriter: recorder}
+
+	err := w.WriteErrorsWithData(207, []map[string]int{{"id": 1}}, "item 2: expected key 'title' missing")
+	assert.Nil(t, err)
+
+	assert.Equal(t, 207, recorder.Code)
+	assert.Equal(t, `{"errors":["item 2: expected key 'title' missing"],"data":[{"id":1}]}`, recorder.Body.String())
+}
+
+func TestWriteErrorsWithDataWritesEmptyErrors(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	w := Writer{ResponseWriter: recorder}
+
+	err := w.WriteErrorsWithData(200, "ok")
+	assert.Nil(t, err)
+
+	assert.Equal(t, `{"errors":[],"data":"ok"}`, recorder.Body.String())
+}
+
+func TestWriteValidatedWritesValidBody(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	w := Writer{ResponseWriter: recorder}
+
+	err := w.WriteValidated(201, taggedAuthor{Name: "jo"})
+	assert.Nil(t, err)
+
+	assert.Equal(t, 201, recorder.Code)
+	assert.Equal(t, `{"name":"jo"}`, recorder.Body.String())
+}
+
+func TestWriteValidatedReturnsErrAndNotWriteIfInvalid(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	w := Writer{ResponseWriter: recorder}
+
+	err := w.WriteValidated(201, taggedAuthor{})
+	assert.IsType(t, &ResponseValidationError{}, err)
+	assert.Equal(t, 0, recorder.Body.Len())
+	assert.False(t, w.written)
+}
+
+func TestWriteJSONAndWriteErrorsSendStatusCode(t *testing.T) {
+	for _, code := range []int{http.StatusCreated, http.StatusAccepted, http.StatusMultiStatus} {
+		recorder := httptest.NewRecorder()
+		w := Writer{ResponseWriter: recorder}
+		assert.Nil(t, w.WriteJSON(code, map[string]string{"id": "1"}))
+		assert.Equal(t, code, recorder.Code)
+		assert.Equal(t, "application/json", recorder.Header().Get("Content-Type"))
+
+		recorder = httptest.NewRecorder()
+		w = Writer{ResponseWriter: recorder}
+		assert.Nil(t, w.WriteErrors(code, "partial failure"))
+		assert.Equal(t, code, recorder.Code)
+		assert.JSONEq(t, `{"errors": ["partial failure"]}`, recorder.Body.String())
+	}
+}